@@ -0,0 +1,81 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// ErrDaemonNotReachable is returned by the calls which talk to the HTTP API
+// of the daemon, like `GetTar`, when the API cannot be found.
+var ErrDaemonNotReachable = errors.New("ipfs daemon api is not reachable")
+
+func (wrap *ipfsCliWrapper) GetTar(ctx context.Context, cid string) (io.ReadCloser, error) {
+	// `ipfs get --archive` always writes the archive to a file, so the
+	// archive is streamed from the HTTP API of the daemon instead, which is
+	// what the command reads it from too.
+	hostPort, ok := wrap.apiHostPort()
+	if !ok {
+		return nil, ErrDaemonNotReachable
+	}
+	endpoint := "http://" + hostPort + "/api/v0/get?archive=true&arg=" + url.QueryEscape(cid)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		wrap.logger.Error("error requesting tar archive from ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err))
+		return nil, fmt.Errorf("failed to get tar of `%s` from ipfs: %w", cid, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		// Failures are reported as a JSON object carrying the same message
+		// the command would print, so they map to the same errors.
+		var apiError struct {
+			Message string
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&apiError)
+		wrap.logger.Error("error getting tar archive from ipfs",
+			slog.String("cid", cid),
+			slog.String("status", resp.Status),
+			slog.String("message", apiError.Message))
+		return nil, wrap.commandError("get tar from ipfs", fmt.Errorf("unexpected status: %s", resp.Status), []byte(apiError.Message))
+	}
+	return &tarStream{resp: resp, cid: cid, wrap: wrap}, nil
+}
+
+// tarStream is the body of a `get` response. The daemon can only report a
+// failure which happens after streaming started, for example a block which
+// cannot be found, in the `X-Stream-Error` trailer, so the trailer is
+// checked once the body is drained.
+type tarStream struct {
+	resp *http.Response
+	cid  string
+	wrap *ipfsCliWrapper
+}
+
+func (s *tarStream) Read(p []byte) (int, error) {
+	n, err := s.resp.Body.Read(p)
+	if err == io.EOF {
+		if message := s.resp.Trailer.Get("X-Stream-Error"); message != "" {
+			s.wrap.logger.Error("error streaming tar archive from ipfs",
+				slog.String("cid", s.cid),
+				slog.String("message", message))
+			return n, s.wrap.commandError("get tar from ipfs", errors.New("stream error"), []byte(message))
+		}
+	}
+	return n, err
+}
+
+func (s *tarStream) Close() error {
+	return s.resp.Body.Close()
+}
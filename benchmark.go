@@ -0,0 +1,147 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Defaults of the `BenchmarkOptions` fields.
+const (
+	DefaultBenchmarkDataSize   = 16 * 1024 * 1024
+	DefaultBenchmarkIterations = 3
+)
+
+// BenchmarkOptions controls what `Benchmark` measures. The zero value of
+// every field selects its default.
+type BenchmarkOptions struct {
+	// DataSize is the number of random bytes added per iteration. Defaults
+	// to `DefaultBenchmarkDataSize`.
+	DataSize int64
+
+	// Iterations is how many times every measurement is repeated. Defaults
+	// to `DefaultBenchmarkIterations`.
+	Iterations int
+
+	// ColdCIDs are objects which are not stored by the node, used to measure
+	// the latency of retrieving content from the network. The cold
+	// measurement is skipped when empty. Please note the objects are stored
+	// by the node afterwards, until the next garbage collection.
+	ColdCIDs []string
+}
+
+// BenchmarkReport holds the results of `Benchmark`. Durations are averages
+// over all iterations.
+type BenchmarkReport struct {
+	// DataSize and Iterations are the effective options of the run.
+	DataSize   int64
+	Iterations int
+
+	// AddDuration is how long adding `DataSize` bytes took and
+	// AddThroughput the resulting rate in bytes per second.
+	AddDuration   time.Duration
+	AddThroughput float64
+
+	// HotCatLatency is how long reading back the added content took.
+	HotCatLatency time.Duration
+
+	// ColdCatLatency is how long retrieving one of the `ColdCIDs` took, or
+	// zero if none were given.
+	ColdCatLatency time.Duration
+
+	// PinDuration is how long pinning the added content took.
+	PinDuration time.Duration
+}
+
+func (wrap *ipfsCliWrapper) Benchmark(ctx context.Context, opts BenchmarkOptions) (*BenchmarkReport, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if opts.DataSize <= 0 {
+		opts.DataSize = DefaultBenchmarkDataSize
+	}
+	if opts.Iterations <= 0 {
+		opts.Iterations = DefaultBenchmarkIterations
+	}
+	report := &BenchmarkReport{DataSize: opts.DataSize, Iterations: opts.Iterations}
+
+	data := make([]byte, opts.DataSize)
+	var addTotal, catTotal, pinTotal time.Duration
+	for i := 0; i < opts.Iterations; i++ {
+		// Fresh random data every iteration, so nothing is deduplicated.
+		if _, err := io.ReadFull(wrap.randomGenerator, data); err != nil {
+			return nil, fmt.Errorf("failed to generate benchmark data: %v", err)
+		}
+
+		// Measure the add without pinning, so pinning is measured on its own.
+		start := time.Now()
+		cmd := wrap.command(ctx, "add", "--quiet", "--cid-version=1", "--pin=false")
+		cmd.Stdin = bytes.NewReader(data)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		output, err := wrap.output(cmd)
+		if err != nil {
+			return nil, wrap.commandError("add benchmark data", err, stderr.Bytes())
+		}
+		addTotal += time.Since(start)
+		cid := string(bytes.TrimSpace(output))
+
+		start = time.Now()
+		if err := wrap.catToDiscard(ctx, cid); err != nil {
+			return nil, err
+		}
+		catTotal += time.Since(start)
+
+		start = time.Now()
+		if err := wrap.Pin(ctx, cid); err != nil {
+			return nil, err
+		}
+		pinTotal += time.Since(start)
+
+		// Leave the benchmark data to the next garbage collection.
+		if err := wrap.Unpin(ctx, cid); err != nil {
+			return nil, err
+		}
+	}
+	report.AddDuration = addTotal / time.Duration(opts.Iterations)
+	report.AddThroughput = float64(opts.DataSize) / report.AddDuration.Seconds()
+	report.HotCatLatency = catTotal / time.Duration(opts.Iterations)
+	report.PinDuration = pinTotal / time.Duration(opts.Iterations)
+
+	if len(opts.ColdCIDs) > 0 {
+		var coldTotal time.Duration
+		for _, cid := range opts.ColdCIDs {
+			start := time.Now()
+			if err := wrap.catToDiscard(ctx, cid); err != nil {
+				return nil, err
+			}
+			coldTotal += time.Since(start)
+		}
+		report.ColdCatLatency = coldTotal / time.Duration(len(opts.ColdCIDs))
+	}
+
+	wrap.logger.Debug("benchmark finished",
+		slog.Duration("add_duration", report.AddDuration),
+		slog.Float64("add_throughput", report.AddThroughput),
+		slog.Duration("hot_cat_latency", report.HotCatLatency),
+		slog.Duration("cold_cat_latency", report.ColdCatLatency),
+		slog.Duration("pin_duration", report.PinDuration))
+	return report, nil
+}
+
+// catToDiscard retrieves the content of the object without keeping it in
+// memory.
+func (wrap *ipfsCliWrapper) catToDiscard(ctx context.Context, cid string) error {
+	cmd := wrap.command(ctx, "cat", "--", cid)
+	cmd.Stdout = io.Discard
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := wrap.run(cmd); err != nil {
+		return wrap.commandError(fmt.Sprintf("cat `%s` from ipfs", cid), err, stderr.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package ipfscliwrapper
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// rebindAddress returns the multiaddress with its IP replaced by the given
+// one. If the address is empty, the current value of the configuration key
+// is used instead.
+func (wrap *ipfsCliWrapper) rebindAddress(addr string, configKey string, ip string) (string, error) {
+	if addr == "" {
+		current, err := wrap.getConfig(configKey)
+		if err != nil {
+			return "", err
+		}
+		addr = current
+	}
+	return rebindMultiaddr(addr, ip)
+}
+
+// rebindMultiaddr replaces the `/ip4` or `/ip6` component of the multiaddress
+// with the given IP, for example "/ip4/127.0.0.1/tcp/5001" rebound to
+// "192.168.1.10" becomes "/ip4/192.168.1.10/tcp/5001".
+func rebindMultiaddr(addr string, ip string) (string, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", fmt.Errorf("invalid bind address `%s`", ip)
+	}
+	protocol := "ip6"
+	if parsed.To4() != nil {
+		protocol = "ip4"
+	}
+
+	parts := strings.Split(addr, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "ip4" || parts[i] == "ip6" {
+			parts[i] = protocol
+			parts[i+1] = parsed.String()
+			return strings.Join(parts, "/"), nil
+		}
+	}
+	return "", fmt.Errorf("address `%s` has no ip4 or ip6 component to rebind", addr)
+}
+
+// warnIfExposed logs a warning if the multiaddress listens on all interfaces.
+// Anyone who can reach the API of kubo has full control over the node, so
+// exposing it should always be a deliberate decision.
+func (wrap *ipfsCliWrapper) warnIfExposed(name string, addr string) {
+	parts := strings.Split(addr, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] != "ip4" && parts[i] != "ip6" {
+			continue
+		}
+		if ip := net.ParseIP(parts[i+1]); ip != nil && ip.IsUnspecified() {
+			wrap.logger.Warn("SECURITY WARNING: ipfs "+name+" is exposed on all network interfaces, anyone who can reach this machine can use it; bind it to 127.0.0.1 unless this is intended",
+				"addr", addr)
+		}
+		return
+	}
+}
@@ -0,0 +1,223 @@
+package ipfscliwrapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
+)
+
+// kuboVersionsFileName is the name of the file, inside the kubo binary
+// directory, which tracks the installed kubo releases.
+const kuboVersionsFileName = "versions.json"
+
+// binLayoutVersion is the version of the layout of the binary directory. It
+// is incremented whenever the layout changes, so installations created by
+// older releases of this package get migrated, see `migrateBinLayout`.
+//
+//	0: the binary is installed to "bin/kubo/ipfs".
+//	1: every release is installed to "bin/kubo/<version>/ipfs".
+const binLayoutVersion = 1
+
+// kuboRelease is an installed kubo release, see `kuboVersions`.
+type kuboRelease struct {
+	Version     string    `json:"version"`
+	InstalledAt time.Time `json:"installed_at"`
+	LastUsed    time.Time `json:"last_used"`
+}
+
+// kuboVersions is the content of the versions file.
+type kuboVersions struct {
+	Layout    int           `json:"layout"`
+	Current   string        `json:"current"`
+	Installed []kuboRelease `json:"installed"`
+}
+
+// kuboDirPath returns the directory the kubo releases are installed into.
+func (wrap *ipfsCliWrapper) kuboDirPath() string {
+	return absPath(filepath.Join(wrap.binDir, "kubo"))
+}
+
+// kuboVersionDirPath returns the directory the kubo release is installed into.
+func (wrap *ipfsCliWrapper) kuboVersionDirPath(version string) string {
+	return filepath.Join(wrap.kuboDirPath(), version)
+}
+
+func (wrap *ipfsCliWrapper) kuboVersionsFilePath() string {
+	return filepath.Join(wrap.kuboDirPath(), kuboVersionsFileName)
+}
+
+// readKuboVersions returns the content of the versions file, or an empty
+// record of the unversioned layout if the file does not exist.
+func (wrap *ipfsCliWrapper) readKuboVersions() (*kuboVersions, error) {
+	var versions kuboVersions
+	b, err := os.ReadFile(wrap.kuboVersionsFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &versions, nil
+		}
+		return nil, fmt.Errorf("failed to read kubo versions file: %v", err)
+	}
+	if err := json.Unmarshal(b, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode kubo versions file: %v", err)
+	}
+	return &versions, nil
+}
+
+func (wrap *ipfsCliWrapper) writeKuboVersions(versions *kuboVersions) error {
+	b, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(wrap.kuboDirPath(), wrap.dirMode); err != nil {
+		return fmt.Errorf("failed creating kubo directory: %v", err)
+	}
+	if err := os.WriteFile(wrap.kuboVersionsFilePath(), b, 0644); err != nil {
+		return fmt.Errorf("failed to write kubo versions file: %v", err)
+	}
+	return nil
+}
+
+// migrateBinLayout moves the binaries installed by older releases of this
+// package into the current layout of the binary directory, see
+// `binLayoutVersion`. The repo, which lives in the same directory by default,
+// is left alone.
+func (wrap *ipfsCliWrapper) migrateBinLayout() error {
+	versions, err := wrap.readKuboVersions()
+	if err != nil {
+		return err
+	}
+	if versions.Layout >= binLayoutVersion {
+		return nil
+	}
+
+	// The unversioned binary does not tell which release it is, so ask it.
+	legacyPath := filepath.Join(wrap.kuboDirPath(), "ipfs")
+	if _, err := os.Stat(legacyPath); err == nil {
+		output, err := exec.Command(legacyPath, "version", "--number").Output()
+		if err != nil {
+			return fmt.Errorf("failed reading version of %s: %v", legacyPath, err)
+		}
+		version, err := versionkit.Parse(string(output))
+		if err != nil {
+			return fmt.Errorf("failed reading version of %s: %v", legacyPath, err)
+		}
+		dirPath := wrap.kuboVersionDirPath(version.String())
+		if err := os.MkdirAll(dirPath, wrap.dirMode); err != nil {
+			return fmt.Errorf("failed creating kubo directory: %v", err)
+		}
+		if err := moveIntoPlace(legacyPath, filepath.Join(dirPath, "ipfs")); err != nil {
+			return err
+		}
+		versions.Installed = append(versions.Installed, kuboRelease{
+			Version:     version.String(),
+			InstalledAt: time.Now(),
+		})
+		wrap.logger.Info("migrated ipfs binary to versioned layout",
+			slog.String("version", version.String()),
+			slog.String("path", dirPath))
+	}
+
+	versions.Layout = binLayoutVersion
+	return wrap.writeKuboVersions(versions)
+}
+
+// recordKuboVersionInUse records the requested kubo release as the current
+// one, so the previous releases can be told apart by `PruneKuboVersions`.
+func (wrap *ipfsCliWrapper) recordKuboVersionInUse() error {
+	versions, err := wrap.readKuboVersions()
+	if err != nil {
+		return err
+	}
+	if versions.Current != "" && versions.Current != wrap.kuboVersion {
+		wrap.logger.Info("switched kubo release",
+			slog.String("from", versions.Current),
+			slog.String("to", wrap.kuboVersion))
+	}
+	versions.Layout = binLayoutVersion
+	versions.Current = wrap.kuboVersion
+
+	now := time.Now()
+	for i := range versions.Installed {
+		if versions.Installed[i].Version == wrap.kuboVersion {
+			versions.Installed[i].LastUsed = now
+			return wrap.writeKuboVersions(versions)
+		}
+	}
+	versions.Installed = append(versions.Installed, kuboRelease{
+		Version:     wrap.kuboVersion,
+		InstalledAt: now,
+		LastUsed:    now,
+	})
+	return wrap.writeKuboVersions(versions)
+}
+
+func (wrap *ipfsCliWrapper) InstalledKuboVersions() ([]string, error) {
+	versions, err := wrap.readKuboVersions()
+	if err != nil {
+		return nil, err
+	}
+	installed := make([]string, 0, len(versions.Installed))
+	for _, release := range versions.Installed {
+		if _, err := os.Stat(filepath.Join(wrap.kuboVersionDirPath(release.Version), "ipfs")); err == nil {
+			installed = append(installed, release.Version)
+		}
+	}
+	sort.Slice(installed, func(i, j int) bool {
+		a, errA := versionkit.Parse(installed[i])
+		b, errB := versionkit.Parse(installed[j])
+		if errA != nil || errB != nil {
+			return installed[i] < installed[j]
+		}
+		return a.Compare(b) < 0
+	})
+	return installed, nil
+}
+
+func (wrap *ipfsCliWrapper) PruneKuboVersions(keep int) ([]string, error) {
+	versions, err := wrap.readKuboVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Never remove the release a running daemon was started from.
+	inUse := map[string]bool{versions.Current: true, wrap.kuboVersion: true}
+	if info := wrap.readDaemonInfo(); info != nil && wrap.isDaemonAlive(info) {
+		inUse[info.KuboVersion] = true
+	}
+
+	// Keep the most recently used previous releases for rollbacks.
+	previous := make([]kuboRelease, 0, len(versions.Installed))
+	kept := make([]kuboRelease, 0, len(versions.Installed))
+	for _, release := range versions.Installed {
+		if inUse[release.Version] {
+			kept = append(kept, release)
+		} else {
+			previous = append(previous, release)
+		}
+	}
+	sort.SliceStable(previous, func(i, j int) bool {
+		return previous[i].LastUsed.After(previous[j].LastUsed)
+	})
+
+	var removed []string
+	for i, release := range previous {
+		if i < keep {
+			kept = append(kept, release)
+			continue
+		}
+		if err := os.RemoveAll(wrap.kuboVersionDirPath(release.Version)); err != nil {
+			return removed, fmt.Errorf("failed removing kubo release %s: %v", release.Version, err)
+		}
+		removed = append(removed, release.Version)
+		wrap.logger.Debug("removed kubo release", slog.String("version", release.Version))
+	}
+	versions.Installed = kept
+	return removed, wrap.writeKuboVersions(versions)
+}
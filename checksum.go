@@ -0,0 +1,81 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
+)
+
+// ErrChecksumMismatch is returned by `NewWrapper` when the downloaded kubo
+// archive does not match the checksum published next to it, or the binary
+// it holds is not the requested release.
+var ErrChecksumMismatch = errors.New("kubo archive checksum mismatch")
+
+// verifyArchiveChecksum compares the archive with the SHA-512 checksum which
+// dist.ipfs.tech publishes next to every archive, e.g.
+// "kubo_v0.29.0_linux-amd64.tar.gz.sha512".
+func (wrap *ipfsCliWrapper) verifyArchiveChecksum(ctx context.Context, archiveURL string, archivePath string) error {
+	checksumPath := archivePath + ".sha512"
+	if err := wrap.urlDownloader.DownloadFile(ctx, archiveURL+".sha512", checksumPath); err != nil {
+		return fmt.Errorf("failed downloading the checksum: %v", err)
+	}
+	defer os.Remove(checksumPath)
+
+	// The file holds the hex encoded checksum followed by the file name.
+	content, err := os.ReadFile(checksumPath)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: empty checksum file", ErrChecksumMismatch)
+	}
+	expected, err := hex.DecodeString(fields[0])
+	if err != nil || len(expected) != sha512.Size {
+		return fmt.Errorf("%w: malformed checksum %q", ErrChecksumMismatch, fields[0])
+	}
+
+	archive, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+	hash := sha512.New()
+	if _, err := io.Copy(hash, archive); err != nil {
+		return fmt.Errorf("failed reading the archive: %v", err)
+	}
+	if actual := hash.Sum(nil); !bytes.Equal(actual, expected) {
+		return fmt.Errorf("%w: %s has checksum %x, expected %x", ErrChecksumMismatch, filepath.Base(archivePath), actual, expected)
+	}
+	return nil
+}
+
+// verifyKuboBinary runs the extracted binary, before it is moved into place,
+// and checks it is the requested kubo release.
+func (wrap *ipfsCliWrapper) verifyKuboBinary(ctx context.Context, binaryPath string) error {
+	// The binary is not installed yet so it cannot run through
+	// `baseCommand`, and printing its version does not open any repo.
+	output, err := exec.CommandContext(ctx, binaryPath, "version", "--number").Output()
+	if err != nil {
+		return wrap.commandError("run extracted ipfs binary", err, output)
+	}
+	actual, err := versionkit.Parse(strings.TrimSpace(string(output)))
+	if err != nil {
+		return fmt.Errorf("%w: unreadable version %q: %v", ErrChecksumMismatch, output, err)
+	}
+	expected, err := versionkit.Parse(wrap.kuboVersion)
+	if err == nil && actual.Compare(expected) != 0 {
+		return fmt.Errorf("%w: extracted kubo %s, expected %s", ErrChecksumMismatch, actual, expected)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+	"golift.io/xtractr"
+)
+
+// Constants related to the optional ipfs-cluster companion binaries.
+const (
+	// IPFSClusterVersion defines the release of the ipfs-cluster binaries
+	// which get downloaded when cluster support is enabled.
+	IPFSClusterVersion = "v1.1.1"
+
+	// IPFSClusterServiceBinaryFilePath defines the path to the
+	// `ipfs-cluster-service` executable used to run a full cluster peer.
+	IPFSClusterServiceBinaryFilePath = "./bin/ipfs-cluster-service/ipfs-cluster-service"
+
+	// IPFSClusterFollowBinaryFilePath defines the path to the
+	// `ipfs-cluster-follow` executable used to join collaborative clusters
+	// as a follower peer.
+	IPFSClusterFollowBinaryFilePath = "./bin/ipfs-cluster-follow/ipfs-cluster-follow"
+
+	// IPFSClusterCtlBinaryFilePath defines the path to the `ipfs-cluster-ctl`
+	// executable used to issue pin and status commands to the cluster peer.
+	IPFSClusterCtlBinaryFilePath = "./bin/ipfs-cluster-ctl/ipfs-cluster-ctl"
+
+	// IPFSClusterDataDirPath defines the path to the directory where the
+	// cluster peer stores its configuration, identity and state.
+	IPFSClusterDataDirPath = "./bin/ipfs-cluster/data"
+)
+
+// Constants representing the ways the wrapper can participate in a cluster.
+const (
+	// ClusterServiceMode runs `ipfs-cluster-service`, a full cluster peer
+	// which can both pin content and accept pins from other peers.
+	ClusterServiceMode = "service"
+
+	// ClusterFollowMode runs `ipfs-cluster-follow`, a follower peer which
+	// replicates the pinset of a collaborative cluster.
+	ClusterFollowMode = "follow"
+)
+
+// ClusterPeerPinStatus represents the status of a pin on a single cluster peer.
+type ClusterPeerPinStatus struct {
+	PeerName string `json:"peername"`
+	IPFSPeer string `json:"ipfs_peer_id"`
+	Status   string `json:"status"`
+	Error    string `json:"error"`
+}
+
+// ClusterPinStatus represents the structured data of the `ipfs-cluster-ctl
+// status` command results.
+type ClusterPinStatus struct {
+	CID     string                          `json:"-"`
+	Name    string                          `json:"name"`
+	PeerMap map[string]ClusterPeerPinStatus `json:"peer_map"`
+}
+
+// clusterConfig holds the settings of the companion cluster peer.
+type clusterConfig struct {
+	// mode is either `ClusterServiceMode` or `ClusterFollowMode`.
+	mode string
+
+	// secret is the shared cluster secret used when initializing a service peer.
+	secret string
+
+	// bootstrap holds the multiaddresses of the peers a service peer joins on startup.
+	bootstrap []string
+
+	// followName is the name of the collaborative cluster to follow.
+	followName string
+
+	// followInitURL is the URL (or ipns path) of the follower configuration.
+	followInitURL string
+}
+
+// getClusterDownloadURL provides a download link for a zipped binary of one of
+// the ipfs-cluster programs (`ipfs-cluster-service`, `ipfs-cluster-follow` or
+// `ipfs-cluster-ctl`) based on the specified operating system and architecture.
+func getClusterDownloadURL(program string, os string, arch string) (string, error) {
+	supported := map[string][]string{
+		"darwin":  {"arm64", "amd64"},
+		"linux":   {"arm", "arm64", "386", "amd64"},
+		"freebsd": {"arm", "386", "amd64"},
+		"openbsd": {"arm", "386", "amd64"},
+		"windows": {"386", "amd64"},
+	}
+	for _, supportedArch := range supported[os] {
+		if supportedArch != arch {
+			continue
+		}
+		ext := "tar.gz"
+		if os == "windows" {
+			ext = "zip"
+		}
+		return fmt.Sprintf("https://dist.ipfs.tech/%s/%s/%s_%s_%s-%s.%s",
+			program, IPFSClusterVersion, program, IPFSClusterVersion, os, arch, ext), nil
+	}
+	return "", fmt.Errorf("could not find downloadable link for `%s` for operating system `%s` and architecture `%s`", program, os, arch)
+}
+
+// downloadClusterBinary downloads and extracts the cluster program into the
+// binaries directory if it was not downloaded before.
+func (wrap *ipfsCliWrapper) downloadClusterBinary(ctx context.Context, program string) error {
+	binaryFilePath := wrap.clusterBinaryPath(program)
+	if _, err := os.Stat(binaryFilePath); err == nil {
+		return nil
+	}
+
+	url, err := getClusterDownloadURL(program, wrap.os, wrap.arch)
+	if err != nil {
+		return fmt.Errorf("failed finding download link: %v", err)
+	}
+
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	// Keep the archive name for the logs, the format is detected from the
+	// content.
+	archiveFilePath := filepath.Join(stageDir, url[strings.LastIndex(url, "/")+1:])
+
+	wrap.logger.Debug("fetching cluster binary",
+		slog.String("program", program),
+		slog.String("url", url))
+
+	if err := wrap.urlDownloader.DownloadFile(ctx, url, archiveFilePath); err != nil {
+		return fmt.Errorf("failed downloading the binary: %v", err)
+	}
+
+	x := &xtractr.XFile{
+		FilePath:  archiveFilePath,
+		OutputDir: wrap.binDir,
+		FileMode:  wrap.fileMode,
+		DirMode:   wrap.dirMode,
+	}
+	if _, _, err := wrap.extractArchive(x); err != nil {
+		return fmt.Errorf("failed extracting %s: %v", program, err)
+	}
+	os.Chmod(binaryFilePath, wrap.fileMode)
+
+	wrap.logger.Debug("cluster binary ready for usage",
+		slog.String("filepath", binaryFilePath))
+	return nil
+}
+
+// clusterPrograms returns the cluster programs the cluster mode needs.
+func (wrap *ipfsCliWrapper) clusterPrograms() []string {
+	if wrap.cluster.mode == ClusterFollowMode {
+		return []string{"ipfs-cluster-ctl", "ipfs-cluster-follow"}
+	}
+	return []string{"ipfs-cluster-ctl", "ipfs-cluster-service"}
+}
+
+// setupCluster downloads the cluster binaries, unless `NewWrapper` did
+// already, and initializes the cluster peer configuration. It is called by
+// `NewWrapper` when cluster support was enabled with `WithClusterService` or
+// `WithClusterFollow`.
+func (wrap *ipfsCliWrapper) setupCluster() error {
+	if err := wrap.osOperator.CreateDirIfDoesNotExist(wrap.clusterDataPath()); err != nil {
+		return err
+	}
+	// The cluster data holds the private key of the cluster peer.
+	if err := os.Chmod(wrap.clusterDataPath(), wrap.dirMode); err != nil {
+		return fmt.Errorf("failed setting permissions of %s: %v", wrap.clusterDataPath(), err)
+	}
+	for _, program := range wrap.clusterPrograms() {
+		if err := wrap.downloadClusterBinary(context.Background(), program); err != nil {
+			return err
+		}
+	}
+
+	var initCmd *exec.Cmd
+	var configPath string
+	switch wrap.cluster.mode {
+	case ClusterServiceMode:
+		initCmd = exec.Command(wrap.clusterBinaryPath("ipfs-cluster-service"), "init", "--consensus", "crdt")
+		configPath = filepath.Join(wrap.clusterDataPath(), "service.json")
+	case ClusterFollowMode:
+		initCmd = exec.Command(wrap.clusterBinaryPath("ipfs-cluster-follow"), wrap.cluster.followName, "init", wrap.cluster.followInitURL)
+		configPath = filepath.Join(wrap.clusterDataPath(), wrap.cluster.followName, "service.json")
+	default:
+		return fmt.Errorf("unsupported cluster mode: %v", wrap.cluster.mode)
+	}
+	initCmd.Env = wrap.clusterEnv()
+
+	// Similar to `ipfs init`, skip the peer if it was already initialized
+	// by a previous run of this app.
+	if _, err := os.Stat(configPath); err == nil {
+		wrap.logger.Debug("ipfs-cluster already initialized")
+	} else if output, err := initCmd.CombinedOutput(); err != nil {
+		wrap.logger.Warn("failed to initialize ipfs-cluster",
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+	} else {
+		wrap.logger.Debug("ipfs-cluster initialization completed successfully",
+			wrap.outputAttr(output))
+	}
+	return nil
+}
+
+// clusterEnv returns the environment used for every cluster command so that
+// all of them operate on the wrapper-managed cluster data directory.
+func (wrap *ipfsCliWrapper) clusterEnv() []string {
+	env := append(os.Environ(),
+		"IPFS_CLUSTER_PATH="+wrap.clusterDataPath(),
+		"IPFS_CLUSTER_FOLLOW_PATH="+wrap.clusterDataPath())
+	if wrap.cluster.secret != "" {
+		env = append(env, "CLUSTER_SECRET="+wrap.cluster.secret)
+	}
+	return env
+}
+
+// startCluster launches the cluster peer alongside the running kubo daemon.
+func (wrap *ipfsCliWrapper) startCluster() error {
+	if wrap.cluster == nil || wrap.clusterProcess != nil {
+		return nil
+	}
+
+	var cmd *exec.Cmd
+	if wrap.cluster.mode == ClusterFollowMode {
+		cmd = exec.Command(wrap.clusterBinaryPath("ipfs-cluster-follow"), wrap.cluster.followName, "run")
+	} else {
+		args := []string{"daemon"}
+		if len(wrap.cluster.bootstrap) > 0 {
+			args = append(args, "--bootstrap", strings.Join(wrap.cluster.bootstrap, ","))
+		}
+		cmd = exec.Command(wrap.clusterBinaryPath("ipfs-cluster-service"), args...)
+	}
+	cmd.Env = wrap.clusterEnv()
+
+	process, err := prockit.Start(cmd)
+	if err != nil {
+		wrap.logger.Error("error starting ipfs-cluster", slog.Any("error", err))
+		return fmt.Errorf("Error starting ipfs-cluster: %v\n", err)
+	}
+	wrap.clusterProcess = process
+
+	wrap.logger.Debug("ipfs-cluster peer is running",
+		slog.String("mode", wrap.cluster.mode))
+	return nil
+}
+
+// stopCluster terminates the cluster peer if it was started by this wrapper.
+func (wrap *ipfsCliWrapper) stopCluster() error {
+	if wrap.clusterProcess == nil {
+		return nil
+	}
+	process := wrap.clusterProcess
+	wrap.clusterProcess = nil
+
+	if err := process.Kill(); err != nil && !prockit.IsKilled(err) {
+		wrap.logger.Error("ipfs-cluster exited with error", slog.Any("error", err))
+		return fmt.Errorf("ipfs-cluster exited with error: %v\n", err)
+	}
+	wrap.logger.Debug("ipfs-cluster peer has exited")
+	return nil
+}
+
+// runClusterCtl executes `ipfs-cluster-ctl` against the companion cluster peer.
+func (wrap *ipfsCliWrapper) runClusterCtl(ctx context.Context, args ...string) ([]byte, error) {
+	if wrap.cluster == nil {
+		return nil, fmt.Errorf("cluster support is not enabled, use `WithClusterService` or `WithClusterFollow`")
+	}
+
+	// Followers do not expose the HTTP API, instead they listen on a unix
+	// socket inside their configuration folder.
+	if wrap.cluster.mode == ClusterFollowMode {
+		socket := filepath.Join(wrap.clusterDataPath(), wrap.cluster.followName, "api-socket")
+		args = append([]string{"--host", "/unix/" + socket}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, wrap.clusterBinaryPath("ipfs-cluster-ctl"), args...)
+	cmd.Env = wrap.clusterEnv()
+	return cmd.CombinedOutput()
+}
+
+func (wrap *ipfsCliWrapper) ClusterPin(ctx context.Context, cid string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	output, err := wrap.runClusterCtl(ctx, "pin", "add", cid)
+	if err != nil {
+		wrap.logger.Error("error pinning on ipfs-cluster",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return fmt.Errorf("failed to pin on ipfs-cluster: %v, output: %s", err, string(output))
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) ClusterUnpin(ctx context.Context, cid string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	output, err := wrap.runClusterCtl(ctx, "pin", "rm", cid)
+	if err != nil {
+		wrap.logger.Error("error removing pin from ipfs-cluster",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return fmt.Errorf("failed to remove pin from ipfs-cluster: %v, output: %s", err, string(output))
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) ClusterStatus(ctx context.Context, cid string) (*ClusterPinStatus, error) {
+	output, err := wrap.runClusterCtl(ctx, "--enc=json", "status", cid)
+	if err != nil {
+		wrap.logger.Error("error getting status from ipfs-cluster",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return nil, fmt.Errorf("failed to get status from ipfs-cluster: %v, output: %s", err, string(output))
+	}
+
+	var status ClusterPinStatus
+	if err := json.Unmarshal(output, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse ipfs-cluster status: %v, output: %s", err, string(output))
+	}
+	status.CID = cid
+	return &status, nil
+}
@@ -0,0 +1,178 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/rotatekit"
+)
+
+// workDirContextKey is the context key under which `WithCommandWorkDir`
+// stores the per-call working directory override.
+type workDirContextKey struct{}
+
+// WithCommandWorkDir returns a copy of the context which makes the commands
+// executed with it run inside the given directory, overriding the directory
+// configured with the `WithWorkDir` option for that call only. This controls,
+// for example, where `GetFile` saves the retrieved file.
+//
+// Example:
+//
+//	ctx := WithCommandWorkDir(context.Background(), "./downloads")
+//	err := wrapper.GetFile(ctx, cid)
+func WithCommandWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirContextKey{}, dir)
+}
+
+// baseCommand returns a command which executes the `ipfs` binary with the
+// given arguments against the wrapper-managed repo. Every spawned `ipfs`
+// process must be created through this function (or `command`) so it never
+// falls back to the default `~/.ipfs` repo of the user.
+//
+// The command runs inside the directory set with `WithCommandWorkDir`, or
+// else the directory configured with the `WithWorkDir` option, or else the
+// current working directory of this app. The binary and repo paths are made
+// absolute so the command behaves the same regardless of that directory.
+//
+// The command runs in its own process group which gets killed as a whole
+// when the context is cancelled, so no helper process outlives it.
+func (wrap *ipfsCliWrapper) baseCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, wrap.binaryPath(), args...)
+	// Running the command fails with the error instead of executing it.
+	cmd.Err = wrap.checkCommandPolicy(args)
+	cmd.Env = append(os.Environ(), "IPFS_PATH="+wrap.repoPath())
+	cmd.Dir = wrap.commandDir(ctx)
+	prockit.KillGroupOnCancel(cmd)
+	wrap.recordTelemetry(TelemetryEvent{Type: TelemetryCommandRun, Command: telemetryCommandName(args)})
+	return cmd
+}
+
+// command returns a command for the `ipfs` client subcommands (add, cat,
+// pin, etc). In addition to the repo environment set by `baseCommand`, the
+// command is pointed at the API of the wrapper-managed daemon when one was
+// configured with the `WithAPIAddress` option. In dry-run mode the command is
+// only recorded.
+func (wrap *ipfsCliWrapper) command(ctx context.Context, args ...string) *exec.Cmd {
+	if wrap.apiAddr != "" {
+		args = append([]string{"--api=" + wrap.apiAddr}, args...)
+	}
+	cmd := wrap.baseCommand(ctx, args...)
+	if wrap.dryRun {
+		wrap.recordDryRun(cmd)
+	}
+	return cmd
+}
+
+// cidBaseArgs appends the `--cid-base` flag set with `WithCIDBase` to the
+// arguments of a subcommand which prints CIDs.
+func (wrap *ipfsCliWrapper) cidBaseArgs(args ...string) []string {
+	if wrap.cidBase == "" {
+		return args
+	}
+	return append(args, "--cid-base="+wrap.cidBase)
+}
+
+func (wrap *ipfsCliWrapper) RunCommand(ctx context.Context, args ...string) ([]byte, error) {
+	if err := wrap.checkWritableCommand(args); err != nil {
+		return nil, err
+	}
+
+	cmd := wrap.command(ctx, args...)
+
+	// Keep stderr apart so warnings printed by kubo do not corrupt output
+	// which callers may want to parse, for example JSON.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error running ipfs command",
+			slog.Any("args", args),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return output, wrap.commandError("run ipfs command", err, stderr.Bytes())
+	}
+	return output, nil
+}
+
+// CommandStream is a running `ipfs` command started by `RunCommandStreaming`.
+type CommandStream struct {
+	// Stdout and Stderr stream the output of the command while it runs.
+	Stdout io.ReadCloser
+	Stderr io.ReadCloser
+
+	cmd *exec.Cmd
+}
+
+// Wait waits for the command to exit and returns its error, if any. As
+// required by `exec.Cmd`, read Stdout and Stderr until EOF (or stop caring
+// about them) before calling Wait, since Wait closes both pipes. To stop a
+// command which never exits on its own, such as `ipfs log tail`, cancel the
+// context passed to `RunCommandStreaming`.
+func (s *CommandStream) Wait() error {
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to run ipfs command: %v", err)
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) RunCommandStreaming(ctx context.Context, args ...string) (*CommandStream, error) {
+	if err := wrap.checkWritableCommand(args); err != nil {
+		return nil, err
+	}
+
+	cmd := wrap.command(ctx, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		wrap.logger.Error("error starting ipfs command",
+			slog.Any("args", args),
+			slog.Any("error", err))
+		return nil, fmt.Errorf("failed to start ipfs command: %v", err)
+	}
+	return &CommandStream{Stdout: stdout, Stderr: stderr, cmd: cmd}, nil
+}
+
+// openDaemonOutputFiles opens the files the stdout and stderr of a detached
+// daemon get redirected to, rotating them first if they grew too large.
+// Streams without a configured file are redirected to /dev/null.
+func (wrap *ipfsCliWrapper) openDaemonOutputFiles() (*os.File, *os.File, error) {
+	open := func(path string) (*os.File, error) {
+		if path == "" {
+			return os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		}
+		return rotatekit.OpenAppend(path, wrap.daemonOutputMaxBytes, wrap.daemonOutputMaxBackups)
+	}
+
+	stdoutFile, err := open(wrap.daemonStdoutPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Share the file when both streams go to the same place, otherwise the
+	// second open would rotate the file the first one just opened.
+	if wrap.daemonStderrPath == wrap.daemonStdoutPath {
+		stderrFile, err := os.OpenFile(stdoutFile.Name(), os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			stdoutFile.Close()
+			return nil, nil, err
+		}
+		return stdoutFile, stderrFile, nil
+	}
+	stderrFile, err := open(wrap.daemonStderrPath)
+	if err != nil {
+		stdoutFile.Close()
+		return nil, nil, err
+	}
+	return stdoutFile, stderrFile, nil
+}
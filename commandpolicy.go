@@ -0,0 +1,104 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrCommandNotAllowed is returned when the wrapper was about to run an
+// `ipfs` subcommand which the `WithAllowedCommands` or `WithDeniedCommands`
+// options forbid.
+var ErrCommandNotAllowed = errors.New("ipfs command not allowed")
+
+// errUnknownFlag is returned by `commandWords` for a flag before the
+// subcommand which is not one of the global options of kubo, as whether it
+// takes a value, and so where the subcommand starts, cannot be told.
+var errUnknownFlag = errors.New("unknown global flag")
+
+// globalValueFlags are the global options of kubo which take a value. Given
+// as a separate argument, the value is the next one, e.g. `--timeout 1s`.
+var globalValueFlags = []string{
+	"--api", "--api-auth", "--cid-base", "-c", "--config", "--config-file",
+	"--enc", "--encoding", "--repo-dir", "--timeout",
+}
+
+// globalBoolFlags are the global options of kubo which take no value.
+var globalBoolFlags = []string{
+	"-D", "--debug", "-h", "--help", "-L", "--local", "--offline",
+	"--stream-channels", "--upgrade-cidv0-in-output",
+}
+
+// commandWords returns the words naming the `ipfs` subcommand and its
+// positional arguments, skipping the flags like kubo parses them: the global
+// options are skipped with their values wherever they are, the options of
+// the subcommand are skipped alone, and every argument after "--" is a
+// positional one. A flag before the subcommand which is not a global option
+// is an error.
+func commandWords(args []string) ([]string, error) {
+	var words []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(words, args[i+1:]...), nil
+		}
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			words = append(words, arg)
+			continue
+		}
+		name, _, hasValue := strings.Cut(arg, "=")
+		switch {
+		case slices.Contains(globalValueFlags, name):
+			if !hasValue {
+				if i+1 == len(args) {
+					return nil, fmt.Errorf("missing value of flag `%s`", arg)
+				}
+				i++
+			}
+		case slices.Contains(globalBoolFlags, name):
+		case len(words) == 0:
+			return nil, fmt.Errorf("%w `%s`", errUnknownFlag, arg)
+		}
+	}
+	return words, nil
+}
+
+// matchesCommand reports whether the words of a subcommand start with the
+// words of one of the commands, so "key" matches `ipfs key export self`.
+func matchesCommand(words []string, commands [][]string) bool {
+	for _, command := range commands {
+		if len(words) >= len(command) && slices.Equal(words[:len(command)], command) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCommandPolicy returns `ErrCommandNotAllowed` if the arguments of
+// `ipfs` run a subcommand which is denied, or is missing from the allowed
+// commands when there are any.
+func (wrap *ipfsCliWrapper) checkCommandPolicy(args []string) error {
+	if len(wrap.allowedCommands) == 0 && len(wrap.deniedCommands) == 0 {
+		return nil
+	}
+	words, err := commandWords(args)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCommandNotAllowed, err)
+	}
+	if matchesCommand(words, wrap.deniedCommands) ||
+		(len(wrap.allowedCommands) > 0 && !matchesCommand(words, wrap.allowedCommands)) {
+		return fmt.Errorf("%w: `ipfs %s`", ErrCommandNotAllowed, strings.Join(words, " "))
+	}
+	return nil
+}
+
+// parseCommands splits the commands given to `WithAllowedCommands` and
+// `WithDeniedCommands` into their words.
+func parseCommands(commands []string) [][]string {
+	parsed := make([][]string, 0, len(commands))
+	for _, command := range commands {
+		parsed = append(parsed, strings.Fields(command))
+	}
+	return parsed
+}
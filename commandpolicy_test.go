@@ -0,0 +1,121 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestCommandWords checks the flags are skipped like kubo parses them, so
+// the values of the global options are not taken for the subcommand.
+func TestCommandWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "NoFlags",
+			args:     []string{"key", "export", "self"},
+			expected: []string{"key", "export", "self"},
+		},
+		{
+			name:     "LeadingFlagWithValue",
+			args:     []string{"--timeout", "1s", "key", "export", "self"},
+			expected: []string{"key", "export", "self"},
+		},
+		{
+			name:     "LeadingFlagsWithValues",
+			args:     []string{"--api", "/ip4/127.0.0.1/tcp/5001", "-c", "/tmp/repo", "--repo-dir", "/tmp/repo", "--config-file", "/tmp/config", "--encoding", "json", "pin", "rm", "bafkqaaa"},
+			expected: []string{"pin", "rm", "bafkqaaa"},
+		},
+		{
+			name:     "LeadingFlagWithAttachedValue",
+			args:     []string{"--timeout=1s", "--offline", "-D", "key", "export", "self"},
+			expected: []string{"key", "export", "self"},
+		},
+		{
+			name:     "GlobalFlagBetweenWords",
+			args:     []string{"key", "--timeout", "1s", "export", "self"},
+			expected: []string{"key", "export", "self"},
+		},
+		{
+			name:     "SubcommandFlags",
+			args:     []string{"pin", "ls", "--type=recursive", "-q"},
+			expected: []string{"pin", "ls"},
+		},
+		{
+			name:     "Separator",
+			args:     []string{"config", "--", "--timeout", "1s"},
+			expected: []string{"config", "--timeout", "1s"},
+		},
+		{
+			name:     "Stdin",
+			args:     []string{"key", "import", "name", "-"},
+			expected: []string{"key", "import", "name", "-"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			words, err := ipfscliwrapper.CommandWords(test.args)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !slices.Equal(words, test.expected) {
+				t.Errorf("Expected %q, but got %q", test.expected, words)
+			}
+		})
+	}
+}
+
+// TestCommandWordsInvalid checks the arguments whose subcommand cannot be
+// told are an error.
+func TestCommandWordsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "UnknownLeadingFlag", args: []string{"--unknown", "key", "export", "self"}},
+		{name: "MissingValue", args: []string{"key", "list", "--timeout"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if words, err := ipfscliwrapper.CommandWords(test.args); err == nil {
+				t.Errorf("Expected an error, but got %q", words)
+			}
+		})
+	}
+}
+
+// TestCommandPolicy checks the denied and allowed commands cannot be got
+// around with flags.
+func TestCommandPolicy(t *testing.T) {
+	wrapper, _ := newFakeKuboWrapper(t,
+		ipfscliwrapper.WithAllowedCommands("init", "config show", "key"),
+		ipfscliwrapper.WithDeniedCommands("key export"))
+	ctx := context.Background()
+
+	if _, err := wrapper.RunCommand(ctx, "key", "list"); err != nil {
+		t.Errorf("Expected listing keys to be allowed, but got: %v", err)
+	}
+	if _, err := wrapper.RunCommand(ctx, "--timeout=1s", "key", "list"); err != nil {
+		t.Errorf("Expected listing keys with a timeout to be allowed, but got: %v", err)
+	}
+	denied := [][]string{
+		{"key", "export", "self"},
+		{"--timeout", "1s", "key", "export", "self"},
+		{"--encoding", "json", "key", "export", "self"},
+		{"key", "--timeout", "1s", "export", "self"},
+		{"--unknown", "key", "export", "self"},
+		{"--timeout", "key", "pin", "rm", "bafkqaaa"},
+		{"pin", "ls"},
+	}
+	for _, args := range denied {
+		if _, err := wrapper.RunCommand(ctx, args...); !errors.Is(err, ipfscliwrapper.ErrCommandNotAllowed) {
+			t.Errorf("Expected ErrCommandNotAllowed running %q, but got: %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
+)
+
+// ErrIncompatibleKubo is returned by `NewWrapper` with the
+// `WithStrictCompatibility` option when the installed `ipfs` binary is outside
+// the range of kubo releases a feature in use was tested with.
+var ErrIncompatibleKubo = errors.New("kubo release outside of the tested range")
+
+// kuboFeature is a feature of the wrapper together with the range of kubo
+// releases it was tested with.
+type kuboFeature struct {
+	name       string
+	minVersion string
+	maxVersion string
+
+	// inUse reports whether the options enable the feature, or is nil if the
+	// feature is always used.
+	inUse func(wrap *ipfsCliWrapper) bool
+}
+
+// kuboCompatibility is the compatibility matrix checked by `NewWrapper`. Raise
+// the maximum versions once the contract tests (see `contract_test.go`) pass
+// against a newer kubo release.
+var kuboCompatibility = []kuboFeature{
+	{
+		// `ipfs routing` replaced `ipfs dht` in v0.18.0.
+		name:       "core commands",
+		minVersion: "v0.18.0",
+		maxVersion: "v0.29.0",
+	},
+	{
+		// `ipfs add --to-files` was added in v0.16.0.
+		name:       "mfs directories (AddFileEntries, SyncDir, WatchDir)",
+		minVersion: "v0.16.0",
+		maxVersion: "v0.29.0",
+	},
+	{
+		// The `Denylists` support of the daemon was added in v0.24.0.
+		name:       "denylist",
+		minVersion: "v0.24.0",
+		maxVersion: "v0.29.0",
+		inUse:      func(wrap *ipfsCliWrapper) bool { return wrap.denylistFilename != "" },
+	},
+	{
+		name:       "badger datastore",
+		minVersion: "v0.18.0",
+		maxVersion: "v0.29.0",
+		inUse: func(wrap *ipfsCliWrapper) bool {
+			return wrap.datastore != nil && wrap.datastore.typeID == BadgerDatastoreType
+		},
+	},
+}
+
+// checkKuboCompatibility compares the release of the installed `ipfs` binary
+// with the compatibility matrix. Mismatches are logged as a warning, or
+// returned as an error with the `WithStrictCompatibility` option.
+func (wrap *ipfsCliWrapper) checkKuboCompatibility() error {
+	output, err := wrap.output(wrap.baseCommand(context.Background(), "version", "--number"))
+	if err != nil {
+		return wrap.reportIncompatibleKubo(wrap.commandError("read kubo version", err, output))
+	}
+	installed, err := versionkit.Parse(string(output))
+	if err != nil {
+		return wrap.reportIncompatibleKubo(fmt.Errorf("%w: %v", ErrIncompatibleKubo, err))
+	}
+	wrap.installedKuboVersion = installed.String()
+
+	var untested []string
+	for _, feature := range kuboCompatibility {
+		if feature.inUse != nil && !feature.inUse(wrap) {
+			continue
+		}
+		minVersion, _ := versionkit.Parse(feature.minVersion)
+		maxVersion, _ := versionkit.Parse(feature.maxVersion)
+		if installed.Compare(minVersion) < 0 || installed.Compare(maxVersion) > 0 {
+			untested = append(untested, fmt.Sprintf("%s tested with %s to %s", feature.name, feature.minVersion, feature.maxVersion))
+		}
+	}
+	if len(untested) == 0 {
+		return nil
+	}
+	return wrap.reportIncompatibleKubo(fmt.Errorf("%w: installed %s, %s", ErrIncompatibleKubo, installed, strings.Join(untested, ", ")))
+}
+
+// reportIncompatibleKubo returns the error with the `WithStrictCompatibility`
+// option, or otherwise only logs it.
+func (wrap *ipfsCliWrapper) reportIncompatibleKubo(err error) error {
+	if wrap.strictCompatibility {
+		return err
+	}
+	wrap.logger.Warn("kubo release was not tested with this wrapper",
+		slog.Any("error", err))
+	return nil
+}
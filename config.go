@@ -0,0 +1,466 @@
+package ipfscliwrapper
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config is a struct-based alternative to the functional options of
+// `NewWrapper` which can be loaded from a configuration file (the fields have
+// `json` and `yaml` tags) or from environment variables with
+// `LoadConfigFromEnv`. The zero value of every field keeps the default
+// behaviour of the wrapper.
+type Config struct {
+	// WorkDir is the working directory of every spawned `ipfs` process. See
+	// `WithWorkDir`.
+	WorkDir string `json:"work_dir" yaml:"work_dir" env:"WORK_DIR"`
+
+	// TempDir is the directory scratch files are created in. See
+	// `WithTempDir`.
+	TempDir string `json:"temp_dir" yaml:"temp_dir" env:"TEMP_DIR"`
+
+	// KeepArchive keeps the downloaded kubo archive for reuse, inside
+	// ArchiveCacheDir if set. See `WithKeepArchive` and
+	// `WithArchiveCacheDir`.
+	KeepArchive     bool   `json:"keep_archive" yaml:"keep_archive" env:"KEEP_ARCHIVE"`
+	ArchiveCacheDir string `json:"archive_cache_dir" yaml:"archive_cache_dir" env:"ARCHIVE_CACHE_DIR"`
+
+	// FileMode and DirMode are the permissions of the binaries and of the
+	// managed directories. In environment variables, use octal notation (e.g.
+	// "0750"). See `WithFileMode` and `WithDirMode`.
+	FileMode os.FileMode `json:"file_mode" yaml:"file_mode" env:"FILE_MODE"`
+	DirMode  os.FileMode `json:"dir_mode" yaml:"dir_mode" env:"DIR_MODE"`
+
+	// XDGLayout places the binaries and repos inside the XDG base
+	// directories. See `WithXDGLayout`.
+	XDGLayout bool `json:"xdg_layout" yaml:"xdg_layout" env:"XDG_LAYOUT"`
+
+	// BinaryPath is the `ipfs` binary to run instead of downloading kubo and
+	// RepoPath the directory of the repo. See `WithBinaryPath` and
+	// `WithRepoPath`.
+	BinaryPath string `json:"binary_path" yaml:"binary_path" env:"BINARY_PATH"`
+	RepoPath   string `json:"repo_path" yaml:"repo_path" env:"REPO_PATH"`
+
+	// APIAddress is the multiaddress of the daemon API. See `WithAPIAddress`.
+	APIAddress string `json:"api_address" yaml:"api_address" env:"API_ADDRESS"`
+
+	// GatewayAddress is the multiaddress of the daemon HTTP gateway. See
+	// `WithGatewayAddress`.
+	GatewayAddress string `json:"gateway_address" yaml:"gateway_address" env:"GATEWAY_ADDRESS"`
+
+	// SwarmAddresses are the multiaddresses the daemon listens on for other
+	// peers. In environment variables, separate the addresses with commas.
+	// See `WithSwarmAddresses`.
+	SwarmAddresses []string `json:"swarm_addresses" yaml:"swarm_addresses" env:"SWARM_ADDRESSES"`
+
+	// DisableGateway turns off the HTTP gateway. See `WithoutGateway`.
+	DisableGateway bool `json:"disable_gateway" yaml:"disable_gateway" env:"DISABLE_GATEWAY"`
+
+	// APIBindAddress and GatewayBindAddress are the interfaces the API and
+	// gateway bind to. See `WithAPIBindAddress` and `WithGatewayBindAddress`.
+	APIBindAddress     string `json:"api_bind_address" yaml:"api_bind_address" env:"API_BIND_ADDRESS"`
+	GatewayBindAddress string `json:"gateway_bind_address" yaml:"gateway_bind_address" env:"GATEWAY_BIND_ADDRESS"`
+
+	// AutoPorts picks free ports for the addresses which are not set. See
+	// `WithAutoPorts`.
+	AutoPorts bool `json:"auto_ports" yaml:"auto_ports" env:"AUTO_PORTS"`
+
+	// HealthAddress is the address the health endpoint listens on. See
+	// `WithHealthEndpoint`.
+	HealthAddress string `json:"health_address" yaml:"health_address" env:"HEALTH_ADDRESS"`
+
+	// KuboVersion is the release of the `ipfs` binary to download. See
+	// `WithKuboVersion`.
+	KuboVersion string `json:"kubo_version" yaml:"kubo_version" env:"KUBO_VERSION"`
+
+	// LogOutputLimit is how many bytes of command output are kept in the
+	// logs, or a negative number to only log the length. See
+	// `WithLogOutputLimit`.
+	LogOutputLimit int `json:"log_output_limit" yaml:"log_output_limit" env:"LOG_OUTPUT_LIMIT"`
+
+	// LogSampling only logs one in that many debug and info records of the
+	// same message. See `WithLogSampling`.
+	LogSampling int `json:"log_sampling" yaml:"log_sampling" env:"LOG_SAMPLING"`
+
+	// StrictCompatibility fails instead of warning when the installed kubo
+	// release was not tested. See `WithStrictCompatibility`.
+	StrictCompatibility bool `json:"strict_compatibility" yaml:"strict_compatibility" env:"STRICT_COMPATIBILITY"`
+
+	// ShutdownGracePeriod is how long the daemon gets to exit before it is
+	// killed. See `WithShutdownGracePeriod`.
+	ShutdownGracePeriod Duration `json:"shutdown_grace_period" yaml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD"`
+
+	// ShutdownOnSignals shuts the daemon down on `SIGINT` and `SIGTERM`. See
+	// `WithShutdownOnSignals`.
+	ShutdownOnSignals bool `json:"shutdown_on_signals" yaml:"shutdown_on_signals" env:"SHUTDOWN_ON_SIGNALS"`
+
+	// OS and Arch override the platform of the downloaded binary. Both must
+	// be set together. See `WithOverrideBinaryOsAndArch`.
+	OS   string `json:"os" yaml:"os" env:"OS"`
+	Arch string `json:"arch" yaml:"arch" env:"ARCH"`
+
+	// ContinousOperation keeps the daemon running after this app exits. See
+	// `WithContinousOperation`.
+	ContinousOperation bool `json:"continous_operation" yaml:"continous_operation" env:"CONTINOUS_OPERATION"`
+
+	// ForcedShutdownDaemonOnStartup terminates previously running daemons on
+	// startup. See `WithForcedShutdownDaemonOnStartup`.
+	ForcedShutdownDaemonOnStartup bool `json:"forced_shutdown_daemon_on_startup" yaml:"forced_shutdown_daemon_on_startup" env:"FORCED_SHUTDOWN_DAEMON_ON_STARTUP"`
+
+	// DaemonWarmupDuration is the delay given to the daemon to load up, e.g.
+	// "5s". See `WithOverrideDaemonInitialWarmupDuration`.
+	DaemonWarmupDuration Duration `json:"daemon_warmup_duration" yaml:"daemon_warmup_duration" env:"DAEMON_WARMUP_DURATION"`
+
+	// DenylistFilename and DenylistURL configure a denylist to download and
+	// apply. Both must be set together. See `WithDenylist`.
+	DenylistFilename string `json:"denylist_filename" yaml:"denylist_filename" env:"DENYLIST_FILENAME"`
+	DenylistURL      string `json:"denylist_url" yaml:"denylist_url" env:"DENYLIST_URL"`
+
+	// DenylistFiles are local denylists to apply. In the environment they
+	// are separated with commas. See `WithDenylistFile`.
+	DenylistFiles []string `json:"denylist_files" yaml:"denylist_files" env:"DENYLIST_FILES"`
+
+	// StorageMax is the disk budget of the repo (e.g. "10GB"). See
+	// `WithStorageMax`.
+	StorageMax string `json:"storage_max" yaml:"storage_max" env:"STORAGE_MAX"`
+
+	// StorageGCWatermark is the percentage of `StorageMax` which triggers
+	// garbage collection. See `WithStorageGCWatermark`.
+	StorageGCWatermark int `json:"storage_gc_watermark" yaml:"storage_gc_watermark" env:"STORAGE_GC_WATERMARK"`
+
+	// AllowedCommands and DeniedCommands restrict the `ipfs` subcommands the
+	// wrapper runs. In the environment they are separated with commas. See
+	// `WithAllowedCommands` and `WithDeniedCommands`.
+	AllowedCommands []string `json:"allowed_commands" yaml:"allowed_commands" env:"ALLOWED_COMMANDS"`
+	DeniedCommands  []string `json:"denied_commands" yaml:"denied_commands" env:"DENIED_COMMANDS"`
+
+	// DryRun records the `ipfs` commands instead of running them. See
+	// `WithDryRun`.
+	DryRun bool `json:"dry_run" yaml:"dry_run" env:"DRY_RUN"`
+
+	// MinFreeDiskSpace is the disk space, in bytes, which downloading kubo
+	// and adding content leave free. See `WithMinFreeDiskSpace`.
+	MinFreeDiskSpace uint64 `json:"min_free_disk_space" yaml:"min_free_disk_space" env:"MIN_FREE_DISK_SPACE"`
+
+	// RepoLockTimeout is how long to wait for another wrapper over the same
+	// repo. See `WithRepoLockTimeout`.
+	RepoLockTimeout Duration `json:"repo_lock_timeout" yaml:"repo_lock_timeout" env:"REPO_LOCK_TIMEOUT"`
+
+	// DownloadConcurrency is how many files are downloaded at once while
+	// provisioning the node. See `WithDownloadConcurrency`.
+	DownloadConcurrency int `json:"download_concurrency" yaml:"download_concurrency" env:"DOWNLOAD_CONCURRENCY"`
+
+	// ReadOnly refuses every call which would modify the node. See
+	// `WithReadOnly`.
+	ReadOnly bool `json:"read_only" yaml:"read_only" env:"READ_ONLY"`
+
+	// GCWatermark is the percentage of `StorageMax` at which the wrapper
+	// collects garbage, and GCWatermarkCheckInterval how often it checks.
+	// See `WithGCWatermark`.
+	GCWatermark              int      `json:"gc_watermark" yaml:"gc_watermark" env:"GC_WATERMARK"`
+	GCWatermarkCheckInterval Duration `json:"gc_watermark_check_interval" yaml:"gc_watermark_check_interval" env:"GC_WATERMARK_CHECK_INTERVAL"`
+
+	// FallbackGateways are the HTTP gateways content is fetched from when the
+	// local node cannot retrieve it. In environment variables, separate the
+	// URLs with commas. See `WithFallbackGateways`.
+	FallbackGateways []string `json:"fallback_gateways" yaml:"fallback_gateways" env:"FALLBACK_GATEWAYS"`
+
+	// LocalFetchTimeout is how long the local node may take to retrieve
+	// content before falling back to the gateways. See
+	// `WithLocalFetchTimeout`.
+	LocalFetchTimeout Duration `json:"local_fetch_timeout" yaml:"local_fetch_timeout" env:"LOCAL_FETCH_TIMEOUT"`
+
+	// CIDBase is the multibase of the returned CIDs, e.g. "base32". See
+	// `WithCIDBase`.
+	CIDBase string `json:"cid_base" yaml:"cid_base" env:"CID_BASE"`
+
+	// PinServiceEndpoint and PinServiceAccessToken configure a remote
+	// Pinning Service API provider as the pin backend. See `WithPinBackend`
+	// and `NewPinningServiceBackend`.
+	PinServiceEndpoint    string `json:"pin_service_endpoint" yaml:"pin_service_endpoint" env:"PIN_SERVICE_ENDPOINT"`
+	PinServiceAccessToken string `json:"pin_service_access_token" yaml:"pin_service_access_token" env:"PIN_SERVICE_ACCESS_TOKEN"`
+
+	// IPNSRepublishInterval is how often tracked IPNS names get republished.
+	// See `WithIPNSRepublishInterval`.
+	IPNSRepublishInterval Duration `json:"ipns_republish_interval" yaml:"ipns_republish_interval" env:"IPNS_REPUBLISH_INTERVAL"`
+
+	// WatchDebounce is how long `WatchDir` waits for changes to settle. See
+	// `WithWatchDebounce`.
+	WatchDebounce Duration `json:"watch_debounce" yaml:"watch_debounce" env:"WATCH_DEBOUNCE"`
+
+	// WatchIPNSKey is the key `WatchDir` publishes the root CID with. See
+	// `WithWatchIPNSKey`.
+	WatchIPNSKey string `json:"watch_ipns_key" yaml:"watch_ipns_key" env:"WATCH_IPNS_KEY"`
+}
+
+// Duration is a `time.Duration` which configuration files and environment
+// variables spell as a string parsed by `time.ParseDuration`, e.g. "5s" or
+// "1m30s".
+type Duration time.Duration
+
+// UnmarshalText parses the duration, see `time.ParseDuration`.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText formats the duration like `time.Duration.String`.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// ConfigEnvPrefix is the prefix of the environment variables read by
+// `LoadConfigFromEnv`, for example `IPFS_CLI_WRAPPER_API_ADDRESS`.
+const ConfigEnvPrefix = "IPFS_CLI_WRAPPER_"
+
+// LoadConfigFromEnv returns a Config populated from the environment variables
+// named after the `env` tag of every field prefixed with `ConfigEnvPrefix`.
+// Unset variables leave the field at its zero value.
+//
+// Example:
+//
+//	// IPFS_CLI_WRAPPER_API_ADDRESS=/ip4/127.0.0.1/tcp/5011
+//	// IPFS_CLI_WRAPPER_DAEMON_WARMUP_DURATION=10s
+//	cfg, err := LoadConfigFromEnv()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	wrapper, err := NewWrapperFromConfig(cfg)
+func LoadConfigFromEnv() (Config, error) {
+	var cfg Config
+	v := reflect.ValueOf(&cfg).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := ConfigEnvPrefix + t.Field(i).Tag.Get("env")
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setConfigField(v.Field(i), raw); err != nil {
+			return cfg, fmt.Errorf("invalid value for environment variable `%s`: %v", name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// setConfigField parses the raw environment variable value into the field.
+func setConfigField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case uint64:
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case os.FileMode:
+		mode, err := strconv.ParseUint(raw, 8, 32)
+		if err != nil {
+			return err
+		}
+		field.SetUint(mode)
+	case Duration:
+		var d Duration
+		if err := d.UnmarshalText([]byte(raw)); err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case []string:
+		parts := strings.Split(raw, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		field.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// Options converts the Config into the equivalent functional options.
+func (cfg Config) Options() []Option {
+	var options []Option
+	if cfg.WorkDir != "" {
+		options = append(options, WithWorkDir(cfg.WorkDir))
+	}
+	if cfg.TempDir != "" {
+		options = append(options, WithTempDir(cfg.TempDir))
+	}
+	if cfg.ArchiveCacheDir != "" {
+		options = append(options, WithArchiveCacheDir(cfg.ArchiveCacheDir))
+	} else if cfg.KeepArchive {
+		options = append(options, WithKeepArchive())
+	}
+	if cfg.FileMode != 0 {
+		options = append(options, WithFileMode(cfg.FileMode))
+	}
+	if cfg.DirMode != 0 {
+		options = append(options, WithDirMode(cfg.DirMode))
+	}
+	if cfg.XDGLayout {
+		options = append(options, WithXDGLayout())
+	}
+	if cfg.BinaryPath != "" {
+		options = append(options, WithBinaryPath(cfg.BinaryPath))
+	}
+	if cfg.RepoPath != "" {
+		options = append(options, WithRepoPath(cfg.RepoPath))
+	}
+	if cfg.APIAddress != "" {
+		options = append(options, WithAPIAddress(cfg.APIAddress))
+	}
+	if cfg.GatewayAddress != "" {
+		options = append(options, WithGatewayAddress(cfg.GatewayAddress))
+	}
+	if len(cfg.SwarmAddresses) > 0 {
+		options = append(options, WithSwarmAddresses(cfg.SwarmAddresses...))
+	}
+	if cfg.DisableGateway {
+		options = append(options, WithoutGateway())
+	}
+	if cfg.APIBindAddress != "" {
+		options = append(options, WithAPIBindAddress(cfg.APIBindAddress))
+	}
+	if cfg.GatewayBindAddress != "" {
+		options = append(options, WithGatewayBindAddress(cfg.GatewayBindAddress))
+	}
+	if cfg.AutoPorts {
+		options = append(options, WithAutoPorts())
+	}
+	if cfg.HealthAddress != "" {
+		options = append(options, WithHealthEndpoint(cfg.HealthAddress))
+	}
+	if cfg.KuboVersion != "" {
+		options = append(options, WithKuboVersion(cfg.KuboVersion))
+	}
+	if cfg.LogOutputLimit != 0 {
+		options = append(options, WithLogOutputLimit(cfg.LogOutputLimit))
+	}
+	if cfg.LogSampling > 1 {
+		options = append(options, WithLogSampling(cfg.LogSampling))
+	}
+	if cfg.StrictCompatibility {
+		options = append(options, WithStrictCompatibility())
+	}
+	if cfg.ShutdownGracePeriod > 0 {
+		options = append(options, WithShutdownGracePeriod(time.Duration(cfg.ShutdownGracePeriod)))
+	}
+	if cfg.ShutdownOnSignals {
+		options = append(options, WithShutdownOnSignals())
+	}
+	if cfg.OS != "" || cfg.Arch != "" {
+		options = append(options, WithOverrideBinaryOsAndArch(cfg.OS, cfg.Arch))
+	}
+	if cfg.ContinousOperation {
+		options = append(options, WithContinousOperation())
+	}
+	if cfg.ForcedShutdownDaemonOnStartup {
+		options = append(options, WithForcedShutdownDaemonOnStartup())
+	}
+	if cfg.DaemonWarmupDuration > 0 {
+		warmup := time.Duration(cfg.DaemonWarmupDuration)
+		options = append(options, func(wrap *ipfsCliWrapper) {
+			wrap.daemonInitialWarmupDuration = warmup
+		})
+	}
+	if cfg.DenylistFilename != "" || cfg.DenylistURL != "" {
+		options = append(options, WithDenylist(cfg.DenylistFilename, cfg.DenylistURL))
+	}
+	for _, path := range cfg.DenylistFiles {
+		options = append(options, WithDenylistFile(path))
+	}
+	if cfg.StorageMax != "" {
+		options = append(options, WithStorageMax(cfg.StorageMax))
+	}
+	if cfg.StorageGCWatermark > 0 {
+		options = append(options, WithStorageGCWatermark(cfg.StorageGCWatermark))
+	}
+	if len(cfg.AllowedCommands) > 0 {
+		options = append(options, WithAllowedCommands(cfg.AllowedCommands...))
+	}
+	if len(cfg.DeniedCommands) > 0 {
+		options = append(options, WithDeniedCommands(cfg.DeniedCommands...))
+	}
+	if cfg.DryRun {
+		options = append(options, WithDryRun())
+	}
+	if cfg.MinFreeDiskSpace > 0 {
+		options = append(options, WithMinFreeDiskSpace(cfg.MinFreeDiskSpace))
+	}
+	if cfg.RepoLockTimeout > 0 {
+		options = append(options, WithRepoLockTimeout(time.Duration(cfg.RepoLockTimeout)))
+	}
+	if cfg.DownloadConcurrency > 0 {
+		options = append(options, WithDownloadConcurrency(cfg.DownloadConcurrency))
+	}
+	if cfg.ReadOnly {
+		options = append(options, WithReadOnly())
+	}
+	if cfg.GCWatermark > 0 {
+		options = append(options, WithGCWatermark(cfg.GCWatermark))
+	}
+	if cfg.GCWatermarkCheckInterval > 0 {
+		options = append(options, WithGCWatermarkCheckInterval(time.Duration(cfg.GCWatermarkCheckInterval)))
+	}
+	if len(cfg.FallbackGateways) > 0 {
+		options = append(options, WithFallbackGateways(cfg.FallbackGateways...))
+	}
+	if cfg.LocalFetchTimeout > 0 {
+		options = append(options, WithLocalFetchTimeout(time.Duration(cfg.LocalFetchTimeout)))
+	}
+	if cfg.CIDBase != "" {
+		options = append(options, WithCIDBase(cfg.CIDBase))
+	}
+	if cfg.PinServiceEndpoint != "" {
+		options = append(options, WithPinBackend(NewPinningServiceBackend(cfg.PinServiceEndpoint, cfg.PinServiceAccessToken)))
+	}
+	if cfg.IPNSRepublishInterval > 0 {
+		options = append(options, WithIPNSRepublishInterval(time.Duration(cfg.IPNSRepublishInterval)))
+	}
+	if cfg.WatchDebounce > 0 {
+		options = append(options, WithWatchDebounce(time.Duration(cfg.WatchDebounce)))
+	}
+	if cfg.WatchIPNSKey != "" {
+		options = append(options, WithWatchIPNSKey(cfg.WatchIPNSKey))
+	}
+	return options
+}
+
+// NewWrapperFromConfig creates a new instance of IpfsCliWrapper configured by
+// the Config struct. Additional functional options, for example the ones
+// injecting custom dependencies, are applied after the configuration.
+//
+// Example usage:
+//
+//	cfg, err := LoadConfigFromEnv()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	wrapper, err := NewWrapperFromConfig(cfg)
+//	if err != nil {
+//	    log.Fatalf("Failed to initialize IPFS CLI wrapper: %v", err)
+//	}
+func NewWrapperFromConfig(cfg Config, options ...Option) (IpfsCliWrapper, error) {
+	return NewWrapper(append(cfg.Options(), options...)...)
+}
@@ -0,0 +1,92 @@
+package ipfscliwrapper_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestLoadConfigFromEnv checks the environment variables are bound to the
+// matching Config fields.
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("IPFS_CLI_WRAPPER_API_ADDRESS", "/ip4/127.0.0.1/tcp/5011")
+	t.Setenv("IPFS_CLI_WRAPPER_SWARM_ADDRESSES", "/ip4/0.0.0.0/tcp/4011, /ip6/::/tcp/4011")
+	t.Setenv("IPFS_CLI_WRAPPER_CONTINOUS_OPERATION", "true")
+	t.Setenv("IPFS_CLI_WRAPPER_DAEMON_WARMUP_DURATION", "10s")
+	t.Setenv("IPFS_CLI_WRAPPER_STORAGE_GC_WATERMARK", "80")
+	t.Setenv("IPFS_CLI_WRAPPER_DIR_MODE", "0750")
+	t.Setenv("IPFS_CLI_WRAPPER_MIN_FREE_DISK_SPACE", "1073741824")
+
+	cfg, err := ipfscliwrapper.LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	expected := ipfscliwrapper.Config{
+		APIAddress:           "/ip4/127.0.0.1/tcp/5011",
+		SwarmAddresses:       []string{"/ip4/0.0.0.0/tcp/4011", "/ip6/::/tcp/4011"},
+		ContinousOperation:   true,
+		DaemonWarmupDuration: ipfscliwrapper.Duration(10 * time.Second),
+		StorageGCWatermark:   80,
+		DirMode:              0750,
+		MinFreeDiskSpace:     1 << 30,
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected config %+v, but got %+v", expected, cfg)
+	}
+}
+
+// TestLoadConfigFromEnvInvalidValue checks malformed values are reported.
+func TestLoadConfigFromEnvInvalidValue(t *testing.T) {
+	t.Setenv("IPFS_CLI_WRAPPER_DAEMON_WARMUP_DURATION", "five seconds")
+
+	if _, err := ipfscliwrapper.LoadConfigFromEnv(); err == nil {
+		t.Fatal("Expected an error, but got none")
+	}
+}
+
+// TestConfigFromJSON checks a configuration file decodes into the Config,
+// with the durations spelled like `time.ParseDuration` expects them.
+func TestConfigFromJSON(t *testing.T) {
+	data := []byte(`{
+		"api_address": "/ip4/127.0.0.1/tcp/5011",
+		"daemon_warmup_duration": "5s",
+		"ipns_republish_interval": "1m30s",
+		"dir_mode": 488
+	}`)
+
+	var cfg ipfscliwrapper.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	expected := ipfscliwrapper.Config{
+		APIAddress:            "/ip4/127.0.0.1/tcp/5011",
+		DaemonWarmupDuration:  ipfscliwrapper.Duration(5 * time.Second),
+		IPNSRepublishInterval: ipfscliwrapper.Duration(90 * time.Second),
+		DirMode:               0750,
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected config %+v, but got %+v", expected, cfg)
+	}
+
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"daemon_warmup_duration":"5s"`) {
+		t.Errorf("Expected the duration encoded as \"5s\", but got %s", encoded)
+	}
+}
+
+// TestConfigFromJSONInvalidDuration checks malformed durations are reported.
+func TestConfigFromJSONInvalidDuration(t *testing.T) {
+	var cfg ipfscliwrapper.Config
+	if err := json.Unmarshal([]byte(`{"daemon_warmup_duration": "five seconds"}`), &cfg); err == nil {
+		t.Fatal("Expected an error, but got none")
+	}
+}
@@ -4,15 +4,23 @@ import "fmt"
 
 // Constants related to the IPFS binary and data directory paths.
 const (
-	// IPFSBinaryFilePath defines the path to the IPFS binary executable
-	// (commonly known as 'kubo'). This path is used when executing IPFS
-	// commands via the command line interface in the application.
+	// DefaultKuboVersion defines the release of the `ipfs` binary (commonly
+	// known as 'kubo') which gets downloaded unless another version was
+	// requested with the `WithKuboVersion` option.
+	DefaultKuboVersion = "v0.29.0"
+
+	// IPFSBinaryFilePath defines the path the IPFS binary executable
+	// (commonly known as 'kubo') was installed to by older releases of this
+	// package. The binaries are now installed per release, e.g. to
+	// "./bin/kubo/v0.29.0/ipfs", and existing installations are migrated.
+	// The `WithBinaryPath` option runs another binary instead.
 	IPFSBinaryFilePath = "./bin/kubo/ipfs"
 
 	// IPFSDataDirPath defines the path to the directory where IPFS stores
 	// its data, including the repository and configuration files. This path
 	// is crucial for ensuring the IPFS node has access to its necessary
-	// data files during operation.
+	// data files during operation. The `WithXDGLayout` and `WithRepoPath`
+	// options move it.
 	IPFSDataDirPath = "./bin/kubo/data"
 
 	// IPFSDenylistDirPath defines the path to the denylist directory within
@@ -54,9 +62,12 @@ const (
 // Supported operating systems include Darwin (macOS), Linux, FreeBSD, OpenBSD, and Windows,
 // and supported architectures include arm, arm64, 386, and amd64. The returned URL points
 // to a compressed archive (either .tar.gz or .zip, depending on the OS) that contains
-// the IPFS binary for the specified platform.
+// the IPFS binary for the specified platform. The archive is extracted based on
+// its content rather than its extension, so .tar.zst archives served in its
+// place work as well.
 //
 // Parameters:
+//   - version: A string representing the kubo release to download, e.g. "v0.29.0".
 //   - os: A string representing the operating system. Expected values include "darwin", "linux",
 //     "freebsd", "openbsd", and "windows".
 //   - arch: A string representing the CPU architecture. Expected values include "arm", "arm64",
@@ -69,7 +80,7 @@ const (
 //
 // Example usage:
 //
-//	url, err := getDownloadURL("linux", "amd64")
+//	url, err := getDownloadURL("v0.29.0", "linux", "amd64")
 //	if err != nil {
 //	    log.Fatalf("Failed to get download URL: %v", err)
 //	}
@@ -81,35 +92,36 @@ const (
 //     architecture combination, helping developers identify unsupported platform configurations.
 //
 // Note:
-//   - This function relies on hardcoded URLs for specific versions (e.g., v0.29.0) of the Kubo binaries.
-//     To update the version or add support for additional OS/arch combinations, modify the `urlsMap`
-//     in the function accordingly.
-func getDownloadURL(os string, arch string) (string, error) {
+//   - This function relies on the naming scheme of the Kubo releases on https://dist.ipfs.tech.
+//     To add support for additional OS/arch combinations, modify the `urlsMap` in the function
+//     accordingly.
+func getDownloadURL(version string, os string, arch string) (string, error) {
+	base := "https://dist.ipfs.tech/kubo/" + version + "/kubo_" + version + "_"
 	urlsMap := map[string]map[string]string{
 		"darwin": map[string]string{
-			"arm64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_darwin-arm64.tar.gz",
-			"amd64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_darwin-amd64.tar.gz",
+			"arm64": base + "darwin-arm64.tar.gz",
+			"amd64": base + "darwin-amd64.tar.gz",
 		},
 		"linux": map[string]string{
-			"arm":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_linux-arm.tar.gz",
-			"arm64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_linux-arm64.tar.gz",
-			"386":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_linux-386.tar.gz",
-			"amd64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_linux-amd64.tar.gz",
+			"arm":   base + "linux-arm.tar.gz",
+			"arm64": base + "linux-arm64.tar.gz",
+			"386":   base + "linux-386.tar.gz",
+			"amd64": base + "linux-amd64.tar.gz",
 		},
 		"freebsd": map[string]string{
-			"arm":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_freebsd-arm.tar.gz",
-			"386":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_freebsd-386.tar.gz",
-			"amd64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_freebsd-amd64.tar.gz",
+			"arm":   base + "freebsd-arm.tar.gz",
+			"386":   base + "freebsd-386.tar.gz",
+			"amd64": base + "freebsd-amd64.tar.gz",
 		},
 		"openbsd": map[string]string{
-			"arm":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_openbsd-arm.tar.gz",
-			"386":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_openbsd-386.tar.gz",
-			"amd64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_openbsd-amd64.tar.gz",
+			"arm":   base + "openbsd-arm.tar.gz",
+			"386":   base + "openbsd-386.tar.gz",
+			"amd64": base + "openbsd-amd64.tar.gz",
 		},
 		"windows": map[string]string{
-			"arm":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_windows-arm64.zip",
-			"386":   "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_windows-386.zip",
-			"amd64": "https://dist.ipfs.tech/kubo/v0.29.0/kubo_v0.29.0_windows-amd64.zip",
+			"arm":   base + "windows-arm64.zip",
+			"386":   base + "windows-386.zip",
+			"amd64": base + "windows-amd64.zip",
 		},
 	}
 
@@ -0,0 +1,288 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+)
+
+// ErrNotEnoughProviders is returned by `WaitForProviders` when fewer than the
+// requested number of providers were found before the timeout.
+var ErrNotEnoughProviders = errors.New("not enough providers found")
+
+// providersRetryInterval is the delay between two provider lookups of
+// `WaitForProviders`.
+const providersRetryInterval = 2 * time.Second
+
+func (wrap *ipfsCliWrapper) HasLocal(ctx context.Context, cid string) (bool, error) {
+	// Prepare the command to look up the block without retrieving it from the
+	// network. The global `--offline` flag makes kubo fail right away instead
+	// of asking other peers for a block which is missing locally.
+	cmd := wrap.command(ctx, "--offline", "block", "stat", "--", cid)
+
+	// Capture the output of the command
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		// For example "Error: block was not found locally (offline): ipld:
+		// could not find <cid>".
+		cmdErr := newCommandError("check local availability on ipfs", err, output)
+		if errors.Is(cmdErr, ErrNotFound) {
+			return false, nil
+		}
+		wrap.logger.Error("error checking local availability on ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		wrap.recordCommandFailure(cmdErr)
+		return false, cmdErr
+	}
+	return true, nil
+}
+
+func (wrap *ipfsCliWrapper) IsPinned(ctx context.Context, cid string) (bool, string, error) {
+	// Prepare the command to look up the pin of a single object. The type in
+	// the output is "recursive", "direct" or "indirect through <cid>".
+	cmd := wrap.command(ctx, "pin", "ls", "--type="+AllPinType, "--enc=json", "--", cid)
+
+	// Capture the output of the command, keeping warnings out of the JSON.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		// Kubo exits with the same status for any failure, so tell a missing
+		// pin apart by its error output.
+		cmdErr := newCommandError("check pin on ipfs", err, stderr.Bytes())
+		if errors.Is(cmdErr, ErrNotPinned) {
+			return false, "", nil
+		}
+		wrap.logger.Error("error checking pin on ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		wrap.recordCommandFailure(cmdErr)
+		return false, "", cmdErr
+	}
+
+	pinType, err := outputkit.ParsePinType(output)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to parse pin of `%s` from output: %s", cid, string(output))
+	}
+	return true, pinType, nil
+}
+
+func (wrap *ipfsCliWrapper) PinIfAbsent(ctx context.Context, cid string) (bool, error) {
+	isPinned, pinType, err := wrap.IsPinned(ctx, cid)
+	if err != nil {
+		return false, err
+	}
+
+	// An indirect pin only lasts as long as the pin of its ancestor, so
+	// the object still gets pinned on its own in that case.
+	if isPinned && pinType != IndirectPinType {
+		return false, nil
+	}
+	if err := wrap.Pin(ctx, cid); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (wrap *ipfsCliWrapper) WaitForProviders(ctx context.Context, cid string, minProviders int, timeout time.Duration) (int, error) {
+	if minProviders < 1 {
+		minProviders = 1
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	found := 0
+	for {
+		count, err := wrap.findProviders(ctx, cid, minProviders)
+		if count > found {
+			found = count
+		}
+		if found >= minProviders {
+			return found, nil
+		}
+		if err != nil && ctx.Err() == nil {
+			// Lookup errors are usually transient (e.g. the routing table
+			// is still being filled right after startup) so keep trying.
+			wrap.logger.Debug("provider lookup failed, retrying",
+				slog.String("cid", cid),
+				slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return found, fmt.Errorf("%w: found %d of %d providers for `%s` within %v", ErrNotEnoughProviders, found, minProviders, cid, timeout)
+		case <-time.After(providersRetryInterval):
+		}
+	}
+}
+
+// findProviders executes `ipfs routing findprovs` and returns the number of
+// distinct providers found, stopping once `max` providers were found.
+func (wrap *ipfsCliWrapper) findProviders(ctx context.Context, cid string, max int) (int, error) {
+	providers, err := wrap.findProviderIDs(ctx, cid, max)
+	return len(providers), err
+}
+
+// findProviderIDs executes `ipfs routing findprovs` and returns the peer IDs
+// of the distinct providers found, stopping once `max` providers were found.
+func (wrap *ipfsCliWrapper) findProviderIDs(ctx context.Context, cid string, max int) (map[string]struct{}, error) {
+	cmd := wrap.command(ctx, "routing", "findprovs", "--num-providers="+strconv.Itoa(max), "--enc=json", "--", cid)
+
+	// Capture the output of the command, one JSON routing event per line.
+	// The output is kept even on error since the lookup may be cut short by
+	// the context after some providers were already printed.
+	output, err := wrap.output(cmd)
+	providers := outputkit.ParseProviders(output)
+	if err != nil {
+		return providers, fmt.Errorf("failed to find providers on ipfs: %v", err)
+	}
+	return providers, nil
+}
+
+func (wrap *ipfsCliWrapper) Provide(ctx context.Context, cid string, recursive bool) error {
+	// Prepare the command to announce the object to the routing system. With
+	// `--recursive` every block of the DAG gets announced, not just the root.
+	args := []string{"routing", "provide"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+	cmd := wrap.command(ctx, append(args, "--", cid)...)
+
+	// Capture the output of the command
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		wrap.logger.Error("error providing content on ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return wrap.commandError("provide content on ipfs", err, output)
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) Reprovide(ctx context.Context) error {
+	// Newer kubo releases moved the command from `ipfs bitswap reprovide` to
+	// `ipfs routing reprovide`; older ones take "reprovide" for an argument
+	// of `ipfs routing` and refuse it.
+	output, err := wrap.combinedOutput(wrap.command(ctx, "routing", "reprovide"))
+	if err != nil && isUnknownSubcommand(output) {
+		output, err = wrap.combinedOutput(wrap.command(ctx, "bitswap", "reprovide"))
+	}
+	if err != nil {
+		wrap.logger.Error("error reproviding content on ipfs",
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return wrap.commandError("reprovide content on ipfs", err, output)
+	}
+	return nil
+}
+
+// isUnknownSubcommand reports whether kubo failed because the subcommand does
+// not exist in the installed release.
+func isUnknownSubcommand(output []byte) bool {
+	lowered := strings.ToLower(string(output))
+	return strings.Contains(lowered, "unknown command") || strings.Contains(lowered, "expected 0 argument")
+}
+
+// BitswapLedgerInfo is the bitswap ledger of the node with a peer, see
+// `BitswapLedger`.
+type BitswapLedgerInfo struct {
+	// Peer is the ID of the peer.
+	Peer string
+
+	// Sent and Received are the number of bytes of blocks the node sent to
+	// and received from the peer, and Exchanged the number of blocks.
+	Sent      uint64
+	Received  uint64
+	Exchanged uint64
+
+	// DebtRatio is `Sent / (Received + 1)`, the ratio bitswap uses to judge
+	// how much the node gave compared to what it got.
+	DebtRatio float64
+}
+
+func (wrap *ipfsCliWrapper) BitswapLedger(ctx context.Context, peerID string) (*BitswapLedgerInfo, error) {
+	cmd := wrap.command(ctx, "bitswap", "ledger", "--enc=json", "--", peerID)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error getting bitswap ledger",
+			slog.String("peer_id", peerID),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("get bitswap ledger", err, stderr.Bytes())
+	}
+	var ledger struct {
+		Peer      string  `json:"Peer"`
+		Value     float64 `json:"Value"`
+		Sent      uint64  `json:"Sent"`
+		Recv      uint64  `json:"Recv"`
+		Exchanged uint64  `json:"Exchanged"`
+	}
+	if err := json.Unmarshal(output, &ledger); err != nil {
+		return nil, fmt.Errorf("failed to parse bitswap ledger: %v", err)
+	}
+	return &BitswapLedgerInfo{
+		Peer:      ledger.Peer,
+		Sent:      ledger.Sent,
+		Received:  ledger.Recv,
+		Exchanged: ledger.Exchanged,
+		DebtRatio: ledger.Value,
+	}, nil
+}
+
+func (wrap *ipfsCliWrapper) AddIfAbsent(ctx context.Context, filepath string) (string, bool, error) {
+	cid, err := wrap.HashOnlyFile(ctx, filepath)
+	if err != nil {
+		return "", false, err
+	}
+
+	isLocal, err := wrap.HasLocal(ctx, cid)
+	if err != nil {
+		return "", false, err
+	}
+	if !isLocal {
+		cid, err := wrap.AddFile(ctx, filepath)
+		if err != nil {
+			return "", false, err
+		}
+		return cid, true, nil
+	}
+
+	// The content may be stored without being pinned, for example if it was
+	// only retrieved, so pin it like `AddFile` would have done to protect it
+	// from garbage collection.
+	if _, err := wrap.PinIfAbsent(ctx, cid); err != nil {
+		return "", false, err
+	}
+	wrap.logger.Debug("file already stored in ipfs, skipped adding",
+		slog.String("filepath", filepath),
+		slog.String("cid", cid))
+	return cid, false, nil
+}
+
+// swarmPeers returns the IDs of the peers the node is connected to.
+func (wrap *ipfsCliWrapper) swarmPeers(ctx context.Context) ([]string, error) {
+	cmd := wrap.command(ctx, "swarm", "peers", "--enc=json")
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return nil, wrap.commandError("list swarm peers", err, output)
+	}
+	peers, err := outputkit.ParseSwarmPeers(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode swarm peers: %v", err)
+	}
+	return peers, nil
+}
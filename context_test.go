@@ -0,0 +1,74 @@
+package ipfscliwrapper_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestContextPropagation checks, like a vet analyzer, that no function taking
+// a context ignores it by creating a new one with `context.Background` or
+// `context.TODO`, so cancellation by the caller always reaches the commands.
+func TestContextPropagation(t *testing.T) {
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, nil, 0)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || !takesContext(fn.Type) {
+				continue
+			}
+			ast.Inspect(fn.Body, func(node ast.Node) bool {
+				// Goroutines outliving the call may need a context of
+				// their own.
+				if _, ok := node.(*ast.GoStmt); ok {
+					return false
+				}
+				if _, ok := node.(*ast.FuncLit); ok {
+					return false
+				}
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				selector, ok := call.Fun.(*ast.SelectorExpr)
+				if !ok {
+					return true
+				}
+				if pkgName, ok := selector.X.(*ast.Ident); ok && pkgName.Name == "context" &&
+					(selector.Sel.Name == "Background" || selector.Sel.Name == "TODO") {
+					t.Errorf("%s: %s takes a context but calls context.%s", fset.Position(call.Pos()), fn.Name.Name, selector.Sel.Name)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// takesContext reports whether the function has a `context.Context`
+// parameter.
+func takesContext(fn *ast.FuncType) bool {
+	for _, param := range fn.Params.List {
+		selector, ok := param.Type.(*ast.SelectorExpr)
+		if !ok {
+			continue
+		}
+		if pkgName, ok := selector.X.(*ast.Ident); ok && pkgName.Name == "context" && selector.Sel.Name == "Context" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,902 @@
+package ipfscliwrapper_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
+)
+
+// contractKuboEnv lists the `ipfs` binaries, separated by commas, which the
+// contract tests run against, for example the oldest and newest supported
+// kubo releases. The tests are skipped if it is not set.
+const contractKuboEnv = "IPFS_CLI_WRAPPER_CONTRACT_KUBO"
+
+// TestKuboContract checks the wrapper understands the output of every given
+// kubo release, so changes to the output of kubo get caught before they
+// break apps.
+//
+// Example:
+//
+//	IPFS_CLI_WRAPPER_CONTRACT_KUBO=/opt/kubo-v0.29.0/ipfs,/opt/kubo-v0.32.1/ipfs go test -run TestKuboContract
+func TestKuboContract(t *testing.T) {
+	binaries := os.Getenv(contractKuboEnv)
+	if binaries == "" {
+		t.Skipf("Set %s to run the contract tests", contractKuboEnv)
+	}
+	for _, binary := range strings.Split(binaries, ",") {
+		binary = strings.TrimSpace(binary)
+		version, err := exec.Command(binary, "version", "--number").Output()
+		if err != nil {
+			t.Fatalf("Expected no error running %s, but got: %v", binary, err)
+		}
+		t.Run(strings.TrimSpace(string(version)), func(t *testing.T) {
+			testKuboContract(t, binary, "v"+strings.TrimSpace(string(version)))
+		})
+	}
+}
+
+func testKuboContract(t *testing.T, binary string, version string) {
+	// Install the binary where `WithXDGLayout` expects it so nothing gets
+	// downloaded.
+	home := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, "state"))
+	installed := filepath.Join(home, "data", "ipfs-cli-wrapper", "bin", "kubo", version, "ipfs")
+	if err := copyExecutable(binary, installed); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	// Content which is never added, blocked by a denylist loaded by the
+	// daemon.
+	const blocked = "bafkreidgvpkjawlxz6sffxzwgooowe5yt7i6wsyg236mfoks77nywkptdq"
+	blockEvents := make(chan ipfscliwrapper.BlockEvent, 16)
+	wrapper, err := ipfscliwrapper.NewWrapper(
+		ipfscliwrapper.WithXDGLayout(),
+		ipfscliwrapper.WithKuboVersion(version),
+		ipfscliwrapper.WithAutoPorts(),
+		ipfscliwrapper.WithDenylistReader("contract.deny", strings.NewReader("# blocked by the contract test\n/ipfs/"+blocked+"\n")),
+		ipfscliwrapper.WithBlockEventHook(func(event ipfscliwrapper.BlockEvent) { blockEvents <- event }),
+		ipfscliwrapper.WithOverrideDaemonInitialWarmupDuration(60))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := wrapper.StartDaemonInBackground(); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer wrapper.ShutdownDaemon()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// Names with spaces and words used by the human readable output of kubo
+	// must survive.
+	dir := filepath.Join(home, "site")
+	files := map[string]string{
+		"index.html":          "<h1>hello</h1>",
+		"added file.txt":      "added",
+		"assets/style.css":    "h1 {}",
+		"assets/fonts/a.woff": "font",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+
+	t.Run("AddFileEntries", func(t *testing.T) {
+		entries, err := wrapper.AddFileEntries(ctx, dir)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		expected := []string{"site/added file.txt", "site/assets/fonts/a.woff", "site/assets/style.css", "site/index.html", "site/assets/fonts", "site/assets", "site"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected entries %v, but got %v", expected, names)
+		}
+		root, err := wrapper.AddFile(ctx, dir)
+		if err != nil || root != entries[len(entries)-1].CID {
+			t.Errorf("Expected AddFile to return the root %s, but got %s and %v", entries[len(entries)-1].CID, root, err)
+		}
+		if mfs, err := wrapper.RunCommand(ctx, "files", "ls", "/"); err != nil || len(bytes.TrimSpace(mfs)) != 0 {
+			t.Errorf("Expected adding to leave MFS alone, but got %q and %v", mfs, err)
+		}
+	})
+
+	cid, err := wrapper.AddFileContent(ctx, "hello.txt", []byte("hello contract"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	t.Run("Cat", func(t *testing.T) {
+		content, err := wrapper.Cat(ctx, cid)
+		if err != nil || string(content) != "hello contract" {
+			t.Errorf("Expected the content back, but got %q and %v", content, err)
+		}
+	})
+
+	t.Run("VerifyContent", func(t *testing.T) {
+		if ok, err := wrapper.VerifyContent(ctx, cid, []byte("hello contract")); err != nil || !ok {
+			t.Errorf("Expected the raw cid to verify, but got %v and %v", ok, err)
+		}
+		if ok, err := wrapper.VerifyContent(ctx, cid, []byte("hello tampered")); err != nil || ok {
+			t.Errorf("Expected a mismatch of the raw cid, but got %v and %v", ok, err)
+		}
+
+		// Spread over several chunks so the layout decides the dag-pb cid.
+		data := bytes.Repeat([]byte("chunked contract data "), 64<<10)
+		defaultCID, err := wrapper.AddFileContent(ctx, "chunked.txt", data)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if ok, err := wrapper.VerifyContent(ctx, defaultCID, data); err != nil || !ok {
+			t.Errorf("Expected the dag-pb cid to verify, but got %v and %v", ok, err)
+		}
+		chunked := filepath.Join(t.TempDir(), "chunked.txt")
+		if err := os.WriteFile(chunked, data, 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		output, err := wrapper.RunCommand(ctx, "add", "--quieter", "--only-hash", "--cid-version=1", "--chunker=size-1024", chunked)
+		otherCID := strings.TrimSpace(string(output))
+		if err != nil || otherCID == defaultCID {
+			t.Fatalf("Expected another cid than %s, but got %s and %v", defaultCID, otherCID, err)
+		}
+		if ok, err := wrapper.VerifyContent(ctx, otherCID, data); !errors.Is(err, ipfscliwrapper.ErrCannotVerify) || ok {
+			t.Errorf("Expected ErrCannotVerify, but got %v and %v", ok, err)
+		}
+
+		// The same chunks in dag-pb leaves instead of raw ones give another
+		// dag-pb cid too.
+		output, err = wrapper.RunCommand(ctx, "add", "--quieter", "--only-hash", "--cid-version=1", "--raw-leaves=false", chunked)
+		leavesCID := strings.TrimSpace(string(output))
+		if err != nil || leavesCID == defaultCID {
+			t.Fatalf("Expected another cid than %s, but got %s and %v", defaultCID, leavesCID, err)
+		}
+		if ok, err := wrapper.VerifyContent(ctx, leavesCID, data); !errors.Is(err, ipfscliwrapper.ErrCannotVerify) || ok {
+			t.Errorf("Expected ErrCannotVerify, but got %v and %v", ok, err)
+		}
+	})
+
+	t.Run("AddFileResilient", func(t *testing.T) {
+		data := bytes.Repeat([]byte("resilient contract data "), 56<<10)
+		path := filepath.Join(t.TempDir(), "large.bin")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		joined, err := wrapper.AddFileResilient(ctx, path, ipfscliwrapper.ResilientAddOptions{SegmentSize: 512 << 10})
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		content, err := wrapper.Cat(ctx, joined)
+		if err != nil || !bytes.Equal(content, data) {
+			t.Errorf("Expected the %d bytes back, but got %d bytes and %v", len(data), len(content), err)
+		}
+		if pinned, _, err := wrapper.IsPinned(ctx, joined); err != nil || !pinned {
+			t.Errorf("Expected the joined file to be pinned, but got %v and %v", pinned, err)
+		}
+	})
+
+	t.Run("Pins", func(t *testing.T) {
+		pinned, pinType, err := wrapper.IsPinned(ctx, cid)
+		if err != nil || !pinned || pinType != ipfscliwrapper.RecursivePinType {
+			t.Errorf("Expected a recursive pin, but got %v, %q and %v", pinned, pinType, err)
+		}
+		pins, err := wrapper.ListPinsByType(ctx, ipfscliwrapper.RecursivePinType)
+		if err != nil || !containsString(pins, cid) {
+			t.Errorf("Expected %s among the pins, but got %v and %v", cid, pins, err)
+		}
+		if err := wrapper.Unpin(ctx, cid); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if pinned, _, err := wrapper.IsPinned(ctx, cid); err != nil || pinned {
+			t.Errorf("Expected no pin, but got %v and %v", pinned, err)
+		}
+		if err := wrapper.Unpin(ctx, cid); !errors.Is(err, ipfscliwrapper.ErrNotPinned) {
+			t.Errorf("Expected ErrNotPinned, but got: %v", err)
+		}
+	})
+
+	t.Run("HasLocal", func(t *testing.T) {
+		if has, err := wrapper.HasLocal(ctx, cid); err != nil || !has {
+			t.Errorf("Expected the content to be local, but got %v and %v", has, err)
+		}
+		path := filepath.Join(home, "missing.txt")
+		if err := os.WriteFile(path, []byte("never added"), 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		missing, err := wrapper.HashOnlyFile(ctx, path)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if has, err := wrapper.HasLocal(ctx, missing); err != nil || has {
+			t.Errorf("Expected the content not to be local, but got %v and %v", has, err)
+		}
+	})
+
+	t.Run("Preflight", func(t *testing.T) {
+		report := wrapper.Preflight(ctx)
+		if err := report.Err(); err != nil || !report.Passed {
+			t.Fatalf("Expected the checks to pass, but got: %v", err)
+		}
+		var names []string
+		for _, check := range report.Checks {
+			names = append(names, check.Name)
+		}
+		expected := []string{ipfscliwrapper.PreflightBinary, ipfscliwrapper.PreflightRepo, ipfscliwrapper.PreflightLock, ipfscliwrapper.PreflightPorts, ipfscliwrapper.PreflightDiskSpace}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected the checks %v, but got %v", expected, names)
+		}
+		if lock := report.Checks[2]; !strings.HasPrefix(lock.Detail, "held by the running daemon") {
+			t.Errorf("Expected the lock held by the daemon, but got %q", lock.Detail)
+		}
+	})
+
+	t.Run("Id", func(t *testing.T) {
+		info, err := wrapper.Id(ctx)
+		if err != nil || info.ID == "" {
+			t.Errorf("Expected the node identity, but got %+v and %v", info, err)
+		}
+		peer, err := wrapper.Id(ctx, info.ID)
+		if err != nil || peer.ID != info.ID {
+			t.Errorf("Expected the identity of %s, but got %+v and %v", info.ID, peer, err)
+		}
+	})
+
+	t.Run("IPNS", func(t *testing.T) {
+		entry, err := wrapper.NamePublish(ctx, cid, ipfscliwrapper.WithPublishTTL(30*time.Minute))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if entry.Name == "" || entry.Value != "/ipfs/"+cid || entry.TTL != 30*time.Minute {
+			t.Errorf("Expected the published entry of %s, but got: %+v", cid, entry)
+		}
+		if path, err := wrapper.NameResolve(ctx, entry.Name); err != nil || path != "/ipfs/"+cid {
+			t.Errorf("Expected the name to resolve to /ipfs/%s, but got %s and %v", cid, path, err)
+		}
+		if info, err := wrapper.NameInspect(ctx, entry.Name); err != nil || info.TTL != entry.TTL {
+			t.Errorf("Expected the record to carry the TTL %v, but got %+v and %v", entry.TTL, info, err)
+		}
+
+		if _, err := wrapper.NameResolve(ctx, "k51qzi5uqu5dlvj2baxnqndepeb86cbk3ng7n3i46uzyxzyqj2xjonzllnv0v8"); !errors.Is(err, ipfscliwrapper.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a name never published, but got: %v", err)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		key, err := wrapper.KeyGen(ctx, "contract")
+		if err != nil || key.Name != "contract" || key.ID == "" {
+			t.Fatalf("Expected the generated key, but got %+v and %v", key, err)
+		}
+		keys, err := wrapper.KeyList(ctx)
+		if err != nil || !slices.Contains(keys, *key) || !slices.ContainsFunc(keys, func(k ipfscliwrapper.KeyInfo) bool { return k.Name == "self" }) {
+			t.Errorf("Expected the self and generated keys, but got %+v and %v", keys, err)
+		}
+		entry, err := wrapper.NamePublish(ctx, cid, ipfscliwrapper.WithPublishKey(key.Name))
+		if err != nil || entry.Name != key.ID {
+			t.Errorf("Expected the name of the key %s, but got %+v and %v", key.ID, entry, err)
+		}
+
+		renamed, err := wrapper.KeyRename(ctx, "contract", "contract-renamed")
+		if err != nil || *renamed != (ipfscliwrapper.KeyInfo{Name: "contract-renamed", ID: key.ID}) {
+			t.Errorf("Expected the renamed key, but got %+v and %v", renamed, err)
+		}
+		exported, err := wrapper.KeyExport(ctx, "contract-renamed")
+		if err != nil || len(exported) == 0 {
+			t.Fatalf("Expected the exported key, but got %d bytes and %v", len(exported), err)
+		}
+		if err := wrapper.KeyRm(ctx, "contract-renamed"); err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+		imported, err := wrapper.KeyImport(ctx, "contract-imported", exported)
+		if err != nil || imported.ID != key.ID {
+			t.Errorf("Expected the key %s back, but got %+v and %v", key.ID, imported, err)
+		}
+
+		if err := wrapper.KeyRm(ctx, "contract-renamed"); !errors.Is(err, ipfscliwrapper.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a removed key, but got: %v", err)
+		}
+		if _, err := wrapper.KeyExport(ctx, "contract-renamed"); !errors.Is(err, ipfscliwrapper.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for a removed key, but got: %v", err)
+		}
+	})
+
+	t.Run("SyncDir", func(t *testing.T) {
+		root, err := wrapper.SyncDir(ctx, dir, "/contract")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		added, err := wrapper.AddFile(ctx, dir)
+		if err != nil || added != root {
+			t.Errorf("Expected the synced root %s to match %s, but got %v", root, added, err)
+		}
+		if flushed, err := wrapper.FilesFlush(ctx, "/contract"); err != nil || flushed != root {
+			t.Errorf("Expected the flushed CID %s, but got %s and %v", root, flushed, err)
+		}
+		mfsRoot, err := wrapper.FilesRootCID(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		links, err := wrapper.DagLinks(ctx, mfsRoot)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		linked := false
+		for _, link := range links {
+			linked = linked || (link.Name == "contract" && link.CID == root)
+		}
+		if !linked {
+			t.Errorf("Expected the MFS root to link the synced directory, but got %+v", links)
+		}
+		if _, err := wrapper.FilesFlush(ctx, "/missing"); !errors.Is(err, ipfscliwrapper.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, but got: %v", err)
+		}
+	})
+
+	t.Run("ToFiles", func(t *testing.T) {
+		mfsHash := func(mfsPath string) string {
+			output, err := wrapper.RunCommand(ctx, "files", "stat", "--hash", mfsPath)
+			if err != nil {
+				t.Fatalf("Expected %s to be linked into MFS, but got: %v", mfsPath, err)
+			}
+			return strings.TrimSpace(string(output))
+		}
+
+		root, err := wrapper.AddFile(ctx, dir, ipfscliwrapper.WithToFiles("/linked/deep/site"))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if linked := mfsHash("/linked/deep/site"); linked != root {
+			t.Errorf("Expected %s to be linked, but got %s", root, linked)
+		}
+
+		cid, err := wrapper.AddFileContent(ctx, "note.txt", []byte("note"), ipfscliwrapper.WithToFiles("/linked/notes/"))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if linked := mfsHash("/linked/notes/note.txt"); linked != cid {
+			t.Errorf("Expected %s to be linked, but got %s", cid, linked)
+		}
+
+		entries, err := wrapper.AddFileEntries(ctx, dir, ipfscliwrapper.WithToFiles("/linked/entries/"))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if linked := mfsHash("/linked/entries/site"); linked != entries[len(entries)-1].CID {
+			t.Errorf("Expected the root %s to stay linked, but got %s", entries[len(entries)-1].CID, linked)
+		}
+
+		if _, err := wrapper.AddFile(ctx, dir, ipfscliwrapper.WithToFiles("relative")); err == nil {
+			t.Errorf("Expected an error for a relative MFS path")
+		}
+	})
+
+	t.Run("WrapDirectory", func(t *testing.T) {
+		wrapped, err := wrapper.AddFileContent(ctx, "photo.jpg", []byte("photo"), ipfscliwrapper.WithWrapDirectory())
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := wrapper.CatPath(ctx, wrapped+"/photo.jpg"); err != nil || string(content) != "photo" {
+			t.Errorf("Expected the file inside the wrapping directory, but got %q and %v", content, err)
+		}
+
+		entries, err := wrapper.AddFileEntries(ctx, filepath.Join(dir, "index.html"), ipfscliwrapper.WithWrapDirectory())
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if len(entries) != 2 || entries[0].Name != "index.html" || entries[1].Name != "" {
+			t.Fatalf("Expected the file and the wrapping directory, but got %+v", entries)
+		}
+		file, err := wrapper.AddFile(ctx, filepath.Join(dir, "index.html"))
+		if err != nil || entries[0].CID != file {
+			t.Errorf("Expected the file CID %s, but got %s and %v", entries[0].CID, file, err)
+		}
+	})
+
+	t.Run("Layout", func(t *testing.T) {
+		// The layouts only differ once a file has more chunks than a node
+		// has links, 174 by default.
+		large := filepath.Join(home, "large.bin")
+		if err := os.WriteFile(large, make([]byte, 48_000_000), 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		balanced, err := wrapper.AddFile(ctx, large)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		trickle, err := wrapper.AddFile(ctx, large, ipfscliwrapper.WithTrickle())
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		expected, err := wrapper.RunCommand(ctx, "add", "--quieter", "--only-hash", "--trickle", "--cid-version=1", large)
+		if err != nil || trickle != strings.TrimSpace(string(expected)) || trickle == balanced {
+			t.Errorf("Expected the trickle layout %s, but got %s (balanced %s) and %v", expected, trickle, balanced, err)
+		}
+
+		_, err = wrapper.AddFile(ctx, large, ipfscliwrapper.WithMaxLinks(64))
+		installed, _ := versionkit.Parse(version)
+		required, _ := versionkit.Parse("v0.35.0")
+		if installed.Compare(required) < 0 {
+			if !errors.Is(err, ipfscliwrapper.ErrIncompatibleKubo) {
+				t.Errorf("Expected ErrIncompatibleKubo, but got: %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Expected no error, but got: %v", err)
+		}
+	})
+
+	t.Run("MultipleRoots", func(t *testing.T) {
+		multi := filepath.Join(home, "multi")
+		for _, name := range []string{"a.txt", "sub/b.txt"} {
+			path := filepath.Join(multi, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+		}
+		paths := []string{filepath.Join(multi, "a.txt"), filepath.Join(multi, "sub")}
+		for _, c := range []struct {
+			opts     []ipfscliwrapper.AddOption
+			expected []string
+		}{
+			{nil, []string{"a.txt", "sub/b.txt", "sub"}},
+			{[]ipfscliwrapper.AddOption{ipfscliwrapper.WithWrapDirectory()}, []string{"a.txt", "sub/b.txt", "sub", ""}},
+			{[]ipfscliwrapper.AddOption{ipfscliwrapper.WithToFiles("/multi/")}, []string{"a.txt", "sub/b.txt", "sub"}},
+		} {
+			entries, err := wrapper.AddFilesEntries(ctx, paths, c.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			if !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("Expected entries %v, but got %v", c.expected, names)
+			}
+			for i, index := range []int{0, 2} {
+				if cid, err := wrapper.AddFile(ctx, paths[i]); err != nil || cid != entries[index].CID {
+					t.Errorf("Expected the root %s of %s, but got %s and %v", cid, paths[i], entries[index].CID, err)
+				}
+			}
+		}
+		linked, err := wrapper.FilesFlush(ctx, "/multi")
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := wrapper.CatPath(ctx, linked+"/sub/b.txt"); err != nil || string(content) != "sub/b.txt" {
+			t.Errorf("Expected the paths linked into MFS, but got %q and %v", content, err)
+		}
+
+		if _, err := wrapper.AddFilesEntries(ctx, []string{paths[0], filepath.Join(dir, "..", "multi", "a.txt")}); err == nil {
+			t.Errorf("Expected an error adding paths with the same name")
+		}
+	})
+
+	t.Run("Hidden", func(t *testing.T) {
+		dotted := filepath.Join(home, "dotted")
+		for _, name := range []string{"visible.txt", ".hidden.txt", ".config/settings.json"} {
+			path := filepath.Join(dotted, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+		}
+		for _, c := range []struct {
+			opts     []ipfscliwrapper.AddOption
+			expected []string
+		}{
+			{nil, []string{"dotted/visible.txt", "dotted"}},
+			{[]ipfscliwrapper.AddOption{ipfscliwrapper.WithIncludeHidden()}, []string{"dotted/.config/settings.json", "dotted/.hidden.txt", "dotted/visible.txt", "dotted/.config", "dotted"}},
+		} {
+			entries, err := wrapper.AddFileEntries(ctx, dotted, c.opts...)
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			if !reflect.DeepEqual(names, c.expected) {
+				t.Errorf("Expected entries %v, but got %v", c.expected, names)
+			}
+		}
+	})
+
+	t.Run("Symlinks", func(t *testing.T) {
+		target := filepath.Join(home, "target.txt")
+		if err := os.WriteFile(target, []byte("target"), 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		linked := filepath.Join(home, "linked")
+		os.MkdirAll(linked, 0755)
+		if err := os.Symlink(target, filepath.Join(linked, "link.txt")); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		link := filepath.Join(home, "link.txt")
+		if err := os.Symlink(target, link); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+
+		// A symlink reads as the path it points to.
+		preserved, err := wrapper.AddFile(ctx, link)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := wrapper.Cat(ctx, preserved); err != nil || string(content) != target {
+			t.Errorf("Expected the symlink to be kept, but got %q and %v", content, err)
+		}
+		dereferenced, err := wrapper.AddFile(ctx, link, ipfscliwrapper.WithSymlinks(ipfscliwrapper.SymlinkDereferenceArgs))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := wrapper.Cat(ctx, dereferenced); err != nil || string(content) != "target" {
+			t.Errorf("Expected the content of the target, but got %q and %v", content, err)
+		}
+
+		followed, err := wrapper.AddFile(ctx, linked, ipfscliwrapper.WithSymlinks(ipfscliwrapper.SymlinkFollow))
+		installed, _ := versionkit.Parse(version)
+		required, _ := versionkit.Parse("v0.35.0")
+		if installed.Compare(required) < 0 {
+			if !errors.Is(err, ipfscliwrapper.ErrIncompatibleKubo) {
+				t.Errorf("Expected ErrIncompatibleKubo, but got: %v", err)
+			}
+		} else if content, err := wrapper.CatPath(ctx, followed+"/link.txt"); err != nil || string(content) != "target" {
+			t.Errorf("Expected the content of the target, but got %q and %v", content, err)
+		}
+	})
+
+	t.Run("GetTar", func(t *testing.T) {
+		root, err := wrapper.AddFile(ctx, dir)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		archive, err := wrapper.GetTar(ctx, root)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		defer archive.Close()
+		contents := map[string]string{}
+		reader := tar.NewReader(archive)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if header.Typeflag == tar.TypeReg {
+				b, _ := io.ReadAll(reader)
+				contents[strings.TrimPrefix(header.Name, root+"/")] = string(b)
+			}
+		}
+		if !reflect.DeepEqual(contents, files) {
+			t.Errorf("Expected the archive to hold %v, but got %v", files, contents)
+		}
+
+		if _, err := wrapper.GetTar(ctx, "not-a-cid"); !errors.Is(err, ipfscliwrapper.ErrInvalidCID) {
+			t.Errorf("Expected ErrInvalidCID, but got: %v", err)
+		}
+	})
+
+	t.Run("DagLinks", func(t *testing.T) {
+		root, err := wrapper.AddFile(ctx, dir)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		links, err := wrapper.DagLinks(ctx, root)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		var names []string
+		for _, link := range links {
+			names = append(names, link.Name)
+			if link.CID == "" || link.Size == 0 {
+				t.Errorf("Expected the link %q to have a CID and size, but got %+v", link.Name, link)
+			}
+		}
+		expected := []string{"added file.txt", "assets", "index.html"}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected links %v, but got %v", expected, names)
+		}
+		if links, err := wrapper.DagLinks(ctx, cid); err != nil || len(links) != 0 {
+			t.Errorf("Expected no links for a small file, but got %v and %v", links, err)
+		}
+	})
+
+	t.Run("Paths", func(t *testing.T) {
+		root, err := wrapper.AddFile(ctx, dir)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		for _, p := range []string{root + "/assets/style.css", "/ipfs/" + root + "/assets/style.css", "ipfs://" + root + "/assets/style.css"} {
+			content, err := wrapper.CatPath(ctx, p)
+			if err != nil || string(content) != files["assets/style.css"] {
+				t.Errorf("Expected the content of %s, but got %q and %v", p, content, err)
+			}
+		}
+		if _, err := wrapper.CatPath(ctx, root+"/missing.txt"); !errors.Is(err, ipfscliwrapper.ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, but got: %v", err)
+		}
+		if _, err := wrapper.CatPath(ctx, root+"/../x"); !errors.Is(err, ipfscliwrapper.ErrInvalidCID) {
+			t.Errorf("Expected ErrInvalidCID, but got: %v", err)
+		}
+
+		out := t.TempDir()
+		if err := wrapper.GetPath(ipfscliwrapper.WithCommandWorkDir(ctx, out), root+"/assets/fonts"); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		content, err := os.ReadFile(filepath.Join(out, "fonts", "a.woff"))
+		if err != nil || string(content) != files["assets/fonts/a.woff"] {
+			t.Errorf("Expected the retrieved directory, but got %q and %v", content, err)
+		}
+	})
+
+	t.Run("UnusualNames", func(t *testing.T) {
+		// Spaces, quotes, a leading dash and non-ASCII characters must
+		// neither be split nor mistaken for flags.
+		names := []string{"with space.txt", `it's "quoted".txt`, "-dash.txt", "naïve ünïcödé.txt", "日本語/ファイル.txt"}
+		unusual := filepath.Join(home, "ûnusual dïr")
+		for _, name := range names {
+			path := filepath.Join(unusual, name)
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+		}
+
+		entries, err := wrapper.AddFileEntries(ctx, unusual)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		root := entries[len(entries)-1]
+		if root.Name != "ûnusual dïr" {
+			t.Errorf("Expected the root named after the directory, but got %q", root.Name)
+		}
+		for _, name := range names {
+			expected := "ûnusual dïr/" + name
+			if !slices.ContainsFunc(entries, func(entry ipfscliwrapper.AddedEntry) bool { return entry.Name == expected }) {
+				t.Errorf("Expected an entry %q, but got %+v", expected, entries)
+			}
+		}
+		if cid, err := wrapper.AddFile(ctx, unusual); err != nil || cid != root.CID {
+			t.Errorf("Expected AddFile to return the root %s, but got %s and %v", root.CID, cid, err)
+		}
+
+		for _, name := range names {
+			content, err := wrapper.CatPath(ctx, root.CID+"/"+name)
+			if err != nil || string(content) != name {
+				t.Errorf("Expected the content of %q, but got %q and %v", name, content, err)
+			}
+		}
+		escaped := "ipfs://" + root.CID + "/" + url.PathEscape("naïve ünïcödé.txt")
+		if content, err := wrapper.CatPath(ctx, escaped); err != nil || string(content) != "naïve ünïcödé.txt" {
+			t.Errorf("Expected the content of %s, but got %q and %v", escaped, content, err)
+		}
+
+		out := t.TempDir()
+		if err := wrapper.GetPath(ipfscliwrapper.WithCommandWorkDir(ctx, out), root.CID+"/"+`it's "quoted".txt`); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := os.ReadFile(filepath.Join(out, `it's "quoted".txt`)); err != nil || string(content) != `it's "quoted".txt` {
+			t.Errorf("Expected the retrieved file, but got %q and %v", content, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := wrapper.Cat(ctx, "not-a-cid"); !errors.Is(err, ipfscliwrapper.ErrInvalidCID) {
+			t.Errorf("Expected ErrInvalidCID, but got: %v", err)
+		}
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		readOnly, err := ipfscliwrapper.NewWrapper(
+			ipfscliwrapper.WithXDGLayout(),
+			ipfscliwrapper.WithKuboVersion(version),
+			ipfscliwrapper.WithReadOnly())
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := readOnly.Cat(ctx, cid); err != nil || string(content) != "hello contract" {
+			t.Errorf("Expected the content back, but got %q and %v", content, err)
+		}
+		if _, err := readOnly.AddFileContent(ctx, "read-only.txt", []byte("read-only")); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+			t.Errorf("Expected ErrReadOnly adding, but got: %v", err)
+		}
+		if err := readOnly.Unpin(ctx, cid); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+			t.Errorf("Expected ErrReadOnly unpinning, but got: %v", err)
+		}
+		if err := readOnly.GarbageCollection(ctx); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+			t.Errorf("Expected ErrReadOnly collecting garbage, but got: %v", err)
+		}
+		if _, err := readOnly.RunCommand(ctx, "config", "Addresses.Gateway", "/ip4/127.0.0.1/tcp/1"); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+			t.Errorf("Expected ErrReadOnly setting config, but got: %v", err)
+		}
+		if _, err := readOnly.RunCommand(ctx, "add", "--only-hash", "-Q", filepath.Join(dir, "index.html")); err != nil {
+			t.Errorf("Expected hashing to be allowed, but got: %v", err)
+		}
+		if _, err := readOnly.RunCommand(ctx, "config", "Addresses.Gateway"); err != nil {
+			t.Errorf("Expected reading config to be allowed, but got: %v", err)
+		}
+	})
+
+	t.Run("CommandPolicy", func(t *testing.T) {
+		restricted, err := ipfscliwrapper.NewWrapper(
+			ipfscliwrapper.WithXDGLayout(),
+			ipfscliwrapper.WithKuboVersion(version),
+			ipfscliwrapper.WithAllowedCommands("config show", "cat", "key"),
+			ipfscliwrapper.WithDeniedCommands("key export"))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if content, err := restricted.Cat(ctx, cid); err != nil || string(content) != "hello contract" {
+			t.Errorf("Expected the content back, but got %q and %v", content, err)
+		}
+		if _, err := restricted.RunCommand(ctx, "key", "list"); err != nil {
+			t.Errorf("Expected listing keys to be allowed, but got: %v", err)
+		}
+		if _, err := restricted.RunCommand(ctx, "key", "export", "self"); !errors.Is(err, ipfscliwrapper.ErrCommandNotAllowed) {
+			t.Errorf("Expected ErrCommandNotAllowed exporting a key, but got: %v", err)
+		}
+		if _, err := restricted.ListPins(ctx); !errors.Is(err, ipfscliwrapper.ErrCommandNotAllowed) {
+			t.Errorf("Expected ErrCommandNotAllowed listing pins, but got: %v", err)
+		}
+	})
+
+	t.Run("Denylists", func(t *testing.T) {
+		if isBlocked, err := wrapper.IsBlocked(ctx, "ipfs://"+blocked); err != nil || !isBlocked {
+			t.Errorf("Expected the denylisted content to be blocked, but got: %v, %v", isBlocked, err)
+		}
+		if isBlocked, err := wrapper.IsBlocked(ctx, cid); err != nil || isBlocked {
+			t.Errorf("Expected the added content not to be blocked, but got: %v, %v", isBlocked, err)
+		}
+		if _, err := wrapper.Cat(ctx, blocked); !errors.Is(err, ipfscliwrapper.ErrBlocked) {
+			t.Errorf("Expected ErrBlocked, but got: %v", err)
+		}
+		select {
+		case event := <-blockEvents:
+			if event.Path != "/ipfs/"+blocked || filepath.Base(event.Denylist) != "contract.deny" || event.Line != 2 {
+				t.Errorf("Expected the block event of the denylisted content, but got: %+v", event)
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("Expected a block event")
+		}
+
+		status, err := wrapper.DenylistStatus(ctx)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		var found bool
+		for _, denylist := range status.Denylists {
+			if denylist.Name == "contract.deny" {
+				found = denylist.Rules == 1 && denylist.Tested && denylist.Enforced
+			}
+		}
+		if !status.Running || !found {
+			t.Errorf("Expected contract.deny to be enforced, but got: %+v", status)
+		}
+	})
+
+	t.Run("CustomPaths", func(t *testing.T) {
+		repo := filepath.Join(home, "isolated")
+		isolated, err := ipfscliwrapper.NewWrapper(
+			ipfscliwrapper.WithBinaryPath(binary),
+			ipfscliwrapper.WithRepoPath(repo))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(repo, "config")); err != nil {
+			t.Errorf("Expected the repo to be initialized in %s, but got: %v", repo, err)
+		}
+		report := isolated.Preflight(ctx)
+		if check := report.Checks[0]; !check.Passed || !strings.HasSuffix(check.Detail, binary) {
+			t.Errorf("Expected the binary check to pass for %s, but got: %+v", binary, check)
+		}
+
+		if _, err := ipfscliwrapper.NewWrapper(
+			ipfscliwrapper.WithBinaryPath(filepath.Join(home, "missing", "ipfs")),
+			ipfscliwrapper.WithRepoPath(repo)); err == nil {
+			t.Error("Expected an error for a missing binary")
+		}
+	})
+
+	t.Run("CommandResult", func(t *testing.T) {
+		var mu sync.Mutex
+		var results []ipfscliwrapper.CommandResult
+		observed, err := ipfscliwrapper.NewWrapper(
+			ipfscliwrapper.WithXDGLayout(),
+			ipfscliwrapper.WithKuboVersion(version),
+			ipfscliwrapper.WithCommandResultHook(func(result ipfscliwrapper.CommandResult) {
+				mu.Lock()
+				defer mu.Unlock()
+				results = append(results, result)
+			}))
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		mu.Lock()
+		results = nil
+		mu.Unlock()
+
+		if content, err := observed.Cat(ctx, cid); err != nil || string(content) != "hello contract" {
+			t.Fatalf("Expected the content back, but got %q and %v", content, err)
+		}
+		if _, err := observed.RunCommand(ctx, "cat", "--", "not-a-cid"); err == nil {
+			t.Fatalf("Expected an error")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, but got %+v", results)
+		}
+		if cat := results[0]; !slices.Contains(cat.Args, "cat") || string(cat.Stdout) != "hello contract" || cat.ExitCode != 0 || cat.Err != nil {
+			t.Errorf("Expected the result of `ipfs cat`, but got %+v", cat)
+		}
+		if failed := results[1]; failed.ExitCode != 1 || len(failed.Stderr) == 0 || failed.Err == nil {
+			t.Errorf("Expected the failure of `ipfs cat`, but got %+v", failed)
+		}
+	})
+
+	t.Run("GarbageCollection", func(t *testing.T) {
+		gcErr := make(chan error, 1)
+		go func() { gcErr <- wrapper.GarbageCollection(ctx) }()
+		added, err := wrapper.AddFileContent(ctx, "during-gc.txt", []byte("added during gc"))
+		if err != nil {
+			t.Fatalf("Expected the add to wait for the garbage collection, but got: %v", err)
+		}
+		if err := <-gcErr; err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if has, err := wrapper.HasLocal(ctx, added); err != nil || !has {
+			t.Errorf("Expected the content added during the garbage collection to be kept, but got %v and %v", has, err)
+		}
+	})
+}
+
+func copyExecutable(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package ipfscliwrapper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+)
+
+// daemonCrashFileName is the name of the file, inside the repo, which holds
+// the details of the last crash of the daemon.
+const daemonCrashFileName = "daemon-crash.json"
+
+// DaemonCrash holds the details of a daemon, started by the wrapper, which
+// exited without being asked to.
+type DaemonCrash struct {
+	// Time is when the exit was noticed.
+	Time time.Time `json:"time"`
+
+	// PID is the process id the daemon had.
+	PID int `json:"pid"`
+
+	// ExitCode is the exit code of the daemon, or -1 if it was terminated
+	// by a signal.
+	ExitCode int `json:"exit_code"`
+
+	// ExitStatus describes how the daemon exited, for example
+	// "exit status 2" or "signal: killed".
+	ExitStatus string `json:"exit_status"`
+
+	// Stdout and Stderr are the tails of the output of the daemon. In
+	// continous operation mode they are only captured for the streams which
+	// the `WithDaemonOutputFiles` option configured a file for.
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+}
+
+func (wrap *ipfsCliWrapper) crashFilePath() string {
+	return filepath.Join(wrap.repoPath(), daemonCrashFileName)
+}
+
+// captureCrash waits for the daemon to exit and, unless it was stopped on
+// purpose, records the crash to the crash file.
+func (wrap *ipfsCliWrapper) captureCrash(daemon *prockit.Process, readStdout func() string, readStderr func() string) {
+	waitErr := daemon.Wait()
+	if daemon.ExitExpected() {
+		return
+	}
+
+	crash := DaemonCrash{
+		Time:       time.Now(),
+		PID:        daemon.Pid(),
+		ExitStatus: "exit status 0",
+		Stdout:     readStdout(),
+		Stderr:     readStderr(),
+	}
+	var exitError *exec.ExitError
+	if errors.As(waitErr, &exitError) {
+		crash.ExitCode = exitError.ExitCode()
+		crash.ExitStatus = exitError.Error()
+	} else if waitErr != nil {
+		crash.ExitCode = -1
+		crash.ExitStatus = waitErr.Error()
+	}
+
+	wrap.recordTelemetry(TelemetryEvent{Type: TelemetryDaemonCrashed, ExitCode: crash.ExitCode})
+
+	wrap.logger.Error("ipfs daemon exited unexpectedly",
+		slog.Int("pid", crash.PID),
+		slog.String("exit_status", crash.ExitStatus),
+		slog.String("crash_file", wrap.crashFilePath()))
+
+	if err := wrap.writeCrashFile(&crash); err != nil {
+		wrap.logger.Warn("failed recording daemon crash", slog.Any("error", err))
+	}
+}
+
+func (wrap *ipfsCliWrapper) writeCrashFile(crash *DaemonCrash) error {
+	b, err := json.MarshalIndent(crash, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The output may contain sensitive details, so keep it to the owner.
+	if err := os.WriteFile(wrap.crashFilePath(), b, 0600); err != nil {
+		return fmt.Errorf("failed to write daemon crash file: %v", err)
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) LastCrash() (*DaemonCrash, error) {
+	b, err := os.ReadFile(wrap.crashFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read daemon crash file: %v", err)
+	}
+	var crash DaemonCrash
+	if err := json.Unmarshal(b, &crash); err != nil {
+		return nil, fmt.Errorf("failed to decode daemon crash file: %v", err)
+	}
+	return &crash, nil
+}
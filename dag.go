@@ -0,0 +1,127 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DagLink is a named link from a node of a DAG to one of its children, as
+// returned by `DagLinks`.
+type DagLink struct {
+	// Name is the name of the link, e.g. the file name inside a directory,
+	// or empty for the chunks of a file.
+	Name string
+
+	// CID is the content identifier of the child.
+	CID string
+
+	// Size is the cumulative size of the child and its descendants in
+	// bytes, as recorded in the link.
+	Size uint64
+}
+
+func (wrap *ipfsCliWrapper) DagLinks(ctx context.Context, cid string) ([]DagLink, error) {
+	// `ipfs object links` was removed from kubo, so the node is decoded with
+	// `ipfs dag get` which prints the links of a dag-pb node as:
+	// {"Links":[{"Hash":{"/":"<cid>"},"Name":"<name>","Tsize":<size>}]}.
+	cmd := wrap.command(ctx, "dag", "get", "--output-codec=dag-json", "--", cid)
+
+	// Capture the output of the command, keeping warnings out of the JSON.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error getting dag node from ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("get dag links from ipfs", err, stderr.Bytes())
+	}
+
+	var node struct {
+		Links []struct {
+			Hash struct {
+				CID string `json:"/"`
+			}
+			Name  string
+			Tsize uint64
+		}
+	}
+	if err := json.Unmarshal(output, &node); err != nil {
+		wrap.logger.Error("error decoding dag node",
+			slog.String("cid", cid),
+			slog.Any("error", err))
+		return nil, newCommandError("decode dag node", err, output)
+	}
+
+	// Nodes of other codecs, e.g. dag-cbor, have no "Links" and therefore
+	// no links are returned.
+	links := make([]DagLink, 0, len(node.Links))
+	for _, link := range node.Links {
+		links = append(links, DagLink{Name: link.Name, CID: link.Hash.CID, Size: link.Tsize})
+	}
+	return links, nil
+}
+
+func (wrap *ipfsCliWrapper) CatPath(ctx context.Context, ipfsPath string) ([]byte, error) {
+	resolved, err := normalizeIPFSPath(ipfsPath)
+	if err != nil {
+		return nil, err
+	}
+	return wrap.Cat(ctx, resolved)
+}
+
+func (wrap *ipfsCliWrapper) GetPath(ctx context.Context, ipfsPath string) error {
+	resolved, err := normalizeIPFSPath(ipfsPath)
+	if err != nil {
+		return err
+	}
+	return wrap.GetFile(ctx, resolved)
+}
+
+// normalizeIPFSPath turns the supported spellings of a path into a content
+// path kubo resolves itself, only retrieving the blocks along the path:
+// "<cid>/sub/file.txt", "/ipfs/<cid>/sub/file.txt", "ipfs://<cid>/sub/file.txt"
+// and their "/ipns/" and "ipns://" counterparts. Names in the URL forms are
+// percent-decoded, so "ipfs://<cid>/my%20file.txt" names "my file.txt".
+func normalizeIPFSPath(ipfsPath string) (string, error) {
+	p := strings.TrimSpace(ipfsPath)
+	namespace := "ipfs"
+	isURL := false
+	switch {
+	case strings.HasPrefix(p, "ipfs://"):
+		p, isURL = strings.TrimPrefix(p, "ipfs://"), true
+	case strings.HasPrefix(p, "ipns://"):
+		namespace, p, isURL = "ipns", strings.TrimPrefix(p, "ipns://"), true
+	case strings.HasPrefix(p, "/ipfs/"):
+		p = strings.TrimPrefix(p, "/ipfs/")
+	case strings.HasPrefix(p, "/ipns/"):
+		namespace, p = "ipns", strings.TrimPrefix(p, "/ipns/")
+	}
+	if isURL {
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			return "", fmt.Errorf("%w: %q is not a valid url: %v", ErrInvalidCID, ipfsPath, err)
+		}
+		p = unescaped
+	}
+	root, rest, _ := strings.Cut(strings.Trim(p, "/"), "/")
+	if root == "" {
+		return "", fmt.Errorf("%w: %q has no root", ErrInvalidCID, ipfsPath)
+	}
+	for _, segment := range strings.Split(rest, "/") {
+		if segment == ".." {
+			return "", fmt.Errorf("%w: %q leaves its root", ErrInvalidCID, ipfsPath)
+		}
+	}
+	if rest == "" {
+		return "/" + namespace + "/" + root, nil
+	}
+	return path.Join("/"+namespace, root, rest), nil
+}
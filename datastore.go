@@ -0,0 +1,208 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Constants representing the datastore implementations the repo can be
+// created with.
+const (
+	// FlatfsDatastoreType stores every block as a separate file on disk. This
+	// is the kubo default and works well for large files.
+	FlatfsDatastoreType = "flatfs"
+
+	// BadgerDatastoreType stores blocks in a badger key-value store, which
+	// performs better than flatfs for workloads with many small blocks.
+	BadgerDatastoreType = "badgerds"
+)
+
+// FlatfsDatastoreParams holds the tunable parameters of the flatfs datastore.
+type FlatfsDatastoreParams struct {
+	// Sync controls whether every write is flushed to disk before returning.
+	// Disabling it is faster but risks data loss on power failure.
+	Sync bool
+}
+
+// BadgerDatastoreParams holds the tunable parameters of the badger datastore.
+type BadgerDatastoreParams struct {
+	// SyncWrites controls whether every write is flushed to disk before
+	// returning.
+	SyncWrites bool
+
+	// Truncate controls whether badger truncates corrupted data on startup
+	// instead of refusing to open the datastore.
+	Truncate bool
+
+	// VLogFileSize sets the maximum size of a value log file (e.g. "1GiB").
+	// Leave empty to keep the kubo default.
+	VLogFileSize string
+}
+
+// datastoreConfig holds the datastore the repo gets created with.
+type datastoreConfig struct {
+	// typeID is either `FlatfsDatastoreType` or `BadgerDatastoreType`.
+	typeID string
+
+	flatfs FlatfsDatastoreParams
+	badger BadgerDatastoreParams
+}
+
+// initProfile returns the `ipfs init --profile` value selecting the datastore.
+func (ds *datastoreConfig) initProfile() string {
+	if ds.typeID == BadgerDatastoreType {
+		return "badgerds"
+	}
+	return "flatfs"
+}
+
+// applyDatastoreParams rewrites the `Datastore.Spec` of a freshly created
+// repo with the configured parameters. Only parameters which are not part of
+// the `datastore_spec` file are changed, so the datastore created by `ipfs
+// init` stays compatible with the configuration.
+func (wrap *ipfsCliWrapper) applyDatastoreParams() error {
+	getCmd := wrap.baseCommand(context.Background(), "config", "Datastore.Spec")
+	output, err := wrap.output(getCmd)
+	if err != nil {
+		return fmt.Errorf("failed to read datastore spec: %v", err)
+	}
+
+	var spec map[string]any
+	if err := json.Unmarshal(output, &spec); err != nil {
+		return fmt.Errorf("failed to parse datastore spec: %v", err)
+	}
+
+	child := findDatastoreSpec(spec, wrap.datastore.typeID)
+	if child == nil {
+		return fmt.Errorf("failed to find `%s` in datastore spec", wrap.datastore.typeID)
+	}
+	switch wrap.datastore.typeID {
+	case FlatfsDatastoreType:
+		child["sync"] = wrap.datastore.flatfs.Sync
+	case BadgerDatastoreType:
+		child["syncWrites"] = wrap.datastore.badger.SyncWrites
+		child["truncate"] = wrap.datastore.badger.Truncate
+		if wrap.datastore.badger.VLogFileSize != "" {
+			child["vlogFileSize"] = wrap.datastore.badger.VLogFileSize
+		}
+	}
+
+	updated, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	return wrap.setConfig("Datastore.Spec", string(updated), true)
+}
+
+// findDatastoreSpec walks the (possibly nested) datastore spec and returns
+// the node describing the datastore of the given type.
+func findDatastoreSpec(spec map[string]any, typeID string) map[string]any {
+	if spec["type"] == typeID {
+		return spec
+	}
+	if child, ok := spec["child"].(map[string]any); ok {
+		if found := findDatastoreSpec(child, typeID); found != nil {
+			return found
+		}
+	}
+	if mounts, ok := spec["mounts"].([]any); ok {
+		for _, mount := range mounts {
+			if m, ok := mount.(map[string]any); ok {
+				if found := findDatastoreSpec(m, typeID); found != nil {
+					return found
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyStorageLimits sets `Datastore.StorageMax` and
+// `Datastore.StorageGCWatermark` so the automatic garbage collection of the
+// daemon triggers within the disk budget configured by the
+// `WithStorageMax` and `WithStorageGCWatermark` options.
+func (wrap *ipfsCliWrapper) applyStorageLimits() error {
+	if wrap.storageMax != "" {
+		if err := wrap.setConfig("Datastore.StorageMax", wrap.storageMax, false); err != nil {
+			return err
+		}
+	}
+	if wrap.storageGCWatermark > 0 {
+		if err := wrap.setConfig("Datastore.StorageGCWatermark", strconv.Itoa(wrap.storageGCWatermark), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyAddresses writes the API, gateway and swarm addresses configured by
+// the `WithAPIAddress`, `WithGatewayAddress` and `WithSwarmAddresses` options
+// into the repo configuration.
+func (wrap *ipfsCliWrapper) applyAddresses() error {
+	// Move the API and gateway to the interfaces configured by the
+	// `WithAPIBindAddress` and `WithGatewayBindAddress` options, keeping
+	// their ports.
+	if wrap.apiBindIP != "" {
+		addr, err := wrap.rebindAddress(wrap.apiAddr, "Addresses.API", wrap.apiBindIP)
+		if err != nil {
+			return err
+		}
+		wrap.apiAddr = addr
+	}
+	if wrap.gatewayBindIP != "" {
+		addr, err := wrap.rebindAddress(wrap.gatewayAddr, "Addresses.Gateway", wrap.gatewayBindIP)
+		if err != nil {
+			return err
+		}
+		wrap.gatewayAddr = addr
+	}
+	wrap.warnIfExposed("api", wrap.apiAddr)
+	wrap.warnIfExposed("gateway", wrap.gatewayAddr)
+
+	if wrap.apiAddr != "" {
+		if err := wrap.setConfig("Addresses.API", wrap.apiAddr, false); err != nil {
+			return err
+		}
+	}
+	if wrap.gatewayDisabled {
+		// An empty list makes kubo not open the gateway listener at all.
+		if err := wrap.setConfig("Addresses.Gateway", "[]", true); err != nil {
+			return err
+		}
+	} else if wrap.gatewayAddr != "" {
+		if err := wrap.setConfig("Addresses.Gateway", wrap.gatewayAddr, false); err != nil {
+			return err
+		}
+	}
+	if len(wrap.swarmAddrs) > 0 {
+		swarm, err := json.Marshal(wrap.swarmAddrs)
+		if err != nil {
+			return err
+		}
+		if err := wrap.setConfig("Addresses.Swarm", string(swarm), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setConfig executes `ipfs config` to set the key to the value. If `isJSON`
+// is true the value is parsed as JSON by kubo instead of as a string. The
+// `--api` flag is not passed because the configuration is usually written
+// before the daemon is started; kubo still routes the command through a
+// running daemon on its own when there is one.
+func (wrap *ipfsCliWrapper) setConfig(key string, value string, isJSON bool) error {
+	args := []string{"config"}
+	if isJSON {
+		args = append(args, "--json")
+	}
+	args = append(args, key, value)
+
+	cmd := wrap.baseCommand(context.Background(), args...)
+	if output, err := wrap.combinedOutput(cmd); err != nil {
+		return wrap.commandError(fmt.Sprintf("set config `%s`", key), err, output)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// denylistExt is the extension of the files kubo loads as denylists from the
+// denylists directory of the repo.
+const denylistExt = ".deny"
+
+// localDenylist is a denylist installed from a local file or a reader rather
+// than downloaded, see `WithDenylistFile` and `WithDenylistReader`.
+type localDenylist struct {
+	name   string
+	path   string
+	reader io.Reader
+}
+
+// validateLocalDenylists checks the names of the local denylists are file
+// names kubo loads and do not collide.
+func (wrap *ipfsCliWrapper) validateLocalDenylists() error {
+	names := map[string]bool{}
+	if wrap.denylistFilename != "" {
+		names[wrap.denylistFilename] = true
+	}
+	for _, denylist := range wrap.localDenylists {
+		switch {
+		case denylist.name == "" || filepath.Base(denylist.name) != denylist.name:
+			return fmt.Errorf("denylist name must be a file name, got `%s`", denylist.name)
+		case !strings.HasSuffix(denylist.name, denylistExt):
+			return fmt.Errorf("denylist name must end with `%s` to be loaded by kubo, got `%s`", denylistExt, denylist.name)
+		case names[denylist.name]:
+			return fmt.Errorf("denylist `%s` is configured more than once", denylist.name)
+		}
+		names[denylist.name] = true
+	}
+	return nil
+}
+
+// installLocalDenylists copies the local denylists into the denylists
+// directory of the repo. They are copied on every run, so changes to the
+// curated lists are applied by the next daemon start.
+func (wrap *ipfsCliWrapper) installLocalDenylists() error {
+	for _, denylist := range wrap.localDenylists {
+		if err := wrap.installLocalDenylist(denylist); err != nil {
+			return fmt.Errorf("failed installing denylist `%s`: %v", denylist.name, err)
+		}
+	}
+	return nil
+}
+
+// installLocalDenylist stages the denylist in the temp directory and moves it
+// into place once complete, so the daemon never loads a partial denylist.
+func (wrap *ipfsCliWrapper) installLocalDenylist(denylist localDenylist) error {
+	reader := denylist.reader
+	if reader == nil {
+		file, err := os.Open(denylist.path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	stagedPath := filepath.Join(stageDir, denylist.name)
+	staged, err := os.OpenFile(stagedPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(staged, reader); err != nil {
+		staged.Close()
+		return err
+	}
+	if err := staged.Close(); err != nil {
+		return err
+	}
+	return moveIntoPlace(stagedPath, filepath.Join(wrap.denylistDirPath(), denylist.name))
+}
+
+// DenylistStatus describes the denylists kubo loads, see `DenylistStatus`.
+type DenylistStatus struct {
+	// Running reports whether the daemon, which is what enforces the
+	// denylists, is running.
+	Running bool `json:"running"`
+
+	Denylists []DenylistInfo `json:"denylists"`
+}
+
+// DenylistInfo describes one of the denylists kubo loads.
+type DenylistInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+
+	// Rules is how many rules the denylist holds, not counting its header
+	// and comments.
+	Rules int `json:"rules"`
+
+	// Tested reports whether one of the rules of the denylist, blocking an
+	// "/ipfs/" path, was tested with `IsBlocked`, in which case Enforced
+	// reports whether the daemon blocked it. A denylist created after the
+	// daemon started is only enforced once it is restarted.
+	Tested   bool `json:"tested"`
+	Enforced bool `json:"enforced"`
+}
+
+func (wrap *ipfsCliWrapper) DenylistStatus(ctx context.Context) (*DenylistStatus, error) {
+	_, running := wrap.apiHostPort()
+	status := &DenylistStatus{Running: running}
+	for _, dir := range wrap.denylistDirPaths() {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+denylistExt))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			rules, err := readDenylistRules(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed reading denylist `%s`: %v", path, err)
+			}
+			info := DenylistInfo{Name: filepath.Base(path), Path: path, Rules: len(rules)}
+			if probe := denylistProbe(rules); running && probe != "" {
+				blocked, err := wrap.IsBlocked(ctx, probe)
+				if err != nil {
+					return nil, err
+				}
+				info.Tested, info.Enforced = true, blocked
+			}
+			status.Denylists = append(status.Denylists, info)
+		}
+	}
+	return status, nil
+}
+
+func (wrap *ipfsCliWrapper) IsBlocked(ctx context.Context, ipfsPath string) (bool, error) {
+	p, err := normalizeIPFSPath(ipfsPath)
+	if err != nil {
+		return false, err
+	}
+	// The denylists are only enforced by the daemon, a command running
+	// without it would not be blocked.
+	if _, ok := wrap.apiHostPort(); !ok {
+		return false, ErrDaemonNotReachable
+	}
+
+	// The global `--offline` flag makes kubo fail right away instead of
+	// asking other peers for content which is missing locally. Blocked
+	// content is refused before it is looked up.
+	cmd := wrap.command(ctx, "--offline", "resolve", "--", p)
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		// For example "Error: /ipfs/<cid> is blocked and cannot be provided".
+		cmdErr := newCommandError("check denylists on ipfs", err, output)
+		if errors.Is(cmdErr, ErrBlocked) {
+			return true, nil
+		}
+		if errors.Is(cmdErr, ErrNotFound) {
+			return false, nil
+		}
+		wrap.logger.Error("error checking denylists on ipfs",
+			slog.String("path", p),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		wrap.recordCommandFailure(cmdErr)
+		return false, cmdErr
+	}
+	return false, nil
+}
+
+// denylistDirPaths returns the directories kubo loads denylists from: the
+// one of the repo, the one of the user and the one of the system.
+func (wrap *ipfsCliWrapper) denylistDirPaths() []string {
+	dirs := []string{wrap.denylistDirPath()}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "ipfs", "denylists"))
+	}
+	return append(dirs, filepath.Join("/etc", "ipfs", "denylists"))
+}
+
+// readDenylistRules returns the rules of the denylist in the compact
+// denylist format, skipping the optional header which ends with a "---"
+// line, comments and blank lines.
+func readDenylistRules(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "---" {
+			lines = lines[i+1:]
+			break
+		}
+	}
+	var rules []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			rules = append(rules, line)
+		}
+	}
+	return rules, nil
+}
+
+// denylistProbe returns the first rule which blocks a plain "/ipfs/" path,
+// which `IsBlocked` can test, or an empty string if there is none. Allow
+// rules, which start with "!", and wildcards are skipped.
+func denylistProbe(rules []string) string {
+	for _, rule := range rules {
+		if strings.HasPrefix(rule, "/ipfs/") && !strings.Contains(rule, "*") {
+			return rule
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package ipfscliwrapper_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestLocalDenylists checks the denylists of `WithDenylistFile` and
+// `WithDenylistReader` are installed into the repo.
+func TestLocalDenylists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file.deny")
+	if err := os.WriteFile(file, []byte("/ipfs/bafkqaaa\n"), 0644); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	_, repoPath := newFakeKuboWrapper(t,
+		ipfscliwrapper.WithDenylistFile(file),
+		ipfscliwrapper.WithDenylistReader("reader.deny", strings.NewReader("/ipfs/bafkqaaa\n")))
+	for _, name := range []string{"file.deny", "reader.deny"} {
+		path := filepath.Join(repoPath, "denylists", name)
+		if content, err := os.ReadFile(path); err != nil || string(content) != "/ipfs/bafkqaaa\n" {
+			t.Errorf("Expected %s to be installed, but got: %q, %v", name, content, err)
+		}
+	}
+}
+
+// TestLocalDenylistsInvalidName checks a denylist name which kubo would
+// ignore is refused.
+func TestLocalDenylistsInvalidName(t *testing.T) {
+	options, _ := fakeKuboOptions(t)
+	_, err := ipfscliwrapper.NewWrapper(append(options,
+		ipfscliwrapper.WithDenylistReader("blocklist.txt", strings.NewReader("")))...)
+	if !errors.Is(err, ipfscliwrapper.ErrInvalidConfiguration) {
+		t.Errorf("Expected ErrInvalidConfiguration for a name kubo ignores, but got: %v", err)
+	}
+}
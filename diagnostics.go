@@ -0,0 +1,192 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors describing why the daemon exited during startup. They are returned
+// by `StartDaemonInBackground` wrapped in a `*DaemonStartupError`, so use
+// `errors.Is` to check for them.
+var (
+	// ErrDaemonExited is returned when the daemon exited during startup for
+	// a reason which is not recognized.
+	ErrDaemonExited = errors.New("ipfs daemon exited during startup")
+
+	// ErrPortInUse is returned when an address the daemon listens on is
+	// already used by another program.
+	ErrPortInUse = errors.New("ipfs daemon address already in use")
+
+	// ErrConfigInvalid is returned when the daemon fails to load the repo
+	// configuration.
+	ErrConfigInvalid = errors.New("ipfs daemon configuration is invalid")
+
+	// ErrRepoLocked is returned when another process holds the lock of the
+	// repo, typically another daemon using the same repo.
+	ErrRepoLocked = errors.New("ipfs repo is locked by another process")
+)
+
+// DaemonStartupError is returned when the daemon exits during startup. It
+// carries the output of the daemon to help diagnose the failure.
+type DaemonStartupError struct {
+	// Err is one of the startup errors, such as `ErrPortInUse`.
+	Err error
+
+	// ExitErr is the error returned when waiting for the daemon process.
+	ExitErr error
+
+	// Output is the tail of the error output of the daemon. It is empty in
+	// continous operation mode unless the `WithDaemonOutputFiles` option
+	// configured a file for the error output.
+	Output string
+}
+
+func (e *DaemonStartupError) Error() string {
+	if e.Output == "" {
+		return fmt.Sprintf("%v: %v", e.Err, e.ExitErr)
+	}
+	return fmt.Sprintf("%v: %v, output: %s", e.Err, e.ExitErr, e.Output)
+}
+
+func (e *DaemonStartupError) Unwrap() error {
+	return e.Err
+}
+
+// daemonFailurePatterns maps known substrings of the daemon error output to
+// the startup error they indicate. The first match wins.
+var daemonFailurePatterns = []struct {
+	pattern string
+	err     error
+}{
+	{"address already in use", ErrPortInUse},
+	{"only one usage of each socket address", ErrPortInUse}, // Windows.
+	{"someone else has the lock", ErrRepoLocked},
+	{"cannot acquire lock", ErrRepoLocked},
+	{"lock is already held", ErrRepoLocked},
+	{"failed to parse config", ErrConfigInvalid},
+	{"failure to decode config", ErrConfigInvalid},
+	{"invalid config", ErrConfigInvalid},
+	{"unknown datastore type", ErrConfigInvalid},
+	{"invalid character", ErrConfigInvalid}, // Malformed JSON.
+}
+
+// newDaemonStartupError classifies the output of a daemon which exited
+// during startup.
+func newDaemonStartupError(exitErr error, output string) *DaemonStartupError {
+	output = strings.TrimSpace(output)
+	lowered := strings.ToLower(output)
+	for _, p := range daemonFailurePatterns {
+		if strings.Contains(lowered, p.pattern) {
+			return &DaemonStartupError{Err: p.err, ExitErr: exitErr, Output: output}
+		}
+	}
+	return &DaemonStartupError{Err: ErrDaemonExited, ExitErr: exitErr, Output: output}
+}
+
+// daemonOutputTailSize is how much of the daemon error output is kept for
+// diagnostics.
+const daemonOutputTailSize = 16 * 1024
+
+// tailBuffer is an `io.Writer` which keeps the last bytes written to it.
+type tailBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.size {
+		b.buf = b.buf[len(b.buf)-b.size:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
+
+// readFileTail returns the content of the file written after the offset,
+// limited to the last `daemonOutputTailSize` bytes.
+func readFileTail(path string, offset int64) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	if fi, err := f.Stat(); err == nil && fi.Size()-offset > daemonOutputTailSize {
+		offset = fi.Size() - daemonOutputTailSize
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return ""
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// DiagCommand is an API command run by the daemon, see `DiagCmds`.
+type DiagCommand struct {
+	// ID is the sequence number the daemon gave the command.
+	ID int
+
+	// Command is the name of the command, e.g. "routing/findprovs", and
+	// Args and Options what it was called with.
+	Command string
+	Args    []string
+	Options map[string]any
+
+	// Active reports whether the command is still running.
+	Active bool
+
+	// StartTime and EndTime are when the command started and finished; the
+	// EndTime of active commands is zero.
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Duration is how long the command ran, or has been running so far if
+	// it is active.
+	Duration time.Duration
+}
+
+func (wrap *ipfsCliWrapper) DiagCmds(ctx context.Context) ([]DiagCommand, error) {
+	cmd := wrap.command(ctx, "diag", "cmds", "--verbose", "--enc=json")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error listing daemon commands",
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("list daemon commands", err, stderr.Bytes())
+	}
+
+	var commands []DiagCommand
+	if err := json.Unmarshal(output, &commands); err != nil {
+		return nil, fmt.Errorf("failed to parse daemon commands: %v", err)
+	}
+	now := time.Now()
+	for i := range commands {
+		if commands[i].Active {
+			commands[i].Duration = now.Sub(commands[i].StartTime)
+		} else {
+			commands[i].Duration = commands[i].EndTime.Sub(commands[i].StartTime)
+		}
+	}
+	return commands, nil
+}
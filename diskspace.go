@@ -0,0 +1,67 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+)
+
+// ErrInsufficientDiskSpace is returned when downloading kubo or adding content
+// would leave less free disk space than the minimum set with
+// `WithMinFreeDiskSpace`, so it fails before leaving partial state behind.
+var ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+// DefaultMinFreeDiskSpace is the disk space, in bytes, which downloading kubo
+// and adding content leave free unless set with `WithMinFreeDiskSpace`.
+const DefaultMinFreeDiskSpace = 256 << 20
+
+// kuboInstallSize is an upper estimate of the disk space, in bytes, which
+// downloading and extracting a kubo release takes.
+const kuboInstallSize = 256 << 20
+
+// checkDiskSpace returns `ErrInsufficientDiskSpace` if writing the number of
+// bytes to the filesystem holding the path would leave less than the minimum
+// free. The check is skipped if the free space could not be read.
+func (wrap *ipfsCliWrapper) checkDiskSpace(path string, needed uint64) error {
+	free, err := wrap.osOperator.FreeDiskSpace(path)
+	if err != nil {
+		wrap.logger.Warn("failed checking free disk space",
+			slog.String("path", path),
+			slog.Any("error", err))
+		return nil
+	}
+	if free < needed || free-needed < wrap.minFreeDiskSpace {
+		return fmt.Errorf("%w: %d bytes free at `%s`, %d bytes needed and %d bytes to keep free", ErrInsufficientDiskSpace, free, path, needed, wrap.minFreeDiskSpace)
+	}
+	return nil
+}
+
+// checkAddDiskSpace checks the repo has room for the local files or
+// directories, see `checkDiskSpace`. Content which is already in the repo is
+// counted too, so the check errs on the safe side.
+func (wrap *ipfsCliWrapper) checkAddDiskSpace(paths ...string) error {
+	var size uint64
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += uint64(info.Size())
+			return nil
+		})
+		if err != nil {
+			// Leave reporting unreadable paths to `ipfs add`.
+			return nil
+		}
+	}
+	return wrap.checkDiskSpace(wrap.repoPath(), size)
+}
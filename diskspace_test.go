@@ -0,0 +1,38 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestDiskSpace checks adding is refused before running `ipfs` when it would
+// leave less than the minimum free disk space, and that `Preflight` reports
+// it.
+func TestDiskSpace(t *testing.T) {
+	wrapper, repoPath := newFakeKuboWrapper(t, ipfscliwrapper.WithMinFreeDiskSpace(math.MaxUint64))
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("disk space"), 0644); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if _, err := wrapper.AddFile(ctx, path); !errors.Is(err, ipfscliwrapper.ErrInsufficientDiskSpace) {
+		t.Errorf("Expected ErrInsufficientDiskSpace, but got: %v", err)
+	}
+	for _, invocation := range fakeKuboInvocations(t, repoPath) {
+		if strings.HasPrefix(invocation, "add") {
+			t.Errorf("Expected the add not to be run, but got `ipfs %s`", invocation)
+		}
+	}
+	if err := wrapper.Preflight(ctx).Err(); !strings.Contains(fmt.Sprint(err), ipfscliwrapper.PreflightDiskSpace) {
+		t.Errorf("Expected the disk space check to fail, but got: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultDownloadConcurrency is how many of the files needed to provision the
+// node `NewWrapper` downloads at once, unless set with
+// `WithDownloadConcurrency`.
+const DefaultDownloadConcurrency = 3
+
+// Names of the downloads reported by `DownloadProgress`, besides the names of
+// the cluster programs, like "ipfs-cluster-ctl".
+const (
+	KuboDownload     = "kubo"
+	DenylistDownload = "denylist"
+)
+
+// DownloadProgress reports that one of the downloads needed to provision the
+// node finished, see `WithDownloadProgressHook`.
+type DownloadProgress struct {
+	// Name is `KuboDownload`, `DenylistDownload` or the name of a cluster
+	// program.
+	Name string
+
+	// Completed is how many of the Total downloads of `NewWrapper` finished
+	// so far, including this one.
+	Completed int
+	Total     int
+
+	// Duration is how long the download took, including the extraction of
+	// archives.
+	Duration time.Duration
+
+	// Err is the error the download failed with, if any.
+	Err error
+}
+
+// DownloadProgressHook receives the progress of the downloads of
+// `NewWrapper`. It is called once per download as it finishes, one call at a
+// time.
+type DownloadProgressHook func(progress DownloadProgress)
+
+// provisionDownload is one of the files `NewWrapper` downloads.
+type provisionDownload struct {
+	name  string
+	fetch func(ctx context.Context) error
+}
+
+// runDownloads runs the downloads concurrently, at most as many at once as
+// set with `WithDownloadConcurrency`, and reports their progress to the hook
+// set with `WithDownloadProgressHook`. Every download runs to completion,
+// unless the context is cancelled, even if another one failed, and the
+// returned error joins their errors.
+func (wrap *ipfsCliWrapper) runDownloads(ctx context.Context, downloads []provisionDownload) error {
+	if len(downloads) == 0 {
+		return nil
+	}
+	startedAt := time.Now()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		completed int
+		errs      []error
+	)
+	slots := make(chan struct{}, wrap.downloadConcurrency)
+	for _, download := range downloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			downloadStartedAt := time.Now()
+			err := download.fetch(ctx)
+
+			// Report one download at a time so the hook sees the completed
+			// count grow in order.
+			mu.Lock()
+			defer mu.Unlock()
+			completed++
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed downloading %s: %w", download.name, err))
+			}
+			if wrap.downloadProgressHook != nil {
+				wrap.downloadProgressHook(DownloadProgress{
+					Name:      download.name,
+					Completed: completed,
+					Total:     len(downloads),
+					Duration:  time.Since(downloadStartedAt),
+					Err:       err,
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	wrap.logger.Debug("provisioning downloads finished",
+		slog.Int("downloads", len(downloads)),
+		slog.Int("failed", len(errs)),
+		slog.Duration("duration", time.Since(startedAt)))
+	return errors.Join(errs...)
+}
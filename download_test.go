@@ -0,0 +1,119 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestRunDownloads checks the downloads run at most as many at once as the
+// concurrency, and that the hook sees them complete in order.
+func TestRunDownloads(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		busy     int
+		maxBusy  int
+		progress []ipfscliwrapper.DownloadProgress
+	)
+	fetch := func(ctx context.Context) error {
+		mu.Lock()
+		busy++
+		maxBusy = max(maxBusy, busy)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		busy--
+		mu.Unlock()
+		return nil
+	}
+	hook := func(p ipfscliwrapper.DownloadProgress) {
+		progress = append(progress, p)
+	}
+	if err := ipfscliwrapper.RunDownloads(context.Background(), 2, hook, fetch, fetch, fetch, fetch, fetch); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if maxBusy != 2 {
+		t.Errorf("Expected 2 downloads at once, but got %d", maxBusy)
+	}
+	if len(progress) != 5 {
+		t.Fatalf("Expected 5 progress reports, but got: %+v", progress)
+	}
+	for i, p := range progress {
+		if p.Completed != i+1 || p.Total != 5 || p.Err != nil {
+			t.Errorf("Expected %d of 5 completed, but got: %+v", i+1, p)
+		}
+	}
+}
+
+// TestRunDownloadsErrors checks every download runs even when one fails, and
+// that the failure is both reported and returned.
+func TestRunDownloadsErrors(t *testing.T) {
+	errDownload := errors.New("download failed")
+	var (
+		mu       sync.Mutex
+		fetched  int
+		progress []ipfscliwrapper.DownloadProgress
+	)
+	succeed := func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fetched++
+		return nil
+	}
+	fail := func(ctx context.Context) error {
+		return errDownload
+	}
+	hook := func(p ipfscliwrapper.DownloadProgress) {
+		progress = append(progress, p)
+	}
+	err := ipfscliwrapper.RunDownloads(context.Background(), 1, hook, fail, succeed, succeed)
+	if !errors.Is(err, errDownload) {
+		t.Errorf("Expected the download error, but got: %v", err)
+	}
+	if fetched != 2 {
+		t.Errorf("Expected the other downloads to run, but got %d", fetched)
+	}
+	var failed int
+	for _, p := range progress {
+		if p.Err != nil {
+			failed++
+		}
+	}
+	if len(progress) != 3 || failed != 1 {
+		t.Errorf("Expected 3 progress reports with 1 failure, but got: %+v", progress)
+	}
+}
+
+// TestDenylistDownload checks `NewWrapper` downloads the denylist into the
+// repo and reports it.
+func TestDenylistDownload(t *testing.T) {
+	var progress []ipfscliwrapper.DownloadProgress
+	_, repoPath := newFakeKuboWrapper(t,
+		ipfscliwrapper.WithDenylist("downloaded.deny", "https://example.com/downloaded.deny"),
+		ipfscliwrapper.WithCustomUrlDownloader(fileDownloader("/ipfs/bafkqaaa\n")),
+		ipfscliwrapper.WithDownloadProgressHook(func(p ipfscliwrapper.DownloadProgress) {
+			progress = append(progress, p)
+		}))
+	if len(progress) != 1 || progress[0].Name != ipfscliwrapper.DenylistDownload || progress[0].Completed != 1 || progress[0].Total != 1 || progress[0].Err != nil {
+		t.Errorf("Expected the denylist download to be reported, but got: %+v", progress)
+	}
+	path := filepath.Join(repoPath, "denylists", "downloaded.deny")
+	if content, err := os.ReadFile(path); err != nil || string(content) != "/ipfs/bafkqaaa\n" {
+		t.Errorf("Expected the denylist to be downloaded, but got: %q, %v", content, err)
+	}
+}
+
+// fileDownloader saves the same content for every url.
+type fileDownloader string
+
+func (d fileDownloader) DownloadFile(ctx context.Context, url string, destination string) error {
+	return os.WriteFile(destination, []byte(d), 0644)
+}
@@ -0,0 +1,54 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"time"
+)
+
+// ErrDryRun is returned by the calls which would have run an `ipfs` command
+// when the wrapper was created with the `WithDryRun` option. The command is
+// recorded instead, see `RecordedCommands`.
+var ErrDryRun = errors.New("ipfs command not run in dry-run mode")
+
+// RecordedCommand is an `ipfs` command which the wrapper would have run in
+// dry-run mode.
+type RecordedCommand struct {
+	// Time is when the command would have run.
+	Time time.Time `json:"time"`
+
+	// Args are the arguments, starting with the path of the `ipfs` binary.
+	Args []string `json:"args"`
+
+	// Env is the environment, including the variables inherited from the
+	// app, like `IPFS_PATH`.
+	Env []string `json:"env"`
+
+	// Dir is the working directory.
+	Dir string `json:"dir"`
+}
+
+// recordDryRun records the command and makes running it fail with
+// `ErrDryRun`, unless it was already refused, for example by
+// `WithDeniedCommands`.
+func (wrap *ipfsCliWrapper) recordDryRun(cmd *exec.Cmd) {
+	if cmd.Err != nil {
+		return
+	}
+	wrap.dryRunMu.Lock()
+	wrap.dryRunCommands = append(wrap.dryRunCommands, RecordedCommand{
+		Time: time.Now(),
+		Args: slices.Clone(cmd.Args),
+		Env:  slices.Clone(cmd.Env),
+		Dir:  cmd.Dir,
+	})
+	wrap.dryRunMu.Unlock()
+	cmd.Err = ErrDryRun
+}
+
+func (wrap *ipfsCliWrapper) RecordedCommands() []RecordedCommand {
+	wrap.dryRunMu.Lock()
+	defer wrap.dryRunMu.Unlock()
+	return slices.Clone(wrap.dryRunCommands)
+}
@@ -0,0 +1,36 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestDryRun checks the client commands are recorded with their environment
+// instead of being run.
+func TestDryRun(t *testing.T) {
+	wrapper, repoPath := newFakeKuboWrapper(t, ipfscliwrapper.WithDryRun())
+	ctx := context.Background()
+
+	const cid = "bafkqaaa"
+	if err := wrapper.Pin(ctx, cid); !errors.Is(err, ipfscliwrapper.ErrDryRun) {
+		t.Fatalf("Expected ErrDryRun, but got: %v", err)
+	}
+	commands := wrapper.RecordedCommands()
+	if len(commands) != 1 || !reflect.DeepEqual(commands[0].Args[1:], []string{"pin", "add", "--", cid}) {
+		t.Fatalf("Expected the recorded `ipfs pin add -- %s`, but got %+v", cid, commands)
+	}
+	if !slices.Contains(commands[0].Env, "IPFS_PATH="+repoPath) {
+		t.Errorf("Expected IPFS_PATH=%s in the recorded environment, but got %v", repoPath, commands[0].Env)
+	}
+	for _, invocation := range fakeKuboInvocations(t, repoPath) {
+		if strings.HasPrefix(invocation, "pin") {
+			t.Errorf("Expected the pin not to be run, but got `ipfs %s`", invocation)
+		}
+	}
+}
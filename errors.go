@@ -0,0 +1,100 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Errors describing why an `ipfs` command failed, classified from the error
+// output of kubo. The methods of `IpfsCliWrapper` return them wrapped in a
+// `*CommandError`, as does `RunCommand`, so use `errors.Is` to check for them
+// instead of matching the error messages. A lock held on the repo is reported
+// with `ErrRepoLocked`.
+var (
+	// ErrNotFound is returned when the content, or a path inside of it,
+	// could not be found.
+	ErrNotFound = errors.New("ipfs content not found")
+
+	// ErrNotPinned is returned when removing a pin which does not exist, or
+	// which is indirect.
+	ErrNotPinned = errors.New("ipfs content is not pinned")
+
+	// ErrInvalidCID is returned when the CID or path given to the command is
+	// malformed.
+	ErrInvalidCID = errors.New("invalid ipfs cid or path")
+
+	// ErrTimeout is returned when kubo gave up on the command because a
+	// deadline expired, for example while searching the network.
+	ErrTimeout = errors.New("ipfs command timed out")
+
+	// ErrBlocked is returned when the daemon refused the content because it
+	// is blocked by one of the denylists, see `WithDenylist`.
+	ErrBlocked = errors.New("ipfs content is blocked by a denylist")
+)
+
+// commandFailurePatterns maps known substrings of the kubo error output to
+// the error they indicate. The first match wins.
+var commandFailurePatterns = []struct {
+	pattern string
+	err     error
+}{
+	{"context deadline exceeded", ErrTimeout},
+	{"is blocked and cannot be provided", ErrBlocked},
+	{"someone else has the lock", ErrRepoLocked},
+	{"cannot acquire lock", ErrRepoLocked},
+	{"lock is already held", ErrRepoLocked},
+	{"pin is not part of the pinset", ErrNotPinned},
+	{"not pinned", ErrNotPinned},
+	{"merkledag: not found", ErrNotFound},
+	{"routing: not found", ErrNotFound},
+	{"not found locally", ErrNotFound},
+	{"could not find", ErrNotFound},
+	{"no link named", ErrNotFound},
+	{"could not resolve name", ErrNotFound},
+	{"no key named", ErrNotFound},
+	{"doesn't exist", ErrNotFound},
+	{"file does not exist", ErrNotFound},
+	{"invalid cid", ErrInvalidCID},
+	{"invalid path", ErrInvalidCID},
+}
+
+// CommandError is returned when an `ipfs` command fails. It wraps both the
+// error of running the command and, when the output is recognized, one of
+// `ErrNotFound`, `ErrNotPinned`, `ErrInvalidCID`, `ErrTimeout`, `ErrBlocked`
+// or `ErrRepoLocked`.
+type CommandError struct {
+	// Op describes what the command was doing, e.g. "cat file from ipfs".
+	Op string
+
+	// Err is the error returned when running the command.
+	Err error
+
+	// Output is the error output of the command.
+	Output string
+}
+
+// newCommandError returns the error of the failed command. Commands run
+// with `exec.Cmd.Output` keep their error output in the error instead.
+func newCommandError(op string, err error, output []byte) *CommandError {
+	var exitError *exec.ExitError
+	if len(output) == 0 && errors.As(err, &exitError) {
+		output = exitError.Stderr
+	}
+	return &CommandError{Op: op, Err: err, Output: string(output)}
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("failed to %s: %v, output: %s", e.Op, e.Err, e.Output)
+}
+
+func (e *CommandError) Unwrap() []error {
+	lowered := strings.ToLower(e.Output)
+	for _, p := range commandFailurePatterns {
+		if strings.Contains(lowered, p.pattern) {
+			return []error{p.err, e.Err}
+		}
+	}
+	return []error{e.Err}
+}
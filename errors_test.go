@@ -0,0 +1,53 @@
+package ipfscliwrapper_test
+
+import (
+	"errors"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestCommandErrorClassification checks common kubo error output maps to
+// the matching sentinel error.
+func TestCommandErrorClassification(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected error
+	}{
+		{"Error: merkledag: not found\n", ipfscliwrapper.ErrNotFound},
+		{"Error: routing: not found\n", ipfscliwrapper.ErrNotFound},
+		{"Error: block was not found locally (offline): ipld: could not find bafkqaaa\n", ipfscliwrapper.ErrNotFound},
+		{"Error: could not resolve name\n", ipfscliwrapper.ErrNotFound},
+		{"Error: no key named nosuch was found\n", ipfscliwrapper.ErrNotFound},
+		{"Error: key with name 'nosuch' doesn't exist\n", ipfscliwrapper.ErrNotFound},
+		{"Error: not pinned or pinned indirectly\n", ipfscliwrapper.ErrNotPinned},
+		{"Error: pin is not part of the pinset\n", ipfscliwrapper.ErrNotPinned},
+		{"Error: someone else has the lock\n", ipfscliwrapper.ErrRepoLocked},
+		{"Error: context deadline exceeded\n", ipfscliwrapper.ErrTimeout},
+		{"Error: /ipfs/bafkqaaa is blocked and cannot be provided\n", ipfscliwrapper.ErrBlocked},
+		{"Error: invalid path \"foo\": invalid cid: illegal base32 data\n", ipfscliwrapper.ErrInvalidCID},
+	}
+	exitErr := errors.New("exit status 1")
+	for _, test := range tests {
+		err := error(&ipfscliwrapper.CommandError{Op: "run ipfs command", Err: exitErr, Output: test.output})
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %q to be classified as %v, but got: %v", test.output, test.expected, err)
+		}
+		if !errors.Is(err, exitErr) {
+			t.Errorf("Expected the error of the command to be kept, but got: %v", err)
+		}
+	}
+}
+
+// TestCommandErrorUnknown checks unrecognized output wraps no sentinel.
+func TestCommandErrorUnknown(t *testing.T) {
+	err := error(&ipfscliwrapper.CommandError{Op: "run ipfs command", Err: errors.New("exit status 1"), Output: "Error: something new"})
+	for _, sentinel := range []error{ipfscliwrapper.ErrNotFound, ipfscliwrapper.ErrNotPinned, ipfscliwrapper.ErrRepoLocked, ipfscliwrapper.ErrTimeout, ipfscliwrapper.ErrInvalidCID, ipfscliwrapper.ErrBlocked} {
+		if errors.Is(err, sentinel) {
+			t.Errorf("Expected no classification, but got %v", sentinel)
+		}
+	}
+	if err.Error() != "failed to run ipfs command: exit status 1, output: Error: something new" {
+		t.Errorf("Unexpected message: %s", err)
+	}
+}
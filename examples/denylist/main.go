@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"time"
 
@@ -39,6 +40,15 @@ func main() {
 		log.Fatal(startErr)
 	}
 
+	// Confirm the denylist is enforced by the running daemon.
+	status, statusErr := wrapper.DenylistStatus(context.Background())
+	if statusErr != nil {
+		log.Fatal(statusErr)
+	}
+	for _, denylist := range status.Denylists {
+		log.Printf("denylist %s: %d rules, enforced: %v", denylist.Name, denylist.Rules, denylist.Enforced)
+	}
+
 	// Artifically wait 10 seconds...
 	log.Println("waiting for 10 seconds...")
 	time.Sleep(10 * time.Second)
@@ -3,9 +3,6 @@ package main
 import (
 	"context"
 	"log"
-	"os"
-	"os/signal"
-	"syscall"
 	"time"
 
 	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
@@ -28,7 +25,8 @@ $ go run main.go
 */
 
 func main() {
-	wrapper, initErr := ipfscliwrapper.NewWrapper()
+	// Shut the daemon down when you click `CTRL` + `C` in your keyboard.
+	wrapper, initErr := ipfscliwrapper.NewWrapper(ipfscliwrapper.WithShutdownOnSignals())
 	if initErr != nil {
 		log.Fatalf("failed creating ipfs-cli-wrapper: %v", initErr)
 	}
@@ -47,29 +45,24 @@ func main() {
 		log.Fatal(startErr)
 	}
 
-	// Channel to receive OS signals
-	sigChan := make(chan os.Signal, 1)
-	// Register for specific signals
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
 	// Give artifical delay
 	log.Println("exiting program in 2 minutes or you can click `CTRL` + `C` in your keyboard to exit early...")
 
-	// Wait for either the context to be done or an OS signal to be received
-	select {
-	case <-ctx.Done():
-		// Wait for the context to be done (which will be in 2 minutes).
-		// While you are waiting, checkout the link `http://127.0.0.1:5002/webui` to
-		// confirm the code is working. If you see a GUI then you have successfully
-		// executed `ipfs` binary from this app.
+	// Wait for the context to be done (which will be in 2 minutes).
+	// While you are waiting, checkout the link `http://127.0.0.1:5002/webui` to
+	// confirm the code is working. If you see a GUI then you have successfully
+	// executed `ipfs` binary from this app.
+	go func() {
+		<-ctx.Done()
 		log.Println("Context deadline reached, terminating process...")
-	case sig := <-sigChan:
-		// OS signal received, terminate the process gracefully
-		log.Printf("Received signal: %v, terminating process...", sig)
-	}
 
-	// After 2 minutes, kill the process
-	if endErr := wrapper.ShutdownDaemon(); endErr != nil {
-		log.Fatal(endErr)
-	}
+		// After 2 minutes, kill the process
+		if endErr := wrapper.ShutdownDaemon(); endErr != nil {
+			log.Fatal(endErr)
+		}
+	}()
+
+	// Block until the daemon was shut down, either by the signal handler of
+	// the wrapper or after 2 minutes.
+	wrapper.Wait()
 }
@@ -0,0 +1,67 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"golift.io/xtractr"
+)
+
+// ValidatePlatform exposes `validatePlatform` to the tests of the package.
+var ValidatePlatform = validatePlatform
+
+// IsOwnDaemonProcess exposes `isOwnDaemonProcess` to the tests of the
+// package.
+var IsOwnDaemonProcess = isOwnDaemonProcess
+
+// UnixfsFileData exposes `unixfsFileData` to the tests of the package.
+var UnixfsFileData = unixfsFileData
+
+// CommandWords exposes `commandWords` to the tests of the package.
+var CommandWords = commandWords
+
+// IsMutatingCommand exposes `isMutatingCommand` to the tests of the package.
+var IsMutatingCommand = isMutatingCommand
+
+// BeginGC marks a garbage collection of the wrapper as running, see
+// `beginGC`.
+func BeginGC(wrapper IpfsCliWrapper) (func(), error) {
+	return wrapper.(*ipfsCliWrapper).beginGC()
+}
+
+// RunDownloads runs the fetches with the `runDownloads` of a wrapper set
+// with the concurrency and hook, naming the downloads by their index.
+func RunDownloads(ctx context.Context, concurrency int, hook DownloadProgressHook, fetches ...func(ctx context.Context) error) error {
+	wrap := &ipfsCliWrapper{
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+		downloadConcurrency:  concurrency,
+		downloadProgressHook: hook,
+	}
+	downloads := make([]provisionDownload, 0, len(fetches))
+	for i, fetch := range fetches {
+		downloads = append(downloads, provisionDownload{name: strconv.Itoa(i), fetch: fetch})
+	}
+	return wrap.runDownloads(ctx, downloads)
+}
+
+// DetectArchiveFormat exposes `detectArchiveFormat` to the tests of the
+// package.
+var DetectArchiveFormat = detectArchiveFormat
+
+// DecompressZstd exposes `decompressZstd` to the tests of the package.
+var DecompressZstd = decompressZstd
+
+// ExtractArchive extracts the archive into the directory with the
+// `extractArchive` of a wrapper, and returns the files written.
+func ExtractArchive(archivePath string, outputDir string) ([]string, error) {
+	wrap := &ipfsCliWrapper{fileMode: 0755, dirMode: 0755}
+	_, files, err := wrap.extractArchive(&xtractr.XFile{
+		FilePath:  archivePath,
+		OutputDir: outputDir,
+		FileMode:  wrap.fileMode,
+		DirMode:   wrap.dirMode,
+	})
+	return files, err
+}
@@ -0,0 +1,113 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"golift.io/xtractr"
+)
+
+// Formats of the archives `extractArchive` can extract.
+const (
+	archiveTarGzip = "tar.gz"
+	archiveTarZstd = "tar.zst"
+	archiveTarBzip = "tar.bz2"
+	archiveTar     = "tar"
+	archiveZip     = "zip"
+)
+
+// detectArchiveFormat tells the format of the archive from its first bytes
+// rather than its name, so an archive served under another name, e.g. by a
+// mirror set up with `WithCustomUrlDownloader`, still gets extracted.
+func detectArchiveFormat(archivePath string) (string, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read archive: %v", err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return archiveTarGzip, nil
+	case bytes.HasPrefix(header, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return archiveTarZstd, nil
+	case bytes.HasPrefix(header, []byte("BZh")):
+		return archiveTarBzip, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return archiveZip, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return archiveTar, nil
+	}
+	return "", fmt.Errorf("unsupported format of archive `%s`", filepath.Base(archivePath))
+}
+
+// extractArchive extracts the archive into its output directory, whichever
+// of the formats of kubo and ipfs-cluster releases it has, and returns how
+// many bytes and which files were written.
+func (wrap *ipfsCliWrapper) extractArchive(x *xtractr.XFile) (int64, []string, error) {
+	format, err := detectArchiveFormat(x.FilePath)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch format {
+	case archiveTarGzip:
+		return xtractr.ExtractTarGzip(x)
+	case archiveTarBzip:
+		return xtractr.ExtractTarBzip(x)
+	case archiveTar:
+		return xtractr.ExtractTar(x)
+	case archiveZip:
+		return xtractr.ExtractZIP(x)
+	}
+
+	// The extractor does not know zstd, so decompress the tar archive into
+	// the temp directory first.
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer os.RemoveAll(stageDir)
+	tarPath := filepath.Join(stageDir, "archive.tar")
+	if err := decompressZstd(x.FilePath, tarPath); err != nil {
+		return 0, nil, err
+	}
+	tarX := *x
+	tarX.FilePath = tarPath
+	return xtractr.ExtractTar(&tarX)
+}
+
+// decompressZstd writes the zstd compressed file to the destination.
+func decompressZstd(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer in.Close()
+
+	decoder, err := zstd.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("failed to read zstd archive: %v", err)
+	}
+	defer decoder.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %v", err)
+	}
+	if _, err := io.Copy(out, decoder); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to decompress zstd archive: %v", err)
+	}
+	return out.Close()
+}
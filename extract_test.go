@@ -0,0 +1,152 @@
+package ipfscliwrapper_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// archiveFormats are the formats `writeKuboArchive` writes, which are also
+// the names `detectArchiveFormat` returns for them.
+var archiveFormats = []string{"tar", "tar.gz", "tar.zst", "zip"}
+
+// TestDetectArchiveFormat checks the format is told from the content of the
+// archive, whatever its name.
+func TestDetectArchiveFormat(t *testing.T) {
+	for _, format := range archiveFormats {
+		t.Run(format, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "kubo.archive")
+			if err := writeKuboArchive(archive, format, []byte("kubo")); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			detected, err := ipfscliwrapper.DetectArchiveFormat(archive)
+			if err != nil || detected != format {
+				t.Errorf("Expected %s, but got %q and %v", format, detected, err)
+			}
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "kubo.tar.gz")
+		if err := os.WriteFile(path, []byte("not an archive"), 0644); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		if detected, err := ipfscliwrapper.DetectArchiveFormat(path); err == nil {
+			t.Errorf("Expected an error, but got %q", detected)
+		}
+	})
+}
+
+// TestDecompressZstd checks the zstd archive is decompressed into a tar
+// archive.
+func TestDecompressZstd(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "kubo.tar.zst")
+	if err := writeKuboArchive(archive, "tar.zst", []byte("kubo")); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	tarPath := filepath.Join(dir, "kubo.tar")
+	if err := ipfscliwrapper.DecompressZstd(archive, tarPath); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	f, err := os.Open(tarPath)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	header, err := tr.Next()
+	if err != nil || header.Name != "kubo/ipfs" {
+		t.Fatalf("Expected kubo/ipfs, but got %+v and %v", header, err)
+	}
+	if content, err := io.ReadAll(tr); err != nil || string(content) != "kubo" {
+		t.Errorf("Expected the binary, but got %q and %v", content, err)
+	}
+
+	if err := ipfscliwrapper.DecompressZstd(tarPath, filepath.Join(dir, "invalid.tar")); err == nil {
+		t.Error("Expected an error decompressing a tar archive")
+	}
+}
+
+// TestExtractArchive checks the binary is extracted from the archives of
+// every format, keeping it executable.
+func TestExtractArchive(t *testing.T) {
+	for _, format := range archiveFormats {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "kubo.archive")
+			if err := writeKuboArchive(archive, format, []byte("kubo")); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			output := filepath.Join(dir, "output")
+			if _, err := ipfscliwrapper.ExtractArchive(archive, output); err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			binary := filepath.Join(output, "kubo", "ipfs")
+			if content, err := os.ReadFile(binary); err != nil || string(content) != "kubo" {
+				t.Errorf("Expected the binary to be extracted, but got %q and %v", content, err)
+			}
+			if info, err := os.Stat(binary); err != nil || info.Mode()&0111 == 0 {
+				t.Errorf("Expected the binary to be executable, but got %v and %v", info, err)
+			}
+		})
+	}
+}
+
+// writeKuboArchive writes an archive of the given format holding the binary
+// as "kubo/ipfs", like the archives of kubo releases.
+func writeKuboArchive(path string, format string, binary []byte) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if format == "zip" {
+		zw := zip.NewWriter(out)
+		header := &zip.FileHeader{Name: "kubo/ipfs", Method: zip.Deflate}
+		header.SetMode(0755)
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(binary); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+
+	var compressed io.WriteCloser
+	switch format {
+	case "tar":
+		compressed = out
+	case "tar.gz":
+		compressed = gzip.NewWriter(out)
+	case "tar.zst":
+		if compressed, err = zstd.NewWriter(out, zstd.WithEncoderLevel(zstd.SpeedFastest)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown archive format %s", format)
+	}
+	tw := tar.NewWriter(compressed)
+	if err := tw.WriteHeader(&tar.Header{Name: "kubo/ipfs", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(binary); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return compressed.Close()
+}
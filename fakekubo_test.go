@@ -0,0 +1,113 @@
+package ipfscliwrapper_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// fakeKuboScript is a stand-in for the `ipfs` binary, for the tests of the
+// wrapper logic which do not need a real node. Every invocation is appended
+// to the `fake-kubo.log` file of the repo. The `add` command prints a CID
+// derived from the checksum of its input, and fails while a `fail-add` file
+// exists in the repo, unless it only computes the hash. A `fail-add-next`
+// file turns into `fail-add` after the next successful add. The `daemon`
+// command only sleeps, without serving the API, and the `cat` command fails
+// as if the content could not be found.
+const fakeKuboScript = `#!/bin/sh
+echo "$*" >> "$IPFS_PATH/fake-kubo.log"
+case "$1" in
+--api=*) shift ;;
+esac
+case "$1" in
+version)
+	echo "0.29.0"
+	;;
+init)
+	mkdir -p "$IPFS_PATH" && echo '{}' > "$IPFS_PATH/config"
+	;;
+config)
+	if [ "$2" = "show" ]; then
+		cat "$IPFS_PATH/config"
+	fi
+	;;
+add)
+	case " $* " in
+	*" --only-hash "*) ;;
+	*)
+		if [ -e "$IPFS_PATH/fail-add" ]; then
+			echo "Error: fake add failure" >&2
+			exit 1
+		fi
+		if [ -e "$IPFS_PATH/fail-add-next" ]; then
+			mv "$IPFS_PATH/fail-add-next" "$IPFS_PATH/fail-add"
+		fi
+		;;
+	esac
+	echo "bafkfake$(cksum | cut -d ' ' -f 1)"
+	;;
+files)
+	echo '{"Hash":"bafkfake","Size":0,"CumulativeSize":100,"Type":"file"}'
+	;;
+daemon)
+	exec sleep 60
+	;;
+cat)
+	echo "Error: block was not found locally (offline)" >&2
+	exit 1
+	;;
+dag)
+	cat > "$IPFS_PATH/dag-put.json"
+	echo "bafyfakejoined"
+	;;
+esac
+`
+
+// newFakeKuboWrapper returns a wrapper running the fake `ipfs` binary of
+// `fakeKuboScript` against a repo in a temporary directory, and the path of
+// the repo.
+func newFakeKuboWrapper(t *testing.T, options ...ipfscliwrapper.Option) (ipfscliwrapper.IpfsCliWrapper, string) {
+	t.Helper()
+	fakeOptions, repoPath := fakeKuboOptions(t)
+	wrapper, err := ipfscliwrapper.NewWrapper(append(fakeOptions, options...)...)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	return wrapper, repoPath
+}
+
+// fakeKuboOptions writes the fake `ipfs` binary of `fakeKuboScript` into a
+// temporary directory, and returns the options of a wrapper running it
+// against a repo there, and the path of the repo.
+func fakeKuboOptions(t *testing.T) ([]ipfscliwrapper.Option, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ipfs binary is a shell script")
+	}
+	dir := t.TempDir()
+	binaryPath := filepath.Join(dir, "ipfs")
+	if err := os.WriteFile(binaryPath, []byte(fakeKuboScript), 0755); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	repoPath := filepath.Join(dir, "repo")
+	return []ipfscliwrapper.Option{
+		ipfscliwrapper.WithBinaryPath(binaryPath),
+		ipfscliwrapper.WithRepoPath(repoPath),
+		ipfscliwrapper.WithWorkDir(dir),
+	}, repoPath
+}
+
+// fakeKuboInvocations returns the arguments of every invocation of the fake
+// `ipfs` binary so far, one line per invocation.
+func fakeKuboInvocations(t *testing.T, repoPath string) []string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(repoPath, "fake-kubo.log"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
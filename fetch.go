@@ -0,0 +1,110 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultLocalFetchTimeout is how long `FetchWithFallback` lets the local
+// node retrieve content before falling back to the HTTP gateways.
+const DefaultLocalFetchTimeout = 30 * time.Second
+
+// DefaultFallbackGateways are the trustless HTTP gateways `FetchWithFallback`
+// falls back to unless others were configured with `WithFallbackGateways`.
+var DefaultFallbackGateways = []string{
+	"https://trustless-gateway.link",
+	"https://ipfs.io",
+}
+
+func (wrap *ipfsCliWrapper) FetchWithFallback(ctx context.Context, cid string) ([]byte, error) {
+	// STEP 1: Give the local node a chance to retrieve the content, either
+	// from its own blockstore or from its peers.
+	localCtx, cancel := context.WithTimeout(ctx, wrap.localFetchTimeout)
+	output, err := wrap.output(wrap.command(localCtx, "cat", "--", cid))
+	cancel()
+	if err == nil {
+		return output, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	wrap.logger.Debug("local fetch failed, falling back to gateways",
+		slog.String("cid", cid),
+		slog.Any("error", err))
+
+	// STEP 2: Try the gateways one after another. The blocks are requested as
+	// a CAR file and imported into the local node, which verifies every block
+	// against its CID, so a malicious or broken gateway cannot make us return
+	// bytes which do not match the CID.
+	// Importing the blocks writes them into the repo, so the gateways are
+	// not used in read-only mode.
+	if writableErr := wrap.checkWritable(); writableErr != nil {
+		return nil, fmt.Errorf("failed to fetch `%s` from the local node: %v, and gateways are not used: %w", cid, err, writableErr)
+	}
+	gateways := wrap.fallbackGateways
+	if len(gateways) == 0 {
+		gateways = DefaultFallbackGateways
+	}
+	var errs []error
+	for _, gateway := range gateways {
+		data, err := wrap.fetchFromGateway(ctx, gateway, cid)
+		if err == nil {
+			wrap.logger.Debug("content retrieved from gateway",
+				slog.String("cid", cid),
+				slog.String("gateway", gateway))
+			return data, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		wrap.logger.Warn("failed fetching content from gateway",
+			slog.String("cid", cid),
+			slog.String("gateway", gateway),
+			slog.Any("error", err))
+		errs = append(errs, fmt.Errorf("%s: %v", gateway, err))
+	}
+	return nil, fmt.Errorf("failed to fetch `%s` from the local node and every gateway: %w", cid, errors.Join(errs...))
+}
+
+// fetchFromGateway downloads the DAG of the CID as a CAR file from the
+// gateway, imports it into the local node and reads the content back offline.
+func (wrap *ipfsCliWrapper) fetchFromGateway(ctx context.Context, gateway string, cid string) ([]byte, error) {
+	url := strings.TrimSuffix(gateway, "/") + "/ipfs/" + cid + "?format=car"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.car")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	// Blocks are left unpinned, so they are cached until the next garbage
+	// collection.
+	if err := wrap.waitForGC(ctx); err != nil {
+		return nil, err
+	}
+	importCmd := wrap.command(ctx, "dag", "import", "--pin-roots=false")
+	importCmd.Stdin = resp.Body
+	if output, err := wrap.combinedOutput(importCmd); err != nil {
+		return nil, wrap.commandError("import car", err, output)
+	}
+
+	// Reading offline fails if the gateway left out any block of the DAG.
+	output, err := wrap.output(wrap.command(ctx, "--offline", "cat", "--", cid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read imported content: %v", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,208 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrGCInProgress is returned when a garbage collection started by the
+// wrapper is running, by `GarbageCollection` itself and by the calls writing
+// to the repo, like `AddFile` or `Pin`, whose context ended while they were
+// waiting for it to finish.
+var ErrGCInProgress = errors.New("ipfs garbage collection in progress")
+
+// beginGC marks a garbage collection as running. It returns the function
+// marking it as finished, or `ErrGCInProgress` if one is already running.
+func (wrap *ipfsCliWrapper) beginGC() (func(), error) {
+	wrap.gcMu.Lock()
+	defer wrap.gcMu.Unlock()
+	if wrap.gcDone != nil {
+		return nil, ErrGCInProgress
+	}
+	done := make(chan struct{})
+	wrap.gcDone = done
+	return func() {
+		wrap.gcMu.Lock()
+		wrap.gcDone = nil
+		wrap.gcMu.Unlock()
+		close(done)
+	}, nil
+}
+
+// waitForGC delays a call writing to the repo until the running garbage
+// collection finished. `ipfs repo gc` holds the lock of the blockstore while
+// it runs, so the writes would otherwise block inside kubo or fail with a
+// lock error, and blocks written just before the collection reached them
+// could get collected.
+func (wrap *ipfsCliWrapper) waitForGC(ctx context.Context) error {
+	wrap.gcMu.Lock()
+	done := wrap.gcDone
+	wrap.gcMu.Unlock()
+	if done == nil {
+		return nil
+	}
+
+	wrap.logger.Debug("waiting for garbage collection to finish")
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrGCInProgress, ctx.Err())
+	}
+}
+
+// DefaultGCWatermarkCheckInterval is how often the usage of the repo is
+// checked against the watermark set with `WithGCWatermark`.
+const DefaultGCWatermarkCheckInterval = time.Minute
+
+// GCWatermarkHysteresis is how many percentage points below the watermark
+// set with `WithGCWatermark` the usage of the repo must drop before reaching
+// the watermark triggers another garbage collection. Without it, a repo
+// whose pinned content alone is above the watermark would be collected on
+// every check.
+const GCWatermarkHysteresis = 10
+
+// GCWatermarkEvent describes a garbage collection triggered because the
+// usage of the repo reached the watermark set with `WithGCWatermark`.
+type GCWatermarkEvent struct {
+	// Time is when the garbage collection was triggered.
+	Time time.Time
+
+	// Watermark is the configured percentage of `StorageMax`.
+	Watermark int
+
+	// StorageMax is the disk budget of the repo in bytes.
+	StorageMax uint64
+
+	// RepoSizeBefore and RepoSizeAfter are the size of the repo in bytes
+	// before and after the garbage collection.
+	RepoSizeBefore uint64
+	RepoSizeAfter  uint64
+
+	// Duration is how long the garbage collection took.
+	Duration time.Duration
+
+	// Err is the error of the garbage collection, or nil if it succeeded.
+	Err error
+}
+
+// GCWatermarkHook is called after every garbage collection triggered by the
+// watermark set with `WithGCWatermark`.
+type GCWatermarkHook func(event GCWatermarkEvent)
+
+// startGCWatermark launches the loop which collects garbage once the usage
+// of the repo reaches the watermark. Calling it while the loop is already
+// running does nothing.
+func (wrap *ipfsCliWrapper) startGCWatermark() {
+	if wrap.gcWatermark <= 0 || wrap.gcWatermarkCancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	wrap.gcWatermarkCancel, wrap.gcWatermarkDone = cancel, done
+	go func() {
+		defer close(done)
+		armed := true
+		ticker := time.NewTicker(wrap.gcWatermarkInterval)
+		defer ticker.Stop()
+		for {
+			armed = wrap.checkGCWatermark(ctx, armed)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopGCWatermark terminates the loop started by `startGCWatermark` and
+// waits for it to exit.
+func (wrap *ipfsCliWrapper) stopGCWatermark() {
+	if wrap.gcWatermarkCancel == nil {
+		return
+	}
+	wrap.gcWatermarkCancel()
+	<-wrap.gcWatermarkDone
+	wrap.gcWatermarkCancel, wrap.gcWatermarkDone = nil, nil
+}
+
+// checkGCWatermark collects garbage if the watermark is armed and the usage
+// of the repo reached it. It returns whether the watermark is armed for the
+// next check: after a collection it is only armed again once the usage
+// dropped `GCWatermarkHysteresis` points below the watermark.
+func (wrap *ipfsCliWrapper) checkGCWatermark(ctx context.Context, armed bool) bool {
+	repoSize, storageMax, err := wrap.repoUsage(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			wrap.logger.Warn("failed checking repo usage", slog.Any("error", err))
+		}
+		return armed
+	}
+	if storageMax == 0 {
+		return armed
+	}
+	usage := int(repoSize * 100 / storageMax)
+	if !armed {
+		return usage < wrap.gcWatermark-GCWatermarkHysteresis
+	}
+	if usage < wrap.gcWatermark {
+		return true
+	}
+
+	wrap.logger.Info("repo usage reached the gc watermark, collecting garbage",
+		slog.Int("usage_percent", usage),
+		slog.Int("watermark", wrap.gcWatermark))
+	event := GCWatermarkEvent{
+		Time:           time.Now(),
+		Watermark:      wrap.gcWatermark,
+		StorageMax:     storageMax,
+		RepoSizeBefore: repoSize,
+	}
+	event.Err = wrap.GarbageCollection(ctx)
+	event.Duration = time.Since(event.Time)
+	if errors.Is(event.Err, ErrGCInProgress) {
+		// Another collection is running, check again once it finished.
+		wrap.logger.Debug("garbage collection already in progress")
+		return true
+	}
+	if event.Err == nil {
+		event.RepoSizeAfter, _, event.Err = wrap.repoUsage(ctx)
+	}
+	if event.Err != nil {
+		wrap.logger.Error("failed collecting garbage at the gc watermark", slog.Any("error", event.Err))
+	} else {
+		wrap.logger.Info("garbage collected at the gc watermark",
+			slog.Uint64("freed_bytes", event.RepoSizeBefore-min(event.RepoSizeBefore, event.RepoSizeAfter)),
+			slog.Duration("duration", event.Duration))
+	}
+	if wrap.gcWatermarkHook != nil {
+		wrap.gcWatermarkHook(event)
+	}
+	// Retry a failed collection on the next check.
+	if event.Err != nil {
+		return true
+	}
+	return int(event.RepoSizeAfter*100/storageMax) < wrap.gcWatermark-GCWatermarkHysteresis
+}
+
+// repoUsage returns the size of the repo and its disk budget in bytes, see
+// `WithStorageMax`.
+func (wrap *ipfsCliWrapper) repoUsage(ctx context.Context) (uint64, uint64, error) {
+	output, err := wrap.output(wrap.command(ctx, "repo", "stat", "--size-only", "--enc=json"))
+	if err != nil {
+		return 0, 0, wrap.commandError("get repo stat", err, output)
+	}
+	var stat struct {
+		RepoSize   uint64 `json:"RepoSize"`
+		StorageMax uint64 `json:"StorageMax"`
+	}
+	if err := json.Unmarshal(output, &stat); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse repo stat: %v", err)
+	}
+	return stat.RepoSize, stat.StorageMax, nil
+}
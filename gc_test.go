@@ -0,0 +1,35 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestWritesWaitForGC checks the calls writing to the repo wait for the
+// running garbage collection and give up when their context ends.
+func TestWritesWaitForGC(t *testing.T) {
+	wrapper, _ := newFakeKuboWrapper(t)
+	endGC, err := ipfscliwrapper.BeginGC(wrapper)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer endGC()
+
+	writes := map[string]func(ctx context.Context) error{
+		"Pin":   func(ctx context.Context) error { return wrapper.Pin(ctx, "bafkqaaa") },
+		"Unpin": func(ctx context.Context) error { return wrapper.Unpin(ctx, "bafkqaaa") },
+	}
+	for name, write := range writes {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := write(ctx); !errors.Is(err, ipfscliwrapper.ErrGCInProgress) {
+				t.Errorf("Expected ErrGCInProgress, but got: %v", err)
+			}
+		})
+	}
+}
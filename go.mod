@@ -2,7 +2,13 @@ module github.com/bartmika/ipfs-cli-wrapper
 
 go 1.23.0
 
-require golift.io/xtractr v0.2.2
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/klauspost/compress v1.16.3
+	github.com/shirou/gopsutil/v4 v4.24.12
+	golang.org/x/sys v0.28.0
+	golift.io/xtractr v0.2.2
+)
 
 require (
 	github.com/andybalholm/brotli v1.0.5 // indirect
@@ -10,13 +16,19 @@ require (
 	github.com/bodgit/sevenzip v1.4.0 // indirect
 	github.com/bodgit/windows v1.0.1 // indirect
 	github.com/connesc/cipherio v0.2.1 // indirect
+	github.com/ebitengine/purego v0.8.1 // indirect
+	github.com/go-ole/go-ole v1.2.6 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/kdomanski/iso9660 v0.3.3 // indirect
-	github.com/klauspost/compress v1.16.3 // indirect
+	github.com/lufia/plan9stats v0.0.0-20211012122336-39d0f177ccd0 // indirect
 	github.com/nwaples/rardecode v1.1.3 // indirect
 	github.com/pierrec/lz4/v4 v4.1.17 // indirect
+	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
+	github.com/tklauser/go-sysconf v0.3.12 // indirect
+	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/ulikunitz/xz v0.5.11 // indirect
+	github.com/yusufpapurcu/wmi v1.2.4 // indirect
 	go4.org v0.0.0-20230225012048-214862532bf5 // indirect
 	golang.org/x/text v0.8.0 // indirect
 )
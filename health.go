@@ -0,0 +1,97 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/healthservice"
+)
+
+// startHealthEndpoint launches the health server configured by the
+// `WithHealthEndpoint` option. Calling it while the server is already running
+// does nothing.
+func (wrap *ipfsCliWrapper) startHealthEndpoint() error {
+	if wrap.healthAddr == "" || wrap.healthServer != nil {
+		return nil
+	}
+
+	// Listen synchronously so an unavailable address is reported to the
+	// caller instead of failing silently in the background.
+	listener, err := net.Listen("tcp", wrap.healthAddr)
+	if err != nil {
+		wrap.logger.Error("error listening for health endpoint", slog.Any("error", err))
+		return fmt.Errorf("failed to listen for health endpoint: %v", err)
+	}
+
+	server := &http.Server{Handler: healthservice.New(wrap.healthStatus)}
+	wrap.healthServer = server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			wrap.logger.Error("health endpoint stopped", slog.Any("error", err))
+		}
+	}()
+
+	wrap.logger.Debug("health endpoint is running",
+		slog.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// stopHealthEndpoint gracefully shuts down the health server.
+func (wrap *ipfsCliWrapper) stopHealthEndpoint() error {
+	if wrap.healthServer == nil {
+		return nil
+	}
+	server := wrap.healthServer
+	wrap.healthServer = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown health endpoint: %v", err)
+	}
+	return nil
+}
+
+// healthStatus probes the daemon for the health endpoint. The node is
+// considered healthy if the daemon is running and answers API calls.
+func (wrap *ipfsCliWrapper) healthStatus(ctx context.Context) healthservice.Status {
+	status := healthservice.Status{DaemonRunning: wrap.isDaemonRunning}
+	if !status.DaemonRunning {
+		status.Error = "daemon is not running"
+		return status
+	}
+
+	// Requires the daemon to be online, so this doubles as the liveness check.
+	peers, err := wrap.swarmPeers(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.PeerCount = len(peers)
+
+	output, err := wrap.combinedOutput(wrap.command(ctx, "repo", "stat", "--size-only", "--enc=json"))
+	if err != nil {
+		status.Error = fmt.Sprintf("failed to get repo stat: %v, output: %s", err, strings.TrimSpace(string(output)))
+		return status
+	}
+	var stat struct {
+		RepoSize   uint64 `json:"RepoSize"`
+		StorageMax uint64 `json:"StorageMax"`
+	}
+	if err := json.Unmarshal(output, &stat); err != nil {
+		status.Error = fmt.Sprintf("failed to parse repo stat: %v", err)
+		return status
+	}
+	status.RepoSize = stat.RepoSize
+	status.StorageMax = stat.StorageMax
+
+	status.Healthy = true
+	return status
+}
@@ -1,25 +1,33 @@
 package ipfscliwrapper
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"golift.io/xtractr"
 
 	"github.com/bartmika/ipfs-cli-wrapper/internal/logger"
 	"github.com/bartmika/ipfs-cli-wrapper/internal/oskit"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
 	"github.com/bartmika/ipfs-cli-wrapper/internal/randomkit"
 	"github.com/bartmika/ipfs-cli-wrapper/internal/urlkit"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
 )
 
 // ipfsCliWrapper represents a wrapper around the `ipfs` executable binary,
@@ -30,14 +38,10 @@ type ipfsCliWrapper struct {
 	// logger is used to log various actions and errors occurring within the wrapper.
 	logger *slog.Logger
 
-	// ipfsDaemonCmd is a pointer to the command shell instance of the `ipfs` binary
-	// running in daemon mode. It allows control over the background process that
-	// runs the IPFS daemon.
-	ipfsDaemonCmd *exec.Cmd
-
-	// stdout captures the output stream from the console of the `ipfs` daemon,
-	// enabling the wrapper to process or log real-time output from the IPFS node.
-	stdout io.ReadCloser
+	// daemon is the `ipfs` binary running in daemon mode started by the
+	// wrapper, or nil if it did not start one. It allows control over the
+	// background process that runs the IPFS daemon.
+	daemon *prockit.Process
 
 	// isDaemonRunning indicates whether the IPFS binary is currently running in daemon mode.
 	// This boolean flag is used internally to track the state of the IPFS daemon.
@@ -65,8 +69,232 @@ type ipfsCliWrapper struct {
 	denylistFilename string
 	denylistURL      string
 
+	// blockEvents hands the content the daemon refused because of a
+	// denylist to the hook set with `WithBlockEventHook`.
+	blockEvents *blockEventMonitor
+
+	// localDenylists are applied from local files and readers, see
+	// `WithDenylistFile` and `WithDenylistReader`.
+	localDenylists []localDenylist
+
 	forceShutdownOnStartup bool
 
+	// ipnsRepublisher keeps the IPNS names registered via `TrackIPNSName`
+	// alive by republishing them while the daemon is running.
+	ipnsRepublisher *ipnsRepublisher
+
+	// cluster holds the companion ipfs-cluster peer settings, or nil if
+	// cluster support was not enabled.
+	cluster *clusterConfig
+
+	// clusterProcess is the running ipfs-cluster peer process started
+	// alongside the `ipfs` daemon.
+	clusterProcess *prockit.Process
+
+	// pinningServiceAddr is the address the Pinning Service API listens on,
+	// or empty if the `WithPinningServiceAPI` option was not used.
+	pinningServiceAddr        string
+	pinningServiceAccessToken string
+	pinningServiceServer      *http.Server
+
+	// healthAddr is the address the health endpoint listens on, or empty if
+	// the `WithHealthEndpoint` option was not used.
+	healthAddr   string
+	healthServer *http.Server
+
+	// datastore holds the datastore the repo gets created with, or nil to use
+	// the kubo default.
+	datastore *datastoreConfig
+
+	// apiAddr is the multiaddress of the daemon API (e.g.
+	// "/ip4/127.0.0.1/tcp/5001") passed to every client command via the
+	// `--api` flag. When empty, kubo looks up the API from the repo.
+	apiAddr string
+
+	// daemonStdoutPath and daemonStderrPath are the files the output of a
+	// detached daemon is appended to, or empty to discard the output.
+	daemonStdoutPath string
+	daemonStderrPath string
+
+	// daemonOutputMaxBytes and daemonOutputMaxBackups control the size-based
+	// rotation of the daemon output files.
+	daemonOutputMaxBytes   int64
+	daemonOutputMaxBackups int
+
+	// kuboVersion is the release of the `ipfs` binary to download.
+	kuboVersion string
+
+	// customBinaryPath is the `ipfs` binary used instead of a downloaded
+	// one and customRepoPath the repo used instead of the one of the
+	// layout, see `WithBinaryPath` and `WithRepoPath`.
+	customBinaryPath string
+	customRepoPath   string
+
+	// logOutputLimit is the number of bytes of command output kept in the
+	// logs and logSampling the sampling rate of the debug and info logs.
+	logOutputLimit int
+	logSampling    int
+
+	// telemetry receives the telemetry events, or is nil to disable
+	// telemetry, see `WithTelemetrySink`.
+	telemetry TelemetrySink
+
+	// strictCompatibility makes `NewWrapper` fail instead of warning when
+	// the installed kubo release was not tested, see `kuboCompatibility`.
+	strictCompatibility bool
+
+	// installedKuboVersion is the release of the installed `ipfs` binary,
+	// e.g. "v0.29.0", or empty if it could not be read.
+	installedKuboVersion string
+
+	// adoptedDaemon describes the daemon started by an earlier run of this
+	// app in continous operation mode, or is nil, see `adoptDaemon`.
+	adoptedDaemon *daemonInfo
+
+	// daemonStdoutTail and daemonStderrTail keep the output of the daemon
+	// when it is not running in continous operation mode, see `LogTail`.
+	daemonStdoutTail *tailBuffer
+	daemonStderrTail *tailBuffer
+
+	// provision holds the timings of provisioning the node, see
+	// `ProvisionReport`.
+	provision   ProvisionReport
+	provisionMu sync.Mutex
+
+	// shutdownGracePeriod is how long the daemon gets to exit before it is
+	// killed and lastShutdown how the daemon was last stopped.
+	shutdownGracePeriod time.Duration
+	lastShutdown        *ShutdownStatus
+
+	// shutdownSignals are the signals which shut the daemon down, see
+	// `WithShutdownOnSignals`. signalStop removes the installed handler and
+	// shutdownDone is closed once the daemon was shut down, see `Wait`.
+	shutdownSignals []os.Signal
+	shutdownMu      sync.Mutex
+	signalStop      chan struct{}
+	shutdownDone    chan struct{}
+
+	// gatewayAddr and swarmAddrs are the multiaddresses the daemon gateway
+	// and swarm listen on, or empty to keep the repo configuration.
+	gatewayAddr string
+	swarmAddrs  []string
+
+	// fallbackGateways are the HTTP gateways `FetchWithFallback` falls back
+	// to after the local node did not retrieve content within
+	// localFetchTimeout.
+	fallbackGateways  []string
+	localFetchTimeout time.Duration
+
+	// gcMu guards gcDone, which is closed once the garbage collection
+	// started by `GarbageCollection` finished, or nil if none is running.
+	gcMu   sync.Mutex
+	gcDone chan struct{}
+
+	// allowedCommands and deniedCommands restrict the `ipfs` subcommands the
+	// wrapper runs, by their leading words.
+	allowedCommands [][]string
+	deniedCommands  [][]string
+
+	// commandResultHook receives the raw result of every `ipfs` command,
+	// see `WithCommandResultHook`.
+	commandResultHook CommandResultHook
+
+	// dryRun controls whether the client commands are recorded to
+	// dryRunCommands instead of being run, see `WithDryRun`.
+	dryRun         bool
+	dryRunCommands []RecordedCommand
+	dryRunMu       sync.Mutex
+
+	// minFreeDiskSpace is the disk space, in bytes, which downloading kubo
+	// and adding content leave free, see `WithMinFreeDiskSpace`.
+	minFreeDiskSpace uint64
+
+	// repoLockTimeout is how long `NewWrapper` and `StartDaemonInBackground`
+	// wait for another wrapper to release the repo, see
+	// `WithRepoLockTimeout`.
+	repoLockTimeout time.Duration
+
+	// downloadConcurrency is how many files `NewWrapper` downloads at once
+	// and downloadProgressHook receives their progress, see
+	// `WithDownloadConcurrency` and `WithDownloadProgressHook`.
+	downloadConcurrency  int
+	downloadProgressHook DownloadProgressHook
+
+	// readOnly controls whether the calls modifying the node are refused
+	// with `ErrReadOnly`.
+	readOnly bool
+
+	// gcWatermark is the percentage of the disk budget of the repo at which
+	// the wrapper collects garbage, or 0 to leave it to kubo. The loop
+	// checking it runs while gcWatermarkCancel is set.
+	gcWatermark         int
+	gcWatermarkInterval time.Duration
+	gcWatermarkHook     GCWatermarkHook
+	gcWatermarkCancel   context.CancelFunc
+	gcWatermarkDone     chan struct{}
+
+	// cidBase is the multibase the CIDs returned by the wrapper are encoded
+	// in, or empty for the default of kubo.
+	cidBase string
+
+	// gatewayDisabled controls whether the HTTP gateway is turned off.
+	gatewayDisabled bool
+
+	// apiBindIP and gatewayBindIP are the interfaces the API and gateway
+	// bind to, or empty to keep the host of their addresses.
+	apiBindIP     string
+	gatewayBindIP string
+
+	// autoPorts controls whether free ports are picked for the addresses
+	// which were not set explicitly.
+	autoPorts bool
+
+	// workDir is the working directory of every spawned `ipfs` process, or
+	// empty to use the current working directory of this app.
+	workDir string
+
+	// binDir is the directory the binaries are installed into, and repoDir
+	// and clusterDataDir the directories of the node and cluster peer data.
+	binDir         string
+	repoDir        string
+	clusterDataDir string
+
+	// pinBackend is the remote service content gets pushed to by
+	// `EnsureRemoteReplica`, or nil.
+	pinBackend PinBackend
+
+	// watchDebounce is how long `WatchDir` waits for changes to settle and
+	// watchIPNSKey the key it publishes the root CID with, or empty.
+	watchDebounce time.Duration
+	watchIPNSKey  string
+
+	// tenant is the name of the tenant the node belongs to when created by
+	// a `TenantManager`, or empty.
+	tenant string
+
+	// xdgLayout controls whether the directories above are placed inside
+	// the XDG base directories instead of the application directory.
+	xdgLayout bool
+
+	// tempDir is the directory scratch files are created in, or empty to use
+	// the default directory of the operating system.
+	tempDir string
+
+	// keepArchive controls whether the downloaded kubo archive is kept in
+	// archiveCacheDir (or the user cache directory if empty) for reuse.
+	keepArchive     bool
+	archiveCacheDir string
+
+	// fileMode and dirMode are the permissions of the binaries and of the
+	// directories (including the repo) managed by this package.
+	fileMode os.FileMode
+	dirMode  os.FileMode
+
+	// storageMax and storageGCWatermark hold the disk budget of the repo
+	// applied to the `Datastore` configuration on every construction.
+	storageMax         string
+	storageGCWatermark int
+
 	// Dependencies to allow for mocking in tests.
 	osOperator      oskit.OSOperater
 	urlDownloader   urlkit.URLDownloader
@@ -107,6 +335,7 @@ type ipfsCliWrapper struct {
 func NewWrapper(options ...Option) (IpfsCliWrapper, error) {
 	// STEP 1. Get the OS and chip architecture to use so we will know what
 	// binary to utilize in our wrapper.
+	constructedAt := time.Now()
 
 	// Get the architecture of the machine
 	archName := runtime.GOARCH
@@ -123,10 +352,27 @@ func NewWrapper(options ...Option) (IpfsCliWrapper, error) {
 		daemonInitialWarmupDuration: time.Duration(5) * time.Second,
 		os:                          osName,
 		arch:                        archName,
+		kuboVersion:                 DefaultKuboVersion,
+		localFetchTimeout:           DefaultLocalFetchTimeout,
+		gcWatermarkInterval:         DefaultGCWatermarkCheckInterval,
+		binDir:                      "./bin",
+		repoDir:                     IPFSDataDirPath,
+		clusterDataDir:              IPFSClusterDataDirPath,
+		fileMode:                    DefaultFileMode,
+		dirMode:                     DefaultDirMode,
+		watchDebounce:               DefaultWatchDebounce,
+		logOutputLimit:              DefaultLogOutputLimit,
+		shutdownGracePeriod:         DefaultShutdownGracePeriod,
+		shutdownDone:                make(chan struct{}),
 		osOperator:                  &oskit.DefaultOSKit{},
+		minFreeDiskSpace:            DefaultMinFreeDiskSpace,
+		downloadConcurrency:         DefaultDownloadConcurrency,
 		urlDownloader:               &urlkit.DefaultURLKit{},
 		randomGenerator:             &randomkit.CryptoRandomGenerator{},
 	}
+	wrapper.ipnsRepublisher = newIPNSRepublisher(wrapper.logger)
+	wrapper.ipnsRepublisher.publish = wrapper.publishIPNSName
+	wrapper.blockEvents = newBlockEventMonitor(wrapper.logger)
 
 	// STEP 3: Apply our option conditions.
 
@@ -135,33 +381,157 @@ func NewWrapper(options ...Option) (IpfsCliWrapper, error) {
 		opt(wrapper)
 	}
 
+	wrapper.applyLogSampling()
+
+	// Fail early with a descriptive error instead of failing mysteriously
+	// when the daemon is started.
+	if err := wrapper.validate(); err != nil {
+		return nil, err
+	}
+
+	// Move the binaries and repos out of the application directory if
+	// requested with the `WithXDGLayout` option.
+	if wrapper.xdgLayout {
+		if err := wrapper.applyXDGLayout(); err != nil {
+			return nil, err
+		}
+	}
+	if wrapper.customRepoPath != "" {
+		wrapper.repoDir = wrapper.customRepoPath
+	}
+	if wrapper.tenant != "" {
+		wrapper.applyTenantLayout()
+	}
+
 	// STEP 4: Create the needed directories in the applications root directory
 	// so we can save our binary data into there.
 
 	dirs := []string{
-		"./bin", // The root folder which holds all our data we are managing.
-		IPFSDataDirPath,
-		IPFSDenylistDirPath,
+		wrapper.repoPath(),
+		wrapper.denylistDirPath(),
+	}
+	// The root folder which holds all our binaries we are managing. Nothing
+	// is installed there when the `WithBinaryPath` option is used, except
+	// for the binaries of the companion ipfs-cluster peer.
+	if wrapper.customBinaryPath == "" || wrapper.cluster != nil {
+		dirs = append(dirs, wrapper.binDir)
+	}
+	if wrapper.workDir != "" {
+		dirs = append(dirs, wrapper.workDir)
 	}
 	if err := wrapper.osOperator.CreateDirsIfDoesNotExist(dirs); err != nil {
-		log.Fatalf("failed to make directory: %v", err)
+		wrapper.logger.Error("failed to make directory", slog.Any("error", err))
+		return nil, fmt.Errorf("failed to make directory: %v", err)
+	}
+
+	// Keep other wrappers over the same repo, in this or other processes,
+	// from downloading, initializing or configuring it at the same time.
+	repoLock, err := wrapper.lockRepo(context.Background())
+	if err != nil {
+		wrapper.logger.Error("failed locking ipfs repo", slog.Any("error", err))
+		return nil, err
+	}
+	defer repoLock.Unlock()
+
+	// The bin directory is shared with the wrappers of other repos, keep
+	// them from installing binaries into it at the same time.
+	if wrapper.customBinaryPath == "" || wrapper.cluster != nil {
+		binLock, err := wrapper.lockBinDir(context.Background())
+		if err != nil {
+			wrapper.logger.Error("failed locking bin directory", slog.Any("error", err))
+			return nil, err
+		}
+		defer binLock.Unlock()
 	}
 
 	// STEP 5: Check to see if we have our `ipfs` binary ready to execute and if
 	// not then we will need to download it and get it ready for execution.
-	if _, err := os.Stat(IPFSBinaryFilePath); err != nil {
-		if err := wrapper.downloadAndUnzip(wrapper.logger, wrapper.os, wrapper.arch); err != nil {
-			log.Fatalf("failed to get ipfs binary from url: %v", err)
+	// Binaries installed by older releases of this package are moved into
+	// the per release directories first, so they do not get downloaded again.
+	// A binary set with the `WithBinaryPath` option is not managed by us.
+	if wrapper.customBinaryPath == "" {
+		if err := wrapper.migrateBinLayout(); err != nil {
+			wrapper.logger.Warn("failed migrating binary directory layout",
+				slog.Any("error", err))
+		}
+	}
+	var downloads []provisionDownload
+	if wrapper.customBinaryPath != "" {
+		// A binary set with the `WithBinaryPath` option is never downloaded.
+		if _, err := os.Stat(wrapper.binaryPath()); err != nil {
+			wrapper.logger.Error("ipfs binary missing", slog.Any("error", err))
+			return nil, fmt.Errorf("ipfs binary missing: %w", err)
+		}
+	} else if _, err := os.Stat(wrapper.binaryPath()); err != nil {
+		downloads = append(downloads, provisionDownload{name: KuboDownload, fetch: func(ctx context.Context) error {
+			return wrapper.downloadAndUnzip(ctx, wrapper.logger, wrapper.os, wrapper.arch)
+		}})
+	}
+
+	// Download the denylist as well if it wasn't downloaded before. This is
+	// configured by the `WithDenylist` option.
+	if wrapper.denylistFilename != "" {
+		downloadedDenylistFilePath := filepath.Join(wrapper.denylistDirPath(), wrapper.denylistFilename)
+		if _, err := os.Stat(downloadedDenylistFilePath); err != nil {
+			downloads = append(downloads, provisionDownload{name: DenylistDownload, fetch: func(ctx context.Context) error {
+				return wrapper.downloadDenylist(ctx, downloadedDenylistFilePath)
+			}})
+		}
+	}
+
+	// And the binaries of the companion ipfs-cluster peer, see STEP 8.
+	if wrapper.cluster != nil {
+		for _, program := range wrapper.clusterPrograms() {
+			if _, err := os.Stat(wrapper.clusterBinaryPath(program)); err != nil {
+				downloads = append(downloads, provisionDownload{name: program, fetch: func(ctx context.Context) error {
+					return wrapper.downloadClusterBinary(ctx, program)
+				}})
+			}
+		}
+	}
+
+	// Fetch everything at once to cut the time a fresh deployment takes to
+	// start, see `WithDownloadConcurrency`.
+	if err := wrapper.runDownloads(context.Background(), downloads); err != nil {
+		wrapper.logger.Error("failed provisioning downloads", slog.Any("error", err))
+		return nil, err
+	}
+
+	// Apply the denylists which are not downloaded. This is configured by
+	// the `WithDenylistFile` and `WithDenylistReader` options.
+	if err := wrapper.installLocalDenylists(); err != nil {
+		wrapper.logger.Error("failed installing denylists", slog.Any("error", err))
+		return nil, err
+	}
+	if wrapper.customBinaryPath == "" {
+		if err := wrapper.recordKuboVersionInUse(); err != nil {
+			wrapper.logger.Warn("failed recording kubo release",
+				slog.Any("error", err))
 		}
 	}
 
+	// Apply the configured permissions to existing installations too, which
+	// may have been created with looser permissions by older releases.
+	if err := wrapper.applyPermissions(); err != nil {
+		wrapper.logger.Warn("failed applying file permissions",
+			slog.Any("error", err))
+	}
+
+	// Make sure the installed binary, which may have been installed by an
+	// older or newer release of this package, is a tested kubo release.
+	if err := wrapper.checkKuboCompatibility(); err != nil {
+		wrapper.logger.Error("incompatible kubo release", slog.Any("error", err))
+		return nil, err
+	}
+
 	// STEP 6: If user wants to force shutdown any pervious running instances.
 	// This is controlled by the `WithForcedShutdownDaemonOnStartup` option.
 	if wrapper.forceShutdownOnStartup {
 		// This code is special because we need to lookup the `ipfs` running
 		// process in the operating system and send a `SIGTERM` signal via
-		// the operating system to cause that app to shutdown.
-		if err := wrapper.osOperator.TerminateProgram("ipfs"); err != nil {
+		// the operating system to cause that app to shutdown. Only daemons
+		// using our repo are terminated, see `findOwnDaemonPIDs`.
+		if err := wrapper.terminateOwnDaemons(); err != nil {
 			// Note: Do not crash program with `log.Fatalf` but instead just
 			// provide a warning in the console output.
 			wrapper.logger.Warn("failed terminating ipfs from os background",
@@ -169,80 +539,154 @@ func NewWrapper(options ...Option) (IpfsCliWrapper, error) {
 		}
 	}
 
-	// STEP 7: Download denylist and setup denylist. This is configured by
-	// the `WithDenylist` option.
-	if wrapper.denylistFilename != "" {
-		downloadedDenylistFilePath := "./bin/kubo/data/denylists/" + wrapper.denylistFilename
-
-		// Download the file if it wasn't downloaded before.
-		if _, err := os.Stat(downloadedDenylistFilePath); err != nil {
-			if downloadErr := wrapper.urlDownloader.DownloadFile(wrapper.denylistURL, downloadedDenylistFilePath); downloadErr != nil {
-				log.Fatalf("failed downloading the binary: %v", downloadErr)
-			}
-		}
+	// Take over the daemon left running by a previous run of this app, so
+	// it can be inspected and shut down gracefully.
+	if wrapper.isDaemonRunningContinously {
+		wrapper.adoptDaemon()
 	}
 
-	// STEP 8: Execute our `ipfs` binary `init` command so the application gets
+	// STEP 7: Execute our `ipfs` binary `init` command so the application gets
 	// setup; however, we will also set the environment variable before
 	// executing the command, therefore pointing to a different location for
-	// saving data. Please note, ignore error and output here. We do this
-	// because if we run `init` again after this app was already called then
-	// `ipfs` will return error so we don't care.
-	initCmd := exec.Command(IPFSBinaryFilePath, "init")
-	initCmd.Env = append(os.Environ(), "IPFS_PATH="+IPFSDataDirPath)
-
-	// Execute the command and check for errors
-	if output, err := initCmd.CombinedOutput(); err != nil {
-		if !strings.Contains(string(output), "ipfs configuration file already exists") {
-			// Log or handle the error appropriately, if needed
-			wrapper.logger.Warn("failed to initialize IPFS",
-				slog.Any("error", err),
-				slog.String("output", string(output)))
+	// saving data. The repo already has a configuration file if this app was
+	// run before, in which case `init` is skipped.
+	_, statErr := os.Stat(filepath.Join(wrapper.repoPath(), "config"))
+	isInitialized := statErr == nil
+
+	var output []byte
+	initStartedAt := time.Now()
+	if !isInitialized {
+		initArgs := []string{"init"}
+		if wrapper.datastore != nil {
+			initArgs = append(initArgs, "--profile="+wrapper.datastore.initProfile())
 		}
+		initCmd := wrapper.baseCommand(context.Background(), initArgs...)
+
+		// Execute the command and check for errors
+		output, err = wrapper.combinedOutput(initCmd)
+	}
+
+	// Wait until the repo is usable before configuring it, instead of
+	// assuming it is right after `init` returned.
+	repoCtx, cancel := context.WithTimeout(context.Background(), repoReadyTimeout)
+	defer cancel()
+	if err := wrapper.waitForRepo(repoCtx); err != nil {
+		wrapper.logger.Error("ipfs repo is not usable", slog.Any("error", err))
+		return nil, err
+	}
+	if !isInitialized {
+		wrapper.provision.Initialized = err == nil
+		wrapper.provision.InitDuration = time.Since(initStartedAt)
+	}
+
+	if isInitialized {
+		wrapper.logger.Debug("IPFS repo already initialized")
+	} else if err != nil {
+		// Log or handle the error appropriately, if needed
+		wrapper.logger.Warn("failed to initialize IPFS",
+			slog.Any("error", err),
+			wrapper.outputAttr(output))
 	} else {
 		wrapper.logger.Debug("IPFS initialization completed successfully",
-			slog.String("output", string(output)))
+			wrapper.outputAttr(output))
+
+		// The datastore parameters can only be chosen when the repo is
+		// created, so only apply them right after a successful `init`.
+		if wrapper.datastore != nil {
+			if err := wrapper.applyDatastoreParams(); err != nil {
+				wrapper.logger.Error("failed applying datastore parameters", slog.Any("error", err))
+				return nil, fmt.Errorf("failed applying datastore parameters: %v", err)
+			}
+		}
 	}
 
-	// Setup the command we will execute in our shell. For more details here,
-	// please visit the developer documentations for the `Kubo CLI` via this link:
-	// https://docs.ipfs.tech/reference/kubo/cli/#ipfs-daemon
-	app := IPFSBinaryFilePath
-	arg0 := "daemon"
-	arg1 := "--enable-gc=true" // Enable automatic garbage collection in runtime.
-	arg2 := "--migrate=true"   // Auto-select "yes" on migrate prompt.
-	daemonCmd := exec.Command(app, arg0, arg1, arg2)
+	// Pick free ports if enabled by the `WithAutoPorts` option.
+	if err := wrapper.assignAutoPorts(); err != nil {
+		wrapper.logger.Error("failed assigning automatic ports", slog.Any("error", err))
+		return nil, fmt.Errorf("failed assigning automatic ports: %v", err)
+	}
 
-	// Set the environment variable before executing the command
-	daemonCmd.Env = append(os.Environ(), "IPFS_PATH="+IPFSDataDirPath)
+	// Make the daemon listen on the addresses configured by the options.
+	if err := wrapper.applyAddresses(); err != nil {
+		wrapper.logger.Error("failed applying addresses", slog.Any("error", err))
+		return nil, fmt.Errorf("failed applying addresses: %v", err)
+	}
 
-	// Create a pipe to read the output of the command
-	stdout, err := daemonCmd.StdoutPipe()
-	if err != nil {
-		wrapper.logger.Error("error creating stdout pipe", slog.Any("error", err))
-		return nil, fmt.Errorf("Error creating stdout pipe: %v\n", err)
+	// Apply the disk budget on every run so changes to the options take
+	// effect for existing repos as well.
+	if err := wrapper.applyStorageLimits(); err != nil {
+		wrapper.logger.Error("failed applying storage limits", slog.Any("error", err))
+		return nil, fmt.Errorf("failed applying storage limits: %v", err)
 	}
 
-	wrapper.ipfsDaemonCmd = daemonCmd
-	wrapper.stdout = stdout
+	// STEP 8: Initialize the companion ipfs-cluster peer, downloaded in STEP 5. This
+	// is configured by the `WithClusterService` or `WithClusterFollow` option.
+	if wrapper.cluster != nil {
+		if err := wrapper.setupCluster(); err != nil {
+			wrapper.logger.Error("failed setting up ipfs-cluster", slog.Any("error", err))
+			return nil, fmt.Errorf("failed setting up ipfs-cluster: %v", err)
+		}
+	}
 
+	wrapper.provision.ConstructorDuration = time.Since(constructedAt)
 	wrapper.logger.Debug("ipfs daemon wrapper initialized",
 		slog.String("os", wrapper.os),
 		slog.String("arch", wrapper.arch),
-		slog.String("ipfs_bin_path", IPFSBinaryFilePath),
-		slog.String("ipfs_data_path", IPFSDataDirPath))
+		slog.String("ipfs_bin_path", wrapper.binaryPath()),
+		slog.String("ipfs_data_path", wrapper.repoPath()),
+		slog.Duration("duration", wrapper.provision.ConstructorDuration))
 
 	return wrapper, nil
 }
 
+// daemonCommand returns a new command running the `ipfs` binary in daemon
+// mode. A command can only be started once, so every start of the daemon
+// gets its own. For more details here, please visit the developer
+// documentations for the `Kubo CLI` via this link:
+// https://docs.ipfs.tech/reference/kubo/cli/#ipfs-daemon
+func (wrap *ipfsCliWrapper) daemonCommand() *exec.Cmd {
+	cmd := wrap.baseCommand(context.Background(), wrap.daemonArgs()...)
+
+	// Keep the daemon in the process group of this app so it receives the
+	// signals of the terminal (e.g. Ctrl+C) along with the app. It is
+	// stopped through `ShutdownDaemon` rather than a context.
+	cmd.SysProcAttr = nil
+	return cmd
+}
+
+// daemonArgs returns the arguments the `ipfs` binary is executed with to run
+// in daemon mode.
+func (wrap *ipfsCliWrapper) daemonArgs() []string {
+	return []string{
+		"daemon",
+		"--enable-gc=true", // Enable automatic garbage collection in runtime.
+		"--migrate=true",   // Auto-select "yes" on migrate prompt.
+
+		// Redundant with the `IPFS_PATH` environment variable, but makes the
+		// repo visible in the process list so `findOwnDaemonPIDs` can tell
+		// our daemon apart from other `ipfs` processes on the machine.
+		"--repo-dir=" + wrap.repoPath(),
+	}
+}
+
 func (wrap *ipfsCliWrapper) StartDaemonInBackground() error {
-	// Before we begin our code, let's check if the `ipfs` binary is already
-	// running in the background, for whatever reason.
-	if isRunningAlready, err := wrap.osOperator.IsProgramRunning("ipfs"); isRunningAlready || err != nil {
+	return wrap.StartDaemon(context.Background())
+}
+
+func (wrap *ipfsCliWrapper) StartDaemon(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("ipfs daemon not started: %w", err)
+	}
+
+	// Before we begin our code, let's check if our `ipfs` daemon is already
+	// running in the background, for whatever reason. Other `ipfs` processes
+	// on the machine which use a different repo are not considered.
+	pids, err := wrap.findOwnDaemonPIDs()
+	if isRunningAlready := len(pids) > 0; isRunningAlready || err != nil {
 		if isRunningAlready {
 			wrap.isDaemonRunning = true
 			wrap.logger.Debug("ipfs daemon is already running and waiting for api call from your app")
-			return nil
+			return wrap.startCompanions()
 		}
 
 		wrap.logger.Error("is program running err", slog.Any("error", err))
@@ -250,77 +694,259 @@ func (wrap *ipfsCliWrapper) StartDaemonInBackground() error {
 	}
 	wrap.logger.Debug("ipfs daemon is starting...")
 
+	// Keep other wrappers over the same repo from starting a daemon of
+	// their own until this one is up, or has failed to start.
+	repoLock, err := wrap.lockRepo(ctx)
+	if err != nil {
+		wrap.logger.Error("failed locking ipfs repo", slog.Any("error", err))
+		return err
+	}
+	defer repoLock.Unlock()
+
+	// Another wrapper may have started the daemon while we waited.
+	if pids, err := wrap.findOwnDaemonPIDs(); err == nil && len(pids) > 0 {
+		wrap.isDaemonRunning = true
+		wrap.logger.Debug("ipfs daemon was started by another wrapper")
+		return wrap.startCompanions()
+	}
+
+	daemonCmd := wrap.daemonCommand()
+
+	// Keep the output of the daemon so we can explain why it exited if it
+	// does so during the warmup or crashes later on. In continous operation
+	// mode the output goes to a file instead, see below.
+	stdout := &tailBuffer{size: daemonOutputTailSize}
+	stderr := &tailBuffer{size: daemonOutputTailSize}
+	daemonCmd.Stdout = stdout
+	daemonCmd.Stderr = stderr
+	wrap.daemonStdoutTail = stdout
+	wrap.daemonStderrTail = stderr
+	if wrap.blockEvents.hook != nil {
+		daemonCmd.Stderr = io.MultiWriter(stderr, wrap.blockEvents.writer())
+	}
+	readStdout := stdout.String
+	readStartupOutput := stderr.String
+
 	// If `isDaemonRunningContinously` is true then
 	if wrap.isDaemonRunningContinously {
 		wrap.logger.Debug("continous operation mode detected, ipfs daemon will run independently of this app")
 
 		// Ensure that the process is disassociated from the Go process and will run independently
-		wrap.ipfsDaemonCmd.SysProcAttr = &syscall.SysProcAttr{
-			Setsid: true, // Create a new session, which makes the process independent
-		}
+		prockit.Detach(daemonCmd)
 
-		// Redirect stdout and stderr to /dev/null to detach from the terminal
-		devNull, err := os.Open(os.DevNull)
+		// Redirect stdout and stderr to the files configured with the
+		// `WithDaemonOutputFiles` option, or else to /dev/null, to detach
+		// from the terminal.
+		stdoutFile, stderrFile, err := wrap.openDaemonOutputFiles()
 		if err != nil {
 			return err
 		}
-		defer devNull.Close()
-		wrap.ipfsDaemonCmd.Stdout = devNull
-		wrap.ipfsDaemonCmd.Stderr = devNull
-
-		// // Redirect stdout and stderr to files or `/dev/null` to detach from terminal
-		// wrap.ipfsDaemonCmd.Stdout = os.Stdout // or you can redirect to a file with os.Create("/path/to/output.log")
-		// wrap.ipfsDaemonCmd.Stderr = os.Stderr // or os.Create("/path/to/error.log")
+		defer stdoutFile.Close()
+		defer stderrFile.Close()
+		daemonCmd.Stdout = stdoutFile
+		daemonCmd.Stderr = stderrFile
+		wrap.daemonStdoutTail = nil
+		wrap.daemonStderrTail = nil
+
+		// A detached daemon must not write to a pipe of this app, so read
+		// back what it appended to the error output file instead.
+		readStdout = func() string { return "" }
+		readStartupOutput = func() string { return "" }
+		if wrap.daemonStdoutPath != "" {
+			if offset, err := stdoutFile.Seek(0, io.SeekEnd); err == nil {
+				path := stdoutFile.Name()
+				readStdout = func() string { return readFileTail(path, offset) }
+			}
+		}
+		if wrap.daemonStderrPath != "" {
+			if offset, err := stderrFile.Seek(0, io.SeekEnd); err == nil {
+				path := stderrFile.Name()
+				readStartupOutput = func() string { return readFileTail(path, offset) }
+			}
+		}
 	}
 
-	// Start the command
-	if err := wrap.ipfsDaemonCmd.Start(); err != nil {
+	// Start the command. It is waited on in the background from now on, so
+	// the process gets reaped whenever and however it exits.
+	daemon, err := prockit.Start(daemonCmd)
+	if err != nil {
 		wrap.logger.Error("error starting command", slog.Any("error", err))
 		return fmt.Errorf("Error starting command: %v\n", err)
 	}
-
+	wrap.daemon = daemon
 	wrap.isDaemonRunning = true
+	exited := daemon.Exited()
+	go wrap.captureCrash(daemon, readStdout, readStartupOutput)
+
+	// Record the process id so later runs of this app can find the daemon,
+	// which is essential in continous operation mode.
+	if err := wrap.writePIDFile(daemon.Pid()); err != nil {
+		wrap.logger.Warn("failed recording daemon pid", slog.Any("error", err))
+	}
+	if err := wrap.writeDaemonInfo(daemon.Pid()); err != nil {
+		wrap.logger.Warn("failed recording daemon info", slog.Any("error", err))
+	}
 
-	// Set an artificial delay to give time for the `ipfs` binary to load up.
-	// Another perspective is this is the `warmup time`.
-	time.Sleep(wrap.daemonInitialWarmupDuration)
+	// Give the `ipfs` binary time to load up, another perspective is this is
+	// the `warmup time`. We return as soon as the API accepts connections;
+	// the longest we wait adapts to the startup durations measured on
+	// previous runs, see `warmupDeadline`. If the daemon exits in the
+	// meantime (port in use, bad config, lock held, etc) then report why
+	// instead of pretending it started.
+	startedAt := time.Now()
+	ready, hasExited := wrap.waitUntilReady(ctx, exited, wrap.warmupDeadline())
+	switch {
+	case ready:
+		wrap.recordStartupDuration(time.Since(startedAt))
+		wrap.recordTelemetry(TelemetryEvent{Type: TelemetryDaemonStarted, Duration: time.Since(startedAt)})
+	case !hasExited && ctx.Err() != nil:
+		// The caller gave up on the startup, so do not leave a daemon behind
+		// which it does not know about.
+		wrap.isDaemonRunning = false
+		wrap.daemon = nil
+		wrap.removePIDFile()
+		if err := daemon.Kill(); err != nil && !prockit.IsKilled(err) {
+			wrap.logger.Warn("failed killing ipfs daemon", slog.Any("error", err))
+		}
+		wrap.logger.Error("ipfs daemon not ready before the context was done",
+			slog.Duration("waited", time.Since(startedAt)))
+		return fmt.Errorf("ipfs daemon not ready: %w", ctx.Err())
+	case !hasExited:
+		// Fall back to the fixed warmup behaviour of proceeding anyway, but
+		// keep measuring so the next startup waits long enough.
+		wrap.logger.Warn("ipfs daemon api not reachable after warmup, proceeding anyway",
+			slog.Duration("waited", time.Since(startedAt)))
+		go wrap.recordStartupWhenReady(exited, startedAt)
+	default:
+		wrap.isDaemonRunning = false
+		wrap.daemon = nil
+		wrap.removePIDFile()
+		err := newDaemonStartupError(daemon.Wait(), readStartupOutput())
+		wrap.logger.Error("ipfs daemon exited during startup", slog.Any("error", err))
+		return err
+	}
 	wrap.logger.Debug("ipfs daemon is running and waiting for api call from your app")
-	return nil
+
+	return wrap.startCompanions()
+}
+
+// startCompanions starts the background services which depend on a running
+// `ipfs` daemon, such as the IPNS republisher and the cluster peer.
+func (wrap *ipfsCliWrapper) startCompanions() error {
+	// Shut the daemon down when the app is asked to exit.
+	wrap.startSignalHandler()
+
+	// Keep any tracked IPNS names alive for as long as the daemon runs.
+	wrap.ipnsRepublisher.start()
+
+	// Keep the repo below its gc watermark.
+	wrap.startGCWatermark()
+
+	// Report the content refused because of a denylist.
+	wrap.startBlockEvents()
+
+	// Run the companion cluster peer now that it has a daemon to talk to.
+	if err := wrap.startCluster(); err != nil {
+		return err
+	}
+
+	// Serve the Pinning Service API now that pins can be fulfilled.
+	if err := wrap.startPinningService(); err != nil {
+		return err
+	}
+
+	// Report the health of the daemon to monitoring probes.
+	return wrap.startHealthEndpoint()
+}
+
+// stopCompanions stops the background services started by `startCompanions`.
+// They are always tied to the lifetime of this app, even in continous
+// operation mode, as none of them are started detached.
+func (wrap *ipfsCliWrapper) stopCompanions() error {
+	wrap.stopSignalHandler()
+	wrap.ipnsRepublisher.stop()
+	wrap.stopGCWatermark()
+	wrap.blockEvents.stop()
+	if err := wrap.stopCluster(); err != nil {
+		return err
+	}
+	if err := wrap.stopPinningService(); err != nil {
+		return err
+	}
+	return wrap.stopHealthEndpoint()
 }
 
 // ForceShutdownDaemon function will send KILL signal to the operating system
 // for the `ipfs` running daemon in background to force that binary to shutdown.
-func (wrap *ipfsCliWrapper) ForceShutdownDaemon() error {
+// Only the daemons using the wrapper-managed repo are affected.
+func (wrap *ipfsCliWrapper) ForceShutdownDaemon() (err error) {
+	defer func() {
+		if err == nil {
+			wrap.markShutdownDone()
+		}
+	}()
+
 	if wrap.isDaemonRunningContinously {
+		if err := wrap.stopCompanions(); err != nil {
+			return err
+		}
 		wrap.isDaemonRunning = false
 
 		// This code is special because we need to lookup the `ipfs` running
 		// process in the operating system and send a `SIGTERM` signal via
 		// the operating system to cause that app to shutdown.
-		return wrap.osOperator.TerminateProgram("ipfs")
+		if wrap.daemon != nil {
+			wrap.daemon.ExpectExit()
+		}
+		return wrap.terminateOwnDaemons()
 	}
 	return wrap.ShutdownDaemon()
 }
 
 func (wrap *ipfsCliWrapper) ShutdownDaemon() error {
+	return wrap.Shutdown(context.Background())
+}
+
+func (wrap *ipfsCliWrapper) Shutdown(ctx context.Context) (err error) {
+	defer func() {
+		if err == nil {
+			wrap.markShutdownDone()
+		}
+	}()
+
+	if err := wrap.stopCompanions(); err != nil {
+		return err
+	}
+
 	if wrap.isDaemonRunningContinously {
 		wrap.logger.Debug("Ignoring daemon shutdown as wrapper is running in continous operation mode")
 		return nil
 	}
 	wrap.isDaemonRunning = false
 
-	// Send the process kill signal to our running application in the shell and
-	// return any errors if anything fails in this operation.
-	if killErr := wrap.ipfsDaemonCmd.Process.Kill(); killErr != nil {
-		wrap.logger.Error("error killing process", slog.Any("error", killErr))
-		return fmt.Errorf("Error killing process: %v\n", killErr)
+	daemon := wrap.daemon
+	if daemon == nil {
+		wrap.logger.Debug("ignoring daemon shutdown as the daemon was not started by this wrapper")
+		return nil
 	}
-
-	// Wait for the command to exit.
-	if waitErr := wrap.ipfsDaemonCmd.Wait(); waitErr != nil {
-		if exitError, ok := waitErr.(*exec.ExitError); ok && exitError.ProcessState.ExitCode() == -1 {
-			// This is the expected behavior, the command was killed.
-			// log.Println("Process was killed as expected.")
+	wrap.daemon = nil
+	defer wrap.removePIDFile()
+
+	// Ask our running application to exit, unless it already exited, and
+	// kill it if it does not exit within the grace period, or before the
+	// context is done.
+	status := ShutdownStatus{Time: time.Now(), Method: ShutdownGraceful}
+	killed, waitErr := daemon.StopContext(ctx, wrap.shutdownGracePeriod, func() error {
+		return wrap.terminateDaemon(daemon.Pid())
+	})
+	status.Duration = time.Since(status.Time)
+	if killed {
+		status.Method = ShutdownKilled
+	}
+	wrap.lastShutdown = &status
+	if waitErr != nil {
+		if prockit.IsKilled(waitErr) {
+			// This is the expected behavior, the command was terminated.
 			wrap.logger.Debug("ipfs daemon has exited")
 		} else {
 			// Handle other errors.
@@ -328,46 +954,106 @@ func (wrap *ipfsCliWrapper) ShutdownDaemon() error {
 			return fmt.Errorf("Command exited with error: %v\n", waitErr)
 		}
 	}
+	wrap.logger.Debug("ipfs daemon has stopped",
+		slog.String("method", string(status.Method)),
+		slog.Duration("duration", status.Duration))
+	wrap.recordTelemetry(TelemetryEvent{Type: TelemetryDaemonStopped})
 	return nil
 }
 
 // downloadAndUnzip function will download the `ipfs` binary based on your
 // machine operating system and CPU architecture; afterwords, unzip the binary
 // and have it ready for execution.
-func (wrap *ipfsCliWrapper) downloadAndUnzip(logger *slog.Logger, osName, archName string) error {
+func (wrap *ipfsCliWrapper) downloadAndUnzip(ctx context.Context, logger *slog.Logger, osName, archName string) error {
 	logger.Debug("ipfs binary does not exist, need to fetch now...")
 
-	binaryDirName := "bin"
-	zippedBinaryFilePath := "./bin/ipfs.tar.gz"
-	unzippedDirPath := "./bin/kubo"
+	unzippedDirPath := filepath.Dir(wrap.binaryPath())
 
-	// Download the file if it wasn't downloaded before.
-	if _, err := os.Stat(zippedBinaryFilePath); err != nil {
-		// Lookup the binary to download based on what OS and architecture you are
-		// using so the correct binary gets downloaded that will work on your
-		// machine.
-		url, err := getDownloadURL(osName, archName)
-		if err != nil {
-			logger.Error("failed finding download link",
-				slog.Any("error", err),
-				slog.String("os", osName),
-				slog.String("arch", archName))
-			return fmt.Errorf("failed finding download link: %v", err)
+	// Lookup the binary to download based on what OS and architecture you are
+	// using so the correct binary gets downloaded that will work on your
+	// machine.
+	url, err := getDownloadURL(wrap.kuboVersion, osName, archName)
+	if err != nil {
+		logger.Error("failed finding download link",
+			slog.Any("error", err),
+			slog.String("os", osName),
+			slog.String("arch", archName))
+		return fmt.Errorf("failed finding download link: %v", err)
+	}
+
+	// Stage the archive in the temp directory so an interrupted download
+	// never leaves a partial archive inside the application directory.
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		logger.Error("failed creating temporary directory",
+			slog.Any("error", err))
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+	zippedBinaryFilePath := filepath.Join(stageDir, path.Base(url))
+
+	// Reuse the archive from the cache if it was kept by a previous install.
+	// This is controlled by the `WithKeepArchive` option.
+	cachedArchivePath := ""
+	if wrap.keepArchive {
+		cachedArchivePath = filepath.Join(wrap.archiveCacheDirPath(), path.Base(url))
+		if _, err := os.Stat(cachedArchivePath); err == nil {
+			logger.Debug("using cached archive",
+				slog.String("path", cachedArchivePath))
+			zippedBinaryFilePath = cachedArchivePath
+		}
+	}
+
+	// Fail before downloading instead of midway with a partial archive.
+	for _, dir := range []string{stageDir, unzippedDirPath} {
+		if err := wrap.checkDiskSpace(dir, kuboInstallSize); err != nil {
+			logger.Error("not enough disk space to install the binary",
+				slog.Any("error", err))
+			return err
 		}
+	}
 
+	if zippedBinaryFilePath != cachedArchivePath {
 		logger.Debug("fetching zip file",
 			slog.String("os", osName),
 			slog.String("arch", archName),
 			slog.String("url", url))
 
-		if downloadErr := wrap.urlDownloader.DownloadFile(url, zippedBinaryFilePath); downloadErr != nil {
+		downloadStartedAt := time.Now()
+		if downloadErr := wrap.urlDownloader.DownloadFile(ctx, url, zippedBinaryFilePath); downloadErr != nil {
 			logger.Error("failed downloading the binary",
-				slog.Any("error", err),
+				slog.Any("error", downloadErr),
 				slog.String("url", url),
 				slog.String("os", osName),
 				slog.String("arch", archName))
 			return fmt.Errorf("failed downloading the binary: %v", downloadErr)
 		}
+		downloadDuration := time.Since(downloadStartedAt)
+		wrap.updateProvisionReport(func(report *ProvisionReport) {
+			report.Downloaded = true
+			report.DownloadDuration = downloadDuration
+			if fi, err := os.Stat(zippedBinaryFilePath); err == nil {
+				report.DownloadBytes = fi.Size()
+			}
+		})
+
+		// Only a verified archive gets cached and extracted.
+		if err := wrap.verifyArchiveChecksum(ctx, url, zippedBinaryFilePath); err != nil {
+			logger.Error("failed verifying the archive",
+				slog.Any("error", err),
+				slog.String("url", url))
+			return err
+		}
+
+		if cachedArchivePath != "" {
+			if err := os.MkdirAll(filepath.Dir(cachedArchivePath), 0755); err != nil {
+				return fmt.Errorf("failed creating archive cache directory: %v", err)
+			}
+			if err := moveIntoPlace(zippedBinaryFilePath, cachedArchivePath); err != nil {
+				return fmt.Errorf("failed caching archive: %v", err)
+			}
+			zippedBinaryFilePath = cachedArchivePath
+		}
 	}
 
 	logger.Debug("ipfs binary unzipping...")
@@ -377,39 +1063,52 @@ func (wrap *ipfsCliWrapper) downloadAndUnzip(logger *slog.Logger, osName, archNa
 			slog.Any("error", err),
 			slog.String("os", osName),
 			slog.String("arch", archName))
-		log.Fatalf("failed to make directory: %v", err)
+		return fmt.Errorf("failed to make directory: %v", err)
 	}
-	if err := wrap.osOperator.CreateDirIfDoesNotExist(IPFSDataDirPath); err != nil {
+	if err := wrap.osOperator.CreateDirIfDoesNotExist(wrap.repoPath()); err != nil {
 		logger.Error("failed to make directory",
 			slog.Any("error", err),
 			slog.String("os", osName),
 			slog.String("arch", archName))
-		log.Fatalf("failed to make directory: %v", err)
+		return fmt.Errorf("failed to make directory: %v", err)
 	}
 
 	// Developers Note:
-	// Permission value of `777` is a permission in Unix based system with full
-	// read/write/execute permission to owner, group and everyone.
+	// The permissions default to `0755` for files and `0700` for directories
+	// and are configurable with the `WithFileMode` and `WithDirMode` options.
+
+	// The archive holds a "kubo" directory, so extract it next to the
+	// archive and move that directory into the directory of the release.
+	extractDirPath := filepath.Join(stageDir, "extract")
 
 	// Special thanks to: https://github.com/golift/xtractr?tab=readme-ov-file
 	x := &xtractr.XFile{
 		FilePath:  zippedBinaryFilePath,
-		OutputDir: binaryDirName,
-		FileMode:  os.FileMode(int(0777)), // Note: https://stackoverflow.com/a/28969523
-		DirMode:   os.FileMode(int(0777)),
+		OutputDir: extractDirPath,
+		FileMode:  wrap.fileMode, // Note: https://stackoverflow.com/a/28969523
+		DirMode:   wrap.dirMode,
 	}
 
 	// size is how many bytes were written.
 	// files may be nil, but will contain any files written (even with an error).
-	size, files, err := xtractr.ExtractTarGzip(x)
+	extractStartedAt := time.Now()
+	size, files, err := wrap.extractArchive(x)
 	if err != nil || files == nil {
-		logger.Error("failed extracting tar gzip",
+		logger.Error("failed extracting the archive",
 			slog.Int64("bytes written", size),
 			slog.Any("files extracted", files),
 			slog.Any("error", err),
 			slog.String("os", osName),
 			slog.String("arch", archName))
-		log.Fatal(size, files, err)
+
+		// Do not keep reusing a corrupt archive on the next install.
+		if cachedArchivePath != "" {
+			os.Remove(cachedArchivePath)
+		}
+		if err == nil {
+			err = errors.New("no files extracted")
+		}
+		return fmt.Errorf("failed extracting the archive: %v", err)
 	}
 
 	logger.Debug("ipfs binary unzipped: Bytes written:",
@@ -417,91 +1116,314 @@ func (wrap *ipfsCliWrapper) downloadAndUnzip(logger *slog.Logger, osName, archNa
 		slog.String("files extracted", strings.Join(files, "\n -")),
 	)
 
-	// Remove our compressed file we downloaded in this function previously above.
-	if err := os.Remove(zippedBinaryFilePath); err != nil {
-		logger.Error("failed deleting zip",
-			slog.String("path", zippedBinaryFilePath),
+	// Make sure the extracted binary runs and is the requested release
+	// before replacing anything.
+	extractedDirPath := filepath.Join(extractDirPath, "kubo")
+	os.Chmod(filepath.Join(extractedDirPath, filepath.Base(wrap.binaryPath())), wrap.fileMode)
+	if err := wrap.verifyKuboBinary(ctx, filepath.Join(extractedDirPath, filepath.Base(wrap.binaryPath()))); err != nil {
+		logger.Error("failed verifying the extracted ipfs binary",
+			slog.Any("error", err))
+		if cachedArchivePath != "" {
+			os.Remove(cachedArchivePath)
+		}
+		return err
+	}
+
+	if err := moveIntoPlace(extractedDirPath, unzippedDirPath); err != nil {
+		logger.Error("failed installing ipfs binary",
 			slog.Any("error", err),
-			slog.String("os", osName),
-			slog.String("arch", archName))
-		return fmt.Errorf("failed deleting zip: %v", err)
+			slog.String("path", unzippedDirPath))
+		return err
 	}
+	extractDuration := time.Since(extractStartedAt)
+	wrap.updateProvisionReport(func(report *ProvisionReport) {
+		report.ExtractDuration = extractDuration
+	})
 
 	// Set the permission of the file to be readable. Do this in case the above
-	// `ExtractTarGzip` library failed in any of the different operating system.
+	// extraction library failed in any of the different operating system.
 	// This code is essentially a `just-in-case` sort of thing to run.
-	os.Chmod(IPFSBinaryFilePath, 0777)
+	os.Chmod(wrap.binaryPath(), wrap.fileMode)
 
 	logger.Debug("ipfs binary ready for usage",
-		slog.String("filepath", IPFSBinaryFilePath))
+		slog.String("filepath", wrap.binaryPath()))
 	return nil
 }
 
-func (wrap *ipfsCliWrapper) AddFile(ctx context.Context, filepath string) (string, error) {
+// AddedEntry is a file or directory added by `AddFileEntries`.
+type AddedEntry struct {
+	// Name is the path of the entry, starting with the name of the added
+	// file or directory, e.g. "photos/2024/beach.jpg", or empty for the
+	// wrapping directory of `WithWrapDirectory`.
+	Name string
+
+	// CID is the content identifier of the entry.
+	CID string
+
+	// Size is the cumulative size of the entry in bytes, or 0 when kubo did
+	// not report it.
+	Size uint64
+}
+
+func (wrap *ipfsCliWrapper) AddFile(ctx context.Context, filepath string, opts ...AddOption) (string, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return "", err
+	}
+
+	if err := wrap.checkAddDiskSpace(filepath); err != nil {
+		return "", err
+	}
+	if err := wrap.waitForGC(ctx); err != nil {
+		return "", err
+	}
+
+	settings := newAddSettings(opts)
+	if err := wrap.prepareToFiles(ctx, settings); err != nil {
+		return "", err
+	}
+
 	// Prepare the command to add the file using the IPFS binary and utilize
-	// the latest cid implementation.
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "add", filepath, "--cid-version=1")
+	// the latest cid implementation, recursing in case a directory was given.
+	// With `--quieter` the only output is the CID of the root, so nothing
+	// depends on the human readable wording of kubo.
+	args := wrap.cidBaseArgs("add", "--recursive", "--cid-version=1", "--quieter")
+	if settings.toFiles != "" {
+		args = append(args, "--to-files="+settings.toFiles)
+	}
+	if settings.wrapDirectory {
+		args = append(args, "--wrap-with-directory")
+	}
+	settingsArgs, err := wrap.addSettingsArgs(settings)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, settingsArgs...)
+	cmd := wrap.command(ctx, append(args, "--", filepath)...)
 
-	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	// Keep stderr separate so warnings never get mistaken for the CID.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
 	if err != nil {
 		wrap.logger.Error("error adding file to ipfs",
 			slog.String("filepath", filepath),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return "", fmt.Errorf("failed to add file to ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(stderr.Bytes()))
+		return "", wrap.commandError("add file to ipfs", err, stderr.Bytes())
 	}
+	cid, err := outputkit.ParseRootCID(output)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ipfs add output: %v", err)
+	}
+
+	wrap.logger.Debug("file added to ipfs successfully",
+		slog.String("filepath", filepath),
+		slog.String("cid", cid))
+
+	return cid, nil
+}
 
-	// ALGORITHM
+// maxFileLinksKuboVersion is the first kubo release supporting
+// `ipfs add --max-file-links`, see `WithMaxLinks`.
+const maxFileLinksKuboVersion = "v0.35.0"
+
+// dereferenceSymlinksKuboVersion is the first kubo release supporting
+// `ipfs add --dereference-symlinks`, see `SymlinkFollow`.
+const dereferenceSymlinksKuboVersion = "v0.35.0"
+
+// addSettingsArgs returns the flags of `ipfs add` for the add options: the
+// DAG layout set with `WithTrickle` and `WithMaxLinks`, the symlink policy
+// set with `WithSymlinks` and the inclusion of hidden files set with
+// `WithIncludeHidden`.
+func (wrap *ipfsCliWrapper) addSettingsArgs(settings *addSettings) ([]string, error) {
+	var args []string
+	if settings.hidden {
+		args = append(args, "--hidden")
+	}
+	if settings.trickle {
+		args = append(args, "--trickle")
+	}
+	if settings.maxLinks < 0 {
+		return nil, fmt.Errorf("max links must not be negative, got %d", settings.maxLinks)
+	}
+	if settings.maxLinks > 0 {
+		if err := wrap.requireKuboVersion(maxFileLinksKuboVersion, "max links"); err != nil {
+			return nil, err
+		}
+		args = append(args, "--max-file-links="+strconv.Itoa(settings.maxLinks))
+	}
+	switch settings.symlinks {
+	case SymlinkPreserve:
+	case SymlinkDereferenceArgs:
+		args = append(args, "--dereference-args")
+	case SymlinkFollow:
+		if err := wrap.requireKuboVersion(dereferenceSymlinksKuboVersion, "following symlinks"); err != nil {
+			return nil, err
+		}
+		args = append(args, "--dereference-symlinks")
+	default:
+		return nil, fmt.Errorf("unknown symlink policy %d", settings.symlinks)
+	}
+	return args, nil
+}
 
-	parts := strings.Fields(string(output))
+// requireKuboVersion returns `ErrIncompatibleKubo` if the installed kubo
+// release is older than the required one, so features of newer releases fail
+// clearly instead of with the unknown option error of older releases. The
+// release is unknown if it could not be read, in which case kubo gets to
+// decide.
+func (wrap *ipfsCliWrapper) requireKuboVersion(required string, feature string) error {
+	installed, err := versionkit.Parse(wrap.installedKuboVersion)
+	if err != nil {
+		return nil
+	}
+	minimum, _ := versionkit.Parse(required)
+	if installed.Compare(minimum) < 0 {
+		return fmt.Errorf("%w: %s require kubo %s or newer, installed %s", ErrIncompatibleKubo, feature, required, installed)
+	}
+	return nil
+}
 
-	// Uncomment for debugging purposes only to see what's going on.
-	// wrap.logger.Debug("command executed",
-	// 	slog.String("filepath", filepath),
-	// 	slog.Int("parts len", len(parts)),
-	// 	slog.Any("parts", parts))
+// prepareToFiles checks the MFS path requested with `WithToFiles` and creates
+// its parent directories, which `ipfs add --to-files` requires to exist.
+func (wrap *ipfsCliWrapper) prepareToFiles(ctx context.Context, settings *addSettings) error {
+	if settings.toFiles == "" {
+		return nil
+	}
+	if !strings.HasPrefix(settings.toFiles, "/") {
+		return fmt.Errorf("mfs path must be absolute: %v", settings.toFiles)
+	}
+	dir := path.Dir(settings.toFiles)
+	if strings.HasSuffix(settings.toFiles, "/") {
+		dir = path.Clean(settings.toFiles)
+	}
+	return wrap.runFilesCommand(ctx, "mkdir", "--parents", dir)
+}
 
-	var filename string
-	var cid string
-	var foundAddedText bool = false
+func (wrap *ipfsCliWrapper) AddFileEntries(ctx context.Context, filePath string, opts ...AddOption) ([]AddedEntry, error) {
+	return wrap.addEntries(ctx, []string{filePath}, opts)
+}
 
-	for _, part := range parts {
-		// wrap.logger.Debug(part) // Uncomment for debugging purposes only to see what's going on.
-		if cid != "" {
-			filename = part
-			break
-		}
-		if foundAddedText {
-			cid = part
-			continue
+func (wrap *ipfsCliWrapper) AddFilesEntries(ctx context.Context, filePaths []string, opts ...AddOption) ([]AddedEntry, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("cannot have missing: %v", "filePaths")
+	}
+	names := make(map[string]bool, len(filePaths))
+	for _, filePath := range filePaths {
+		name := filepath.Base(filepath.Clean(filePath))
+		if names[name] {
+			return nil, fmt.Errorf("cannot add several paths named `%s`", name)
 		}
-		if strings.Contains(part, "added") {
-			foundAddedText = true
-			continue
+		names[name] = true
+	}
+	return wrap.addEntries(ctx, filePaths, opts)
+}
+
+// addEntries adds the files or directories and returns every entry, see
+// `AddFilesEntries`.
+func (wrap *ipfsCliWrapper) addEntries(ctx context.Context, filePaths []string, opts []AddOption) ([]AddedEntry, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	settings := newAddSettings(opts)
+	settingsArgs, err := wrap.addSettingsArgs(settings)
+	if err != nil {
+		return nil, err
+	}
+	if settings.toFiles != "" && len(filePaths) > 1 {
+		// Several paths which are not wrapped are linked into the directory
+		// at the path under their own names.
+		switch intoDir := strings.HasSuffix(settings.toFiles, "/"); {
+		case !settings.wrapDirectory && !intoDir:
+			return nil, fmt.Errorf("mfs path must end with a slash to add several paths: %v", settings.toFiles)
+		case settings.wrapDirectory && intoDir:
+			return nil, fmt.Errorf("mfs path must not end with a slash to wrap several paths: %v", settings.toFiles)
 		}
 	}
+	if err := wrap.checkAddDiskSpace(filePaths...); err != nil {
+		return nil, err
+	}
+	if err := wrap.waitForGC(ctx); err != nil {
+		return nil, err
+	}
+	if err := wrap.prepareToFiles(ctx, settings); err != nil {
+		return nil, err
+	}
 
-	wrap.logger.Debug("file added to ipfs successfully",
-		slog.String("filepath", filepath),
-		slog.String("filename", filename),
-		slog.String("cid", cid))
+	// Progress bars are turned off and JSON requested so the output stays
+	// machine readable; kubo releases which ignore the encoding for `add`
+	// print `added <cid> <name>` lines, which the parser understands too.
+	args := wrap.cidBaseArgs("add", "--recursive", "--cid-version=1", "--progress=false", "--enc=json")
+	if settings.toFiles != "" {
+		args = append(args, "--to-files="+settings.toFiles)
+	}
+	if settings.wrapDirectory {
+		args = append(args, "--wrap-with-directory")
+	}
+	args = append(args, settingsArgs...)
+	cmd := wrap.command(ctx, append(append(args, "--"), filePaths...)...)
 
-	return cid, nil
+	// Keep stderr separate so warnings never get mistaken for entries.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error adding file to ipfs",
+			slog.Any("filepaths", filePaths),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("add file to ipfs", err, stderr.Bytes())
+	}
+	parsed, err := outputkit.ParseAdded(output)
+	if err != nil {
+		wrap.logger.Error("error parsing ipfs add output",
+			slog.Any("filepaths", filePaths),
+			slog.Any("error", err))
+		return nil, fmt.Errorf("failed to parse ipfs add output: %v", err)
+	}
+
+	entries := make([]AddedEntry, 0, len(parsed))
+	for _, entry := range parsed {
+		entries = append(entries, AddedEntry{Name: entry.Name, CID: entry.Hash, Size: entry.Size})
+	}
+	return entries, nil
 }
 
-func (wrap *ipfsCliWrapper) AddFileContent(ctx context.Context, fileContent []byte) (string, error) {
+func (wrap *ipfsCliWrapper) AddFileContent(ctx context.Context, filename string, fileContent []byte, opts ...AddOption) (string, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return "", err
+	}
+
 	if fileContent == nil {
 		return "", fmt.Errorf("cannot have missing: %v", "fileContent")
 	}
+	if filename == "" {
+		filename = fmt.Sprintf("ipfscliwrapper_tempfile_%v", randomkit.String(5))
+	}
+	if filename != filepath.Base(filename) || filename == "." || filename == ".." {
+		return "", fmt.Errorf("filename must not contain a directory: %v", filename)
+	}
 
-	// Save in the current directory this application is running; however,
-	// generate a random filename to be used to store the content locally and
-	// then we will delete.
-	filepath := fmt.Sprintf("./ipfscliwrapper_tempfile_%v", randomkit.String(5))
+	// Stage the content inside a directory of its own so the file can never
+	// clobber an existing file, regardless of the name it was given.
+	dir, err := wrap.makeScratchDir()
+	if err != nil {
+		wrap.logger.Error("failed creating temporary directory",
+			slog.Any("error", err))
+		return "", err
+	}
+
+	// Delete our temporary directory after we finished submitting
+	defer func() {
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			wrap.logger.Error("failed removing from local filesystem",
+				slog.Any("error", rmErr))
+		}
+	}()
 
-	// open output file
-	fo, err := os.Create(filepath)
+	path := filepath.Join(dir, filename)
+	fo, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
 	if err != nil {
 		wrap.logger.Error("failed creating file in local filesystem",
 			slog.Any("error", err))
@@ -509,6 +1431,7 @@ func (wrap *ipfsCliWrapper) AddFileContent(ctx context.Context, fileContent []by
 	}
 
 	if _, err := fo.Write(fileContent); err != nil {
+		fo.Close()
 		wrap.logger.Error("failed writing file to local filesystem",
 			slog.Any("error", err))
 		return "", err
@@ -521,18 +1444,10 @@ func (wrap *ipfsCliWrapper) AddFileContent(ctx context.Context, fileContent []by
 		return "", err
 	}
 
-	// Delete our tempfile after we finished submitting
-	defer func() {
-		if rmErr := os.Remove(filepath); rmErr != nil {
-			wrap.logger.Error("failed removing from local filesystem",
-				slog.Any("error", err))
-			return
-		}
-	}()
-
-	cid, err := wrap.AddFile(ctx, filepath)
+	cid, err := wrap.AddFile(ctx, path, opts...)
 	if err != nil {
 		wrap.logger.Error("failed adding file to ipfs",
+			slog.String("filename", filename),
 			slog.Any("error", err))
 		return "", err
 	}
@@ -541,17 +1456,40 @@ func (wrap *ipfsCliWrapper) AddFileContent(ctx context.Context, fileContent []by
 }
 
 func (wrap *ipfsCliWrapper) GetFile(ctx context.Context, cid string) error {
+	// Retrieve into the temp directory first and only move the result into
+	// the working directory once complete, so an interrupted retrieval never
+	// leaves a partial file behind. Like `ipfs get`, name the result after
+	// the last segment of the path.
+	name := filepath.Base(filepath.Clean(strings.TrimRight(cid, "/")))
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		wrap.logger.Error("failed creating temporary directory",
+			slog.Any("error", err))
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+	stagedPath := filepath.Join(stageDir, name)
+
 	// Prepare the command to get the file using the IPFS binary
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "get", cid)
+	cmd := wrap.command(ctx, "get", "--output="+stagedPath, "--", cid)
 
 	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	output, err := wrap.combinedOutput(cmd)
 	if err != nil {
 		wrap.logger.Error("error getting file from ipfs",
 			slog.String("cid", cid),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return fmt.Errorf("failed to get file from ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(output))
+		return wrap.commandError("get file from ipfs", err, output)
+	}
+
+	destination := filepath.Join(wrap.commandDir(ctx), name)
+	if err := moveIntoPlace(stagedPath, destination); err != nil {
+		wrap.logger.Error("failed moving retrieved file into place",
+			slog.String("cid", cid),
+			slog.String("destination", destination),
+			slog.Any("error", err))
+		return fmt.Errorf("failed to get file from ipfs: %v", err)
 	}
 
 	return nil
@@ -559,22 +1497,26 @@ func (wrap *ipfsCliWrapper) GetFile(ctx context.Context, cid string) error {
 
 func (wrap *ipfsCliWrapper) Cat(ctx context.Context, cid string) ([]byte, error) {
 	// Prepare the command to retrieve the file contents using the IPFS binary
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "cat", cid)
+	cmd := wrap.command(ctx, "cat", "--", cid)
 
-	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	// Capture the output of the command, keeping warnings out of the
+	// content.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
 	if err != nil {
 		wrap.logger.Error("error catting file from ipfs",
 			slog.String("cid", cid),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return []byte{}, fmt.Errorf("failed to cat file from ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(stderr.Bytes()))
+		return []byte{}, wrap.commandError("cat file from ipfs", err, stderr.Bytes())
 	}
 
-	// Log successful retrieval of the file contents
+	// Log successful retrieval of the file contents. Only the length is
+	// logged since the content may be large or sensitive.
 	wrap.logger.Debug("file content retrieved from ipfs successfully",
 		slog.String("cid", cid),
-		slog.String("output", string(output)))
+		slog.Int("content_length", len(output)))
 
 	// Return the file content as a string
 	return output, nil
@@ -600,120 +1542,246 @@ func (wrap *ipfsCliWrapper) ListPinsByType(ctx context.Context, typeID string) (
 	// `--stream=true` <-- if you get such an error because of large list, you can make use of the streaming option
 	// https://stackoverflow.com/questions/60926526/how-can-one-list-all-of-the-currently-pinned-files-for-an-ipfs-instance
 
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "pin", "ls", "--type="+typeID, "--stream=true")
+	//
+	// (3)
+	// `--enc=json` <-- one JSON object per pin, e.g. {"Cid":"<cid>","Type":"recursive"}.
+
+	cmd := wrap.command(ctx, wrap.cidBaseArgs("pin", "ls", "--type="+typeID, "--stream=true", "--enc=json")...)
 
 	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
 	if err != nil {
-		wrap.logger.Error("error pinning file content on ipfs",
+		wrap.logger.Error("error listing pins on ipfs",
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return nil, fmt.Errorf("failed to pin file content on ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("list pins on ipfs", err, stderr.Bytes())
 	}
 
-	parts := strings.Fields(string(output))
+	cids, err := outputkit.ParsePins(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pins: %v", err)
+	}
+	return cids, nil
+}
 
-	// // Uncomment for debugging purposes only to see what's going on.
-	// wrap.logger.Debug("command executed",
-	// 	slog.Int("parts len", len(parts)),
-	// 	slog.Any("parts", parts))
+// PinInfo is a pin listed by `ListPinsStream`.
+type PinInfo struct {
+	// CID is the content identifier of the pinned object.
+	CID string
 
-	cids := make([]string, 0)
-	ignorePartArr := []string{"recursive", "indirect", "direct"}
-	var ignoreFound bool = false
+	// Type is the type of the pin (`RecursivePinType`, `DirectPinType` or
+	// `IndirectPinType`).
+	Type string
+}
 
-	for _, part := range parts {
-		// wrap.logger.Debug(part) // Uncomment for debugging purposes only to see what's going on.
+func (wrap *ipfsCliWrapper) ListPinsStream(ctx context.Context, typeID string) (<-chan PinInfo, <-chan error) {
+	pins := make(chan PinInfo)
+	errs := make(chan error, 1)
+
+	go func() {
+		// Close the pins first so ranging over them ends before the error
+		// gets read.
+		defer close(errs)
+		defer close(pins)
+
+		// Same command as `ListPinsByType`, but the pins are decoded while
+		// kubo is still printing them instead of buffering the whole output.
+		cmd := wrap.command(ctx, wrap.cidBaseArgs("pin", "ls", "--type="+typeID, "--stream=true", "--enc=json")...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			errs <- fmt.Errorf("failed to create stdout pipe: %v", err)
+			return
+		}
+		if err := cmd.Start(); err != nil {
+			errs <- fmt.Errorf("failed to list pins on ipfs: %v", err)
+			return
+		}
 
-		for _, ignorePart := range ignorePartArr {
-			if part == ignorePart {
-				ignoreFound = true
-				continue // Skip to the next root.
+		var streamErr error
+		decoder := json.NewDecoder(stdout)
+	decode:
+		for {
+			var pin struct {
+				Cid  string `json:"Cid"`
+				Type string `json:"Type"`
+			}
+			if err := decoder.Decode(&pin); err == io.EOF {
+				break
+			} else if err != nil {
+				streamErr = fmt.Errorf("failed to decode pins: %v", err)
+				// Stop kubo, which may be blocked writing the remaining
+				// pins nobody reads anymore.
+				cmd.Process.Kill()
+				break
+			}
+			select {
+			case pins <- PinInfo{CID: pin.Cid, Type: pin.Type}:
+			case <-ctx.Done():
+				// Cancelling the context kills kubo.
+				streamErr = ctx.Err()
+				break decode
 			}
 		}
 
-		// Record our content ID if it's not a reserved word.
-		if !ignoreFound {
-			cids = append(cids, part)
+		if err := cmd.Wait(); err != nil && streamErr == nil {
+			wrap.logger.Error("error listing pins on ipfs",
+				slog.Any("error", err),
+				wrap.outputAttr(stderr.Bytes()))
+			streamErr = wrap.commandError("list pins on ipfs", err, stderr.Bytes())
 		}
+		if streamErr != nil {
+			errs <- streamErr
+		}
+	}()
+	return pins, errs
+}
 
-		ignoreFound = false // Reset the checker since it's the end of the loop.
+func (wrap *ipfsCliWrapper) ListPinsPage(ctx context.Context, typeID string, offset int, limit int) ([]PinInfo, error) {
+	if offset < 0 || limit < 1 {
+		return nil, fmt.Errorf("invalid page with offset %d and limit %d", offset, limit)
 	}
 
-	return cids, nil
+	// Stop kubo once the page is complete instead of listing the rest.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	page := make([]PinInfo, 0, limit)
+	pins, errs := wrap.ListPinsStream(ctx, typeID)
+	skipped := 0
+	for pin := range pins {
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		page = append(page, pin)
+		if len(page) == limit {
+			cancel()
+			break
+		}
+	}
+	// Drain the channel so the listing goroutine can finish.
+	for range pins {
+	}
+	if err := <-errs; err != nil && len(page) < limit {
+		return nil, err
+	}
+	return page, nil
 }
 
 func (wrap *ipfsCliWrapper) Pin(ctx context.Context, cid string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := wrap.waitForGC(ctx); err != nil {
+		return err
+	}
+
 	// Prepare the command to pin the file contents using the IPFS binary
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "pin", "add", cid)
+	cmd := wrap.command(ctx, "pin", "add", "--", cid)
 
 	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	output, err := wrap.combinedOutput(cmd)
 	if err != nil {
 		wrap.logger.Error("error pinning file content on ipfs",
 			slog.String("cid", cid),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return fmt.Errorf("failed to pin file content on ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(output))
+		return wrap.commandError("pin file content on ipfs", err, output)
 	}
 	return nil
 }
 
 func (wrap *ipfsCliWrapper) Unpin(ctx context.Context, cid string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	if err := wrap.waitForGC(ctx); err != nil {
+		return err
+	}
+
 	// Prepare the command to remove the pin using the IPFS binary
-	cmd := exec.CommandContext(ctx, IPFSBinaryFilePath, "pin", "rm", cid)
+	cmd := wrap.command(ctx, "pin", "rm", "--", cid)
 
 	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	output, err := wrap.combinedOutput(cmd)
 	if err != nil {
 		wrap.logger.Error("error removing pinning from ipfs",
 			slog.String("cid", cid),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return fmt.Errorf("failed to remove pin from ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(output))
+		return wrap.commandError("remove pin from ipfs", err, output)
 	}
 
 	return nil
 }
 
 func (wrap *ipfsCliWrapper) GarbageCollection(ctx context.Context) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	// Let the calls writing to the repo wait until the collection finished.
+	endGC, err := wrap.beginGC()
+	if err != nil {
+		return err
+	}
+	defer endGC()
+
 	// Prepare the command run garbage collection for the `ipfs` binary.
-	cmd := exec.CommandContext(context.Background(), IPFSBinaryFilePath, "repo", "gc")
+	cmd := wrap.command(ctx, "repo", "gc")
 
 	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	output, err := wrap.combinedOutput(cmd)
 	if err != nil {
 		wrap.logger.Error("error garbage collecting in ipfs",
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return fmt.Errorf("failed to run garbage collection pin from ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(output))
+		return wrap.commandError("run garbage collection pin from ipfs", err, output)
 	}
 
 	return nil
 }
 
-func (wrap *ipfsCliWrapper) Id(ctx context.Context) (*IpfsNodeInfo, error) {
+func (wrap *ipfsCliWrapper) Id(ctx context.Context, peerID ...string) (*IpfsNodeInfo, error) {
 	// Special thanks:
 	// https://github.com/ipfs-shipyard/ipfs-primer/blob/12d7298f436fa83e8395ade6969d2a4df298b334/going-online/lessons/connect-your-node.md
 
-	// Prepare the command run garbage collection for the `ipfs` binary.
-	cmd := exec.CommandContext(context.Background(), IPFSBinaryFilePath, "id")
+	if len(peerID) > 1 {
+		return nil, fmt.Errorf("at most one peer id can be given, got %d", len(peerID))
+	}
 
-	// Capture the output of the command
-	output, err := cmd.CombinedOutput()
+	// Prepare the command looking up the node for the `ipfs` binary.
+	args := append([]string{"id", "--enc=json"}, peerID...)
+	cmd := wrap.command(ctx, args...)
+
+	// Capture the output of the command, keeping warnings out of the JSON.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
 	if err != nil {
 		wrap.logger.Error("error getting ipfs id",
+			slog.Any("peer_id", peerID),
 			slog.Any("error", err),
-			slog.String("output", string(output)))
-		return nil, fmt.Errorf("failed to run `id` in ipfs: %v, output: %s", err, string(output))
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("run `id` in ipfs", err, stderr.Bytes())
 	}
 
 	// Create an instance of IPFSInfo.
 	var info IpfsNodeInfo
 
-	// Parse the JSON string into the struct.
+	// Parse the JSON string into the struct, keeping the raw output with
+	// the error since it is unexpected.
 	if err := json.Unmarshal([]byte(output), &info); err != nil {
-		log.Fatalf("Error unmarshalling JSON: %v", err)
+		wrap.logger.Error("error decoding ipfs id",
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return nil, newCommandError("decode `id` output", err, output)
 	}
 
 	return &info, nil
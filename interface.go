@@ -3,19 +3,41 @@
 // Golang applications more easily.
 package ipfscliwrapper
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // IpfsCliWrapper interface represents a wrapper around the `ipfs` executable binary
 // in the operating system, providing methods to control the IPFS daemon and perform
 // various operations such as adding files, retrieving content, pinning, and garbage collection.
+//
+// A wrapper created with the `WithReadOnly` option returns `ErrReadOnly` from
+// every method which would modify the node.
 type IpfsCliWrapper interface {
 	// StartDaemonInBackground starts the IPFS daemon process in the background,
 	// making it ready to accept API requests. It should ensure that the daemon
 	// runs independently of the calling application.
 	//
-	// Returns an error if the daemon fails to start.
+	// Returns an error if the daemon fails to start. If the daemon exits during
+	// the warmup, the error is a `*DaemonStartupError` carrying the output of
+	// the daemon and wrapping `ErrPortInUse`, `ErrConfigInvalid`,
+	// `ErrRepoLocked` or `ErrDaemonExited`, so use `errors.Is` to check for
+	// the cause.
 	StartDaemonInBackground() error
 
+	// StartDaemon is `StartDaemonInBackground`, bounded by the context. If
+	// the context is done before the daemon API accepts connections, the
+	// daemon is killed and the error wraps the error of the context.
+	//
+	// Parameters:
+	//   ctx - The context bounding how long the startup may take.
+	//
+	// Returns an error if the daemon fails to start, like
+	// `StartDaemonInBackground`.
+	StartDaemon(ctx context.Context) error
+
 	// ShutdownDaemon gracefully shuts down the running IPFS daemon.
 	// It sends a termination signal to the daemon process, allowing it
 	// to perform cleanup tasks before shutting down.
@@ -23,6 +45,16 @@ type IpfsCliWrapper interface {
 	// Returns an error if the daemon could not be shut down.
 	ShutdownDaemon() error
 
+	// Shutdown is `ShutdownDaemon`, bounded by the context. The daemon is
+	// killed as soon as the context is done, even before the grace period
+	// set with `WithShutdownGracePeriod` elapsed.
+	//
+	// Parameters:
+	//   ctx - The context bounding how long the shutdown may take.
+	//
+	// Returns an error if the daemon could not be shut down.
+	Shutdown(ctx context.Context) error
+
 	// ForceShutdownDaemon immediately terminates the IPFS daemon process,
 	// without allowing it to perform any cleanup. This is a forceful operation
 	// that should be used when the daemon does not respond to a graceful shutdown.
@@ -30,34 +62,174 @@ type IpfsCliWrapper interface {
 	// Returns an error if the daemon could not be forcefully terminated.
 	ForceShutdownDaemon() error
 
+	// Wait blocks until the daemon was shut down by `ShutdownDaemon` or
+	// `ForceShutdownDaemon`, for example once one of the signals set with
+	// `WithShutdownOnSignals` was received. It returns right away if the
+	// daemon was already shut down.
+	Wait()
+
+	// LastCrash returns the details of the last time a daemon started by the
+	// wrapper exited without being shut down through the wrapper, including
+	// its exit status and the tail of its output. The crash is recorded to a
+	// file in the repo directory, so it is still available after the app
+	// restarted.
+	//
+	// Returns:
+	//   The last crash, or nil if the daemon never crashed.
+	//   An error if the recorded crash could not be read.
+	LastCrash() (*DaemonCrash, error)
+
+	// LastShutdown returns how the daemon was stopped the last time
+	// `ShutdownDaemon` or `ForceShutdownDaemon` stopped it: gracefully within
+	// the grace period set with `WithShutdownGracePeriod`, or killed.
+	//
+	// Returns:
+	//   The last shutdown, or nil if the wrapper did not stop the daemon yet.
+	LastShutdown() *ShutdownStatus
+
+	// Status returns the state of the daemon started by the wrapper, including
+	// a daemon which an earlier run of the app left running in continous
+	// operation mode and which the wrapper adopted on construction.
+	//
+	// Returns:
+	//   The state of the daemon; `Running` is false if there is none.
+	Status() *DaemonStatus
+
+	// LogTail returns the last lines of the output of the daemon. In continous
+	// operation mode the output is read from the files set with
+	// `WithDaemonOutputFiles`, or the files an adopted daemon was started
+	// with.
+	//
+	// Parameters:
+	//   lines - The number of lines to return per stream, or 0 for all the
+	//           output which is kept.
+	//
+	// Returns:
+	//   The tail of the stdout and stderr of the daemon.
+	//   `ErrDaemonOutputDiscarded` if the output is not written to files.
+	LogTail(lines int) (*DaemonLogs, error)
+
+	// InstalledKuboVersions lists the kubo releases installed in the binary
+	// directory, e.g. after switching releases with `WithKuboVersion`.
+	//
+	// Returns:
+	//   The installed releases, oldest first, e.g. ["v0.28.0", "v0.29.0"].
+	//   An error if the installed releases could not be read.
+	InstalledKuboVersions() ([]string, error)
+
+	// PruneKuboVersions removes the binaries of the kubo releases which are
+	// not in use anymore. The current release and the release a running
+	// daemon was started from are always kept.
+	//
+	// Parameters:
+	//   keep - The number of most recently used previous releases to keep,
+	//          so the app can be rolled back without downloading them again.
+	//
+	// Returns:
+	//   The removed releases.
+	//   An error if a release could not be removed.
+	PruneKuboVersions(keep int) ([]string, error)
+
+	// ProvisionReport returns how long provisioning the node took: the
+	// download and extraction of the kubo binary and the creation of the
+	// repo by `NewWrapper`, and the startup of the first daemon, so the
+	// cold-start performance of deployments can be monitored.
+	//
+	// Returns:
+	//   The timings; the steps which were skipped have a zero duration.
+	ProvisionReport() ProvisionReport
+
+	// Preflight checks the node can be started, before starting it, so
+	// deploy tooling can fail early with clear reasons: the `ipfs` binary
+	// exists and runs, the repo can be opened and is not locked by another
+	// process, the ports of the API, gateway and swarm are free and the disk
+	// of the repo has space left. A daemon the wrapper already runs passes
+	// the lock and port checks, as starting keeps using it.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The outcome of every check; `PreflightReport.Err` returns an error
+	//   describing the failed ones.
+	Preflight(ctx context.Context) *PreflightReport
+
+	// RecordedCommands returns the `ipfs` commands which were recorded
+	// instead of being run in the dry-run mode set with `WithDryRun`.
+	//
+	// Returns:
+	//   The recorded commands, oldest first, or nil if there are none.
+	RecordedCommands() []RecordedCommand
+
 	// AddFile adds a file to the IPFS network using its file path. The function
 	// executes the `ipfs add` command to store the file in the IPFS node.
 	//
 	// Parameters:
 	//   ctx - Context for controlling cancellation and deadlines.
 	//   filepath - The path to the file to be added to IPFS.
+	//   opts - Options of the call, e.g. `WithToFiles` to link the added
+	//          content into MFS or `WithWrapDirectory` to keep its name.
 	//
 	// Returns:
 	//   The CID (Content Identifier) of the added file on success.
 	//   An error if the file could not be added.
-	AddFile(ctx context.Context, filepath string) (string, error)
+	AddFile(ctx context.Context, filepath string, opts ...AddOption) (string, error)
+
+	// AddFileEntries adds a file or directory to the IPFS network like
+	// `AddFile` but returns every entry `ipfs add` emitted instead of only
+	// the root. The root is always the last entry.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   filepath - The path to the file or directory to be added to IPFS.
+	//   opts - Options of the call, see `AddFile`.
+	//
+	// Returns:
+	//   The added entries, in the order kubo emitted them, on success.
+	//   An error if the content could not be added.
+	AddFileEntries(ctx context.Context, filepath string, opts ...AddOption) ([]AddedEntry, error)
+
+	// AddFilesEntries adds several files or directories to the IPFS network
+	// in one `ipfs add` and returns every entry, like `AddFileEntries`. Each
+	// added path is a root which follows its own entries, in the order of
+	// the paths. With `WithWrapDirectory` the paths are wrapped in one
+	// directory, which is the last entry. With `WithToFiles` the path must
+	// end with a slash, so the paths are linked into that directory.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   filePaths - The paths to the files or directories to be added to
+	//               IPFS, which must have different names.
+	//   opts - Options of the call, see `AddFile`.
+	//
+	// Returns:
+	//   The added entries on success.
+	//   An error if the content could not be added.
+	AddFilesEntries(ctx context.Context, filePaths []string, opts ...AddOption) ([]AddedEntry, error)
 
 	// AddFileContent adds a file to the IPFS network from a byte slice containing
-	// the file content, rather than a file path. The function handles the creation
-	// and storage of the file directly in the IPFS node.
+	// the file content, rather than a file path. The content is staged in a
+	// fresh directory inside the directory set with `WithTempDir`, so existing
+	// files are never overwritten, and removed once added.
 	//
 	// Parameters:
 	//   ctx - Context for controlling cancellation and deadlines.
+	//   filename - The optional logical name of the file, which must not
+	//              contain a directory. A random name is used if empty.
 	//   fileContent - The byte slice containing the content of the file.
+	//   opts - Options of the call, see `AddFile`.
 	//
 	// Returns:
 	//   The CID (Content Identifier) of the added file on success.
 	//   An error if the file could not be added.
-	AddFileContent(ctx context.Context, fileContent []byte) (string, error)
+	AddFileContent(ctx context.Context, filename string, fileContent []byte, opts ...AddOption) (string, error)
 
 	// GetFile retrieves a file from the IPFS network using its CID (Content Identifier).
 	// The function executes the `ipfs get` command, which downloads the file from the
-	// IPFS network to the local machine.
+	// IPFS network to the local machine. The file is retrieved into the directory set
+	// with `WithTempDir` and, once complete, moved into the directory set with the
+	// `WithWorkDir` option or the `WithCommandWorkDir` context, replacing any file
+	// of the same name.
 	//
 	// Parameters:
 	//   ctx - Context for controlling cancellation and deadlines.
@@ -66,6 +238,22 @@ type IpfsCliWrapper interface {
 	// Returns an error if the file could not be retrieved.
 	GetFile(ctx context.Context, cid string) error
 
+	// GetTar streams the file or directory of the CID as a tar archive, like
+	// `ipfs get --archive`, without writing anything to disk, so callers can
+	// unpack it themselves or forward it, for example over HTTP. The archive
+	// is read from the HTTP API of the running daemon.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines of the whole stream.
+	//   cid - The CID of the file or directory to retrieve.
+	//
+	// Returns:
+	//   A reader of the tar archive, which must be closed. Reading it returns
+	//   an error if the retrieval fails midway.
+	//   ErrDaemonNotReachable if the daemon API cannot be found.
+	//   An error if the retrieval could not be started.
+	GetTar(ctx context.Context, cid string) (io.ReadCloser, error)
+
 	// Cat retrieves the content of a file from the IPFS network using its CID and returns it as a byte slice.
 	// The function executes the `ipfs cat` command, which outputs the file content directly.
 	//
@@ -78,6 +266,32 @@ type IpfsCliWrapper interface {
 	//   An error if the file content could not be retrieved.
 	Cat(ctx context.Context, cid string) ([]byte, error)
 
+	// CatPath retrieves the content of a single file inside a directory, like
+	// `Cat`, but only retrieves the blocks along the path instead of the
+	// whole directory.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   ipfsPath - The path of the file, e.g. "<cid>/sub/dir/file.txt",
+	//              "/ipfs/<cid>/sub/dir/file.txt", "ipfs://<cid>/file.txt" or
+	//              "/ipns/<name>/file.txt".
+	//
+	// Returns:
+	//   The content of the file on success.
+	//   ErrInvalidCID if the path is malformed, ErrNotFound if the path does
+	//   not exist, or another error if the content could not be retrieved.
+	CatPath(ctx context.Context, ipfsPath string) ([]byte, error)
+
+	// GetPath retrieves a single file or directory inside a directory, like
+	// `GetFile`, naming the result after the last segment of the path.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   ipfsPath - The path of the file or directory, see `CatPath`.
+	//
+	// Returns an error if the path is malformed or could not be retrieved.
+	GetPath(ctx context.Context, ipfsPath string) error
+
 	// ListPins retrieves a list of all pinned objects' CIDs from the IPFS node.
 	// The function executes the `ipfs pin ls` command to fetch the list of pins.
 	//
@@ -101,6 +315,60 @@ type IpfsCliWrapper interface {
 	//   An error if the pins could not be listed.
 	ListPinsByType(ctx context.Context, typeID string) ([]string, error)
 
+	// ListPinsStream lists the pinned objects like `ListPinsByType`, but
+	// sends them over a channel while `ipfs pin ls --stream` prints them,
+	// so huge pinsets never have to fit in memory.
+	//
+	// Example:
+	//
+	//	pins, errs := wrapper.ListPinsStream(ctx, RecursivePinType)
+	//	for pin := range pins {
+	//	    fmt.Println(pin.CID, pin.Type)
+	//	}
+	//	if err := <-errs; err != nil {
+	//	    log.Fatal(err)
+	//	}
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines. Cancel it
+	//         to stop listing early.
+	//   typeID - The type of pins to list (e.g., "all", "recursive", "direct", "indirect").
+	//
+	// Returns:
+	//   A channel of the pins, closed once all pins were sent or listing failed.
+	//   A channel receiving at most one error, closed after the pin channel.
+	ListPinsStream(ctx context.Context, typeID string) (<-chan PinInfo, <-chan error)
+
+	// ListPinsPage returns one page of the pinned objects, for example for
+	// admin UIs, without keeping the whole pinset in memory. The pins are in
+	// the order kubo lists them, so pages may shift while pins get added or
+	// removed.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   typeID - The type of pins to list (e.g., "all", "recursive", "direct", "indirect").
+	//   offset - The number of pins to skip.
+	//   limit - The maximum number of pins to return, at least 1.
+	//
+	// Returns:
+	//   The pins of the page, fewer than `limit` on the last page.
+	//   An error if the pins could not be listed.
+	ListPinsPage(ctx context.Context, typeID string, offset int, limit int) ([]PinInfo, error)
+
+	// Summary returns the storage health of the node in one call, for
+	// dashboards: the number of pins by type, the size of the pinned content,
+	// the repo size and whether it reached the garbage collection watermark.
+	// Measuring the pinned content reads every pinned block, so it takes a
+	// while for big repos.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The summary of the node.
+	//   An error if any of the statistics could not be read.
+	Summary(ctx context.Context) (*NodeSummary, error)
+
 	// Pin pins an object in the IPFS node using its CID, ensuring the object
 	// remains available locally on the IPFS node and is not removed during
 	// garbage collection.
@@ -122,23 +390,685 @@ type IpfsCliWrapper interface {
 	// Returns an error if the object could not be unpinned.
 	Unpin(ctx context.Context, cid string) error
 
+	// DenylistStatus lists the denylists kubo loads, from the repo and from
+	// the denylist directories of the user and the system, and, while the
+	// daemon is running, tests one rule of each with `IsBlocked` so
+	// moderation tooling can confirm they are enforced.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The status of every denylist.
+	//   An error if a denylist could not be read or tested.
+	DenylistStatus(ctx context.Context) (*DenylistStatus, error)
+
+	// IsBlocked checks if the running daemon refuses the content because of
+	// its denylists, without retrieving the content.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   ipfsPath - The CID, "/ipfs/" path or "ipfs://" URL to check.
+	//
+	// Returns:
+	//   True if the content is blocked, false otherwise.
+	//   An error if the check failed, or `ErrDaemonNotReachable` if the
+	//   daemon, which enforces the denylists, is not running.
+	IsBlocked(ctx context.Context, ipfsPath string) (bool, error)
+
+	// HasLocal checks if the object is stored by the IPFS node, without
+	// retrieving it from the network, so apps can decide between serving the
+	// content locally and fetching it. Only the root block is checked, the
+	// children of a partially retrieved object may still be missing.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to look up.
+	//
+	// Returns:
+	//   True if the object is stored locally, false otherwise.
+	//   An error if the lookup failed.
+	HasLocal(ctx context.Context, cid string) (bool, error)
+
+	// DagLinks returns the named links of a node of a DAG, e.g. the entries
+	// of a directory or the chunks of a file, without retrieving its
+	// children, so apps can implement their own traversal or retrieve only
+	// parts of large DAGs.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the node.
+	//
+	// Returns:
+	//   The links of the node in the order they are stored, empty for leaves
+	//   and for nodes of codecs other than dag-pb.
+	//   An error if the node could not be retrieved.
+	DagLinks(ctx context.Context, cid string) ([]DagLink, error)
+
+	// IsPinned checks if the object is pinned in the IPFS node.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to look up.
+	//
+	// Returns:
+	//   True if the object is pinned, false otherwise.
+	//   The type of the pin (`RecursivePinType`, `DirectPinType` or
+	//   `IndirectPinType`), or empty if the object is not pinned.
+	//   An error if the lookup failed.
+	IsPinned(ctx context.Context, cid string) (bool, string, error)
+
+	// PinIfAbsent pins the object unless it is already pinned, avoiding the
+	// cost of pinning it again in hot paths. Objects which are only pinned
+	// indirectly through an ancestor are pinned on their own.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to pin in IPFS.
+	//
+	// Returns:
+	//   True if the object was pinned by this call, false if it was already
+	//   pinned.
+	//   An error if the object could not be pinned.
+	PinIfAbsent(ctx context.Context, cid string) (bool, error)
+
+	// WaitForProviders looks up the providers of the object in the network
+	// until at least `minProviders` were found or the timeout elapsed, so
+	// publishers can confirm their content is discoverable before handing out
+	// links. The node itself counts as a provider once it announced the
+	// object.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to look up.
+	//   minProviders - The number of providers to wait for.
+	//   timeout - How long to keep looking for providers.
+	//
+	// Returns:
+	//   The number of providers found.
+	//   An error wrapping `ErrNotEnoughProviders` if fewer providers were
+	//   found before the timeout.
+	WaitForProviders(ctx context.Context, cid string, minProviders int, timeout time.Duration) (int, error)
+
+	// Provide announces to the network that the IPFS node stores the object,
+	// so freshly added content becomes discoverable right away instead of on
+	// the next reprovide cycle. The object must be stored locally.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to announce.
+	//   recursive - Whether to announce every block of the object instead of
+	//   only its root.
+	//
+	// Returns an error if the object could not be announced.
+	Provide(ctx context.Context, cid string, recursive bool) error
+
+	// Reprovide announces all the content the node provides to the routing
+	// system right away instead of waiting for the next reprovide interval,
+	// for example after a network partition. It runs
+	// `ipfs routing reprovide`, or `ipfs bitswap reprovide` on kubo releases
+	// without it, and requires a running daemon.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   An error if the content could not be announced.
+	Reprovide(ctx context.Context) error
+
+	// BitswapLedger returns the number of bytes exchanged with the peer over
+	// bitswap since the daemon started, for fairness accounting and debugging
+	// asymmetric exchanges between cooperating nodes.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   peerID - The ID of the peer.
+	//
+	// Returns:
+	//   The ledger with the peer, with zero counts if nothing was exchanged.
+	//   An error if the ledger could not be retrieved.
+	BitswapLedger(ctx context.Context, peerID string) (*BitswapLedgerInfo, error)
+
+	// SwarmStats returns the memory, file descriptors, connections and
+	// streams used by the libp2p swarm of the node, together with their
+	// limits, as accounted for by the libp2p resource manager.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The resource usage of the node, in total and by scope.
+	//   An error if the usage could not be retrieved, e.g. because the daemon
+	//   is not running or the resource manager is disabled.
+	SwarmStats(ctx context.Context) (*SwarmStatsInfo, error)
+
+	// DiagCmds lists the API commands the daemon is running and recently
+	// ran, with their durations, to find stuck operations when the app
+	// appears hung. The list includes the `ipfs diag cmds` call itself.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The commands, oldest first.
+	//   An error if the commands could not be listed, e.g. because the daemon
+	//   is not running.
+	DiagCmds(ctx context.Context) ([]DiagCommand, error)
+
+	// EnsureRemoteReplica pushes the content to the backend configured with
+	// the `WithPinBackend` option if no other peer provides it, so it stays
+	// available when the local node goes away. The addresses of the local
+	// node are sent along so the backend can retrieve the content directly.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the content to replicate.
+	//
+	// Returns:
+	//   True if the content was pushed to the backend, false if another peer
+	//   already provides it.
+	//   `ErrNoPinBackend` if no backend was configured, or another error if
+	//   the backend could not be reached.
+	EnsureRemoteReplica(ctx context.Context, cid string) (bool, error)
+
+	// SyncDir mirrors the local directory into the MFS (Mutable File System)
+	// directory: files which are new or changed are added, files and
+	// directories which no longer exist locally are removed and unchanged
+	// files are left alone, so publishing a directory again only adds what
+	// changed. The added content is kept by MFS rather than pinned.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   localDir - The path of the local directory to mirror.
+	//   mfsPath - The absolute MFS path to mirror into, e.g. "/site". It is
+	//             created if it does not exist.
+	//
+	// Returns:
+	//   The CID of the MFS directory after the sync on success.
+	//   An error if the directory could not be synced.
+	SyncDir(ctx context.Context, localDir string, mfsPath string) (string, error)
+
+	// FilesFlush writes the pending changes to the MFS path, and everything
+	// below it, to the blockstore.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   mfsPath - The absolute MFS path to flush, e.g. "/site".
+	//
+	// Returns:
+	//   The CID of the path once flushed.
+	//   An error if the path could not be flushed.
+	FilesFlush(ctx context.Context, mfsPath string) (string, error)
+
+	// FilesRootCID flushes the whole MFS tree and returns the CID of its root
+	// in a single step, so the CID matches the tree at one point in time and
+	// can be published or recorded.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The CID of the MFS root.
+	//   An error if the tree could not be flushed.
+	FilesRootCID(ctx context.Context) (string, error)
+
+	// WatchDir keeps the MFS directory in sync with the local directory
+	// until the context is cancelled. The directory is synced with `SyncDir`
+	// right away and then every time it stops changing for the debounce
+	// duration (see `WithWatchDebounce`); whenever the root CID changes,
+	// `onUpdate` is called and, if configured with `WithWatchIPNSKey`, the
+	// root is published to IPNS. Failed syncs are logged and retried on the
+	// next change.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation of the watch.
+	//   localDir - The path of the local directory to watch.
+	//   mfsPath - The absolute MFS path to mirror into, e.g. "/site".
+	//   onUpdate - Called with the new root CID, may be nil.
+	//
+	// Returns:
+	//   The error of the context once it is cancelled.
+	//   An error if the directory could not be watched.
+	WatchDir(ctx context.Context, localDir string, mfsPath string, onUpdate WatchDirUpdateHook) error
+
+	// FetchWithFallback retrieves the content of the object with the local
+	// node and, if that does not succeed within the local fetch timeout,
+	// falls back to the trustless HTTP gateways configured with
+	// `WithFallbackGateways`. Content from a gateway is imported into the
+	// local node, which verifies every block against its CID, before it is
+	// returned.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to retrieve.
+	//
+	// Returns:
+	//   The content of the object.
+	//   An error if neither the local node nor any gateway provided it.
+	FetchWithFallback(ctx context.Context, cid string) ([]byte, error)
+
+	// VerifyContent checks if the data matches the CID by recomputing its
+	// hash, so bytes obtained outside the daemon can be validated before
+	// use. The data is not stored. Raw CIDs are verified exactly, by
+	// hashing the data as a single raw block, while dag-pb CIDs can only be
+	// verified if the data was added with the default chunker and layout of
+	// `ipfs add`.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID the data is expected to have.
+	//   data - The data to verify.
+	//
+	// Returns:
+	//   True if the data matches the CID, false otherwise.
+	//   `ErrCannotVerify` if the data does not match a dag-pb CID with the
+	//   default settings, as it may have been added with other settings.
+	//   An error if the CID could not be parsed or the hash computed.
+	VerifyContent(ctx context.Context, cid string, data []byte) (bool, error)
+
+	// HashOnly computes the CID the content would get from `AddFile`, without
+	// storing it in the IPFS node, for example to check for duplicates or to
+	// sign the CID before uploading.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   r - The content to compute the CID of.
+	//
+	// Returns:
+	//   The CID of the content.
+	//   An error if the CID could not be computed.
+	HashOnly(ctx context.Context, r io.Reader) (string, error)
+
+	// HashOnlyFile computes the CID the file would get from `AddFile`, without
+	// storing it in the IPFS node.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   filepath - The path to the file to compute the CID of.
+	//
+	// Returns:
+	//   The CID of the file.
+	//   An error if the CID could not be computed.
+	HashOnlyFile(ctx context.Context, filepath string) (string, error)
+
+	// AddIfAbsent adds the file to IPFS unless its content is already stored
+	// by the IPFS node, which avoids the cost of adding the same data again
+	// in backup-style workloads. Content which is already stored gets pinned
+	// if it is not yet, just like `AddFile` pins what it adds.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   filepath - The path to the file to add.
+	//
+	// Returns:
+	//   The CID of the file.
+	//   True if the data was newly stored, false if it already existed.
+	//   An error if the file could not be added.
+	AddIfAbsent(ctx context.Context, filepath string) (string, bool, error)
+
+	// AddFileResilient adds a large file to IPFS in segments, for multi-GB
+	// files on flaky storage. Every segment is verified and retried on its
+	// own, and the progress is recorded in a state file so calling the method
+	// again after an interruption resumes from the last completed segment
+	// instead of starting over. The segments are then joined into a single
+	// file which gets pinned.
+	//
+	// Please note the CID may differ from the one `AddFile` returns for the
+	// same file if it spans more than one segment, since the DAG can be
+	// shaped differently.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   filepath - The path to the file to add.
+	//   opts - The segment size, retry policy and state file location.
+	//
+	// Returns:
+	//   The CID of the file.
+	//   An error if a segment could not be added after all retries.
+	AddFileResilient(ctx context.Context, filepath string, opts ResilientAddOptions) (string, error)
+
+	// Benchmark measures the add throughput, the latency of reading hot
+	// (local) and cold (network) content and the pin duration of the running
+	// node, which helps with capacity planning. The random data added for the
+	// measurements is left unpinned.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   opts - The data size, number of iterations and cold content to use.
+	//
+	// Returns:
+	//   The measurements.
+	//   An error if any of the measured operations failed.
+	Benchmark(ctx context.Context, opts BenchmarkOptions) (*BenchmarkReport, error)
+
+	// RunCommand executes the `ipfs` binary managed by this package with the
+	// given arguments against the managed repo and daemon, for kubo features
+	// which are not wrapped by this package yet.
+	//
+	// Example:
+	//
+	//	output, err := wrapper.RunCommand(ctx, "swarm", "peers")
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   args - The subcommand and its arguments, without the binary name.
+	//
+	// Returns:
+	//   The standard output of the command.
+	//   A `*CommandError` including the standard error output if the command
+	//   failed, which wraps `ErrNotFound`, `ErrNotPinned`, etc when the cause
+	//   is recognized.
+	RunCommand(ctx context.Context, args ...string) ([]byte, error)
+
+	// RunCommandStreaming starts the `ipfs` binary like `RunCommand` but
+	// returns right away with live streams of its output, for long-running
+	// subcommands such as `ipfs refs local` or `ipfs log tail`.
+	//
+	// Example:
+	//
+	//	stream, err := wrapper.RunCommandStreaming(ctx, "refs", "local")
+	//	if err != nil {
+	//	    log.Fatal(err)
+	//	}
+	//	scanner := bufio.NewScanner(stream.Stdout)
+	//	for scanner.Scan() {
+	//	    fmt.Println(scanner.Text())
+	//	}
+	//	err = stream.Wait()
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines; cancelling
+	//   it kills the command.
+	//   args - The subcommand and its arguments, without the binary name.
+	//
+	// Returns:
+	//   The running command with its output streams.
+	//   An error if the command could not be started.
+	RunCommandStreaming(ctx context.Context, args ...string) (*CommandStream, error)
+
 	// GarbageCollection runs the garbage collection process on the IPFS node,
 	// removing any unpinned objects that are no longer needed, freeing up space.
+	// While it runs, the calls writing to the repo, like `AddFile`, `Pin`,
+	// `Unpin` or `SyncDir`, wait for it to finish and return `ErrGCInProgress` if their
+	// context ends first.
 	//
 	// Parameters:
 	//   ctx - Context for controlling cancellation and deadlines.
 	//
-	// Returns an error if the garbage collection process failed.
+	// Returns `ErrGCInProgress` if a garbage collection is already running,
+	// or an error if the garbage collection process failed.
 	GarbageCollection(ctx context.Context) error
 
-	// Id returns the IPFS node connection details of the running daemon.
+	// Id returns the IPFS node connection details of the running daemon, or
+	// of another peer if its peer ID is given, which the daemon then looks
+	// up in the network.
 	//
 	// Parameters:
 	//   ctx - Context for controlling cancellation and deadlines.
+	//   peerID - Optional, the peer ID of the node to look up.
 	//
 	// Returns an error if the failed getting connection details from IPFS.
-	Id(ctx context.Context) (*IpfsNodeInfo, error)
+	Id(ctx context.Context, peerID ...string) (*IpfsNodeInfo, error)
+
+	// TrackIPNSName registers an IPNS name to be kept alive by the wrapper.
+	// While the daemon is running, the name is republished right away and
+	// then on every republish interval, so the record does not expire.
+	//
+	// Parameters:
+	//   key - The name of the key used to sign the record (e.g. "self").
+	//   value - The path the name should point to (e.g. "/ipfs/<cid>").
+	//
+	// Returns an error if the key or value is missing.
+	TrackIPNSName(key string, value string) error
+
+	// UntrackIPNSName stops republishing the IPNS name signed by the key.
+	//
+	// Parameters:
+	//   key - The name of the key previously passed to `TrackIPNSName`.
+	UntrackIPNSName(key string)
+
+	// NameInspect retrieves the published IPNS record of the name and
+	// returns its value, sequence number, TTL and expiry, so apps can tell
+	// when a record is about to expire.
+	//
+	// Example:
+	//
+	//	info, err := wrapper.NameInspect(ctx, "k51qzi5uqu5d...")
+	//	if err == nil && info.ExpiresIn() < time.Hour {
+	//	    // Republish the name.
+	//	}
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The IPNS name, with or without the "/ipns/" prefix.
+	//
+	// Returns:
+	//   The details of the record, including whether its signature matches
+	//   the name.
+	//   An error wrapping `ErrNotFound` if no record was published, or
+	//   another error if the record could not be retrieved or decoded.
+	NameInspect(ctx context.Context, name string) (*IPNSRecordInfo, error)
+
+	// NamePublish publishes the content under an IPNS name, a mutable
+	// pointer which can later be published again to point to other content,
+	// using the `ipfs name publish` command. The name of the node's own key
+	// is used unless another key is set with `WithPublishKey`.
+	//
+	// Example:
+	//
+	//	entry, err := wrapper.NamePublish(ctx, cid, ipfscliwrapper.WithPublishTTL(time.Minute))
+	//	if err == nil {
+	//	    fmt.Println("published at /ipns/" + entry.Name)
+	//	}
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the content, or a path like "/ipfs/<cid>/file.txt".
+	//   opts - Optional settings, like `WithPublishKey`, `WithPublishTTL`,
+	//     `WithPublishLifetime` and `WithPublishAllowOffline`.
+	//
+	// Returns:
+	//   The published name, value and TTL.
+	//   An error if the name could not be published.
+	NamePublish(ctx context.Context, cid string, opts ...PublishOption) (*IPNSEntry, error)
+
+	// NameResolve resolves the IPNS name to the path it points to, using the
+	// `ipfs name resolve` command.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The IPNS name, with or without the "/ipns/" prefix.
+	//
+	// Returns:
+	//   The path the name points to, e.g. "/ipfs/<cid>".
+	//   An error wrapping `ErrNotFound` if the name could not be resolved,
+	//   or another error if the command failed.
+	NameResolve(ctx context.Context, name string) (string, error)
+
+	// KeyGen generates a new ed25519 key in the keychain of the node, using
+	// the `ipfs key gen` command. The key can then sign IPNS names with
+	// `WithPublishKey`.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The name of the new key.
+	//
+	// Returns:
+	//   The name and peer ID of the key.
+	//   An error if a key with the name exists or the command failed.
+	KeyGen(ctx context.Context, name string) (*KeyInfo, error)
+
+	// KeyList returns the keys of the keychain of the node, including the
+	// "self" key of the node, using the `ipfs key list` command.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//
+	// Returns:
+	//   The names and peer IDs of the keys.
+	//   An error if the command failed.
+	KeyList(ctx context.Context) ([]KeyInfo, error)
+
+	// KeyRm removes the key from the keychain of the node, using the
+	// `ipfs key rm` command. The IPNS name of the key cannot be published
+	// anymore, unless the key was exported with `KeyExport`.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The name of the key.
+	//
+	// Returns an error wrapping `ErrNotFound` if there is no such key, or
+	// another error if the command failed.
+	KeyRm(ctx context.Context, name string) error
+
+	// KeyRename renames the key, using the `ipfs key rename` command. Its
+	// peer ID, and so its IPNS name, stays the same.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   oldName - The name of the key.
+	//   newName - The new name of the key.
+	//
+	// Returns:
+	//   The new name and the peer ID of the key.
+	//   An error wrapping `ErrNotFound` if there is no such key, or another
+	//   error if the command failed.
+	KeyRename(ctx context.Context, oldName string, newName string) (*KeyInfo, error)
+
+	// KeyExport returns the private key in the libp2p-protobuf-cleartext
+	// format, using the `ipfs key export` command, e.g. to back it up or to
+	// publish the same IPNS name from another node with `KeyImport`. Keep it
+	// secret, anyone holding it can publish the IPNS name.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The name of the key.
+	//
+	// Returns:
+	//   The private key.
+	//   An error wrapping `ErrNotFound` if there is no such key, or another
+	//   error if the command failed.
+	KeyExport(ctx context.Context, name string) ([]byte, error)
+
+	// KeyImport adds a private key exported with `KeyExport` to the keychain
+	// of the node, using the `ipfs key import` command.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   name - The name to store the key under.
+	//   key - The private key in the libp2p-protobuf-cleartext format.
+	//
+	// Returns:
+	//   The name and peer ID of the key.
+	//   An error if a key with the name exists, the key is invalid or the
+	//   command failed.
+	KeyImport(ctx context.Context, name string, key []byte) (*KeyInfo, error)
+
+	// TrackedIPNSNames returns the IPNS names currently kept alive by the
+	// wrapper along with the outcome of their most recent publish attempt.
+	TrackedIPNSNames() []IPNSTrackedName
+
+	// ClusterPin pins an object across the ipfs-cluster the companion cluster
+	// peer belongs to, using the `ipfs-cluster-ctl pin add` command. Requires
+	// the `WithClusterService` or `WithClusterFollow` option.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to pin in the cluster.
+	//
+	// Returns an error if the object could not be pinned.
+	ClusterPin(ctx context.Context, cid string) error
+
+	// ClusterUnpin removes a pin from the ipfs-cluster using the
+	// `ipfs-cluster-ctl pin rm` command.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to unpin from the cluster.
+	//
+	// Returns an error if the object could not be unpinned.
+	ClusterUnpin(ctx context.Context, cid string) error
+
+	// ClusterStatus returns the per-peer pin status of an object in the
+	// ipfs-cluster using the `ipfs-cluster-ctl status` command.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   cid - The CID of the object to look up.
+	//
+	// Returns:
+	//   The status of the object on every cluster peer.
+	//   An error if the status could not be retrieved.
+	ClusterStatus(ctx context.Context, cid string) (*ClusterPinStatus, error)
+
+	// InstallService registers the `ipfs` daemon with the service manager of
+	// the operating system, so the node survives reboots and not only restarts
+	// of your app. The daemon runs the binary and repo managed by this package
+	// and is started right away. On Linux a systemd unit is installed, on
+	// macOS a launchd daemon (or agent) and on Windows a scheduled task which
+	// starts on boot (or logon), since the `ipfs` binary cannot run as a
+	// native Windows Service. Requires the `WithContinousOperation` option.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   opts - The name, scope and account of the service.
+	//
+	// Returns an error if the service could not be installed.
+	InstallService(ctx context.Context, opts ServiceOptions) error
+
+	// UninstallService stops the daemon registered by `InstallService` and
+	// removes the registration. Uninstalling a service which is not installed
+	// does nothing.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   opts - The same options the service was installed with.
+	//
+	// Returns an error if the service could not be uninstalled.
+	UninstallService(ctx context.Context, opts ServiceOptions) error
+
+	// StatusService returns whether the daemon registered by `InstallService`
+	// is installed and running.
+	//
+	// Parameters:
+	//   ctx - Context for controlling cancellation and deadlines.
+	//   opts - The same options the service was installed with.
+	//
+	// Returns:
+	//   The status of the service.
+	//   An error if the status could not be determined.
+	StatusService(ctx context.Context, opts ServiceOptions) (ServiceStatus, error)
+
+	// APIPort returns the TCP port the daemon API listens on, which is useful
+	// together with the `WithAutoPorts` option.
+	//
+	// Returns:
+	//   The port, or 0 if it could not be determined.
+	APIPort() int
+
+	// GatewayPort returns the TCP port the daemon HTTP gateway listens on.
+	//
+	// Returns:
+	//   The port, or 0 if the gateway is disabled with `WithoutGateway` or
+	//   the port could not be determined.
+	GatewayPort() int
+
+	// SwarmPort returns the TCP port the daemon listens on for connections
+	// from other peers.
+	//
+	// Returns:
+	//   The port of the first TCP swarm address, or 0 if it could not be
+	//   determined.
+	SwarmPort() int
 }
 
 // Option is a functional option type that allows us to configure the IpfsCliWrapper.
 type Option func(*ipfsCliWrapper)
+
+// AddOption is a functional option type that allows us to configure a single
+// call adding content, such as `AddFile`.
+type AddOption func(*addSettings)
+
+// PublishOption is a functional option type that allows us to configure a
+// single call publishing an IPNS name, see `NamePublish`.
+type PublishOption func(*publishSettings)
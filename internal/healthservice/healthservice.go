@@ -0,0 +1,95 @@
+// Package healthservice provides an HTTP handler exposing the health and
+// status of the wrapper-managed node, suitable for Kubernetes-style liveness
+// and readiness probes.
+package healthservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// probeTimeout bounds how long a single probe may take so a hanging daemon
+// makes the probe fail instead of blocking the caller.
+const probeTimeout = 5 * time.Second
+
+// Status is the response body of the status endpoint.
+type Status struct {
+	// Healthy is true if the daemon is running and answering API calls.
+	Healthy bool `json:"healthy"`
+
+	// DaemonRunning is true if the wrapper started (or adopted) the daemon.
+	DaemonRunning bool `json:"daemon_running"`
+
+	// PeerCount is the number of peers the daemon is connected to.
+	PeerCount int `json:"peer_count"`
+
+	// RepoSize is the size of the repo in bytes.
+	RepoSize uint64 `json:"repo_size"`
+
+	// StorageMax is the disk budget of the repo in bytes.
+	StorageMax uint64 `json:"storage_max"`
+
+	// Error describes why the node is unhealthy, if it is.
+	Error string `json:"error,omitempty"`
+}
+
+// ProbeFunc inspects the node and returns its current status.
+type ProbeFunc func(ctx context.Context) Status
+
+// Service is an `http.Handler` serving the `/healthz` and `/status`
+// endpoints.
+type Service struct {
+	probe ProbeFunc
+	mux   *http.ServeMux
+}
+
+// New returns a Service which reports the status returned by the probe.
+func New(probe ProbeFunc) *Service {
+	s := &Service{
+		probe: probe,
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	s.mux.HandleFunc("GET /status", s.status)
+	return s
+}
+
+// ServeHTTP dispatches the request to the endpoint.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// healthz responds with `200 OK` while the node is healthy and with
+// `503 Service Unavailable` otherwise.
+func (s *Service) healthz(w http.ResponseWriter, r *http.Request) {
+	status := s.run(r.Context())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("unhealthy: " + status.Error + "\n"))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// status responds with the JSON encoded status, using the same status codes
+// as `healthz`.
+func (s *Service) status(w http.ResponseWriter, r *http.Request) {
+	status := s.run(r.Context())
+	code := http.StatusOK
+	if !status.Healthy {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+func (s *Service) run(ctx context.Context) Status {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	return s.probe(ctx)
+}
@@ -0,0 +1,56 @@
+package healthservice_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/healthservice"
+)
+
+// TestHealthz checks the probe outcome is mapped to the status code.
+func TestHealthz(t *testing.T) {
+	healthy := true
+	svc := healthservice.New(func(ctx context.Context) healthservice.Status {
+		if !healthy {
+			return healthservice.Status{Error: "daemon is not running"}
+		}
+		return healthservice.Status{Healthy: true, DaemonRunning: true}
+	})
+
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status %d, but got %d", http.StatusOK, rec.Code)
+	}
+
+	healthy = false
+	rec = httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, but got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+}
+
+// TestStatus checks the status endpoint returns the probed status as JSON.
+func TestStatus(t *testing.T) {
+	svc := healthservice.New(func(ctx context.Context) healthservice.Status {
+		return healthservice.Status{Healthy: true, DaemonRunning: true, PeerCount: 7, RepoSize: 1024}
+	})
+
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, but got %d", http.StatusOK, rec.Code)
+	}
+
+	var status healthservice.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.PeerCount != 7 || status.RepoSize != 1024 || !status.DaemonRunning {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
@@ -0,0 +1,73 @@
+// Package lockkit provides an exclusive lock on a file which is shared by
+// the processes on the machine, for guarding a directory against concurrent
+// use.
+package lockkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by `TryLock` when the lock is held, by another
+// process or by another `Lock` of this process.
+var ErrLocked = errors.New("file is locked")
+
+// pollInterval is how often `Acquire` retries taking a held lock.
+const pollInterval = 100 * time.Millisecond
+
+// Lock is a held lock, which is released by `Unlock` or when the process
+// exits.
+type Lock struct {
+	file *os.File
+}
+
+// TryLock takes the lock on the file at the path, creating the file if
+// needed, or returns `ErrLocked` right away if it is held.
+func TryLock(path string) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %v", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Lock{file: file}, nil
+}
+
+// Acquire takes the lock on the file at the path like `TryLock`, waiting for
+// it to be released until the context is done, in which case `ErrLocked` is
+// returned.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	lock, err := lockkit.Acquire(ctx, "/var/lib/app/repo/app.lock")
+func Acquire(ctx context.Context, path string) (*Lock, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		lock, err := TryLock(path)
+		if !errors.Is(err, ErrLocked) {
+			return lock, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-ticker.C:
+		}
+	}
+}
+
+// Unlock releases the lock.
+func (l *Lock) Unlock() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to unlock file: %v", err)
+	}
+	return l.file.Close()
+}
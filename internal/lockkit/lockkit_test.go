@@ -0,0 +1,52 @@
+package lockkit_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/lockkit"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+
+	lock, err := lockkit.TryLock(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := lockkit.TryLock(path); !errors.Is(err, lockkit.ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	if err := lock.Unlock(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	again, err := lockkit.TryLock(path)
+	if err != nil {
+		t.Fatalf("expected the released lock to be taken, got %v", err)
+	}
+	again.Unlock()
+}
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "repo.lock")
+	lock, err := lockkit.TryLock(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if _, err := lockkit.Acquire(ctx, path); !errors.Is(err, lockkit.ErrLocked) {
+		t.Fatalf("expected ErrLocked once the context is done, got %v", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { lock.Unlock() })
+	waited, err := lockkit.Acquire(context.Background(), path)
+	if err != nil {
+		t.Fatalf("expected the lock once released, got %v", err)
+	}
+	waited.Unlock()
+}
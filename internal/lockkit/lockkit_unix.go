@@ -0,0 +1,27 @@
+//go:build !windows
+
+package lockkit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func lockFile(file *os.File) error {
+	// Unlike `fcntl` locks, `flock` locks conflict between the open files
+	// of a single process as well.
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrLocked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock file: %v", err)
+	}
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
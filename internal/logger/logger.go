@@ -4,8 +4,12 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
 )
 
 // NewProvider initializes and returns a new instance of slog.Logger with default settings
@@ -69,3 +73,62 @@ func NewProvider() *slog.Logger {
 	// Return the configured logger instance
 	return logger
 }
+
+// NewSamplingHandler returns a handler which passes records below the warning
+// level on to the next handler only once every `every` times per message, so
+// high-volume operations do not flood the logs. The first record of every
+// message is always passed on, as are warnings and errors.
+//
+// Example:
+//
+//	sampled := slog.New(logger.NewSamplingHandler(log.Handler(), 100))
+func NewSamplingHandler(next slog.Handler, every int) slog.Handler {
+	if every < 1 {
+		every = 1
+	}
+	return &samplingHandler{next: next, every: uint64(every), counts: &sync.Map{}}
+}
+
+type samplingHandler struct {
+	next  slog.Handler
+	every uint64
+
+	// counts holds a `*atomic.Uint64` per message. It is shared with the
+	// handlers derived through `WithAttrs` and `WithGroup`.
+	counts *sync.Map
+}
+
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		counter, _ := h.counts.LoadOrStore(r.Message, &atomic.Uint64{})
+		if (counter.(*atomic.Uint64).Add(1)-1)%h.every != 0 {
+			return nil
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{next: h.next.WithAttrs(attrs), every: h.every, counts: h.counts}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{next: h.next.WithGroup(name), every: h.every, counts: h.counts}
+}
+
+// TruncateOutput returns the output of a command for logging, cut down to the
+// first `maxBytes` bytes, or only its length if `maxBytes` is zero or less,
+// so large outputs neither flood the logs nor leak the data they contain.
+func TruncateOutput(output []byte, maxBytes int) string {
+	if maxBytes <= 0 {
+		return fmt.Sprintf("(%d bytes)", len(output))
+	}
+	if len(output) <= maxBytes {
+		return string(output)
+	}
+	return fmt.Sprintf("%s... (truncated, %d bytes)", output[:maxBytes], len(output))
+}
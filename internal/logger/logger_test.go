@@ -2,6 +2,7 @@ package logger_test
 
 import (
 	"bytes"
+	"log/slog"
 	"os"
 	"strings"
 	"testing"
@@ -49,6 +50,53 @@ func TestNewProviderInitialization(t *testing.T) {
 	}
 }
 
+// TestSamplingHandler checks repeated debug messages are sampled while
+// warnings always get through.
+func TestSamplingHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(logger.NewSamplingHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), 3))
+
+	for i := 0; i < 7; i++ {
+		log.Debug("repeated", slog.Int("i", i))
+		log.With(slog.String("k", "v")).Debug("other")
+		log.Warn("warning")
+	}
+
+	output := buf.String()
+	if got := strings.Count(output, "msg=repeated"); got != 3 {
+		t.Errorf("Expected 3 sampled debug records, but got %d in %q", got, output)
+	}
+	for _, i := range []string{"i=0", "i=3", "i=6"} {
+		if !strings.Contains(output, i) {
+			t.Errorf("Expected the sampled records to contain %q, but got %q", i, output)
+		}
+	}
+	if got := strings.Count(output, "msg=other"); got != 3 {
+		t.Errorf("Expected messages to be sampled separately, but got %d records", got)
+	}
+	if got := strings.Count(output, "msg=warning"); got != 7 {
+		t.Errorf("Expected every warning to be logged, but got %d", got)
+	}
+}
+
+// TestTruncateOutput checks large outputs are cut down for logging.
+func TestTruncateOutput(t *testing.T) {
+	tests := []struct {
+		output   string
+		maxBytes int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"0123456789abc", 10, "0123456789... (truncated, 13 bytes)"},
+		{"secret content", 0, "(14 bytes)"},
+	}
+	for _, test := range tests {
+		if got := logger.TruncateOutput([]byte(test.output), test.maxBytes); got != test.expected {
+			t.Errorf("Expected %q, but got %q", test.expected, got)
+		}
+	}
+}
+
 // func TestNewProviderLoggingLevel(t *testing.T) {
 // 	// Initialize the logger.
 // 	log := logger.NewProvider()
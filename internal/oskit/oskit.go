@@ -4,16 +4,35 @@
 package oskit
 
 import (
-	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
-	"strconv"
+	"path/filepath"
 	"strings"
-	"syscall"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/process"
 )
 
+// ProcessInfo describes a running process.
+type ProcessInfo struct {
+	// PID is the process id.
+	PID int
+
+	// CommandLine is the full command line the process was started with, or
+	// empty if it could not be read.
+	CommandLine string
+
+	// Args are the arguments of the command line, starting with the program,
+	// or nil if they could not be read.
+	Args []string
+
+	// Environ holds the environment variables of the process in `KEY=value`
+	// form, or nil if they could not be read (e.g. insufficient permissions).
+	Environ []string
+}
+
 // OSOperater defines methods related to OS operations.
 type OSOperater interface {
 	// CreateDirIfDoesNotExist creates a directory at the specified path if it does not already exist.
@@ -51,9 +70,9 @@ type OSOperater interface {
 	CreateDirsIfDoesNotExist(dirs []string) error
 
 	// TerminateProgram attempts to terminate all processes matching the given
-	// program name by sending a SIGTERM signal. It uses the `pgrep` command to
-	// find the process IDs of the running instances and iterates over each to
-	// send the termination signal.
+	// program name by sending a SIGTERM signal. It looks up the process IDs of
+	// the running instances in the process table of the operating system and
+	// iterates over each to send the termination signal.
 	//
 	// Parameters:
 	// - processName (string): The name of the process to terminate.
@@ -89,15 +108,15 @@ type OSOperater interface {
 	MoveFile(sourcePath string, destPath string) error
 
 	// IsProgramRunning checks if a program with the given name is currently running
-	// in the operating system. It uses the `pgrep` command to search for processes
-	// matching the exact program name.
+	// in the operating system. It searches the process table of the operating
+	// system for processes matching the exact program name.
 	//
 	// Parameters:
 	// - programName (string): The name of the program to check.
 	//
 	// Returns:
 	// - bool: Returns true if the program is running, false otherwise.
-	// - error: Returns an error if the check fails due to issues with executing the `pgrep` command.
+	// - error: Returns an error if the check fails due to issues with listing the processes.
 	//
 	// Example:
 	//
@@ -109,6 +128,80 @@ type OSOperater interface {
 	//	    fmt.Println("IPFS is running.")
 	//	}
 	IsProgramRunning(programName string) (bool, error)
+
+	// FindProcessesByName returns the running processes with the exact
+	// given name along with their command line and environment, so callers
+	// can tell apart multiple instances of the same program.
+	//
+	// Parameters:
+	// - programName (string): The name of the program to look up.
+	//
+	// Returns:
+	// - []ProcessInfo: The matching processes, empty if none are running.
+	// - error: Returns an error if the processes could not be listed.
+	//
+	// Example:
+	//
+	//	procs, err := FindProcessesByName("ipfs")
+	//	if err != nil {
+	//	    log.Fatal(err)
+	//	}
+	//	for _, proc := range procs {
+	//	    fmt.Println(proc.PID, proc.CommandLine)
+	//	}
+	FindProcessesByName(programName string) ([]ProcessInfo, error)
+
+	// IsProcessRunning checks if a process with the given PID is running.
+	//
+	// Parameters:
+	// - pid (int): The process id to check.
+	//
+	// Returns:
+	// - bool: Returns true if the process is running, false otherwise.
+	// - error: Returns an error if the check fails.
+	IsProcessRunning(pid int) (bool, error)
+
+	// TerminateProcess sends a SIGTERM signal to the process with the given PID.
+	//
+	// Parameters:
+	// - pid (int): The process id to terminate.
+	//
+	// Returns:
+	// - error: Returns an error if the process cannot be terminated.
+	TerminateProcess(pid int) error
+
+	// KillProcess sends a SIGKILL signal to the process with the given PID,
+	// for processes which did not exit after `TerminateProcess`.
+	//
+	// Parameters:
+	// - pid (int): The process id to kill.
+	//
+	// Returns:
+	// - error: Returns an error if the process cannot be killed.
+	KillProcess(pid int) error
+
+	// ProcessStartTime returns when the process with the given PID was
+	// started, to tell it apart from a later process reusing the same PID.
+	//
+	// Parameters:
+	// - pid (int): The process id to look up.
+	//
+	// Returns:
+	// - time.Time: The time the process was started.
+	// - error: Returns an error if the process does not exist or cannot be inspected.
+	ProcessStartTime(pid int) (time.Time, error)
+
+	// FreeDiskSpace returns the space available to unprivileged users on the
+	// filesystem holding the path. If the path does not exist yet, the
+	// filesystem of its nearest existing parent is used.
+	//
+	// Parameters:
+	// - path (string): The path whose filesystem to check.
+	//
+	// Returns:
+	// - uint64: The available space in bytes.
+	// - error: Returns an error if the filesystem cannot be inspected.
+	FreeDiskSpace(path string) (uint64, error)
 }
 
 // DefaultOSKit is the default implementation of OSOperater.
@@ -134,58 +227,25 @@ func (d *DefaultOSKit) CreateDirsIfDoesNotExist(dirs []string) error {
 }
 
 func (d *DefaultOSKit) TerminateProgram(processName string) error {
-	// DEVELOPERS NOTE:
-	// (1)
-	// `pgrep` is a unix app used to lookup programs running in background and
-	// it returns the process id value of the running instance.
-	//
-	// (2)
-	// To ensure that code targets only processes with the exact name "ipfs" and
-	// not those that include "ipfs" as a substring (e.g.,
-	// "comicbookss_ipfs_backend"), you can refine the pgrep command by using
-	// the -x flag, which matches the exact process name.
-
-	// Use `pgrep` to get the PIDs of the processes with the given name
-	cmd := exec.Command("pgrep", "-x", processName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
+	procs, err := findProcesses(processName)
+	if err != nil {
 		return fmt.Errorf("Failed to find process: %v\n", err)
 	}
 
-	// Split the output to get individual PIDs
-	pids := strings.Fields(out.String())
-
-	// Iterate over each PID and terminate the process
-	for _, pidStr := range pids {
-		pid, err := strconv.Atoi(pidStr)
-		if err != nil {
-			return fmt.Errorf("Failed to parse PID: %v\n", err)
-		}
-
-		// Find the process by PID
-		process, err := os.FindProcess(pid)
-		if err != nil {
-			return fmt.Errorf("Failed to find process with PID %d: %v\n", pid, err)
-		}
-
+	// Iterate over each process and terminate it
+	for _, proc := range procs {
 		// Developers Note
 		// SIGTERM (syscall.SIGTERM): This is a gentle request for the process to terminate. The process can handle this signal and clean up resources before exiting.
 		// SIGKILL (syscall.SIGKILL): This forces the process to terminate immediately, and the process doesn’t get a chance to clean up.
+		// On Windows there are no signals and `Terminate` ends the process.
 
 		// Send a SIGTERM signal to the process (soft kill)
-		if err := process.Signal(syscall.SIGTERM); err != nil {
-			fmt.Printf("Failed to terminate process with PID %d: %v\n", pid, err)
+		if err := proc.Terminate(); err != nil {
+			fmt.Printf("Failed to terminate process with PID %d: %v\n", proc.Pid, err)
 			continue
 		}
 
-		// // Send a SIGKILL signal to the process (force kill)
-		// if err := process.Signal(syscall.SIGKILL); err != nil {
-		// 	fmt.Printf("Failed to kill process: %v\n", err)
-		// 	return
-		// }
-
-		fmt.Printf("Process with PID %d terminated successfully.\n", pid)
+		fmt.Printf("Process with PID %d terminated successfully.\n", proc.Pid)
 	}
 	return nil
 }
@@ -232,31 +292,122 @@ func (d *DefaultOSKit) MoveFile(sourcePath string, destPath string) error {
 }
 
 func (d *DefaultOSKit) IsProgramRunning(programName string) (bool, error) {
-	// DEVELOPERS NOTE:
-	// (1)
-	// `pgrep` is a unix app used to lookup programs running in background and
-	// it returns the process id value of the running instance.
-	//
-	// (2)
-	// To ensure that code targets only processes with the exact name "ipfs" and
-	// not those that include "ipfs" as a substring (e.g.,
-	// "comicbookss_ipfs_backend"), you can refine the pgrep command by using
-	// the -x flag, which matches the exact process name.
-
-	// Execute the `pgrep` command to find processes by name
-	cmd := exec.Command("pgrep", "-x", programName)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-
-	err := cmd.Run()
+	procs, err := findProcesses(programName)
 	if err != nil {
-		// If `pgrep` exits with a status 1, it means no processes were found
-		if exitError, ok := err.(*exec.ExitError); ok && exitError.ExitCode() == 1 {
-			return false, nil
-		}
 		return false, err
 	}
+	return len(procs) > 0, nil
+}
+
+func (d *DefaultOSKit) FindProcessesByName(programName string) ([]ProcessInfo, error) {
+	procs, err := findProcesses(programName)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ProcessInfo, 0, len(procs))
+	for _, proc := range procs {
+		// The command line and environment are best effort as reading them
+		// can be denied for processes owned by other users, or unsupported
+		// on the platform (e.g. the environment on macOS).
+		cmdline, _ := proc.Cmdline()
+		args, _ := proc.CmdlineSlice()
+		environ, _ := proc.Environ()
+		infos = append(infos, ProcessInfo{
+			PID:         int(proc.Pid),
+			CommandLine: cmdline,
+			Args:        args,
+			Environ:     environ,
+		})
+	}
+	return infos, nil
+}
+
+// findProcesses returns the running processes whose executable name exactly
+// matches the program name.
+//
+// DEVELOPERS NOTE:
+// (1)
+// The processes are listed with `gopsutil` which reads the process table of
+// the operating system directly, so this works the same on Linux, macOS, the
+// BSDs and Windows without depending on external tools such as `pgrep`.
+//
+// (2)
+// To ensure that code targets only processes with the exact name "ipfs" and
+// not those that include "ipfs" as a substring (e.g.,
+// "comicbookss_ipfs_backend"), the names are compared for equality. The
+// `.exe` suffix is ignored so "ipfs" also matches "ipfs.exe" on Windows.
+func findProcesses(programName string) ([]*process.Process, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %v", err)
+	}
+
+	matches := make([]*process.Process, 0)
+	for _, proc := range procs {
+		name, err := proc.Name()
+		if err != nil {
+			continue // The process exited or cannot be inspected.
+		}
+		if strings.TrimSuffix(name, ".exe") == programName {
+			matches = append(matches, proc)
+		}
+	}
+	return matches, nil
+}
+
+func (d *DefaultOSKit) IsProcessRunning(pid int) (bool, error) {
+	return process.PidExists(int32(pid))
+}
+
+func (d *DefaultOSKit) TerminateProcess(pid int) error {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return fmt.Errorf("Failed to find process with PID %d: %v\n", pid, err)
+	}
+	if err := proc.Terminate(); err != nil {
+		return fmt.Errorf("Failed to terminate process with PID %d: %v\n", pid, err)
+	}
+	return nil
+}
+
+func (d *DefaultOSKit) KillProcess(pid int) error {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return fmt.Errorf("Failed to find process with PID %d: %v\n", pid, err)
+	}
+	if err := proc.Kill(); err != nil {
+		return fmt.Errorf("Failed to kill process with PID %d: %v\n", pid, err)
+	}
+	return nil
+}
+
+func (d *DefaultOSKit) ProcessStartTime(pid int) (time.Time, error) {
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to find process with PID %d: %v\n", pid, err)
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Failed to read start time of process with PID %d: %v\n", pid, err)
+	}
+	return time.UnixMilli(createTime), nil
+}
 
-	// If the output from `pgrep` is not empty, the process is running
-	return strings.TrimSpace(out.String()) != "", nil
+func (d *DefaultOSKit) FreeDiskSpace(path string) (uint64, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve path: %v", err)
+	}
+	for {
+		if _, err := os.Stat(path); err == nil || filepath.Dir(path) == path {
+			break
+		}
+		path = filepath.Dir(path)
+	}
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read disk usage: %v", err)
+	}
+	return usage.Free, nil
 }
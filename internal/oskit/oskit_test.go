@@ -2,7 +2,13 @@ package oskit_test
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/oskit"
 )
 
 // MockOSOperator is a mock implementation of the OSOperater interface for testing.
@@ -12,8 +18,17 @@ type MockOSOperator struct {
 	TerminateProgramFunc func(string) error
 	MoveFileFunc         func(string, string) error
 	IsProgramRunningFunc func(string) (bool, error)
+	FindProcessesFunc    func(string) ([]oskit.ProcessInfo, error)
+	IsProcessRunningFunc func(int) (bool, error)
+	TerminateProcessFunc func(int) error
+	KillProcessFunc      func(int) error
+	ProcessStartTimeFunc func(int) (time.Time, error)
+	FreeDiskSpaceFunc    func(string) (uint64, error)
 }
 
+// Ensure the mock satisfies the interface it stands in for.
+var _ oskit.OSOperater = (*MockOSOperator)(nil)
+
 func (m *MockOSOperator) CreateDirIfDoesNotExist(dirPath string) error {
 	return m.CreateDirFunc(dirPath)
 }
@@ -34,6 +49,30 @@ func (m *MockOSOperator) IsProgramRunning(programName string) (bool, error) {
 	return m.IsProgramRunningFunc(programName)
 }
 
+func (m *MockOSOperator) FindProcessesByName(programName string) ([]oskit.ProcessInfo, error) {
+	return m.FindProcessesFunc(programName)
+}
+
+func (m *MockOSOperator) IsProcessRunning(pid int) (bool, error) {
+	return m.IsProcessRunningFunc(pid)
+}
+
+func (m *MockOSOperator) TerminateProcess(pid int) error {
+	return m.TerminateProcessFunc(pid)
+}
+
+func (m *MockOSOperator) KillProcess(pid int) error {
+	return m.KillProcessFunc(pid)
+}
+
+func (m *MockOSOperator) ProcessStartTime(pid int) (time.Time, error) {
+	return m.ProcessStartTimeFunc(pid)
+}
+
+func (m *MockOSOperator) FreeDiskSpace(path string) (uint64, error) {
+	return m.FreeDiskSpaceFunc(path)
+}
+
 // Test for CreateDirIfDoesNotExist
 func TestCreateDirIfDoesNotExist(t *testing.T) {
 	mock := &MockOSOperator{
@@ -148,3 +187,80 @@ func TestIsProgramRunning(t *testing.T) {
 		t.Errorf("expected program to not be running, got %v, %v", running, err)
 	}
 }
+
+// Test for IsProcessRunning using the current process
+func TestDefaultIsProcessRunning(t *testing.T) {
+	kit := &oskit.DefaultOSKit{}
+
+	running, err := kit.IsProcessRunning(os.Getpid())
+	if err != nil || !running {
+		t.Errorf("expected current process to be running, got %v, %v", running, err)
+	}
+}
+
+// Test for ProcessStartTime using the current process
+func TestDefaultProcessStartTime(t *testing.T) {
+	kit := &oskit.DefaultOSKit{}
+
+	started, err := kit.ProcessStartTime(os.Getpid())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if started.After(time.Now()) || time.Since(started) > time.Hour {
+		t.Errorf("expected the start time of the current process, got %v", started)
+	}
+}
+
+// Test for FindProcessesByName using the current process
+func TestDefaultFindProcessesByName(t *testing.T) {
+	kit := &oskit.DefaultOSKit{}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to get executable: %v", err)
+	}
+	name := strings.TrimSuffix(filepath.Base(exe), ".exe")
+
+	procs, err := kit.FindProcessesByName(name)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	found := false
+	for _, proc := range procs {
+		if proc.PID == os.Getpid() {
+			found = true
+			if len(proc.Args) != len(os.Args) {
+				t.Errorf("expected the arguments %q, got %q", os.Args, proc.Args)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("expected current process %d in %+v", os.Getpid(), procs)
+	}
+
+	procs, err = kit.FindProcessesByName("no_such_program_for_oskit_tests")
+	if err != nil || len(procs) != 0 {
+		t.Errorf("expected no processes, got %+v, %v", procs, err)
+	}
+}
+
+// Test for FreeDiskSpace with a path which does not exist yet
+func TestDefaultFreeDiskSpace(t *testing.T) {
+	kit := &oskit.DefaultOSKit{}
+
+	dir := t.TempDir()
+	free, err := kit.FreeDiskSpace(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if free == 0 {
+		t.Errorf("expected free space, got %d", free)
+	}
+	missing, err := kit.FreeDiskSpace(filepath.Join(dir, "missing", "repo"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if missing == 0 {
+		t.Errorf("expected the free space of the parent, got %d", missing)
+	}
+}
@@ -0,0 +1,243 @@
+// Package outputkit parses the machine-readable output of the `ipfs`
+// commands, the JSON of `--enc=json` and the bare CIDs of `--quieter`, so
+// nothing depends on the human readable wording of kubo. The entries of
+// `ipfs add`, which the command line of kubo prints as `added` lines
+// whatever the encoding, are the one exception.
+package outputkit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseRootCID returns the CID printed by `ipfs add --quieter`, which is the
+// CID of the root of the added content.
+func ParseRootCID(output []byte) (string, error) {
+	fields := strings.Fields(string(output))
+	if len(fields) != 1 {
+		return "", fmt.Errorf("expected a single cid, got %q", string(output))
+	}
+	return fields[0], nil
+}
+
+// AddedEntry is a file or directory reported by `ipfs add`.
+type AddedEntry struct {
+	// Name is the path of the entry as printed by `ipfs add`, relative to
+	// the directory of the added path.
+	Name string
+
+	// Hash is the CID of the entry.
+	Hash string
+
+	// Size is the cumulative size of the entry in bytes, or 0 when the
+	// output did not include it, which the `added` lines never do.
+	Size uint64
+}
+
+// addedObject is an object printed by `ipfs add --enc=json`. Progress
+// objects only carry `Bytes` and have an empty `Hash`.
+type addedObject struct {
+	Name  string `json:"Name"`
+	Hash  string `json:"Hash"`
+	Size  string `json:"Size"`
+	Bytes int64  `json:"Bytes"`
+}
+
+// ParseAdded returns the entries printed by `ipfs add --progress=false
+// --enc=json`, in the order they were emitted, so the root of every added
+// path follows its own entries. Both the JSON objects and the `added <cid>
+// <name>` lines, which kubo prints instead on the command line, are
+// understood; anything else is skipped. An error is returned if the output
+// holds no entry.
+func ParseAdded(output []byte) ([]AddedEntry, error) {
+	var entries []AddedEntry
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		entry, ok, err := parseAddedLine(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading add output: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no added entries in output %q", string(output))
+	}
+	return entries, nil
+}
+
+func parseAddedLine(line string) (AddedEntry, bool, error) {
+	if strings.HasPrefix(line, "{") {
+		var object addedObject
+		if err := json.Unmarshal([]byte(line), &object); err != nil {
+			return AddedEntry{}, false, fmt.Errorf("failed decoding add output line %q: %v", line, err)
+		}
+		if object.Hash == "" {
+			return AddedEntry{}, false, nil
+		}
+		entry := AddedEntry{Name: object.Name, Hash: object.Hash}
+		if object.Size != "" {
+			size, err := strconv.ParseUint(object.Size, 10, 64)
+			if err != nil {
+				return AddedEntry{}, false, fmt.Errorf("failed parsing size of %q: %v", object.Hash, err)
+			}
+			entry.Size = size
+		}
+		return entry, true, nil
+	}
+
+	// The name is everything after the CID, so it may itself contain
+	// spaces or the word "added". The wrapping directory of
+	// `--wrap-with-directory` has an empty name.
+	rest, ok := strings.CutPrefix(line, "added ")
+	if !ok {
+		return AddedEntry{}, false, nil
+	}
+	hash, name, _ := strings.Cut(rest, " ")
+	if hash == "" {
+		return AddedEntry{}, false, nil
+	}
+	return AddedEntry{Name: name, Hash: hash}, true, nil
+}
+
+// ParsePinType returns the type of the pin printed by `ipfs pin ls
+// --enc=json <cid>`, e.g. "recursive", "direct" or "indirect" without the
+// CID of the ancestor of an indirect pin.
+func ParsePinType(output []byte) (string, error) {
+	var pins struct {
+		Keys map[string]struct {
+			Type string `json:"Type"`
+		} `json:"Keys"`
+	}
+	if err := json.Unmarshal(output, &pins); err != nil {
+		return "", err
+	}
+	// The key is the normalized CID, which may differ from the given one.
+	for _, pin := range pins.Keys {
+		if fields := strings.Fields(pin.Type); len(fields) > 0 {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no pin in output %q", string(output))
+}
+
+// ParsePins returns the CIDs printed by `ipfs pin ls --stream=true
+// --enc=json`, one JSON object per pin.
+func ParsePins(output []byte) ([]string, error) {
+	cids := make([]string, 0)
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var pin struct {
+			Cid string `json:"Cid"`
+		}
+		if err := decoder.Decode(&pin); err != nil {
+			return nil, err
+		}
+		cids = append(cids, pin.Cid)
+	}
+	return cids, nil
+}
+
+// routingEvent is an event emitted by `ipfs routing` commands with
+// `--enc=json`.
+type routingEvent struct {
+	Type      int `json:"Type"`
+	Responses []struct {
+		ID string `json:"ID"`
+	} `json:"Responses"`
+}
+
+// routingProviderEvent is the `Type` of routing events reporting providers.
+const routingProviderEvent = 4
+
+// ParseProviders returns the peer IDs of the distinct providers reported by
+// `ipfs routing findprovs --enc=json`, one JSON event per line. The output
+// of a lookup cut short may end with a partial event, which is ignored.
+func ParseProviders(output []byte) map[string]struct{} {
+	providers := make(map[string]struct{})
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	for decoder.More() {
+		var event routingEvent
+		if decoder.Decode(&event) != nil {
+			break
+		}
+		if event.Type != routingProviderEvent {
+			continue
+		}
+		for _, response := range event.Responses {
+			providers[response.ID] = struct{}{}
+		}
+	}
+	return providers
+}
+
+// ParseSwarmPeers returns the IDs of the peers printed by `ipfs swarm peers
+// --enc=json`.
+func ParseSwarmPeers(output []byte) ([]string, error) {
+	var swarm struct {
+		Peers []struct {
+			Peer string `json:"Peer"`
+		} `json:"Peers"`
+	}
+	if err := json.Unmarshal(output, &swarm); err != nil {
+		return nil, err
+	}
+	peers := make([]string, 0, len(swarm.Peers))
+	for _, peer := range swarm.Peers {
+		peers = append(peers, peer.Peer)
+	}
+	return peers, nil
+}
+
+// FilesDirectoryType is the `Type` of directories in `FilesEntry`.
+const FilesDirectoryType = 1
+
+// FilesEntry is an entry printed by `ipfs files ls --long --enc=json`.
+type FilesEntry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"`
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// ParseFilesLs returns the entries of the MFS directory printed by `ipfs
+// files ls --long --enc=json`.
+func ParseFilesLs(output []byte) ([]FilesEntry, error) {
+	var listing struct {
+		Entries []FilesEntry `json:"Entries"`
+	}
+	if err := json.Unmarshal(output, &listing); err != nil {
+		return nil, err
+	}
+	return listing.Entries, nil
+}
+
+// FilesStat is the output of `ipfs files stat --enc=json`.
+type FilesStat struct {
+	Hash           string `json:"Hash"`
+	Size           uint64 `json:"Size"`
+	CumulativeSize uint64 `json:"CumulativeSize"`
+	Type           string `json:"Type"`
+}
+
+// ParseFilesStat returns the details of the MFS path printed by `ipfs files
+// stat --enc=json`.
+func ParseFilesStat(output []byte) (*FilesStat, error) {
+	var stat FilesStat
+	if err := json.Unmarshal(output, &stat); err != nil {
+		return nil, err
+	}
+	if stat.Hash == "" {
+		return nil, fmt.Errorf("no cid in output %q", string(output))
+	}
+	return &stat, nil
+}
@@ -0,0 +1,217 @@
+package outputkit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+)
+
+// The outputs below were captured from kubo v0.29.0 for a directory `site`
+// holding "file added here.txt" and "sub/b.txt".
+const (
+	rootCID   = "bafybeiccg5fuqmcb3fr4cvzflk3mg4hmpvahsncgn623bdbysj4vksyjsy"
+	fileCID   = "bafkreidhx3e5p6bb4urcnkb4j32szr3mqk4l3gmtssj6zku5fztk6r5agq"
+	subdirCID = "bafybeibnez2w5vouaf4tjpk2ejscjvpjnbdltgzijio2mx5ahfx67dbz4q"
+	nestedCID = "bafkreibdgvrn4gqcrcyttrh2ic35dcpya3uqn3vqjbixv23h6nfmbyx26e"
+	peerID    = "12D3KooWD38Q1dbQ65w9kJkEs5dNdKdDucvFg3RcScywdVe6DNEK"
+)
+
+// TestParseRootCID checks the CID printed by `ipfs add --quieter` is read.
+func TestParseRootCID(t *testing.T) {
+	cid, err := outputkit.ParseRootCID([]byte(rootCID + "\n"))
+	if err != nil || cid != rootCID {
+		t.Errorf("Expected %s, but got %s and %v", rootCID, cid, err)
+	}
+	for _, output := range []string{"", "\n", "added " + rootCID + " site\n", fileCID + "\n" + rootCID + "\n"} {
+		if _, err := outputkit.ParseRootCID([]byte(output)); err == nil {
+			t.Errorf("Expected an error for output %q", output)
+		}
+	}
+}
+
+// TestParseAdded checks the entries of `ipfs add` are read in the order
+// they were emitted, from the `added` lines and the JSON objects alike.
+func TestParseAdded(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []outputkit.AddedEntry
+	}{
+		{
+			name: "Recursive",
+			output: "added " + fileCID + " site/file added here.txt\n" +
+				"added " + nestedCID + " site/sub/b.txt\n" +
+				"added " + subdirCID + " site/sub\n" +
+				"added " + rootCID + " site\n",
+			expected: []outputkit.AddedEntry{
+				{Name: "site/file added here.txt", Hash: fileCID},
+				{Name: "site/sub/b.txt", Hash: nestedCID},
+				{Name: "site/sub", Hash: subdirCID},
+				{Name: "site", Hash: rootCID},
+			},
+		},
+		{
+			name: "WrapWithDirectory",
+			output: "added " + fileCID + " file added here.txt\n" +
+				"added " + nestedCID + " sub/b.txt\n" +
+				"added " + subdirCID + " sub\n" +
+				"added " + rootCID + " \n",
+			expected: []outputkit.AddedEntry{
+				{Name: "file added here.txt", Hash: fileCID},
+				{Name: "sub/b.txt", Hash: nestedCID},
+				{Name: "sub", Hash: subdirCID},
+				{Name: "", Hash: rootCID},
+			},
+		},
+		{
+			name: "JSON",
+			output: `{"Name":"","Bytes":13}` + "\n" +
+				`{"Name":"site/file added here.txt","Hash":"` + fileCID + `","Size":"13"}` + "\n" +
+				`{"Name":"site","Hash":"` + rootCID + `","Size":"172"}` + "\n",
+			expected: []outputkit.AddedEntry{
+				{Name: "site/file added here.txt", Hash: fileCID, Size: 13},
+				{Name: "site", Hash: rootCID, Size: 172},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := outputkit.ParseAdded([]byte(test.output))
+			if err != nil {
+				t.Fatalf("Expected no error, but got: %v", err)
+			}
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Errorf("Expected %+v, but got %+v", test.expected, entries)
+			}
+		})
+	}
+	for _, output := range []string{"", rootCID + "\n", `{"Name":"site","Hash":"` + rootCID + `","Size":"large"}`} {
+		if _, err := outputkit.ParseAdded([]byte(output)); err == nil {
+			t.Errorf("Expected an error for output %q", output)
+		}
+	}
+}
+
+// TestParsePinType checks the type of the pin of a single CID is read,
+// without the ancestor of indirect pins.
+func TestParsePinType(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{`{"Keys":{"` + rootCID + `":{"Type":"recursive","Name":""}}}` + "\n", "recursive"},
+		{`{"Keys":{"` + nestedCID + `":{"Type":"indirect through ` + rootCID + `","Name":""}}}` + "\n", "indirect"},
+		{`{"Keys":{"` + fileCID + `":{"Type":"direct"}}}`, "direct"},
+	}
+	for _, test := range tests {
+		pinType, err := outputkit.ParsePinType([]byte(test.output))
+		if err != nil || pinType != test.expected {
+			t.Errorf("Expected %q for output %q, but got %q and %v", test.expected, test.output, pinType, err)
+		}
+	}
+	for _, output := range []string{"", `{"Keys":{}}`, rootCID + " recursive\n"} {
+		if _, err := outputkit.ParsePinType([]byte(output)); err == nil {
+			t.Errorf("Expected an error for output %q", output)
+		}
+	}
+}
+
+// TestParsePins checks the CIDs of a streamed pin listing are read in order.
+func TestParsePins(t *testing.T) {
+	output := `{"Cid":"` + fileCID + `","Type":"recursive"}` + "\n" +
+		`{"Cid":"` + rootCID + `","Type":"recursive"}` + "\n" +
+		`{"Cid":"` + subdirCID + `","Type":"indirect"}` + "\n" +
+		`{"Cid":"` + nestedCID + `","Type":"indirect"}` + "\n"
+	cids, err := outputkit.ParsePins([]byte(output))
+	if expected := []string{fileCID, rootCID, subdirCID, nestedCID}; err != nil || !reflect.DeepEqual(cids, expected) {
+		t.Errorf("Expected %v, but got %v and %v", expected, cids, err)
+	}
+
+	if cids, err := outputkit.ParsePins(nil); err != nil || len(cids) != 0 {
+		t.Errorf("Expected no pins, but got %v and %v", cids, err)
+	}
+	if _, err := outputkit.ParsePins([]byte(`{"Cid":"` + fileCID + `","Type":`)); err == nil {
+		t.Error("Expected an error for a truncated listing")
+	}
+}
+
+// TestParseProviders checks only the provider events are counted, and an
+// event cut short by a cancelled lookup is ignored.
+func TestParseProviders(t *testing.T) {
+	output := `{"Extra":"","ID":"` + peerID + `","Responses":null,"Type":0}` + "\n" +
+		`{"Extra":"","ID":"` + peerID + `","Responses":[],"Type":1}` + "\n" +
+		`{"Extra":"","ID":"","Responses":[{"ID":"` + peerID + `","Addrs":[]}],"Type":4}` + "\n" +
+		`{"Extra":"","ID":"","Responses":[{"ID":"` + peerID + `","Addrs":[]}],"Type":4}` + "\n" +
+		`{"Extra":"","ID":"","Responses":[{"ID":"12D3KooWOther`
+	providers := outputkit.ParseProviders([]byte(output))
+	if expected := map[string]struct{}{peerID: {}}; !reflect.DeepEqual(providers, expected) {
+		t.Errorf("Expected %v, but got %v", expected, providers)
+	}
+	if providers := outputkit.ParseProviders(nil); len(providers) != 0 {
+		t.Errorf("Expected no providers, but got %v", providers)
+	}
+}
+
+// TestParseSwarmPeers checks the peer IDs of the connections are read.
+func TestParseSwarmPeers(t *testing.T) {
+	output := `{"Peers":[{"Addr":"/ip4/127.0.0.1/tcp/14001","Peer":"` + peerID + `","Identify":{"ID":"","PublicKey":"","Addresses":null,"AgentVersion":"","Protocols":null}}]}` + "\n"
+	peers, err := outputkit.ParseSwarmPeers([]byte(output))
+	if expected := []string{peerID}; err != nil || !reflect.DeepEqual(peers, expected) {
+		t.Errorf("Expected %v, but got %v and %v", expected, peers, err)
+	}
+
+	peers, err = outputkit.ParseSwarmPeers([]byte(`{"Peers":null}` + "\n"))
+	if err != nil || len(peers) != 0 {
+		t.Errorf("Expected no peers, but got %v and %v", peers, err)
+	}
+	if _, err := outputkit.ParseSwarmPeers([]byte("/ip4/127.0.0.1/tcp/14001/p2p/" + peerID + "\n")); err == nil {
+		t.Error("Expected an error for the text output")
+	}
+}
+
+// TestParseFilesLs checks the entries of an MFS directory are read.
+func TestParseFilesLs(t *testing.T) {
+	output := `{"Entries":[{"Name":"file added here.txt","Type":0,"Size":13,"Hash":"` + fileCID + `"},{"Name":"sub","Type":1,"Size":0,"Hash":"` + subdirCID + `"}]}` + "\n"
+	entries, err := outputkit.ParseFilesLs([]byte(output))
+	expected := []outputkit.FilesEntry{
+		{Name: "file added here.txt", Type: 0, Size: 13, Hash: fileCID},
+		{Name: "sub", Type: outputkit.FilesDirectoryType, Size: 0, Hash: subdirCID},
+	}
+	if err != nil || !reflect.DeepEqual(entries, expected) {
+		t.Errorf("Expected %+v, but got %+v and %v", expected, entries, err)
+	}
+
+	entries, err = outputkit.ParseFilesLs([]byte(`{"Entries":null}` + "\n"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries, but got %+v and %v", entries, err)
+	}
+}
+
+// TestParseFilesStat checks the details of files and directories are read.
+func TestParseFilesStat(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected outputkit.FilesStat
+	}{
+		{
+			`{"Hash":"` + rootCID + `","Size":0,"CumulativeSize":186,"Blocks":2,"Type":"directory"}` + "\n",
+			outputkit.FilesStat{Hash: rootCID, Size: 0, CumulativeSize: 186, Type: "directory"},
+		},
+		{
+			`{"Hash":"` + fileCID + `","Size":13,"CumulativeSize":13,"Blocks":0,"Type":"file"}` + "\n",
+			outputkit.FilesStat{Hash: fileCID, Size: 13, CumulativeSize: 13, Type: "file"},
+		},
+	}
+	for _, test := range tests {
+		stat, err := outputkit.ParseFilesStat([]byte(test.output))
+		if err != nil || *stat != test.expected {
+			t.Errorf("Expected %+v, but got %+v and %v", test.expected, stat, err)
+		}
+	}
+	for _, output := range []string{"", "{}", rootCID + "\n"} {
+		if _, err := outputkit.ParseFilesStat([]byte(output)); err == nil {
+			t.Errorf("Expected an error for output %q", output)
+		}
+	}
+}
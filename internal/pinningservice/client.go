@@ -0,0 +1,85 @@
+package pinningservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client talks to a remote service implementing the Pinning Service API, for
+// example a commercial pinning provider.
+type Client struct {
+	endpoint    string
+	accessToken string
+
+	// HTTPClient is the client used for the requests, `http.DefaultClient`
+	// if nil.
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the service at the endpoint (e.g.
+// "https://api.pinata.cloud/psa") authenticating with the access token.
+func NewClient(endpoint string, accessToken string) *Client {
+	return &Client{
+		endpoint:    strings.TrimRight(endpoint, "/"),
+		accessToken: accessToken,
+	}
+}
+
+// Add asks the service to pin the object and returns the status of the
+// created pin request.
+func (c *Client) Add(ctx context.Context, pin Pin) (PinStatus, error) {
+	body, err := json.Marshal(pin)
+	if err != nil {
+		return PinStatus{}, err
+	}
+	var status PinStatus
+	err = c.do(ctx, http.MethodPost, "/pins", bytes.NewReader(body), &status)
+	return status, err
+}
+
+// Get returns the status of the pin request.
+func (c *Client) Get(ctx context.Context, requestID string) (PinStatus, error) {
+	var status PinStatus
+	err := c.do(ctx, http.MethodGet, "/pins/"+url.PathEscape(requestID), nil, &status)
+	return status, err
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var f failure
+		if err := json.NewDecoder(resp.Body).Decode(&f); err == nil && f.Error.Reason != "" {
+			return fmt.Errorf("pinning service returned status %d: %s: %s", resp.StatusCode, f.Error.Reason, f.Error.Details)
+		}
+		return fmt.Errorf("pinning service returned status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed decoding pinning service response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,473 @@
+// Package pinningservice provides an HTTP handler implementing the IPFS
+// Pinning Service API [0] on top of a local pinning backend, allowing other
+// tools to target the wrapper-managed node as a remote pinning service.
+//
+// [0] https://ipfs.github.io/pinning-services-api-spec/
+package pinningservice
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/randomkit"
+)
+
+// Constants representing the status of a pin request.
+const (
+	StatusQueued  = "queued"
+	StatusPinning = "pinning"
+	StatusPinned  = "pinned"
+	StatusFailed  = "failed"
+)
+
+// defaultLimit and maxLimit mirror the values defined by the specification.
+const (
+	defaultLimit = 10
+	maxLimit     = 1000
+)
+
+// Pinner defines the local pin operations the service translates requests into.
+type Pinner interface {
+	Pin(ctx context.Context, cid string) error
+	Unpin(ctx context.Context, cid string) error
+}
+
+// Pin is the pin object sent by clients.
+type Pin struct {
+	CID     string            `json:"cid"`
+	Name    string            `json:"name,omitempty"`
+	Origins []string          `json:"origins,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// PinStatus is the pin request status returned to clients.
+type PinStatus struct {
+	RequestID string            `json:"requestid"`
+	Status    string            `json:"status"`
+	Created   time.Time         `json:"created"`
+	Pin       Pin               `json:"pin"`
+	Delegates []string          `json:"delegates"`
+	Info      map[string]string `json:"info,omitempty"`
+}
+
+// PinResults is the response body of the list endpoint.
+type PinResults struct {
+	Count   int         `json:"count"`
+	Results []PinStatus `json:"results"`
+}
+
+type failure struct {
+	Error failureReason `json:"error"`
+}
+
+type failureReason struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details,omitempty"`
+}
+
+// Service is an `http.Handler` implementing the Pinning Service API.
+type Service struct {
+	pinner      Pinner
+	accessToken string
+
+	// Delegates optionally returns the multiaddrs of the local node which
+	// clients may connect to in order to speed up content transfer.
+	Delegates func(ctx context.Context) []string
+
+	mu        sync.Mutex
+	requests  map[string]*PinStatus
+	statePath string
+	mux       *http.ServeMux
+}
+
+// New returns a Service which performs pin operations with the pinner and
+// requires clients to authenticate with the access token. An empty access
+// token disables authentication. The pin requests are persisted in the file
+// at the state path, so they survive a restart, and requests which were
+// still queued or pinning when the service stopped are pinned again. An
+// empty state path keeps the requests in memory only.
+func New(pinner Pinner, accessToken string, statePath string) (*Service, error) {
+	s := &Service{
+		pinner:      pinner,
+		accessToken: accessToken,
+		requests:    make(map[string]*PinStatus),
+		statePath:   statePath,
+		mux:         http.NewServeMux(),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	s.mux.HandleFunc("GET /pins", s.listPins)
+	s.mux.HandleFunc("POST /pins", s.addPin)
+	s.mux.HandleFunc("GET /pins/{requestid}", s.getPin)
+	s.mux.HandleFunc("POST /pins/{requestid}", s.replacePin)
+	s.mux.HandleFunc("DELETE /pins/{requestid}", s.removePin)
+
+	for _, req := range s.requests {
+		if req.Status == StatusQueued || req.Status == StatusPinning {
+			go s.pin(req.RequestID, req.Pin.CID)
+		}
+	}
+	return s, nil
+}
+
+// ServeHTTP authenticates the request and dispatches it to the endpoint.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.accessToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.accessToken)) != 1 {
+			writeFailure(w, http.StatusUnauthorized, "UNAUTHORIZED", "access token is missing or invalid")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Service) listPins(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			writeFailure(w, http.StatusBadRequest, "BAD_REQUEST", "limit must be between 1 and 1000")
+			return
+		}
+		limit = n
+	}
+
+	var before, after time.Time
+	for name, t := range map[string]*time.Time{"before": &before, "after": &after} {
+		if v := query.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeFailure(w, http.StatusBadRequest, "BAD_REQUEST", name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	cids := splitList(query.Get("cid"))
+	statuses := splitList(query.Get("status"))
+	if len(statuses) == 0 {
+		statuses = []string{StatusPinned}
+	}
+	name := query.Get("name")
+	match := query.Get("match")
+
+	s.mu.Lock()
+	results := make([]PinStatus, 0)
+	for _, req := range s.requests {
+		if len(cids) > 0 && !contains(cids, req.Pin.CID) {
+			continue
+		}
+		if !contains(statuses, req.Status) {
+			continue
+		}
+		if name != "" && !matchName(req.Pin.Name, name, match) {
+			continue
+		}
+		if !before.IsZero() && !req.Created.Before(before) {
+			continue
+		}
+		if !after.IsZero() && !req.Created.After(after) {
+			continue
+		}
+		results = append(results, *req)
+	}
+	s.mu.Unlock()
+
+	// Newest requests come first as required by the specification.
+	sort.Slice(results, func(i, j int) bool { return results[i].Created.After(results[j].Created) })
+
+	count := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	writeJSON(w, http.StatusOK, PinResults{Count: count, Results: results})
+}
+
+func (s *Service) addPin(w http.ResponseWriter, r *http.Request) {
+	pin, ok := decodePin(w, r)
+	if !ok {
+		return
+	}
+
+	status := &PinStatus{
+		RequestID: randomkit.String(32),
+		Status:    StatusQueued,
+		Created:   time.Now().UTC(),
+		Pin:       pin,
+		Delegates: s.delegates(r.Context()),
+	}
+
+	s.mu.Lock()
+	s.requests[status.RequestID] = status
+	response := *status
+	err := s.save()
+	if err != nil {
+		delete(s.requests, status.RequestID)
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		writeFailure(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", err.Error())
+		return
+	}
+
+	go s.pin(status.RequestID, pin.CID)
+
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+func (s *Service) getPin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	status, ok := s.requests[r.PathValue("requestid")]
+	var response PinStatus
+	if ok {
+		response = *status
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeFailure(w, http.StatusNotFound, "NOT_FOUND", "the specified resource was not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Service) replacePin(w http.ResponseWriter, r *http.Request) {
+	pin, ok := decodePin(w, r)
+	if !ok {
+		return
+	}
+
+	requestID := r.PathValue("requestid")
+	s.mu.Lock()
+	old, found := s.requests[requestID]
+	s.mu.Unlock()
+
+	if !found {
+		writeFailure(w, http.StatusNotFound, "NOT_FOUND", "the specified resource was not found")
+		return
+	}
+
+	status := &PinStatus{
+		RequestID: randomkit.String(32),
+		Status:    StatusQueued,
+		Created:   time.Now().UTC(),
+		Pin:       pin,
+		Delegates: s.delegates(r.Context()),
+	}
+	s.mu.Lock()
+	delete(s.requests, requestID)
+	s.requests[status.RequestID] = status
+	response := *status
+	err := s.save()
+	if err != nil {
+		delete(s.requests, status.RequestID)
+		s.requests[requestID] = old
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		writeFailure(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", err.Error())
+		return
+	}
+
+	// Pin the new content before unpinning the old one so the data does not
+	// become eligible for garbage collection in between.
+	go func(oldCID string) {
+		s.pin(status.RequestID, pin.CID)
+		if oldCID != pin.CID && !s.isReferenced(oldCID) {
+			_ = s.pinner.Unpin(context.Background(), oldCID)
+		}
+	}(old.Pin.CID)
+
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+func (s *Service) removePin(w http.ResponseWriter, r *http.Request) {
+	requestID := r.PathValue("requestid")
+	s.mu.Lock()
+	status, ok := s.requests[requestID]
+	var err error
+	if ok {
+		delete(s.requests, requestID)
+		if err = s.save(); err != nil {
+			s.requests[requestID] = status
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeFailure(w, http.StatusNotFound, "NOT_FOUND", "the specified resource was not found")
+		return
+	}
+	if err != nil {
+		writeFailure(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", err.Error())
+		return
+	}
+
+	// Several requests may pin the same content, only unpin locally once no
+	// other request references it.
+	if !s.isReferenced(status.Pin.CID) {
+		if err := s.pinner.Unpin(r.Context(), status.Pin.CID); err != nil {
+			writeFailure(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", err.Error())
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pin performs the local pin operation and records its outcome.
+func (s *Service) pin(requestID string, cid string) {
+	s.setStatus(requestID, StatusPinning, nil)
+	if err := s.pinner.Pin(context.Background(), cid); err != nil {
+		s.setStatus(requestID, StatusFailed, map[string]string{"error": err.Error()})
+		return
+	}
+	s.setStatus(requestID, StatusPinned, nil)
+}
+
+func (s *Service) setStatus(requestID string, status string, info map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req, ok := s.requests[requestID]; ok {
+		req.Status = status
+		req.Info = info
+		// The status is reported again after a restart if it cannot be
+		// saved, the pin is retried then.
+		_ = s.save()
+	}
+}
+
+// load reads the pin requests persisted at the state path, if any.
+func (s *Service) load() error {
+	if s.statePath == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.statePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read pin requests: %v", err)
+	}
+	if err := json.Unmarshal(b, &s.requests); err != nil {
+		return fmt.Errorf("failed to parse pin requests in `%s`: %v", s.statePath, err)
+	}
+	return nil
+}
+
+// save persists the pin requests at the state path. The file is replaced
+// at once so a crash never leaves it half written. It must be called with
+// the mutex held.
+func (s *Service) save() error {
+	if s.statePath == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.requests)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.statePath), filepath.Base(s.statePath)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to save pin requests: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save pin requests: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save pin requests: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.statePath); err != nil {
+		return fmt.Errorf("failed to save pin requests: %v", err)
+	}
+	return nil
+}
+
+// isReferenced returns true if any remaining request pins the cid.
+func (s *Service) isReferenced(cid string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, req := range s.requests {
+		if req.Pin.CID == cid {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) delegates(ctx context.Context) []string {
+	if s.Delegates == nil {
+		return []string{}
+	}
+	return s.Delegates(ctx)
+}
+
+func decodePin(w http.ResponseWriter, r *http.Request) (Pin, bool) {
+	var pin Pin
+	if err := json.NewDecoder(r.Body).Decode(&pin); err != nil {
+		writeFailure(w, http.StatusBadRequest, "BAD_REQUEST", "request body must be a valid pin object")
+		return pin, false
+	}
+	if pin.CID == "" {
+		writeFailure(w, http.StatusBadRequest, "BAD_REQUEST", "cid is required")
+		return pin, false
+	}
+	return pin, true
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeFailure(w http.ResponseWriter, code int, reason string, details string) {
+	writeJSON(w, code, failure{Error: failureReason{Reason: reason, Details: details}})
+}
+
+func splitList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// matchName applies the text matching strategy defined by the specification.
+func matchName(value string, name string, match string) bool {
+	switch match {
+	case "iexact":
+		return strings.EqualFold(value, name)
+	case "partial":
+		return strings.Contains(value, name)
+	case "ipartial":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(name))
+	default:
+		return value == name
+	}
+}
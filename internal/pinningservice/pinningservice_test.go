@@ -0,0 +1,219 @@
+package pinningservice_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/pinningservice"
+)
+
+// MockPinner is a mock implementation of the Pinner interface for testing.
+type MockPinner struct {
+	mu     sync.Mutex
+	pinned map[string]bool
+}
+
+func (m *MockPinner) Pin(ctx context.Context, cid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pinned[cid] = true
+	return nil
+}
+
+func (m *MockPinner) Unpin(ctx context.Context, cid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pinned, cid)
+	return nil
+}
+
+func (m *MockPinner) isPinned(cid string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pinned[cid]
+}
+
+func newService(t *testing.T, pinner pinningservice.Pinner, statePath string) *pinningservice.Service {
+	t.Helper()
+	svc, err := pinningservice.New(pinner, "secret", statePath)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	return svc
+}
+
+func doRequest(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestUnauthorized checks requests without a valid access token are rejected.
+func TestUnauthorized(t *testing.T) {
+	svc := newService(t, &MockPinner{pinned: map[string]bool{}}, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/pins", nil)
+	rec := httptest.NewRecorder()
+	svc.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d, but got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	for _, header := range []string{"secret", "Basic secret", "bearer secret", "Bearer wrong"} {
+		req = httptest.NewRequest(http.MethodGet, "/pins", nil)
+		req.Header.Set("Authorization", header)
+		rec = httptest.NewRecorder()
+		svc.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d with header %q, but got %d", http.StatusUnauthorized, header, rec.Code)
+		}
+	}
+}
+
+// TestAddGetAndRemovePin checks the full lifecycle of a pin request.
+func TestAddGetAndRemovePin(t *testing.T) {
+	pinner := &MockPinner{pinned: map[string]bool{}}
+	svc := newService(t, pinner, "")
+
+	rec := doRequest(t, svc, http.MethodPost, "/pins", `{"cid":"bafytest","name":"hello"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, but got %d", http.StatusAccepted, rec.Code)
+	}
+	var status pinningservice.PinStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if status.RequestID == "" || status.Pin.CID != "bafytest" {
+		t.Fatalf("Unexpected pin status: %+v", status)
+	}
+
+	// Wait for the background pin to complete.
+	deadline := time.Now().Add(time.Second)
+	for {
+		rec = doRequest(t, svc, http.MethodGet, "/pins/"+status.RequestID, "")
+		if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if status.Status == pinningservice.StatusPinned || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status.Status != pinningservice.StatusPinned || !pinner.isPinned("bafytest") {
+		t.Fatalf("Expected content to be pinned, got status %q", status.Status)
+	}
+
+	rec = doRequest(t, svc, http.MethodGet, "/pins?name=HEL&match=ipartial", "")
+	var results pinningservice.PinResults
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if results.Count != 1 {
+		t.Errorf("Expected 1 result, but got %d", results.Count)
+	}
+
+	rec = doRequest(t, svc, http.MethodDelete, "/pins/"+status.RequestID, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, but got %d", http.StatusAccepted, rec.Code)
+	}
+	if pinner.isPinned("bafytest") {
+		t.Errorf("Expected content to be unpinned")
+	}
+
+	rec = doRequest(t, svc, http.MethodGet, "/pins/"+status.RequestID, "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, but got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// TestAddPinMissingCID checks invalid pin objects are rejected.
+func TestAddPinMissingCID(t *testing.T) {
+	svc := newService(t, &MockPinner{pinned: map[string]bool{}}, "")
+
+	rec := doRequest(t, svc, http.MethodPost, "/pins", `{"name":"hello"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, but got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// TestClient checks the client against the service implementation.
+func TestClient(t *testing.T) {
+	pinner := &MockPinner{pinned: map[string]bool{}}
+	server := httptest.NewServer(newService(t, pinner, ""))
+	defer server.Close()
+
+	client := pinningservice.NewClient(server.URL+"/", "secret")
+	status, err := client.Add(context.Background(), pinningservice.Pin{CID: "bafytest", Origins: []string{"/ip4/127.0.0.1/tcp/4001/p2p/PeerA"}})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if status.RequestID == "" || status.Pin.CID != "bafytest" {
+		t.Fatalf("Unexpected pin status: %+v", status)
+	}
+
+	got, err := client.Get(context.Background(), status.RequestID)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if got.RequestID != status.RequestID {
+		t.Errorf("Expected request %s, but got %s", status.RequestID, got.RequestID)
+	}
+
+	unauthorized := pinningservice.NewClient(server.URL, "wrong")
+	if _, err := unauthorized.Get(context.Background(), status.RequestID); err == nil || !strings.Contains(err.Error(), "UNAUTHORIZED") {
+		t.Errorf("Expected an unauthorized error, but got: %v", err)
+	}
+}
+
+// TestPinRequestsSurviveRestart checks the pin requests are still listed by
+// a service started again on the same state file.
+func TestPinRequestsSurviveRestart(t *testing.T) {
+	pinner := &MockPinner{pinned: map[string]bool{}}
+	statePath := filepath.Join(t.TempDir(), "pins.json")
+	svc := newService(t, pinner, statePath)
+
+	rec := doRequest(t, svc, http.MethodPost, "/pins", `{"cid":"bafytest","name":"hello"}`)
+	var status pinningservice.PinStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	// Wait for the pinned status to be saved, only pinned requests are
+	// listed by default.
+	var restarted *pinningservice.Service
+	var results pinningservice.PinResults
+	deadline := time.Now().Add(time.Second)
+	for {
+		restarted = newService(t, pinner, statePath)
+		rec = doRequest(t, restarted, http.MethodGet, "/pins", "")
+		if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if results.Count > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if results.Count != 1 || results.Results[0].RequestID != status.RequestID {
+		t.Fatalf("Expected request %s to be listed, but got %+v", status.RequestID, results)
+	}
+
+	rec = doRequest(t, restarted, http.MethodDelete, "/pins/"+status.RequestID, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, but got %d", http.StatusAccepted, rec.Code)
+	}
+	rec = doRequest(t, newService(t, pinner, statePath), http.MethodGet, "/pins/"+status.RequestID, "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, but got %d", http.StatusNotFound, rec.Code)
+	}
+}
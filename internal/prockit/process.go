@@ -0,0 +1,123 @@
+package prockit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// Process is a long-lived child process, such as a daemon. Its exit is
+// waited on in the background as soon as it is started, so a process which
+// exits on its own (crash, killed by the user, etc) never lingers as a
+// zombie and releases its resources right away, however often the app
+// restarts it.
+type Process struct {
+	cmd      *exec.Cmd
+	done     chan struct{}
+	err      error
+	expected atomic.Bool
+}
+
+// Start starts the command and begins waiting for it.
+//
+// Example:
+//
+//	process, err := prockit.Start(exec.Command("ipfs", "daemon"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer process.Kill()
+func Start(cmd *exec.Cmd) (*Process, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Process{cmd: cmd, done: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+	}()
+	return p, nil
+}
+
+// Pid returns the process id.
+func (p *Process) Pid() int {
+	return p.cmd.Process.Pid
+}
+
+// Exited returns a channel which is closed once the process exited.
+func (p *Process) Exited() <-chan struct{} {
+	return p.done
+}
+
+// Wait blocks until the process exited and returns the result of waiting for
+// it, as `exec.Cmd.Wait` does. It may be called any number of times.
+func (p *Process) Wait() error {
+	<-p.done
+	return p.err
+}
+
+// Kill kills the process, unless it already exited, and waits for it. Use
+// `IsKilled` to tell the expected error of a killed process apart from
+// others.
+func (p *Process) Kill() error {
+	p.ExpectExit()
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return p.Wait()
+}
+
+// Stop asks the process to exit with `terminate`, for example by calling
+// `Terminate`, and kills it if it did not exit within the grace period. The
+// process is killed right away if the grace period is not positive or
+// `terminate` fails. It returns whether the process had to be killed, along
+// with the result of waiting for it.
+func (p *Process) Stop(grace time.Duration, terminate func() error) (bool, error) {
+	return p.StopContext(context.Background(), grace, terminate)
+}
+
+// StopContext is `Stop`, except the process is also killed as soon as the
+// context is done, even before the grace period elapsed.
+func (p *Process) StopContext(ctx context.Context, grace time.Duration, terminate func() error) (bool, error) {
+	p.ExpectExit()
+	if grace > 0 && ctx.Err() == nil {
+		if err := terminate(); err == nil || errors.Is(err, os.ErrProcessDone) {
+			timer := time.NewTimer(grace)
+			defer timer.Stop()
+			select {
+			case <-p.done:
+				return false, p.err
+			case <-timer.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+	select {
+	case <-p.done:
+		return false, p.err
+	default:
+	}
+	return true, p.Kill()
+}
+
+// ExpectExit records that the process is about to be stopped on purpose,
+// for example by signalling it through other means than `Kill`.
+func (p *Process) ExpectExit() {
+	p.expected.Store(true)
+}
+
+// ExitExpected returns true if the process was stopped on purpose with `Kill`
+// or after `ExpectExit`, as opposed to exiting on its own.
+func (p *Process) ExitExpected() bool {
+	return p.expected.Load()
+}
+
+// IsKilled returns true if the error returned by `Process.Wait` is the
+// expected result of the process being killed.
+func IsKilled(err error) bool {
+	var exitError *exec.ExitError
+	return errors.As(err, &exitError) && exitError.ProcessState.ExitCode() == -1
+}
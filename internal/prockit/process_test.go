@@ -0,0 +1,127 @@
+//go:build !windows
+
+package prockit_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+)
+
+// TestProcessReaped checks a process which exits on its own gets waited on
+// without anyone calling Wait, so it does not linger as a zombie.
+func TestProcessReaped(t *testing.T) {
+	process, err := prockit.Start(exec.Command("sh", "-c", "exit 3"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	select {
+	case <-process.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the process to be reaped")
+	}
+	if _, err := os.Stat("/proc"); err == nil {
+		// A zombie still has its /proc entry, a reaped process does not.
+		if _, err := os.Stat("/proc/" + strconv.Itoa(process.Pid())); !os.IsNotExist(err) {
+			t.Errorf("Expected the process to be gone, but got: %v", err)
+		}
+	}
+	if err := process.Wait(); err == nil || prockit.IsKilled(err) {
+		t.Errorf("Expected the exit status error, but got: %v", err)
+	}
+	if process.ExitExpected() {
+		t.Errorf("Expected the exit of the process to be unexpected")
+	}
+}
+
+// TestProcessKill checks killing a process waits for it and reports it as
+// killed, also when done repeatedly.
+func TestProcessKill(t *testing.T) {
+	process, err := prockit.Start(exec.Command("sleep", "60"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := process.Kill(); !prockit.IsKilled(err) {
+		t.Errorf("Expected the killed error, but got: %v", err)
+	}
+	if !process.ExitExpected() {
+		t.Errorf("Expected the exit of the killed process to be expected")
+	}
+	if err := process.Kill(); !prockit.IsKilled(err) {
+		t.Errorf("Expected killing an exited process to be harmless, but got: %v", err)
+	}
+}
+
+// TestProcessStopGraceful checks a process which exits when asked is not
+// killed.
+func TestProcessStopGraceful(t *testing.T) {
+	process, err := prockit.Start(exec.Command("sleep", "60"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	killed, err := process.Stop(5*time.Second, func() error {
+		return prockit.Terminate(process.Pid())
+	})
+	if killed {
+		t.Errorf("Expected the process to exit without being killed")
+	}
+	if !prockit.IsKilled(err) {
+		t.Errorf("Expected the process to exit from the signal, but got: %v", err)
+	}
+	if !process.ExitExpected() {
+		t.Errorf("Expected the exit of the stopped process to be expected")
+	}
+}
+
+// TestProcessStopEscalates checks a process which ignores the request to
+// exit gets killed once the grace period elapsed.
+func TestProcessStopEscalates(t *testing.T) {
+	process, err := prockit.Start(exec.Command("sh", "-c", "trap '' TERM; sleep 60 & wait"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	// Give the shell time to install the trap.
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	killed, err := process.Stop(300*time.Millisecond, func() error {
+		return prockit.Terminate(process.Pid())
+	})
+	if !killed || !prockit.IsKilled(err) {
+		t.Errorf("Expected the process to be killed, but got %v and %v", killed, err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected the grace period to be waited, but only took %v", elapsed)
+	}
+}
+
+// TestProcessStopContext checks a process which ignores the request to exit
+// gets killed once the context is done, without waiting for the rest of the
+// grace period.
+func TestProcessStopContext(t *testing.T) {
+	process, err := prockit.Start(exec.Command("sh", "-c", "trap '' TERM; sleep 60 & wait"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	// Give the shell time to install the trap.
+	time.Sleep(200 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	killed, err := process.StopContext(ctx, time.Minute, func() error {
+		return prockit.Terminate(process.Pid())
+	})
+	if !killed || !prockit.IsKilled(err) {
+		t.Errorf("Expected the process to be killed, but got %v and %v", killed, err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("Expected the process to be killed when the context is done, but took %v", elapsed)
+	}
+}
@@ -0,0 +1,46 @@
+// Package prockit provides helpers to manage the processes spawned for the
+// commands of the `ipfs` binary.
+package prockit
+
+import (
+	"os/exec"
+	"time"
+)
+
+// DefaultWaitDelay is how long `exec.Cmd.Wait` waits for the output pipes of
+// a cancelled command to be closed before giving up on them, in case some
+// process outside of the group of the command still holds them open.
+const DefaultWaitDelay = 5 * time.Second
+
+// KillGroupOnCancel makes the command run in its own process group and kill
+// the whole group, instead of only the direct child, once its context is
+// cancelled. This way helper processes spawned by the command do not linger
+// after the command was cancelled. It must be called before the command is
+// started.
+//
+// Example:
+//
+//	cmd := exec.CommandContext(ctx, "ipfs", "get", cid)
+//	prockit.KillGroupOnCancel(cmd)
+//	err := cmd.Run()
+func KillGroupOnCancel(cmd *exec.Cmd) {
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = DefaultWaitDelay
+}
+
+// Detach makes the command run in a session of its own, detached from the
+// terminal and the process group of this app, so it keeps running after the
+// app exits. It must be called before the command is started.
+func Detach(cmd *exec.Cmd) {
+	detach(cmd)
+}
+
+// Terminate asks the process to exit gracefully by sending it a `SIGTERM`
+// signal. It returns `os.ErrProcessDone` if the process does not exist
+// anymore and `errors.ErrUnsupported` on Windows, which has no such signal.
+func Terminate(pid int) error {
+	return terminate(pid)
+}
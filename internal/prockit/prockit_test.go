@@ -0,0 +1,68 @@
+//go:build !windows
+
+package prockit_test
+
+import (
+	"bufio"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+)
+
+// TestKillGroupOnCancel checks the helpers spawned by a command are killed
+// along with it when its context is cancelled.
+func TestKillGroupOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The shell spawns a helper, reports its pid and waits for it.
+	cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 60 & echo $!; wait")
+	prockit.KillGroupOnCancel(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	helperPid, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil {
+		t.Fatalf("Expected a pid, but got: %q", line)
+	}
+
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Fatalf("Expected the cancelled command to fail")
+	}
+
+	// The helper is not our child, so poll until it is gone.
+	deadline := time.Now().Add(5 * time.Second)
+	for syscall.Kill(helperPid, 0) == nil {
+		if time.Now().After(deadline) {
+			syscall.Kill(helperPid, syscall.SIGKILL)
+			t.Fatalf("Expected helper process %d to be killed", helperPid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestKillGroupOnCancelFinished checks commands which exit on their own are
+// not affected.
+func TestKillGroupOnCancelFinished(t *testing.T) {
+	cmd := exec.CommandContext(context.Background(), "sh", "-c", "echo done")
+	prockit.KillGroupOnCancel(cmd)
+	output, err := cmd.Output()
+	if err != nil || string(output) != "done\n" {
+		t.Errorf("Expected \"done\", but got %q and %v", output, err)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package prockit
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// detachedProcess is the `DETACHED_PROCESS` process creation flag, which the
+// syscall package does not define.
+const detachedProcess = 0x00000008
+
+// Windows has no process groups which can be signalled like on Unix, so only
+// the direct child gets killed, matching the default of `exec.Cmd`.
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+	}
+}
+
+func terminate(pid int) error {
+	return errors.ErrUnsupported
+}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,61 @@
+// Package rotatekit provides size-based rotation of log files which are
+// written to directly by other processes, such as a detached daemon.
+package rotatekit
+
+import (
+	"fmt"
+	"os"
+)
+
+// RotateIfLarger rotates the file at `path` if it is at least `maxBytes`
+// large: `path` is renamed to `path.1`, `path.1` to `path.2` and so on,
+// keeping at most `maxBackups` rotated files. Nothing happens if the file
+// does not exist, is smaller than `maxBytes` or `maxBytes` is not positive.
+//
+// Example:
+//
+//	if err := RotateIfLarger("./daemon.log", 10<<20, 3); err != nil {
+//	    log.Fatal(err)
+//	}
+func RotateIfLarger(path string, maxBytes int64, maxBackups int) error {
+	if maxBytes <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Size() < maxBytes {
+		return nil
+	}
+
+	if maxBackups <= 0 {
+		return os.Remove(path)
+	}
+
+	// Drop the oldest backup and shift the remaining ones up by one.
+	oldest := fmt.Sprintf("%s.%d", path, maxBackups)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := maxBackups - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", path, i)
+		to := fmt.Sprintf("%s.%d", path, i+1)
+		if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(path, path+".1")
+}
+
+// OpenAppend opens the file at `path` for appending, creating it if needed,
+// after rotating it with `RotateIfLarger`.
+func OpenAppend(path string, maxBytes int64, maxBackups int) (*os.File, error) {
+	if err := RotateIfLarger(path, maxBytes, maxBackups); err != nil {
+		return nil, fmt.Errorf("failed rotating %s: %v", path, err)
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
@@ -0,0 +1,63 @@
+package rotatekit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/rotatekit"
+)
+
+// TestRotateIfLarger checks files get shifted and the oldest backup dropped.
+func TestRotateIfLarger(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemon.log")
+
+	for _, content := range []string{"first", "second", "third"} {
+		f, err := rotatekit.OpenAppend(path, 3, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+		f.WriteString(content)
+		f.Close()
+	}
+
+	expected := map[string]string{
+		path:        "third",
+		path + ".1": "second",
+		path + ".2": "first",
+	}
+	for file, content := range expected {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if string(b) != content {
+			t.Errorf("Expected %s to contain %q, but got %q", file, content, string(b))
+		}
+	}
+
+	// A fourth write must drop the oldest backup.
+	f, err := rotatekit.OpenAppend(path, 3, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	f.Close()
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected at most 2 backups to be kept")
+	}
+}
+
+// TestRotateIfLargerSmallFile checks small files are left untouched.
+func TestRotateIfLargerSmallFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.log")
+	if err := os.WriteFile(path, []byte("ok"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := rotatekit.RotateIfLarger(path, 1024, 2); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("Expected file to not be rotated")
+	}
+}
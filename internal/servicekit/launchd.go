@@ -0,0 +1,185 @@
+package servicekit
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// LaunchdSystemDir is the directory system-wide launchd daemons are
+// installed to.
+const LaunchdSystemDir = "/Library/LaunchDaemons"
+
+var launchdPlistTemplate = template.Must(template.New("plist").Funcs(template.FuncMap{
+	"xml": xmlEscape,
+	"key": envKey,
+	"val": envValue,
+}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>{{ xml .Name }}</string>
+	<key>ProgramArguments</key>
+	<array>
+		<string>{{ xml .BinaryPath }}</string>
+		{{- range .Args }}
+		<string>{{ xml . }}</string>
+		{{- end }}
+	</array>
+	{{- if .Env }}
+	<key>EnvironmentVariables</key>
+	<dict>
+		{{- range .Env }}
+		<key>{{ xml (key .) }}</key>
+		<string>{{ xml (val .) }}</string>
+		{{- end }}
+	</dict>
+	{{- end }}
+	{{- if .WorkingDir }}
+	<key>WorkingDirectory</key>
+	<string>{{ xml .WorkingDir }}</string>
+	{{- end }}
+	{{- if .User }}
+	<key>UserName</key>
+	<string>{{ xml .User }}</string>
+	{{- end }}
+	<key>RunAtLoad</key>
+	<true/>
+	<key>KeepAlive</key>
+	<dict>
+		<key>SuccessfulExit</key>
+		<false/>
+	</dict>
+</dict>
+</plist>
+`))
+
+// Launchd registers services as macOS launchd agents or daemons.
+type Launchd struct {
+	// UserScope installs a launch agent for the current user instead of a
+	// system-wide launch daemon, which does not require root privileges.
+	UserScope bool
+
+	// PlistDir overrides the directory the property list is written to.
+	// Leave empty to use the launchd default for the scope.
+	PlistDir string
+
+	// Runner executes `launchctl`. Leave nil to use `DefaultCommandRunner`.
+	Runner CommandRunner
+}
+
+// RenderPlist returns the content of the property list for the spec. The
+// spec name is used as the launchd label.
+func (l *Launchd) RenderPlist(spec Spec) (string, error) {
+	if l.UserScope {
+		spec.User = "" // Agents always run as the user who loaded them.
+	}
+	var buf bytes.Buffer
+	if err := launchdPlistTemplate.Execute(&buf, spec); err != nil {
+		return "", fmt.Errorf("failed rendering launchd plist: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// PlistPath returns the path of the property list for the service.
+func (l *Launchd) PlistPath(name string) (string, error) {
+	dir := l.PlistDir
+	if dir == "" {
+		if !l.UserScope {
+			dir = LaunchdSystemDir
+		} else {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, "Library", "LaunchAgents")
+		}
+	}
+	return filepath.Join(dir, name+".plist"), nil
+}
+
+// Install writes the property list and loads it, which starts the service
+// and keeps it registered across reboots.
+func (l *Launchd) Install(ctx context.Context, spec Spec) error {
+	plist, err := l.RenderPlist(spec)
+	if err != nil {
+		return err
+	}
+	path, err := l.PlistPath(spec.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(plist), 0644); err != nil {
+		return fmt.Errorf("failed writing launchd plist: %v", err)
+	}
+	return l.launchctl(ctx, "load", "-w", path)
+}
+
+// Uninstall unloads the service and removes the property list.
+func (l *Launchd) Uninstall(ctx context.Context, name string) error {
+	path, err := l.PlistPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil // Nothing to uninstall.
+	}
+	if err := l.launchctl(ctx, "unload", "-w", path); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed removing launchd plist: %v", err)
+	}
+	return nil
+}
+
+// Status returns whether the service is installed and has a running process.
+func (l *Launchd) Status(ctx context.Context, name string) (Status, error) {
+	path, err := l.PlistPath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return StatusNotInstalled, nil
+	}
+
+	// Note: `launchctl list <label>` fails if the service is not loaded and
+	// only includes a "PID" entry while the process runs.
+	output, err := run(l.Runner, ctx, "launchctl", "list", name)
+	if err == nil && strings.Contains(string(output), `"PID" =`) {
+		return StatusRunning, nil
+	}
+	return StatusStopped, nil
+}
+
+func (l *Launchd) launchctl(ctx context.Context, args ...string) error {
+	if output, err := run(l.Runner, ctx, "launchctl", args...); err != nil {
+		return fmt.Errorf("failed running `launchctl %s`: %v, output: %s", strings.Join(args, " "), err, string(output))
+	}
+	return nil
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func envKey(kv string) string {
+	key, _, _ := strings.Cut(kv, "=")
+	return key
+}
+
+func envValue(kv string) string {
+	_, value, _ := strings.Cut(kv, "=")
+	return value
+}
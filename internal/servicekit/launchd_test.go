@@ -0,0 +1,51 @@
+package servicekit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/servicekit"
+)
+
+// TestLaunchdRenderPlist checks the property list contains the escaped
+// program arguments and environment.
+func TestLaunchdRenderPlist(t *testing.T) {
+	l := &servicekit.Launchd{UserScope: true}
+	plist, err := l.RenderPlist(servicekit.Spec{
+		Name:       "ipfs-cli-wrapper",
+		BinaryPath: "/Users/me/R&D/bin/kubo/ipfs",
+		Args:       []string{"daemon"},
+		Env:        []string{"IPFS_PATH=/Users/me/R&D/bin/kubo/data"},
+		User:       "ignored",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	for _, expected := range []string{
+		"<string>ipfs-cli-wrapper</string>",
+		"<string>/Users/me/R&amp;D/bin/kubo/ipfs</string>",
+		"<key>IPFS_PATH</key>",
+		"<string>/Users/me/R&amp;D/bin/kubo/data</string>",
+	} {
+		if !strings.Contains(plist, expected) {
+			t.Errorf("Expected plist to contain %q, but got:\n%s", expected, plist)
+		}
+	}
+	if strings.Contains(plist, "UserName") {
+		t.Errorf("Expected launch agents to not set a user name")
+	}
+}
+
+// TestLaunchdStatusNotInstalled checks a missing plist is reported as not installed.
+func TestLaunchdStatusNotInstalled(t *testing.T) {
+	l := &servicekit.Launchd{PlistDir: t.TempDir()}
+	status, err := l.Status(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if status != servicekit.StatusNotInstalled {
+		t.Errorf("Expected status %q, but got %q", servicekit.StatusNotInstalled, status)
+	}
+}
@@ -0,0 +1,125 @@
+package servicekit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TaskScheduler registers services as Windows scheduled tasks which start
+// the program on boot (or logon, for the current user only).
+//
+// DEVELOPERS NOTE:
+// A program registered with the Windows Service Control Manager must
+// implement the service control protocol, otherwise Windows terminates it
+// with error 1053 shortly after starting. Since the `ipfs` binary does not
+// implement that protocol, a scheduled task is used as the reliable
+// equivalent of a service.
+type TaskScheduler struct {
+	// UserScope runs the task when the current user logs on instead of on
+	// boot as the SYSTEM account, which does not require administrator
+	// privileges.
+	UserScope bool
+
+	// ScriptDir overrides the directory of the launcher script the task
+	// executes. Leave empty to use the working directory of the spec.
+	ScriptDir string
+
+	// Runner executes `schtasks`. Leave nil to use `DefaultCommandRunner`.
+	Runner CommandRunner
+}
+
+// RenderScript returns the content of the batch script which sets up the
+// environment and runs the program. A script is used because the command
+// line of a scheduled task can neither set environment variables nor exceed
+// 261 characters.
+func (ts *TaskScheduler) RenderScript(spec Spec) string {
+	var b strings.Builder
+	b.WriteString("@echo off\r\n")
+	for _, kv := range spec.Env {
+		fmt.Fprintf(&b, "set \"%s\"\r\n", kv)
+	}
+	if spec.WorkingDir != "" {
+		fmt.Fprintf(&b, "cd /d \"%s\"\r\n", spec.WorkingDir)
+	}
+	b.WriteString(quoteWindowsArg(spec.BinaryPath))
+	for _, arg := range spec.Args {
+		b.WriteString(" " + quoteWindowsArg(arg))
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// ScriptPath returns the path of the launcher script for the service.
+func (ts *TaskScheduler) ScriptPath(spec Spec) string {
+	dir := ts.ScriptDir
+	if dir == "" {
+		dir = spec.WorkingDir
+	}
+	return filepath.Join(dir, spec.Name+".cmd")
+}
+
+// Install writes the launcher script, registers the task and runs it.
+func (ts *TaskScheduler) Install(ctx context.Context, spec Spec) error {
+	path := ts.ScriptPath(spec)
+	if err := os.WriteFile(path, []byte(ts.RenderScript(spec)), 0755); err != nil {
+		return fmt.Errorf("failed writing launcher script: %v", err)
+	}
+
+	args := []string{"/Create", "/F", "/TN", spec.Name, "/TR", quoteWindowsArg(path)}
+	if ts.UserScope {
+		args = append(args, "/SC", "ONLOGON")
+	} else {
+		args = append(args, "/SC", "ONSTART", "/RU", "SYSTEM")
+		if spec.User != "" {
+			args[len(args)-1] = spec.User
+		}
+	}
+	if err := ts.schtasks(ctx, args...); err != nil {
+		return err
+	}
+	return ts.schtasks(ctx, "/Run", "/TN", spec.Name)
+}
+
+// Uninstall stops and deletes the task.
+func (ts *TaskScheduler) Uninstall(ctx context.Context, name string) error {
+	if status, err := ts.Status(ctx, name); err != nil || status == StatusNotInstalled {
+		return err
+	}
+	// Ignore the error as the task may not be running.
+	_ = ts.schtasks(ctx, "/End", "/TN", name)
+	return ts.schtasks(ctx, "/Delete", "/F", "/TN", name)
+}
+
+// Status returns whether the task is registered and currently running.
+func (ts *TaskScheduler) Status(ctx context.Context, name string) (Status, error) {
+	output, err := run(ts.Runner, ctx, "schtasks", "/Query", "/TN", name, "/FO", "LIST")
+	if err != nil {
+		return StatusNotInstalled, nil
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "Status" && strings.TrimSpace(value) == "Running" {
+			return StatusRunning, nil
+		}
+	}
+	return StatusStopped, nil
+}
+
+func (ts *TaskScheduler) schtasks(ctx context.Context, args ...string) error {
+	if output, err := run(ts.Runner, ctx, "schtasks", args...); err != nil {
+		return fmt.Errorf("failed running `schtasks %s`: %v, output: %s", strings.Join(args, " "), err, string(output))
+	}
+	return nil
+}
+
+// quoteWindowsArg quotes the argument if it contains whitespace. Unlike
+// `quoteArg`, backslashes are kept as is since they are path separators.
+func quoteWindowsArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t") {
+		return arg
+	}
+	return `"` + arg + `"`
+}
@@ -0,0 +1,40 @@
+package servicekit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/servicekit"
+)
+
+// TestTaskSchedulerRenderScript checks the launcher script sets the
+// environment before running the program.
+func TestTaskSchedulerRenderScript(t *testing.T) {
+	ts := &servicekit.TaskScheduler{}
+	script := ts.RenderScript(servicekit.Spec{
+		BinaryPath: `C:\My App\bin\kubo\ipfs.exe`,
+		Args:       []string{"daemon"},
+		Env:        []string{`IPFS_PATH=C:\My App\bin\kubo\data`},
+	})
+
+	expected := "@echo off\r\nset \"IPFS_PATH=C:\\My App\\bin\\kubo\\data\"\r\n\"C:\\My App\\bin\\kubo\\ipfs.exe\" daemon\r\n"
+	if script != expected {
+		t.Errorf("Expected script %q, but got %q", expected, script)
+	}
+}
+
+// TestTaskSchedulerStatus checks the task status is parsed from `schtasks`.
+func TestTaskSchedulerStatus(t *testing.T) {
+	ts := &servicekit.TaskScheduler{
+		Runner: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("TaskName:      \\ipfs\r\nStatus:        Running\r\n"), nil
+		},
+	}
+	status, err := ts.Status(context.Background(), "ipfs")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if status != servicekit.StatusRunning {
+		t.Errorf("Expected status %q, but got %q", servicekit.StatusRunning, status)
+	}
+}
@@ -0,0 +1,105 @@
+// Package servicekit provides utility functions for registering a program as
+// an operating system service (for example a systemd unit) so it is started
+// on boot and restarted on failure independently of the application which
+// registered it.
+package servicekit
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Status represents the state of a registered service.
+type Status string
+
+// Constants representing the states a service can be in.
+const (
+	StatusRunning      Status = "running"
+	StatusStopped      Status = "stopped"
+	StatusNotInstalled Status = "not-installed"
+)
+
+// Manager registers and unregisters services with the service manager of
+// the operating system.
+type Manager interface {
+	// Install registers the service, enables it to start on boot and
+	// starts it right away.
+	Install(ctx context.Context, spec Spec) error
+
+	// Uninstall stops the service and removes its registration.
+	Uninstall(ctx context.Context, name string) error
+
+	// Status returns the state of the service.
+	Status(ctx context.Context, name string) (Status, error)
+}
+
+// NewManager returns the Manager for the operating system (a `runtime.GOOS`
+// value). If `userScope` is true the service is registered for the current
+// user only, which does not require administrator privileges.
+func NewManager(goos string, userScope bool) (Manager, error) {
+	switch goos {
+	case "linux":
+		return &Systemd{UserScope: userScope}, nil
+	case "darwin":
+		return &Launchd{UserScope: userScope}, nil
+	case "windows":
+		return &TaskScheduler{UserScope: userScope}, nil
+	default:
+		return nil, fmt.Errorf("services are not supported on `%s`", goos)
+	}
+}
+
+// Spec describes the program to run as a service.
+type Spec struct {
+	// Name is the service name, for example "ipfs-cli-wrapper".
+	Name string
+
+	// Description is a human readable description of the service.
+	Description string
+
+	// BinaryPath is the absolute path of the executable to run.
+	BinaryPath string
+
+	// Args are the arguments passed to the executable.
+	Args []string
+
+	// Env holds extra environment variables in `KEY=value` form.
+	Env []string
+
+	// WorkingDir is the absolute path of the directory the service runs in.
+	WorkingDir string
+
+	// User is the account a system-wide service runs as. Leave empty to use
+	// the service manager default.
+	User string
+}
+
+// CommandRunner executes the program and returns its combined output. The
+// service managers accept a custom runner to allow for mocking in tests.
+type CommandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// DefaultCommandRunner executes the program with `os/exec`.
+func DefaultCommandRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// run executes the program with the runner, or `DefaultCommandRunner` if nil.
+func run(runner CommandRunner, ctx context.Context, name string, args ...string) ([]byte, error) {
+	if runner == nil {
+		runner = DefaultCommandRunner
+	}
+	return runner(ctx, name, args...)
+}
+
+// quoteArg quotes the argument if it contains whitespace or quotes so the
+// service manager treats it as a single argument.
+func quoteArg(arg string) string {
+	if !strings.ContainsAny(arg, " \t\"\\") {
+		return arg
+	}
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
+}
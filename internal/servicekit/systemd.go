@@ -0,0 +1,187 @@
+package servicekit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// SystemdSystemUnitDir is the directory system-wide unit files are installed to.
+const SystemdSystemUnitDir = "/etc/systemd/system"
+
+var systemdUnitTemplate = template.Must(template.New("unit").Funcs(template.FuncMap{
+	"quote":      systemdQuote,
+	"execArg":    systemdExecArg,
+	"specifiers": escapeSystemdSpecifiers,
+}).Parse(`[Unit]
+Description={{ specifiers .Spec.Description }}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=notify
+ExecStart={{ execArg .Spec.BinaryPath }}{{ range .Spec.Args }} {{ execArg . }}{{ end }}
+{{- range .Spec.Env }}
+Environment={{ quote . }}
+{{- end }}
+{{- if .Spec.WorkingDir }}
+WorkingDirectory={{ quote .Spec.WorkingDir }}
+{{- end }}
+{{- if and .Spec.User (not .UserScope) }}
+User={{ specifiers .Spec.User }}
+{{- end }}
+Restart=on-failure
+RestartSec=5s
+
+[Install]
+WantedBy={{ if .UserScope }}default.target{{ else }}multi-user.target{{ end }}
+`))
+
+// Systemd registers services as systemd units.
+type Systemd struct {
+	// UserScope installs the unit for the current user (`systemctl --user`)
+	// instead of system-wide, which does not require root privileges.
+	UserScope bool
+
+	// UnitDir overrides the directory the unit file is written to. Leave
+	// empty to use the systemd default for the scope.
+	UnitDir string
+
+	// Runner executes `systemctl`. Leave nil to use `DefaultCommandRunner`.
+	Runner CommandRunner
+}
+
+// RenderUnit returns the content of the unit file for the spec. Values
+// containing line breaks are rejected, as they would add directives to the
+// unit.
+func (s *Systemd) RenderUnit(spec Spec) (string, error) {
+	values := append([]string{spec.Name, spec.Description, spec.BinaryPath, spec.WorkingDir, spec.User}, spec.Args...)
+	for _, value := range append(values, spec.Env...) {
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("failed rendering systemd unit: line break in value %q", value)
+		}
+	}
+	var buf bytes.Buffer
+	data := struct {
+		Spec      Spec
+		UserScope bool
+	}{spec, s.UserScope}
+	if err := systemdUnitTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed rendering systemd unit: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// escapeSystemdSpecifiers escapes the `%` of the value so systemd does not
+// expand it as a specifier, e.g. `%h` as the home directory.
+func escapeSystemdSpecifiers(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// systemdQuote quotes the value of a setting with `quoteArg`, escaping its
+// specifiers.
+func systemdQuote(value string) string {
+	return quoteArg(escapeSystemdSpecifiers(value))
+}
+
+// systemdExecArg quotes an argument of `ExecStart` like `systemdQuote`, also
+// escaping the `$` which systemd expands as environment variables there.
+func systemdExecArg(arg string) string {
+	return strings.ReplaceAll(systemdQuote(arg), "$", "$$")
+}
+
+// UnitPath returns the path of the unit file for the service.
+func (s *Systemd) UnitPath(name string) (string, error) {
+	dir := s.UnitDir
+	if dir == "" {
+		if !s.UserScope {
+			dir = SystemdSystemUnitDir
+		} else {
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(configDir, "systemd", "user")
+		}
+	}
+	return filepath.Join(dir, name+".service"), nil
+}
+
+// Install writes the unit file, reloads systemd and enables and starts the
+// service.
+func (s *Systemd) Install(ctx context.Context, spec Spec) error {
+	unit, err := s.RenderUnit(spec)
+	if err != nil {
+		return err
+	}
+	path, err := s.UnitPath(spec.Name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to make directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(unit), 0644); err != nil {
+		return fmt.Errorf("failed writing systemd unit: %v", err)
+	}
+	if err := s.systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+	return s.systemctl(ctx, "enable", "--now", spec.Name+".service")
+}
+
+// Uninstall stops and disables the service, removes the unit file and
+// reloads systemd.
+func (s *Systemd) Uninstall(ctx context.Context, name string) error {
+	path, err := s.UnitPath(name)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil // Nothing to uninstall.
+	}
+	if err := s.systemctl(ctx, "disable", "--now", name+".service"); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed removing systemd unit: %v", err)
+	}
+	return s.systemctl(ctx, "daemon-reload")
+}
+
+// Status returns whether the unit is installed and active.
+func (s *Systemd) Status(ctx context.Context, name string) (Status, error) {
+	path, err := s.UnitPath(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return StatusNotInstalled, nil
+	}
+
+	args := []string{"is-active", name + ".service"}
+	if s.UserScope {
+		args = append([]string{"--user"}, args...)
+	}
+	// Note: `is-active` exits with a non-zero code for inactive units, so
+	// only the printed state is relevant.
+	output, _ := run(s.Runner, ctx, "systemctl", args...)
+	if strings.TrimSpace(string(output)) == "active" {
+		return StatusRunning, nil
+	}
+	return StatusStopped, nil
+}
+
+func (s *Systemd) systemctl(ctx context.Context, args ...string) error {
+	if s.UserScope {
+		args = append([]string{"--user"}, args...)
+	}
+	if output, err := run(s.Runner, ctx, "systemctl", args...); err != nil {
+		return fmt.Errorf("failed running `systemctl %s`: %v, output: %s", strings.Join(args, " "), err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package servicekit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/servicekit"
+)
+
+// TestSystemdRenderUnit checks the unit file contains the command, the
+// environment and the install target.
+func TestSystemdRenderUnit(t *testing.T) {
+	s := &servicekit.Systemd{}
+	unit, err := s.RenderUnit(servicekit.Spec{
+		Name:        "ipfs-cli-wrapper",
+		Description: "IPFS daemon",
+		BinaryPath:  "/opt/my app/bin/kubo/ipfs",
+		Args:        []string{"daemon", "--enable-gc=true"},
+		Env:         []string{"IPFS_PATH=/opt/my app/bin/kubo/data"},
+		User:        "ipfs",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	for _, expected := range []string{
+		`ExecStart="/opt/my app/bin/kubo/ipfs" daemon --enable-gc=true`,
+		`Environment="IPFS_PATH=/opt/my app/bin/kubo/data"`,
+		"User=ipfs",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(unit, expected) {
+			t.Errorf("Expected unit to contain %q, but got:\n%s", expected, unit)
+		}
+	}
+}
+
+// TestSystemdRenderUnitEscaping checks the specifiers and variables systemd
+// expands are escaped, and line breaks which would add directives rejected.
+func TestSystemdRenderUnitEscaping(t *testing.T) {
+	s := &servicekit.Systemd{}
+	unit, err := s.RenderUnit(servicekit.Spec{
+		Name:        "ipfs-cli-wrapper",
+		Description: "IPFS daemon at 100%",
+		BinaryPath:  "/opt/%h/ipfs",
+		Args:        []string{"daemon", "--api=$API", "50%"},
+		Env:         []string{"IPFS_PATH=/data/%i", "PRICE=$5"},
+		WorkingDir:  "/opt/%h",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	for _, expected := range []string{
+		"Description=IPFS daemon at 100%%\n",
+		"ExecStart=/opt/%%h/ipfs daemon --api=$$API 50%%\n",
+		"Environment=IPFS_PATH=/data/%%i\n",
+		"Environment=PRICE=$5\n",
+		"WorkingDirectory=/opt/%%h\n",
+	} {
+		if !strings.Contains(unit, expected) {
+			t.Errorf("Expected unit to contain %q, but got:\n%s", expected, unit)
+		}
+	}
+
+	for _, spec := range []servicekit.Spec{
+		{Name: "ipfs", BinaryPath: "/bin/ipfs", Description: "IPFS\nExecStartPre=/bin/sh -c evil"},
+		{Name: "ipfs", BinaryPath: "/bin/ipfs", User: "ipfs\nUser=root"},
+		{Name: "ipfs", BinaryPath: "/bin/ipfs", Args: []string{"daemon\rExecStartPre=/bin/evil"}},
+		{Name: "ipfs", BinaryPath: "/bin/ipfs", Env: []string{"A=1\nExecStartPre=/bin/evil"}},
+	} {
+		if unit, err := s.RenderUnit(spec); err == nil {
+			t.Errorf("Expected an error for %+v, but got:\n%s", spec, unit)
+		}
+	}
+}
+
+// TestSystemdInstall checks the unit gets written and enabled.
+func TestSystemdInstall(t *testing.T) {
+	var calls []string
+	runner := func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls = append(calls, name+" "+strings.Join(args, " "))
+		return nil, nil
+	}
+
+	dir := t.TempDir()
+	s := &servicekit.Systemd{UserScope: true, UnitDir: dir, Runner: runner}
+	if err := s.Install(context.Background(), servicekit.Spec{Name: "ipfs", BinaryPath: "/bin/ipfs"}); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "ipfs.service")); err != nil {
+		t.Errorf("Expected unit file to exist: %v", err)
+	}
+	expected := []string{"systemctl --user daemon-reload", "systemctl --user enable --now ipfs.service"}
+	if strings.Join(calls, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected calls %q, but got %q", expected, calls)
+	}
+}
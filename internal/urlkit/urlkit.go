@@ -3,6 +3,7 @@
 package urlkit
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,17 +12,19 @@ import (
 
 // URLDownloader defines methods for downloading files.
 type URLDownloader interface {
-	DownloadFile(url, destination string) error
+	DownloadFile(ctx context.Context, url, destination string) error
 }
 
 // DefaultURLKit is the default implementation of URLDownloader.
 type DefaultURLKit struct{}
 
 // DownloadFile downloads a file from the specified URL and saves it to the specified file path.
-// It handles creating the destination file, making the HTTP GET request, and writing the response
-// body to the file. If the HTTP response status is not OK (200), it returns an error.
+// The body is written to a ".tmp" file next to the destination which is renamed into place once
+// complete, so the destination never holds a partial file. On failure, including an HTTP response
+// status other than OK (200), nothing is left behind.
 //
 // Parameters:
+// - ctx (context.Context): Cancels the download, or limits its duration with a deadline.
 // - fromUrl (string): The URL of the file to download.
 // - saveToFilepath (string): The local file path where the downloaded file should be saved.
 //
@@ -31,35 +34,48 @@ type DefaultURLKit struct{}
 //
 // Example:
 //
-//	err := DownloadFile("https://example.com/file.txt", "/local/path/to/file.txt")
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+//	defer cancel()
+//	err := DownloadFile(ctx, "https://example.com/file.txt", "/local/path/to/file.txt")
 //	if err != nil {
 //	    log.Fatalf("Failed to download file: %v", err)
 //	}
-func (d *DefaultURLKit) DownloadFile(fromUrl string, saveToFilepath string) (err error) {
-	// Create the file
-	out, err := os.Create(saveToFilepath)
+func (d *DefaultURLKit) DownloadFile(ctx context.Context, fromUrl string, saveToFilepath string) (err error) {
+	// Get the data from the specified URL
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fromUrl, nil)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
-
-	// Get the data from the specified URL
-	resp, err := http.Get(fromUrl)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	// Check server response status
+	// Check server response status before creating any file
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("bad status: %s", resp.Status)
 	}
 
-	// Write the body to the file
-	_, err = io.Copy(out, resp.Body)
+	// Create the temporary file, which is removed unless it was renamed
+	tmpFilepath := saveToFilepath + ".tmp"
+	out, err := os.Create(tmpFilepath)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(tmpFilepath)
+		}
+	}()
 
-	return nil
+	// Write the body to the file
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFilepath, saveToFilepath)
 }
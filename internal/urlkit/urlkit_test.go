@@ -1,13 +1,16 @@
 package urlkit_test
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bartmika/ipfs-cli-wrapper/internal/urlkit"
 )
@@ -27,7 +30,7 @@ func TestDownloadFileSuccess(t *testing.T) {
 
 	urlDownloader := &urlkit.DefaultURLKit{}
 
-	err := urlDownloader.DownloadFile(server.URL, tempFile)
+	err := urlDownloader.DownloadFile(context.Background(), server.URL, tempFile)
 	if err != nil {
 		t.Fatalf("Expected no error, but got: %v", err)
 	}
@@ -61,8 +64,9 @@ func TestDownloadFileHTTPError(t *testing.T) {
 	defer server.Close()
 
 	urlDownloader := &urlkit.DefaultURLKit{}
+	destination := filepath.Join(t.TempDir(), "should_not_exist.txt")
 
-	err := urlDownloader.DownloadFile(server.URL, "should_not_exist.txt")
+	err := urlDownloader.DownloadFile(context.Background(), server.URL, destination)
 	if err == nil {
 		t.Fatal("Expected an error, but got none")
 	}
@@ -71,6 +75,9 @@ func TestDownloadFileHTTPError(t *testing.T) {
 	if err.Error() != expectedError {
 		t.Errorf("Expected error %q, but got %q", expectedError, err.Error())
 	}
+	if _, err := os.Stat(destination); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be created, but got: %v", err)
+	}
 }
 
 // TestDownloadFileNetworkError tests the download function when there is a network error.
@@ -79,7 +86,7 @@ func TestDownloadFileNetworkError(t *testing.T) {
 
 	urlDownloader := &urlkit.DefaultURLKit{}
 
-	err := urlDownloader.DownloadFile(invalidURL, "should_not_exist.txt")
+	err := urlDownloader.DownloadFile(context.Background(), invalidURL, filepath.Join(t.TempDir(), "should_not_exist.txt"))
 	if err == nil {
 		t.Fatal("Expected an error, but got none")
 	}
@@ -103,8 +110,58 @@ func TestDownloadFileWriteError(t *testing.T) {
 	invalidFilePath := "/invalid_path/testfile.txt"
 
 	urlDownloader := &urlkit.DefaultURLKit{}
-	err := urlDownloader.DownloadFile(server.URL, invalidFilePath)
+	err := urlDownloader.DownloadFile(context.Background(), server.URL, invalidFilePath)
 	if err == nil {
 		t.Fatal("Expected an error, but got none")
 	}
 }
+
+// TestDownloadFilePartialBody tests the download function when the connection drops midway,
+// which must not leave a partial file behind.
+func TestDownloadFilePartialBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("Test file content"))
+	}))
+	defer server.Close()
+
+	destination := filepath.Join(t.TempDir(), "testfile.txt")
+	urlDownloader := &urlkit.DefaultURLKit{}
+	if err := urlDownloader.DownloadFile(context.Background(), server.URL, destination); err == nil {
+		t.Fatal("Expected an error, but got none")
+	}
+	for _, path := range []string{destination, destination + ".tmp"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to not exist, but got: %v", path, err)
+		}
+	}
+}
+
+// TestDownloadFileContextCanceled tests the download function stops when the context is canceled.
+func TestDownloadFileContextCanceled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("Test file content"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	destination := filepath.Join(t.TempDir(), "testfile.txt")
+	urlDownloader := &urlkit.DefaultURLKit{}
+	err := urlDownloader.DownloadFile(ctx, server.URL, destination)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, but got: %v", err)
+	}
+	for _, path := range []string{destination, destination + ".tmp"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to not exist, but got: %v", path, err)
+		}
+	}
+}
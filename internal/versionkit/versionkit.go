@@ -0,0 +1,54 @@
+package versionkit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a release number in the `major.minor.patch` form.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// Parse parses a release number such as "v0.29.0", "0.29.0" or
+// "0.30.0-rc1". The pre-release and build suffixes are ignored, so a release
+// candidate compares equal to its release.
+func Parse(s string) (Version, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	parts := strings.Split(trimmed, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version `%s`", s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid version `%s`", s)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// Compare returns -1 if v is older than other, 1 if it is newer, or 0 if both
+// are the same release.
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// String returns the release number with the "v" prefix, e.g. "v0.29.0".
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
@@ -0,0 +1,58 @@
+package versionkit_test
+
+import (
+	"testing"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/versionkit"
+)
+
+// TestParse checks the supported notations of release numbers.
+func TestParse(t *testing.T) {
+	tests := map[string]versionkit.Version{
+		"v0.29.0":       {Major: 0, Minor: 29, Patch: 0},
+		"0.29.0\n":      {Major: 0, Minor: 29, Patch: 0},
+		"0.30.0-rc1":    {Major: 0, Minor: 30, Patch: 0},
+		"1.2.3+abcdef0": {Major: 1, Minor: 2, Patch: 3},
+	}
+	for input, expected := range tests {
+		got, err := versionkit.Parse(input)
+		if err != nil {
+			t.Errorf("Expected no error for %q, but got: %v", input, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("Expected %v for %q, but got %v", expected, input, got)
+		}
+	}
+}
+
+// TestParseInvalid checks malformed release numbers are rejected.
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"", "v0.29", "0.29.x", "latest", "0.-1.0"} {
+		if _, err := versionkit.Parse(input); err == nil {
+			t.Errorf("Expected an error for %q, but got none", input)
+		}
+	}
+}
+
+// TestCompare checks releases are ordered numerically rather than
+// alphabetically.
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v0.29.0", "v0.29.0", 0},
+		{"v0.9.0", "v0.29.0", -1},
+		{"v0.29.1", "v0.29.0", 1},
+		{"v1.0.0", "v0.99.99", 1},
+		{"v0.30.0-rc1", "v0.30.0", 0},
+	}
+	for _, test := range tests {
+		a, _ := versionkit.Parse(test.a)
+		b, _ := versionkit.Parse(test.b)
+		if got := a.Compare(b); got != test.expected {
+			t.Errorf("Expected %s compared to %s to be %d, but got %d", test.a, test.b, test.expected, got)
+		}
+	}
+}
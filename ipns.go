@@ -0,0 +1,182 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// IPNSEntry is the IPNS name published by `NamePublish`.
+type IPNSEntry struct {
+	// Name is the IPNS name the value was published under, e.g.
+	// "k51qzi5uqu5d...".
+	Name string
+
+	// Value is the path the name points to, e.g. "/ipfs/<cid>".
+	Value string
+
+	// TTL is how long resolvers may cache the record, as set with
+	// `WithPublishTTL`. It is zero if the record carries the default TTL of
+	// the kubo release, see `NameInspect` to read it.
+	TTL time.Duration
+}
+
+// IPNSRecordInfo describes a published IPNS record, see `NameInspect`.
+type IPNSRecordInfo struct {
+	// Name is the IPNS name the record was published under.
+	Name string
+
+	// Value is the path the name points to, e.g. "/ipfs/<cid>".
+	Value string
+
+	// Sequence is incremented every time the name gets published.
+	Sequence uint64
+
+	// TTL is how long resolvers may cache the record.
+	TTL time.Duration
+
+	// ValidUntil is when the record expires unless it gets republished.
+	ValidUntil time.Time
+
+	// Valid reports whether the record is signed by the key of the name;
+	// InvalidReason tells why not. Check ValidUntil for the expiry.
+	Valid         bool
+	InvalidReason string
+}
+
+// ExpiresIn returns how long the record remains valid, or a negative duration
+// if it already expired.
+func (info *IPNSRecordInfo) ExpiresIn() time.Duration {
+	return time.Until(info.ValidUntil)
+}
+
+func (wrap *ipfsCliWrapper) NameInspect(ctx context.Context, name string) (*IPNSRecordInfo, error) {
+	name = strings.TrimPrefix(name, "/ipns/")
+
+	// Retrieve the raw record from the routing system.
+	getCmd := wrap.command(ctx, "routing", "get", "/ipns/"+name)
+	var stderr bytes.Buffer
+	getCmd.Stderr = &stderr
+	record, err := wrap.output(getCmd)
+	if err != nil {
+		wrap.logger.Error("error retrieving ipns record",
+			slog.String("name", name),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("retrieve ipns record", err, stderr.Bytes())
+	}
+
+	// Decode the record and verify its signature against the name.
+	inspectCmd := wrap.command(ctx, "name", "inspect", "--enc=json", "--dump=false", "--verify="+name)
+	inspectCmd.Stdin = bytes.NewReader(record)
+	stderr.Reset()
+	inspectCmd.Stderr = &stderr
+	output, err := wrap.output(inspectCmd)
+	if err != nil {
+		wrap.logger.Error("error inspecting ipns record",
+			slog.String("name", name),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("inspect ipns record", err, stderr.Bytes())
+	}
+
+	var inspection struct {
+		Entry struct {
+			Value    string    `json:"Value"`
+			Validity time.Time `json:"Validity"`
+			Sequence uint64    `json:"Sequence"`
+			TTL      int64     `json:"TTL"`
+		} `json:"Entry"`
+		Validation struct {
+			Valid  bool   `json:"Valid"`
+			Reason string `json:"Reason"`
+		} `json:"Validation"`
+	}
+	if err := json.Unmarshal(output, &inspection); err != nil {
+		return nil, fmt.Errorf("failed to parse ipns record: %v", err)
+	}
+	return &IPNSRecordInfo{
+		Name:          name,
+		Value:         inspection.Entry.Value,
+		Sequence:      inspection.Entry.Sequence,
+		TTL:           time.Duration(inspection.Entry.TTL),
+		ValidUntil:    inspection.Entry.Validity,
+		Valid:         inspection.Validation.Valid,
+		InvalidReason: inspection.Validation.Reason,
+	}, nil
+}
+
+func (wrap *ipfsCliWrapper) NamePublish(ctx context.Context, cid string, opts ...PublishOption) (*IPNSEntry, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	settings := newPublishSettings(opts)
+	value := cid
+	if !strings.HasPrefix(value, "/") {
+		value = "/ipfs/" + value
+	}
+	args := []string{"name", "publish", "--enc=json"}
+	if settings.key != "" {
+		args = append(args, "--key="+settings.key)
+	}
+	if settings.lifetime > 0 {
+		args = append(args, "--lifetime="+settings.lifetime.String())
+	}
+	if settings.ttl > 0 {
+		args = append(args, "--ttl="+settings.ttl.String())
+	}
+	if settings.allowOffline {
+		args = append(args, "--allow-offline")
+	}
+	cmd := wrap.command(ctx, append(args, "--", value)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error publishing ipns name",
+			slog.String("key", settings.key),
+			slog.String("value", value),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("publish ipns name", err, stderr.Bytes())
+	}
+
+	var published struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	if err := json.Unmarshal(output, &published); err != nil {
+		return nil, fmt.Errorf("failed to parse published ipns name: %v", err)
+	}
+	return &IPNSEntry{Name: published.Name, Value: published.Value, TTL: settings.ttl}, nil
+}
+
+func (wrap *ipfsCliWrapper) NameResolve(ctx context.Context, name string) (string, error) {
+	if !strings.HasPrefix(name, "/ipns/") {
+		name = "/ipns/" + name
+	}
+	cmd := wrap.command(ctx, "name", "resolve", "--enc=json", "--", name)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error resolving ipns name",
+			slog.String("name", name),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return "", wrap.commandError("resolve ipns name", err, stderr.Bytes())
+	}
+
+	var resolved struct {
+		Path string `json:"Path"`
+	}
+	if err := json.Unmarshal(output, &resolved); err != nil {
+		return "", fmt.Errorf("failed to parse resolved ipns name: %v", err)
+	}
+	return resolved.Path, nil
+}
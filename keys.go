@@ -0,0 +1,128 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// KeyInfo describes a key of the keychain of the node, see `KeyList`.
+type KeyInfo struct {
+	// Name is the name of the key, e.g. "self" for the key of the node.
+	Name string `json:"Name"`
+
+	// ID is the peer ID of the key, which is also the IPNS name published
+	// with it, e.g. "k51qzi5uqu5d...".
+	ID string `json:"Id"`
+}
+
+func (wrap *ipfsCliWrapper) KeyGen(ctx context.Context, name string) (*KeyInfo, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+	var key KeyInfo
+	cmd := wrap.command(ctx, "key", "gen", "--enc=json", "--type=ed25519", "--", name)
+	if err := wrap.runKeyCommand(cmd, "generate key", name, &key, nil); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (wrap *ipfsCliWrapper) KeyList(ctx context.Context) ([]KeyInfo, error) {
+	var list struct {
+		Keys []KeyInfo `json:"Keys"`
+	}
+	cmd := wrap.command(ctx, "key", "list", "--enc=json", "-l")
+	if err := wrap.runKeyCommand(cmd, "list keys", "", &list, nil); err != nil {
+		return nil, err
+	}
+	return list.Keys, nil
+}
+
+func (wrap *ipfsCliWrapper) KeyRm(ctx context.Context, name string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+	cmd := wrap.command(ctx, "key", "rm", "--enc=json", "--", name)
+	return wrap.runKeyCommand(cmd, "remove key", name, nil, nil)
+}
+
+func (wrap *ipfsCliWrapper) KeyRename(ctx context.Context, oldName string, newName string) (*KeyInfo, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+	var renamed struct {
+		Now string `json:"Now"`
+		ID  string `json:"Id"`
+	}
+	cmd := wrap.command(ctx, "key", "rename", "--enc=json", "--", oldName, newName)
+	if err := wrap.runKeyCommand(cmd, "rename key", oldName, &renamed, nil); err != nil {
+		return nil, err
+	}
+	return &KeyInfo{Name: renamed.Now, ID: renamed.ID}, nil
+}
+
+func (wrap *ipfsCliWrapper) KeyExport(ctx context.Context, name string) ([]byte, error) {
+	// `ipfs key export` only writes to a file, so export into the temp
+	// directory and read it back.
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+	keyPath := filepath.Join(stageDir, "exported.key")
+
+	// The command cannot run on the daemon, but can alongside it.
+	cmd := wrap.baseCommand(ctx, "key", "export", "--output="+keyPath, "--", name)
+	if err := wrap.runKeyCommand(cmd, "export key", name, nil, nil); err != nil {
+		return nil, err
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exported key: %v", err)
+	}
+	return key, nil
+}
+
+func (wrap *ipfsCliWrapper) KeyImport(ctx context.Context, name string, key []byte) (*KeyInfo, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return nil, err
+	}
+	var imported KeyInfo
+	cmd := wrap.command(ctx, "key", "import", "--enc=json", "--", name, "-")
+	if err := wrap.runKeyCommand(cmd, "import key", name, &imported, key); err != nil {
+		return nil, err
+	}
+	return &imported, nil
+}
+
+// runKeyCommand runs the `ipfs key` command, with the input on stdin if not
+// nil, and decodes its JSON output into the result if not nil.
+func (wrap *ipfsCliWrapper) runKeyCommand(cmd *exec.Cmd, op string, name string, result any, input []byte) error {
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error running ipfs key command",
+			slog.String("op", op),
+			slog.String("name", name),
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return wrap.commandError(op, err, stderr.Bytes())
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(output, result); err != nil {
+		return fmt.Errorf("failed to parse output of %s: %v", op, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package ipfscliwrapper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// xdgAppDirName is the directory created inside the XDG base directories by
+// the `WithXDGLayout` option.
+const xdgAppDirName = "ipfs-cli-wrapper"
+
+// binaryPath returns the absolute path of the `ipfs` binary set with
+// `WithBinaryPath`, or else of the requested kubo release, see
+// `WithKuboVersion`.
+func (wrap *ipfsCliWrapper) binaryPath() string {
+	if wrap.customBinaryPath != "" {
+		return absPath(wrap.customBinaryPath)
+	}
+	return filepath.Join(wrap.kuboVersionDirPath(wrap.kuboVersion), "ipfs")
+}
+
+// repoPath returns the absolute path of the repo of the wrapper-managed
+// node, which is passed to every `ipfs` process as `IPFS_PATH`.
+func (wrap *ipfsCliWrapper) repoPath() string {
+	return absPath(wrap.repoDir)
+}
+
+// denylistDirPath returns the directory kubo loads denylists from.
+func (wrap *ipfsCliWrapper) denylistDirPath() string {
+	return filepath.Join(wrap.repoPath(), "denylists")
+}
+
+// clusterBinaryPath returns the absolute path of the given cluster program.
+func (wrap *ipfsCliWrapper) clusterBinaryPath(program string) string {
+	return absPath(filepath.Join(wrap.binDir, program, program))
+}
+
+// clusterDataPath returns the absolute path of the cluster peer data.
+func (wrap *ipfsCliWrapper) clusterDataPath() string {
+	return absPath(wrap.clusterDataDir)
+}
+
+// applyXDGLayout points the binaries to `$XDG_DATA_HOME/ipfs-cli-wrapper`
+// and the repos to `$XDG_STATE_HOME/ipfs-cli-wrapper`, falling back to the
+// defaults of the XDG Base Directory Specification when the variables are
+// not set.
+func (wrap *ipfsCliWrapper) applyXDGLayout() error {
+	dataHome, err := xdgDir("XDG_DATA_HOME", ".local", "share")
+	if err != nil {
+		return err
+	}
+	stateHome, err := xdgDir("XDG_STATE_HOME", ".local", "state")
+	if err != nil {
+		return err
+	}
+	wrap.binDir = filepath.Join(dataHome, xdgAppDirName, "bin")
+	wrap.repoDir = filepath.Join(stateHome, xdgAppDirName, "kubo")
+	wrap.clusterDataDir = filepath.Join(stateHome, xdgAppDirName, "ipfs-cluster")
+	return nil
+}
+
+// xdgDir returns the directory of the environment variable, or the default
+// directory relative to the home directory if the variable is not set or
+// not absolute as required by the specification.
+func xdgDir(env string, defaultElems ...string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed finding home directory for %s: %v", env, err)
+	}
+	return filepath.Join(append([]string{home}, defaultElems...)...), nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,27 @@
+package ipfscliwrapper_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCustomPathsLeaveWorkingDirectory checks nothing is installed into the
+// default binary directory of the working directory when the binary and the
+// repo are set with `WithBinaryPath` and `WithRepoPath`.
+func TestCustomPathsLeaveWorkingDirectory(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	newFakeKuboWrapper(t)
+	if _, err := os.Stat(filepath.Join(dir, "bin")); !os.IsNotExist(err) {
+		t.Errorf("Expected no bin directory in the working directory, but got: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestContextLifecycle checks a daemon which is not ready before the context
+// of `StartDaemon` is done gets killed, and that `Shutdown` kills the daemon
+// once its context is done.
+func TestContextLifecycle(t *testing.T) {
+	// Reserve a port for the API, which only accepts connections once the
+	// listener below is opened again.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	node, _ := newFakeKuboWrapper(t, ipfscliwrapper.WithAPIAddress("/ip4/127.0.0.1/tcp/"+port))
+	ctx := context.Background()
+
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := node.StartDaemon(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the startup to exceed the deadline, but got: %v", err)
+	}
+	if status := node.Status(); status.Running {
+		t.Errorf("Expected the daemon to be killed, but got: %+v", status)
+	}
+
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	defer listener.Close()
+	if err := node.StartDaemon(ctx); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	expired, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := node.Shutdown(expired); err != nil {
+		t.Errorf("Expected no error, but got: %v", err)
+	}
+	if shutdown := node.LastShutdown(); shutdown == nil || shutdown.Method != ipfscliwrapper.ShutdownKilled {
+		t.Errorf("Expected the daemon to be killed, but got: %+v", shutdown)
+	}
+}
@@ -0,0 +1,27 @@
+package ipfscliwrapper
+
+import (
+	"log/slog"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/logger"
+)
+
+// DefaultLogOutputLimit is the number of bytes of command output kept in the
+// logs unless changed with the `WithLogOutputLimit` option.
+const DefaultLogOutputLimit = 1024
+
+// outputAttr returns the log attribute of the command output, truncated to
+// the limit set with the `WithLogOutputLimit` option.
+func (wrap *ipfsCliWrapper) outputAttr(output []byte) slog.Attr {
+	return slog.String("output", logger.TruncateOutput(output, wrap.logOutputLimit))
+}
+
+// applyLogSampling samples the debug and info logs of the wrapper as
+// configured with the `WithLogSampling` option.
+func (wrap *ipfsCliWrapper) applyLogSampling() {
+	if wrap.logSampling <= 1 {
+		return
+	}
+	wrap.logger = slog.New(logger.NewSamplingHandler(wrap.logger.Handler(), wrap.logSampling))
+	wrap.ipnsRepublisher.logger = wrap.logger
+}
@@ -0,0 +1,39 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+func (wrap *ipfsCliWrapper) FilesFlush(ctx context.Context, mfsPath string) (string, error) {
+	if !strings.HasPrefix(mfsPath, "/") {
+		return "", fmt.Errorf("mfs path must be absolute, got `%s`", mfsPath)
+	}
+
+	// Flushing writes the changes made below the path to the blockstore and
+	// prints the CID the path has afterwards, e.g. {"Cid":"<cid>"}, so the
+	// CID always matches the flushed state even while others modify MFS.
+	cmd := wrap.command(ctx, wrap.cidBaseArgs("files", "flush", "--enc=json", "--", mfsPath)...)
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error flushing mfs path",
+			slog.String("mfs_path", mfsPath),
+			slog.Any("error", err))
+		return "", wrap.commandError("flush mfs path", err, output)
+	}
+
+	var flushed struct {
+		Cid string `json:"Cid"`
+	}
+	if err := json.Unmarshal(output, &flushed); err != nil {
+		return "", newCommandError("decode mfs flush", err, output)
+	}
+	return flushed.Cid, nil
+}
+
+func (wrap *ipfsCliWrapper) FilesRootCID(ctx context.Context) (string, error) {
+	return wrap.FilesFlush(ctx, "/")
+}
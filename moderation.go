@@ -0,0 +1,242 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlockEvent reports content the daemon refused to provide because of a
+// denylist, through its API or its gateway, see `WithBlockEventHook`.
+type BlockEvent struct {
+	// Time is when the daemon refused the content.
+	Time time.Time
+
+	// Path is the refused path, for example "/ipfs/<cid>/index.html".
+	Path string
+
+	// Denylist is the path of the denylist file and Line the line of the
+	// rule which blocked the content.
+	Denylist string
+	Line     int
+}
+
+// BlockEventHook receives the content the daemon refused because of a
+// denylist, one event at a time.
+type BlockEventHook func(event BlockEvent)
+
+const (
+	// blockEventSubsystem is the logging subsystem of kubo reporting the
+	// content refused because of a denylist, at the warn level.
+	blockEventSubsystem = "nopfs-blocks"
+
+	// blockEventBufferSize is how many events are kept for the hook before
+	// further ones are dropped, so a slow hook never stalls the daemon
+	// writing its output.
+	blockEventBufferSize = 256
+
+	// blockEventPollInterval is how often the output file of a detached
+	// daemon is read for new events.
+	blockEventPollInterval = time.Second
+)
+
+// blockEventPattern matches the message kubo logs when refusing content,
+// for example "/ipfs/<cid>: blocked (/repo/denylists/badbits.deny:12)".
+var blockEventPattern = regexp.MustCompile(`^(/\S+): blocked \((.+):(\d+)\)$`)
+
+// parseBlockEvent returns the event reported by the line of the daemon
+// output, which the logger of kubo writes as tab separated time, level,
+// subsystem, source location and message.
+func parseBlockEvent(line string) (BlockEvent, bool) {
+	fields := strings.SplitN(strings.TrimSpace(line), "\t", 5)
+	if len(fields) != 5 || fields[2] != blockEventSubsystem {
+		return BlockEvent{}, false
+	}
+	match := blockEventPattern.FindStringSubmatch(fields[4])
+	if match == nil {
+		return BlockEvent{}, false
+	}
+	event := BlockEvent{Time: time.Now(), Path: match[1], Denylist: match[2]}
+	event.Line, _ = strconv.Atoi(match[3])
+	if t, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		event.Time = t
+	}
+	return event, true
+}
+
+// blockEventWriter scans the output of the daemon written to it for block
+// events.
+type blockEventWriter struct {
+	monitor *blockEventMonitor
+	pending []byte
+}
+
+func (w *blockEventWriter) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if event, ok := parseBlockEvent(string(w.pending[:i])); ok {
+			w.monitor.emit(event)
+		}
+		w.pending = w.pending[i+1:]
+	}
+	return len(p), nil
+}
+
+// blockEventMonitor hands the block events found in the output of the daemon
+// to the hook set with `WithBlockEventHook` in a background goroutine.
+type blockEventMonitor struct {
+	mu     sync.Mutex
+	hook   BlockEventHook
+	events chan BlockEvent
+	logger *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newBlockEventMonitor(logger *slog.Logger) *blockEventMonitor {
+	return &blockEventMonitor{
+		events: make(chan BlockEvent, blockEventBufferSize),
+		logger: logger,
+	}
+}
+
+// writer returns a writer scanning the output of the daemon for block
+// events.
+func (m *blockEventMonitor) writer() io.Writer {
+	return &blockEventWriter{monitor: m}
+}
+
+// emit queues the event for the hook, dropping it if the hook fell behind.
+func (m *blockEventMonitor) emit(event BlockEvent) {
+	m.logger.Info("ipfs content blocked by denylist",
+		slog.String("path", event.Path),
+		slog.String("denylist", event.Denylist),
+		slog.Int("line", event.Line))
+	select {
+	case m.events <- event:
+	default:
+		m.logger.Warn("dropped block event, the hook is falling behind",
+			slog.String("path", event.Path))
+	}
+}
+
+// start launches the background loop calling the hook and, if the path of
+// the output file of a detached daemon is given, following that file for
+// new events. Calling start while the loop is already running does nothing.
+func (m *blockEventMonitor) start(followPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.hook == nil || m.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	go m.run(ctx, m.done, followPath)
+}
+
+// stop terminates the background loop and waits for it to exit.
+func (m *blockEventMonitor) stop() {
+	m.mu.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel, m.done = nil, nil
+	m.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (m *blockEventMonitor) run(ctx context.Context, done chan struct{}, followPath string) {
+	defer close(done)
+
+	var follow <-chan time.Time
+	var offset int64
+	writer := &blockEventWriter{monitor: m}
+	if followPath != "" {
+		// Only report the events from now on.
+		if info, err := os.Stat(followPath); err == nil {
+			offset = info.Size()
+		}
+		ticker := time.NewTicker(blockEventPollInterval)
+		defer ticker.Stop()
+		follow = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-m.events:
+			m.hook(event)
+		case <-follow:
+			offset = m.readNewOutput(followPath, offset, writer)
+		}
+	}
+}
+
+// readNewOutput scans what was appended to the output file since the offset
+// for block events and returns the new offset. The file is read from the
+// start again if it got smaller, as it does when it is rotated.
+func (m *blockEventMonitor) readNewOutput(path string, offset int64, writer *blockEventWriter) int64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return offset
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() < offset {
+		offset, writer.pending = 0, nil
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return offset
+	}
+	n, _ := io.Copy(writer, f)
+	return offset + n
+}
+
+// startBlockEvents makes the daemon report the content it refuses because of
+// a denylist and starts handing those events to the hook set with
+// `WithBlockEventHook`. The output of a daemon attached to this app is
+// scanned as it is written, see `StartDaemonInBackground`, while the output
+// file of a detached daemon is followed.
+func (wrap *ipfsCliWrapper) startBlockEvents() {
+	if wrap.blockEvents.hook == nil {
+		return
+	}
+	// Kubo only logs the refused content at the warn level, which is below
+	// its default level.
+	cmd := wrap.command(context.Background(), "log", "level", blockEventSubsystem, "warn")
+	if output, err := wrap.combinedOutput(cmd); err != nil {
+		wrap.logger.Warn("failed enabling block event logging",
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+	}
+
+	if wrap.daemon != nil && wrap.daemonStderrTail != nil {
+		wrap.blockEvents.start("")
+		return
+	}
+	// A detached daemon writes its output to the files it was started with,
+	// which may not be the ones configured for this run of the app.
+	stderrPath := wrap.daemonStderrPath
+	if info := wrap.readDaemonInfo(); info != nil && wrap.isDaemonAlive(info) {
+		stderrPath = info.StderrPath
+	}
+	if stderrPath == "" {
+		wrap.logger.Warn("block events cannot be followed", slog.Any("error", ErrDaemonOutputDiscarded))
+	}
+	wrap.blockEvents.start(stderrPath)
+}
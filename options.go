@@ -1,6 +1,9 @@
 package ipfscliwrapper
 
 import (
+	"io"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/bartmika/ipfs-cli-wrapper/internal/oskit"
@@ -23,7 +26,8 @@ func WithContinousOperation() Option {
 // WithOverrideBinaryOsAndArch is a functional option to configure our wrapper
 // to use a specific binary. The available `os` options are: darwin, linux,
 // freebsd, openbsd and windows. The available `arch` choices are: arm, arm64,
-// 386, and amd64.
+// 386, and amd64. The binary must be able to run on this machine (e.g. `386`
+// on an `amd64` host), otherwise `NewWrapper` returns `ErrInvalidConfiguration`.
 func WithOverrideBinaryOsAndArch(overrideOS, overrideArch string) Option {
 	return func(wrap *ipfsCliWrapper) {
 		wrap.os = overrideOS
@@ -34,6 +38,9 @@ func WithOverrideBinaryOsAndArch(overrideOS, overrideArch string) Option {
 // WithOverrideDaemonInitialWarmupDuration is a functional option to configure
 // our wrapper to set a custom warmup delay for our app to give a custom delay
 // to allow the `ipfs` to loadup before giving your app execution control.
+// Execution control is given back as soon as the daemon API is reachable, so
+// the delay is the longest wait; it is raised automatically on machines where
+// the daemon was measured to start slower.
 func WithOverrideDaemonInitialWarmupDuration(seconds int) Option {
 	return func(wrap *ipfsCliWrapper) {
 		wrap.daemonInitialWarmupDuration = time.Duration(seconds) * time.Second
@@ -60,6 +67,622 @@ func WithDenylist(denylistFilename string, denylistURL string) Option {
 	}
 }
 
+// WithDenylistFile is a functional option which applies the `denylist` at
+// the local path to the `ipfs` binary running instance, like `WithDenylist`
+// does for a downloaded one, so curated internal blocklists can be applied
+// without hosting them on an HTTP server. The file name must end with
+// ".deny". The file is copied into the repo by every `NewWrapper` call, so
+// changes to it are applied by the next daemon start. Use it several times
+// to apply several denylists.
+func WithDenylistFile(path string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.localDenylists = append(wrap.localDenylists, localDenylist{name: filepath.Base(path), path: path})
+	}
+}
+
+// WithDenylistReader is a functional option which applies the `denylist`
+// read from the reader to the `ipfs` binary running instance, saved under
+// the name, which must end with ".deny". The reader is read once, by
+// `NewWrapper`. Use it several times to apply several denylists.
+//
+// Example:
+//
+//	denylist := strings.NewReader("/ipfs/bafybeihfg3d7rdltd43u3tfvncx7n5loqofbsobojcadtmokrljfthuc7y\n")
+//	wrapper, err := NewWrapper(WithDenylistReader("internal.deny", denylist))
+func WithDenylistReader(name string, r io.Reader) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.localDenylists = append(wrap.localDenylists, localDenylist{name: name, reader: r})
+	}
+}
+
+// WithBlockEventHook is a functional option to receive the content the
+// daemon refuses to provide because of a denylist, through its API or its
+// gateway, so operators can audit the enforcement of their blocklists. The
+// events are found in the output of the daemon; the output of a daemon in
+// continous operation mode is only followed if it is written to files, see
+// `WithDaemonOutputFiles`. `IsBlocked` and `DenylistStatus` report the
+// content they test as well. The hook is called in a background goroutine,
+// and events are dropped rather than stalling the daemon if it falls behind.
+func WithBlockEventHook(hook BlockEventHook) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.blockEvents.hook = hook
+	}
+}
+
+// WithIPNSRepublishInterval is a functional option to configure how often the
+// IPNS names registered with `TrackIPNSName` get republished while the daemon
+// is running. Defaults to `DefaultIPNSRepublishInterval`.
+func WithIPNSRepublishInterval(interval time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.ipnsRepublisher.interval = interval
+	}
+}
+
+// WithIPNSRepublishFailureHook is a functional option to register a callback
+// which gets invoked every time a tracked IPNS name fails to republish.
+func WithIPNSRepublishFailureHook(hook IPNSRepublishFailureHook) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.ipnsRepublisher.onFailure = hook
+	}
+}
+
+// WithClusterService is a functional option to download `ipfs-cluster-service`
+// and run a cluster peer alongside the `ipfs` daemon so your node can take
+// part in an ipfs-cluster [0]. The `secret` is the shared cluster secret and
+// the optional `bootstrap` multiaddresses are peers to join on startup.
+// [0] https://ipfscluster.io/documentation/
+func WithClusterService(secret string, bootstrap ...string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.cluster = &clusterConfig{
+			mode:      ClusterServiceMode,
+			secret:    secret,
+			bootstrap: bootstrap,
+		}
+	}
+}
+
+// WithClusterFollow is a functional option to download `ipfs-cluster-follow`
+// and run it alongside the `ipfs` daemon so your node replicates the pinset
+// of the collaborative cluster [0] with the given name. The `initURL` points
+// to the follower configuration published by the cluster operators.
+// [0] https://ipfscluster.io/documentation/collaborative/joining/
+func WithClusterFollow(clusterName string, initURL string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.cluster = &clusterConfig{
+			mode:          ClusterFollowMode,
+			followName:    clusterName,
+			followInitURL: initURL,
+		}
+	}
+}
+
+// WithPinningServiceAPI is a functional option to serve the IPFS Pinning
+// Service API [0] on the given address (e.g. "127.0.0.1:5003") while the
+// daemon is running. Requests are translated into local pin operations so
+// other tools can use your node as a remote pinning service. Clients must
+// authenticate with the `accessToken` as a bearer token; leave it empty to
+// disable authentication. The pin requests are kept in the repo directory so
+// they are still listed after a restart.
+// [0] https://ipfs.github.io/pinning-services-api-spec/
+func WithPinningServiceAPI(addr string, accessToken string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.pinningServiceAddr = addr
+		wrap.pinningServiceAccessToken = accessToken
+	}
+}
+
+// WithHealthEndpoint is a functional option to serve a health endpoint on
+// the given address (e.g. "127.0.0.1:8081") while the daemon is running. The
+// `/healthz` path responds with `200 OK` while the daemon answers API calls
+// and with `503 Service Unavailable` otherwise, which suits Kubernetes-style
+// probes. The `/status` path additionally returns the daemon state, peer
+// count and repo size as JSON.
+func WithHealthEndpoint(addr string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.healthAddr = addr
+	}
+}
+
+// WithFlatfsDatastore is a functional option to create the repo with the
+// flatfs datastore (the kubo default) using the given parameters. The option
+// only takes effect when the repo is created for the first time.
+func WithFlatfsDatastore(params FlatfsDatastoreParams) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.datastore = &datastoreConfig{typeID: FlatfsDatastoreType, flatfs: params}
+	}
+}
+
+// WithBadgerDatastore is a functional option to create the repo with the
+// badger datastore using the given parameters, which performs better for
+// workloads with many small blocks. The option only takes effect when the
+// repo is created for the first time.
+func WithBadgerDatastore(params BadgerDatastoreParams) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.datastore = &datastoreConfig{typeID: BadgerDatastoreType, badger: params}
+	}
+}
+
+// WithStorageMax is a functional option to set `Datastore.StorageMax`, the
+// amount of disk space the repo may use (e.g. "10GB"). Together with the
+// `--enable-gc` daemon flag used by this package, the daemon runs garbage
+// collection once the repo grows past the watermark of this size.
+func WithStorageMax(size string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.storageMax = size
+	}
+}
+
+// WithAllowedCommands is a functional option to only let the wrapper run the
+// given `ipfs` subcommands, like "cat" or "pin ls". A command allows all the
+// subcommands starting with its words, so "pin" allows "pin add" and
+// "pin ls". Every other subcommand fails with `ErrCommandNotAllowed` instead
+// of running, including the ones the wrapper runs itself, like "init",
+// "config show" and "daemon" when setting up and starting the node.
+func WithAllowedCommands(commands ...string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.allowedCommands = append(wrap.allowedCommands, parseCommands(commands)...)
+	}
+}
+
+// WithDeniedCommands is a functional option to never let the wrapper run the
+// given `ipfs` subcommands, like "key export" or "config". A command denies
+// all the subcommands starting with its words, and takes precedence over
+// `WithAllowedCommands`. Denied subcommands fail with
+// `ErrCommandNotAllowed` instead of running.
+func WithDeniedCommands(commands ...string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.deniedCommands = append(wrap.deniedCommands, parseCommands(commands)...)
+	}
+}
+
+// WithCommandResultHook is a functional option to receive the raw result of
+// every `ipfs` command run by the wrapper: its arguments, stdout, stderr,
+// exit code and duration. The calls still return their typed results, so
+// the hook is for logging or post-processing the output kubo printed. The
+// output is kept in memory until the hook returned, including the content
+// read by `Cat` or `Benchmark`. The long-running `ipfs daemon` and the
+// commands streaming their output, like `RunCommandStreaming`, are not
+// reported.
+func WithCommandResultHook(hook CommandResultHook) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.commandResultHook = hook
+	}
+}
+
+// WithDryRun is a functional option to record the `ipfs` commands the calls
+// of the wrapper would run, like `ipfs add` for `AddFile`, instead of running
+// them. The calls return `ErrDryRun` in place of their result and the exact
+// arguments and environment of the commands are returned by
+// `RecordedCommands`, for testing the logic of an app or previewing what it
+// would do. Setting up the repo in `NewWrapper` and the daemon lifecycle are
+// not affected.
+func WithDryRun() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.dryRun = true
+	}
+}
+
+// WithMinFreeDiskSpace is a functional option to set the disk space, in
+// bytes, which downloading kubo and adding content must leave free, instead
+// of `DefaultMinFreeDiskSpace`. Adds which would not fit, counting the size
+// of the local content, and downloads fail early with
+// `ErrInsufficientDiskSpace` instead of midway. `Preflight` fails below it
+// as well. Set it to 0 to only require room for the content itself.
+func WithMinFreeDiskSpace(bytes uint64) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.minFreeDiskSpace = bytes
+	}
+}
+
+// WithRepoLockTimeout is a functional option to wait up to the duration for
+// another wrapper over the same repo, in this or another process, to finish
+// provisioning the repo in `NewWrapper` or starting the daemon in
+// `StartDaemonInBackground`. By default both fail right away with
+// `ErrRepoInUse` instead. It is also how long `NewWrapper` waits for another
+// wrapper installing binaries into the same bin directory, before failing
+// with `ErrBinDirInUse`.
+func WithRepoLockTimeout(timeout time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.repoLockTimeout = timeout
+	}
+}
+
+// WithDownloadConcurrency is a functional option to set how many of the
+// files needed to provision the node, like the kubo archive, the denylist
+// and the cluster binaries, `NewWrapper` downloads at once, instead of
+// `DefaultDownloadConcurrency`. Set it to 1 to download one at a time.
+func WithDownloadConcurrency(n int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.downloadConcurrency = n
+	}
+}
+
+// WithDownloadProgressHook is a functional option to receive the progress of
+// the downloads of `NewWrapper`, for example to show it while a fresh
+// deployment starts. The hook is called as each download finishes.
+func WithDownloadProgressHook(hook DownloadProgressHook) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.downloadProgressHook = hook
+	}
+}
+
+// WithReadOnly is a functional option to refuse every call which would modify
+// the node with `ErrReadOnly`: adding content, pinning and unpinning,
+// garbage collection, writing to MFS, publishing IPNS names and running
+// modifying subcommands, like `ipfs config <key> <value>`, with
+// `RunCommand`. Retrieving content still works, so the wrapper can be handed
+// to untrusted parts of an app. The setup done by `NewWrapper` and the
+// daemon lifecycle are not affected.
+func WithReadOnly() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.readOnly = true
+	}
+}
+
+// WithGCWatermark is a functional option to let the wrapper collect garbage
+// once the size of the repo reaches the given percentage of its disk budget,
+// see `WithStorageMax`. Unlike `WithStorageGCWatermark`, it does not require
+// the daemon to run with automatic garbage collection. The usage is checked
+// every `DefaultGCWatermarkCheckInterval`, or the interval set with
+// `WithGCWatermarkCheckInterval`, and after a collection the watermark is
+// only armed again once the usage dropped `GCWatermarkHysteresis` points
+// below it.
+func WithGCWatermark(percent int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gcWatermark = percent
+	}
+}
+
+// WithGCWatermarkCheckInterval is a functional option to set how often the
+// usage of the repo is checked against the watermark set with
+// `WithGCWatermark`. Defaults to `DefaultGCWatermarkCheckInterval`.
+func WithGCWatermarkCheckInterval(interval time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gcWatermarkInterval = interval
+	}
+}
+
+// WithGCWatermarkHook is a functional option to register a callback which
+// gets invoked after every garbage collection triggered by the watermark set
+// with `WithGCWatermark`.
+func WithGCWatermarkHook(hook GCWatermarkHook) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gcWatermarkHook = hook
+	}
+}
+
+// WithStorageGCWatermark is a functional option to set
+// `Datastore.StorageGCWatermark`, the percentage of `StorageMax` at which the
+// daemon starts garbage collection. Kubo defaults to 90.
+func WithStorageGCWatermark(percent int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.storageGCWatermark = percent
+	}
+}
+
+// WithAPIAddress is a functional option to set the multiaddress the daemon
+// API listens on (e.g. "/ip4/127.0.0.1/tcp/5011"). The address is written to
+// `Addresses.API` in the repo configuration and passed via the `--api` flag
+// to every command this package executes.
+func WithAPIAddress(addr string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.apiAddr = addr
+	}
+}
+
+// WithDaemonOutputFiles is a functional option to append the stdout and
+// stderr of the daemon running in continous operation mode to the given files
+// instead of discarding them, so detached nodes remain debuggable. Both may
+// be the same file; an empty path discards that stream. Use
+// `WithDaemonOutputRotation` to limit the size of the files.
+func WithDaemonOutputFiles(stdoutPath string, stderrPath string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.daemonStdoutPath = stdoutPath
+		wrap.daemonStderrPath = stderrPath
+	}
+}
+
+// WithDaemonOutputRotation is a functional option to rotate the files set
+// with `WithDaemonOutputFiles` once they reach `maxBytes`, keeping at most
+// `maxBackups` rotated files (e.g. "daemon.log.1"). Since the detached daemon
+// writes to the files directly, the rotation happens every time the daemon
+// gets started by this package.
+func WithDaemonOutputRotation(maxBytes int64, maxBackups int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.daemonOutputMaxBytes = maxBytes
+		wrap.daemonOutputMaxBackups = maxBackups
+	}
+}
+
+// WithGatewayAddress is a functional option to set the multiaddress the
+// daemon HTTP gateway listens on (e.g. "/ip4/127.0.0.1/tcp/8080"). The
+// address is written to `Addresses.Gateway` in the repo configuration.
+func WithGatewayAddress(addr string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gatewayAddr = addr
+	}
+}
+
+// WithSwarmAddresses is a functional option to set the multiaddresses the
+// daemon listens on for connections from other peers (e.g.
+// "/ip4/0.0.0.0/tcp/4001"). The addresses are written to `Addresses.Swarm`
+// in the repo configuration.
+func WithSwarmAddresses(addrs ...string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.swarmAddrs = addrs
+	}
+}
+
+// WithFallbackGateways is a functional option to set the trustless HTTP
+// gateways (e.g. "https://trustless-gateway.link") `FetchWithFallback` falls
+// back to, in order, replacing `DefaultFallbackGateways`.
+func WithFallbackGateways(gateways ...string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.fallbackGateways = gateways
+	}
+}
+
+// WithLocalFetchTimeout is a functional option to set how long
+// `FetchWithFallback` lets the local node retrieve content before falling back
+// to the HTTP gateways. Defaults to `DefaultLocalFetchTimeout`.
+func WithLocalFetchTimeout(timeout time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.localFetchTimeout = timeout
+	}
+}
+
+// WithCIDBase is a functional option to set the multibase, e.g. "base32" or
+// "base36", of the CIDs returned by the calls which add content, list pins or
+// flush MFS, so all returned CIDs are encoded the same way and can be
+// compared as strings. CIDv0 is upgraded to CIDv1 when printed in a base
+// other than base58btc.
+func WithCIDBase(base string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.cidBase = base
+	}
+}
+
+// WithoutGateway is a functional option to turn off the daemon HTTP gateway
+// so no listener besides the API is opened, for apps which only use the RPC
+// API. This clears `Addresses.Gateway` in the repo configuration.
+func WithoutGateway() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gatewayDisabled = true
+	}
+}
+
+// WithAPIBindAddress is a functional option to bind the daemon API to the
+// interface with the given IP (e.g. "127.0.0.1" or "192.168.1.10") while
+// keeping its port. Binding to "0.0.0.0" or "::" exposes the API on all
+// interfaces, which gives anyone who can reach the machine full control over
+// the node, so a warning is logged on every startup in that case.
+func WithAPIBindAddress(ip string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.apiBindIP = ip
+	}
+}
+
+// WithGatewayBindAddress is a functional option to bind the daemon HTTP
+// gateway to the interface with the given IP while keeping its port. Binding
+// to all interfaces logs a warning, see `WithAPIBindAddress`.
+func WithGatewayBindAddress(ip string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.gatewayBindIP = ip
+	}
+}
+
+// WithAutoPorts is a functional option to pick free ports for the API,
+// gateway and swarm addresses on every construction, so many nodes can run
+// side by side (e.g. in parallel tests). Addresses set explicitly with
+// `WithAPIAddress`, `WithGatewayAddress` or `WithSwarmAddresses` are kept.
+// The chosen ports are available through `APIPort`, `GatewayPort` and
+// `SwarmPort`. If the daemon is already running, its ports are kept.
+func WithAutoPorts() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.autoPorts = true
+	}
+}
+
+// WithBinaryPath is a functional option to run the `ipfs` binary at the path,
+// for example one installed by the system package manager, instead of
+// downloading kubo. `NewWrapper` fails if the binary does not exist, and
+// `WithKuboVersion` has no effect.
+func WithBinaryPath(path string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.customBinaryPath = path
+	}
+}
+
+// WithRepoPath is a functional option to keep the repo of the node in the
+// directory, instead of `IPFSDataDirPath` or the directory chosen by
+// `WithXDGLayout`, so several apps or tests on the same machine can use
+// isolated repos.
+func WithRepoPath(path string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.customRepoPath = path
+	}
+}
+
+// WithKuboVersion is a functional option to choose the release of the `ipfs`
+// binary to download (e.g. "v0.29.0"). Defaults to `DefaultKuboVersion`.
+// Please note the binary is only downloaded if it does not exist yet.
+func WithKuboVersion(version string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.kuboVersion = version
+	}
+}
+
+// WithLogOutputLimit is a functional option to set how many bytes of command
+// output are kept in the logs, so large outputs neither flood the logs nor
+// leak the data they contain. Use 0 to only log the length of the output.
+// Defaults to `DefaultLogOutputLimit`.
+func WithLogOutputLimit(maxBytes int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.logOutputLimit = maxBytes
+	}
+}
+
+// WithLogSampling is a functional option to only log one in `every` debug and
+// info records of the same message, so high-volume workloads do not flood the
+// logs. The first record of every message, warnings and errors are always
+// logged.
+func WithLogSampling(every int) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.logSampling = every
+	}
+}
+
+// WithTelemetrySink is a functional option to report anonymous, high-level
+// events (daemon starts, stops and crashes, commands run and the categories
+// of their errors) to the sink, for example to aggregate reliability data of
+// a fleet of embedded nodes. Telemetry is disabled by default and nothing is
+// sent anywhere other than the sink.
+//
+// Example:
+//
+//	WithTelemetrySink(TelemetrySinkFunc(func(event TelemetryEvent) {
+//		metrics.Inc(string(event.Type), event.Command, event.ErrorCategory)
+//	}))
+func WithTelemetrySink(sink TelemetrySink) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.telemetry = sink
+	}
+}
+
+// WithStrictCompatibility is a functional option to make `NewWrapper` fail
+// with `ErrIncompatibleKubo` when the installed `ipfs` binary is outside the
+// range of kubo releases the features in use were tested with. By default a
+// warning is logged instead.
+func WithStrictCompatibility() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.strictCompatibility = true
+	}
+}
+
+// WithShutdownGracePeriod is a functional option to set how long the daemon
+// gets to exit after `ShutdownDaemon` or `ForceShutdownDaemon` asked it to,
+// before it is killed. Defaults to `DefaultShutdownGracePeriod`; zero kills
+// the daemon right away. See `LastShutdown` for how it was stopped.
+func WithShutdownGracePeriod(d time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.shutdownGracePeriod = d
+	}
+}
+
+// WithShutdownOnSignals is a functional option to shut the daemon down with
+// `ShutdownDaemon` when the app receives one of the signals, by default
+// `SIGINT` and `SIGTERM`, instead of handling the signals in your app. Use
+// `Wait` to block until the daemon was shut down. In continous operation mode
+// the daemon keeps running, as with `ShutdownDaemon`.
+func WithShutdownOnSignals(signals ...os.Signal) Option {
+	return func(wrap *ipfsCliWrapper) {
+		if len(signals) == 0 {
+			signals = defaultShutdownSignals
+		}
+		wrap.shutdownSignals = signals
+	}
+}
+
+// WithWorkDir is a functional option to set the working directory of every
+// `ipfs` process spawned by this package, for example the directory `GetFile`
+// saves retrieved files into. Defaults to the current working directory of
+// your app. Use `WithCommandWorkDir` to override the directory per call.
+func WithWorkDir(dir string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.workDir = dir
+	}
+}
+
+// WithTempDir is a functional option to set the directory scratch files are
+// created in: the content staged by `AddFileContent`, the files retrieved by
+// `GetFile` before they are moved into the working directory, and the
+// downloaded kubo archive and denylist. Defaults to `os.TempDir()` so
+// scratch data never lands in the working directory of your app or on a
+// read-only filesystem.
+func WithTempDir(dir string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.tempDir = dir
+	}
+}
+
+// WithKeepArchive is a functional option to keep the downloaded kubo archive
+// in the user cache directory (for example `~/.cache/ipfs-cli-wrapper` on
+// Linux) instead of deleting it after extraction. Subsequent installs of the
+// same release, for example into another directory, extract from the cached
+// archive instead of downloading it again.
+func WithKeepArchive() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.keepArchive = true
+	}
+}
+
+// WithArchiveCacheDir is a functional option like `WithKeepArchive` which
+// keeps the downloaded kubo archive in the given directory.
+func WithArchiveCacheDir(dir string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.keepArchive = true
+		wrap.archiveCacheDir = dir
+	}
+}
+
+// WithFileMode is a functional option to set the permissions of the binaries
+// downloaded by this package. Defaults to `DefaultFileMode`.
+func WithFileMode(mode os.FileMode) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.fileMode = mode
+	}
+}
+
+// WithDirMode is a functional option to set the permissions of the
+// directories managed by this package, including the repo which holds the
+// private key of the node. Defaults to `DefaultDirMode`.
+func WithDirMode(mode os.FileMode) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.dirMode = mode
+	}
+}
+
+// WithXDGLayout is a functional option to install the binaries into
+// `$XDG_DATA_HOME/ipfs-cli-wrapper` and keep the repos in
+// `$XDG_STATE_HOME/ipfs-cli-wrapper`, following the XDG Base Directory
+// Specification, instead of creating a `./bin` directory inside the working
+// directory of your app. When unset, the variables default to
+// `~/.local/share` and `~/.local/state` respectively.
+func WithXDGLayout() Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.xdgLayout = true
+	}
+}
+
+// WithPinBackend is a functional option to configure the remote service,
+// for example the one returned by `NewPinningServiceBackend`, which
+// `EnsureRemoteReplica` pushes content to when the local node is its only
+// replica.
+func WithPinBackend(backend PinBackend) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.pinBackend = backend
+	}
+}
+
+// WithWatchDebounce is a functional option to configure how long `WatchDir`
+// waits for the directory to stop changing before publishing it. Defaults to
+// `DefaultWatchDebounce`.
+func WithWatchDebounce(debounce time.Duration) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.watchDebounce = debounce
+	}
+}
+
+// WithWatchIPNSKey is a functional option to make `WatchDir` publish the root
+// CID of the watched directory to IPNS, signed with the key (e.g. "self"),
+// every time it changes. The name is also tracked like with `TrackIPNSName`
+// so the record stays alive between changes.
+func WithWatchIPNSKey(key string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.watchIPNSKey = key
+	}
+}
+
 func WithCustomOsOperator(osOperator oskit.OSOperater) Option {
 	return func(wrap *ipfsCliWrapper) {
 		wrap.osOperator = osOperator
@@ -77,3 +700,155 @@ func WithCustomRandomGenerator(gen randomkit.RandomGenerator) Option {
 		wrap.randomGenerator = gen
 	}
 }
+
+// addSettings holds the settings of a call adding content, see `AddOption`.
+type addSettings struct {
+	toFiles       string
+	wrapDirectory bool
+	trickle       bool
+	maxLinks      int
+	symlinks      SymlinkPolicy
+	hidden        bool
+}
+
+func newAddSettings(opts []AddOption) *addSettings {
+	settings := &addSettings{}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	return settings
+}
+
+// WithToFiles is an add option to link the added content into MFS at the
+// given absolute path while adding it, instead of running `ipfs files cp`
+// afterwards. A path ending with a slash links the content into that
+// directory under its own name. Missing parent directories are created and
+// an existing entry at the path is an error.
+func WithToFiles(mfsPath string) AddOption {
+	return func(settings *addSettings) {
+		settings.toFiles = mfsPath
+	}
+}
+
+// WithWrapDirectory is an add option to wrap the added file or directory in
+// a directory, like `ipfs add --wrap-with-directory`, so its name survives,
+// e.g. in gateway URLs like "/ipfs/<directory cid>/photo.jpg". `AddFile`
+// then returns the CID of the wrapping directory, while `AddFileEntries`
+// returns both the CID of the file and, as the last entry with an empty
+// name, the CID of the wrapping directory.
+func WithWrapDirectory() AddOption {
+	return func(settings *addSettings) {
+		settings.wrapDirectory = true
+	}
+}
+
+// WithTrickle is an add option to build the DAG of files with the trickle
+// layout, like `ipfs add --trickle`, which suits content that is streamed
+// from the start or appended to, e.g. logs or media, at the cost of slower
+// random access than the default balanced layout.
+func WithTrickle() AddOption {
+	return func(settings *addSettings) {
+		settings.trickle = true
+	}
+}
+
+// WithMaxLinks is an add option to limit the number of links of the nodes of
+// the DAG of files, like `ipfs add --max-file-links`. Fewer links make deeper
+// DAGs with smaller nodes. It requires kubo v0.35.0 or newer, older releases
+// fail with `ErrIncompatibleKubo`.
+func WithMaxLinks(maxLinks int) AddOption {
+	return func(settings *addSettings) {
+		settings.maxLinks = maxLinks
+	}
+}
+
+// WithIncludeHidden is an add option to include the hidden files and
+// directories, whose names start with a dot, when adding a directory, like
+// `ipfs add --hidden`. Without it they are left out.
+func WithIncludeHidden() AddOption {
+	return func(settings *addSettings) {
+		settings.hidden = true
+	}
+}
+
+// SymlinkPolicy controls what adding content does with the symlinks in it,
+// see `WithSymlinks`.
+type SymlinkPolicy int
+
+const (
+	// SymlinkPreserve adds symlinks as links, which store the path they
+	// point to rather than the content, including when the added path
+	// itself is a symlink. This is the default.
+	SymlinkPreserve SymlinkPolicy = iota
+
+	// SymlinkDereferenceArgs adds the content the added path points to if
+	// it is a symlink, like `ipfs add --dereference-args`, while the
+	// symlinks inside an added directory stay links.
+	SymlinkDereferenceArgs
+
+	// SymlinkFollow adds the content every symlink points to instead of the
+	// symlink, like `ipfs add --dereference-symlinks`, which suits backups
+	// needing the content to be complete. It requires kubo v0.35.0 or
+	// newer, older releases fail with `ErrIncompatibleKubo`.
+	SymlinkFollow
+)
+
+// WithSymlinks is an add option to choose what happens to the symlinks in the
+// added content, instead of `SymlinkPreserve`.
+func WithSymlinks(policy SymlinkPolicy) AddOption {
+	return func(settings *addSettings) {
+		settings.symlinks = policy
+	}
+}
+
+// publishSettings holds the settings of a call publishing an IPNS name, see
+// `PublishOption`.
+type publishSettings struct {
+	key          string
+	lifetime     time.Duration
+	ttl          time.Duration
+	allowOffline bool
+}
+
+func newPublishSettings(opts []PublishOption) *publishSettings {
+	settings := &publishSettings{}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	return settings
+}
+
+// WithPublishKey is a publish option to publish under the IPNS name of the
+// given key, created with `ipfs key gen`, instead of the "self" key of the
+// node.
+func WithPublishKey(key string) PublishOption {
+	return func(settings *publishSettings) {
+		settings.key = key
+	}
+}
+
+// WithPublishLifetime is a publish option to set how long the record stays
+// valid, like `ipfs name publish --lifetime`, instead of the default of kubo.
+func WithPublishLifetime(lifetime time.Duration) PublishOption {
+	return func(settings *publishSettings) {
+		settings.lifetime = lifetime
+	}
+}
+
+// WithPublishTTL is a publish option to set how long resolvers may cache the
+// record, like `ipfs name publish --ttl`. A short TTL makes updates visible
+// sooner at the cost of more lookups.
+func WithPublishTTL(ttl time.Duration) PublishOption {
+	return func(settings *publishSettings) {
+		settings.ttl = ttl
+	}
+}
+
+// WithPublishAllowOffline is a publish option to store the record in the
+// repo when the node runs offline, like `ipfs name publish --allow-offline`,
+// instead of failing.
+func WithPublishAllowOffline() PublishOption {
+	return func(settings *publishSettings) {
+		settings.allowOffline = true
+	}
+}
@@ -0,0 +1,53 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// DefaultFileMode is the default permission of the downloaded binaries,
+	// executable by everyone but only writable by the owner.
+	DefaultFileMode os.FileMode = 0755
+
+	// DefaultDirMode is the default permission of the managed directories.
+	// Only the owner may access them since the repo holds the private key of
+	// the node.
+	DefaultDirMode os.FileMode = 0700
+)
+
+// applyPermissions sets the configured permissions on the binary and on the
+// directories managed by this package. Please note the permissions are not
+// enforced on Windows, where `os.Chmod` only toggles the read-only flag.
+func (wrap *ipfsCliWrapper) applyPermissions() error {
+	var errs []error
+	dirs := []string{
+		wrap.binDir,
+		wrap.repoPath(),
+		wrap.denylistDirPath(),
+	}
+	// The binary set with `WithBinaryPath` is not managed by this package.
+	if wrap.customBinaryPath == "" {
+		dirs = append(dirs, filepath.Dir(wrap.binaryPath()))
+	}
+	for _, dir := range dirs {
+		if err := chmodIfExists(dir, wrap.dirMode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if wrap.customBinaryPath == "" {
+		if err := chmodIfExists(wrap.binaryPath(), wrap.fileMode); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func chmodIfExists(path string, mode os.FileMode) error {
+	if err := os.Chmod(path, mode); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed setting permissions of %s: %v", path, err)
+	}
+	return nil
+}
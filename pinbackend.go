@@ -0,0 +1,112 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/pinningservice"
+)
+
+// ErrNoPinBackend is returned by `EnsureRemoteReplica` when no backend was
+// configured with the `WithPinBackend` option.
+var ErrNoPinBackend = errors.New("no pin backend configured, use `WithPinBackend`")
+
+// DefaultReplicaLookupTimeout bounds the provider lookup `EnsureRemoteReplica`
+// performs to find out whether other peers hold the content.
+const DefaultReplicaLookupTimeout = 30 * time.Second
+
+// RemotePinRequest describes the content to pin on a remote backend.
+type RemotePinRequest struct {
+	// CID is the content to pin.
+	CID string
+
+	// Name is an optional human readable name of the pin.
+	Name string
+
+	// Origins are the multiaddresses of the local node, which the backend
+	// may connect to in order to retrieve the content quickly.
+	Origins []string
+}
+
+// PinBackend is a remote service content can be pushed to so it stays
+// available when the local node goes away, for example a commercial pinning
+// provider. Use `NewPinningServiceBackend` for providers implementing the
+// IPFS Pinning Service API, or implement the interface for other services.
+type PinBackend interface {
+	// PinRemote asks the backend to pin the content. Backends may pin
+	// asynchronously; the call returns once the request was accepted.
+	PinRemote(ctx context.Context, req RemotePinRequest) error
+}
+
+// pinningServiceBackend is the `PinBackend` for the Pinning Service API.
+type pinningServiceBackend struct {
+	client *pinningservice.Client
+}
+
+// NewPinningServiceBackend returns a `PinBackend` for a provider implementing
+// the IPFS Pinning Service API [0] at the endpoint, authenticating with the
+// access token.
+//
+// [0] https://ipfs.github.io/pinning-services-api-spec/
+func NewPinningServiceBackend(endpoint string, accessToken string) PinBackend {
+	return &pinningServiceBackend{client: pinningservice.NewClient(endpoint, accessToken)}
+}
+
+func (b *pinningServiceBackend) PinRemote(ctx context.Context, req RemotePinRequest) error {
+	status, err := b.client.Add(ctx, pinningservice.Pin{
+		CID:     req.CID,
+		Name:    req.Name,
+		Origins: req.Origins,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pin on remote pinning service: %v", err)
+	}
+	if status.Status == pinningservice.StatusFailed {
+		return fmt.Errorf("remote pinning service rejected pin of `%s`: %v", req.CID, status.Info)
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) EnsureRemoteReplica(ctx context.Context, cid string) (bool, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return false, err
+	}
+
+	if wrap.pinBackend == nil {
+		return false, ErrNoPinBackend
+	}
+
+	self, err := nodeInfo(ctx, wrap)
+	if err != nil {
+		return false, err
+	}
+
+	// Look for another provider than ourselves. Failing to find one within
+	// the timeout means the local node is the only replica.
+	lookupCtx, cancel := context.WithTimeout(ctx, DefaultReplicaLookupTimeout)
+	providers, err := wrap.findProviderIDs(lookupCtx, cid, 2)
+	cancel()
+	if err != nil && ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	delete(providers, self.ID)
+	if len(providers) > 0 {
+		wrap.logger.Debug("content has other providers, not pushing to pin backend",
+			slog.String("cid", cid),
+			slog.Int("providers", len(providers)))
+		return false, nil
+	}
+
+	if err := wrap.pinBackend.PinRemote(ctx, RemotePinRequest{CID: cid, Origins: self.Addresses}); err != nil {
+		wrap.logger.Error("error pushing content to pin backend",
+			slog.String("cid", cid),
+			slog.Any("error", err))
+		return false, err
+	}
+	wrap.logger.Debug("content pushed to pin backend",
+		slog.String("cid", cid))
+	return true, nil
+}
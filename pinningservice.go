@@ -0,0 +1,77 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/pinningservice"
+)
+
+// pinningServiceStateFileName is the name of the file, inside the repo
+// directory, in which the pin requests of the Pinning Service API are kept
+// across runs.
+const pinningServiceStateFileName = "ipfs-cli-wrapper-pins.json"
+
+// startPinningService launches the Pinning Service API server configured by
+// the `WithPinningServiceAPI` option. Calling it while the server is already
+// running does nothing.
+func (wrap *ipfsCliWrapper) startPinningService() error {
+	if wrap.pinningServiceAddr == "" || wrap.pinningServiceServer != nil {
+		return nil
+	}
+
+	svc, err := pinningservice.New(wrap, wrap.pinningServiceAccessToken,
+		filepath.Join(wrap.repoPath(), pinningServiceStateFileName))
+	if err != nil {
+		return fmt.Errorf("failed to start pinning service api: %v", err)
+	}
+	svc.Delegates = func(ctx context.Context) []string {
+		info, err := wrap.Id(ctx)
+		if err != nil {
+			return []string{}
+		}
+		return info.Addresses
+	}
+
+	// Listen synchronously so an unavailable address is reported to the
+	// caller instead of failing silently in the background.
+	listener, err := net.Listen("tcp", wrap.pinningServiceAddr)
+	if err != nil {
+		wrap.logger.Error("error listening for pinning service api", slog.Any("error", err))
+		return fmt.Errorf("failed to listen for pinning service api: %v", err)
+	}
+
+	server := &http.Server{Handler: svc}
+	wrap.pinningServiceServer = server
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			wrap.logger.Error("pinning service api stopped", slog.Any("error", err))
+		}
+	}()
+
+	wrap.logger.Debug("pinning service api is running",
+		slog.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// stopPinningService gracefully shuts down the Pinning Service API server.
+func (wrap *ipfsCliWrapper) stopPinningService() error {
+	if wrap.pinningServiceServer == nil {
+		return nil
+	}
+	server := wrap.pinningServiceServer
+	wrap.pinningServiceServer = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shutdown pinning service api: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Constants representing the strategies a `NodePool` uses to pick the node
+// executing a call.
+const (
+	// RoundRobinStrategy hands calls to the nodes in turn.
+	RoundRobinStrategy = "round-robin"
+
+	// LeastLoadedStrategy hands calls to the node with the fewest calls in
+	// flight, which copes better with calls of uneven duration.
+	LeastLoadedStrategy = "least-loaded"
+)
+
+// ErrEmptyNodePool is returned by `NewNodePool` when no node was given.
+var ErrEmptyNodePool = errors.New("node pool has no nodes")
+
+// NodePool spreads calls across several managed nodes, for example the
+// nodes created by a `TenantManager` or by several wrappers with
+// `WithAutoPorts`, to improve the throughput of heavy ingestion pipelines.
+// Every call is executed by a single node, picked according to the strategy.
+type NodePool struct {
+	strategy string
+	nodes    []IpfsCliWrapper
+
+	mu       sync.Mutex
+	next     int
+	inflight []int
+}
+
+// NewNodePool returns a pool over the given nodes using the strategy, either
+// `RoundRobinStrategy` or `LeastLoadedStrategy`. The daemons of the nodes
+// must be started by the caller.
+//
+// Example:
+//
+//	pool, err := NewNodePool(LeastLoadedStrategy, nodeA, nodeB, nodeC)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	cids, err := pool.AddFiles(ctx, paths)
+func NewNodePool(strategy string, nodes ...IpfsCliWrapper) (*NodePool, error) {
+	if len(nodes) == 0 {
+		return nil, ErrEmptyNodePool
+	}
+	if strategy != RoundRobinStrategy && strategy != LeastLoadedStrategy {
+		return nil, fmt.Errorf("unsupported node pool strategy `%s`", strategy)
+	}
+	return &NodePool{
+		strategy: strategy,
+		nodes:    nodes,
+		inflight: make([]int, len(nodes)),
+	}, nil
+}
+
+// Nodes returns the nodes of the pool.
+func (p *NodePool) Nodes() []IpfsCliWrapper {
+	return append([]IpfsCliWrapper(nil), p.nodes...)
+}
+
+// acquire picks the node for the next call and marks it busy. The returned
+// function must be called once the call completed.
+func (p *NodePool) acquire() (int, func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := p.next % len(p.nodes)
+	if p.strategy == LeastLoadedStrategy {
+		// Start the scan at the round-robin position so ties are broken
+		// fairly instead of always favouring the first node.
+		for j := 0; j < len(p.nodes); j++ {
+			k := (p.next + j) % len(p.nodes)
+			if p.inflight[k] < p.inflight[i] {
+				i = k
+			}
+		}
+	}
+	p.next = (p.next + 1) % len(p.nodes)
+	p.inflight[i]++
+
+	return i, func() {
+		p.mu.Lock()
+		p.inflight[i]--
+		p.mu.Unlock()
+	}
+}
+
+// AddFile adds the file with the next node of the pool, see
+// `IpfsCliWrapper.AddFile`.
+func (p *NodePool) AddFile(ctx context.Context, filepath string, opts ...AddOption) (string, error) {
+	i, release := p.acquire()
+	defer release()
+	return p.nodes[i].AddFile(ctx, filepath, opts...)
+}
+
+// AddFileContent adds the content with the next node of the pool, see
+// `IpfsCliWrapper.AddFileContent`.
+func (p *NodePool) AddFileContent(ctx context.Context, filename string, fileContent []byte, opts ...AddOption) (string, error) {
+	i, release := p.acquire()
+	defer release()
+	return p.nodes[i].AddFileContent(ctx, filename, fileContent, opts...)
+}
+
+// AddFiles adds the files concurrently, with as many calls in flight as the
+// pool has nodes, and returns the CID of every file keyed by its path. The
+// errors of the failed files are joined; the CIDs of the files which were
+// added are returned regardless.
+func (p *NodePool) AddFiles(ctx context.Context, filepaths []string) (map[string]string, error) {
+	var (
+		mu   sync.Mutex
+		cids = make(map[string]string, len(filepaths))
+		errs []error
+		wg   sync.WaitGroup
+	)
+	work := make(chan string)
+	for w := 0; w < len(p.nodes); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range work {
+				cid, err := p.AddFile(ctx, path)
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed adding %s: %w", path, err))
+				} else {
+					cids[path] = cid
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, path := range filepaths {
+		if ctx.Err() != nil {
+			mu.Lock()
+			errs = append(errs, ctx.Err())
+			mu.Unlock()
+			break
+		}
+		work <- path
+	}
+	close(work)
+	wg.Wait()
+	return cids, errors.Join(errs...)
+}
+
+// Cat retrieves the content with the next node of the pool, see
+// `IpfsCliWrapper.Cat`. If the node fails, the remaining nodes are tried in
+// turn before giving up.
+func (p *NodePool) Cat(ctx context.Context, cid string) ([]byte, error) {
+	var errs []error
+	for attempt := 0; attempt < len(p.nodes); attempt++ {
+		i, release := p.acquire()
+		content, err := p.nodes[i].Cat(ctx, cid)
+		release()
+		if err == nil {
+			return content, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Pin pins the content with the next node of the pool, see
+// `IpfsCliWrapper.Pin`.
+func (p *NodePool) Pin(ctx context.Context, cid string) error {
+	i, release := p.acquire()
+	defer release()
+	return p.nodes[i].Pin(ctx, cid)
+}
+
+// Unpin removes the pin of the content from every node of the pool, since
+// the pin may have been created by any of them. Nodes which do not pin the
+// content are skipped.
+func (p *NodePool) Unpin(ctx context.Context, cid string) error {
+	var errs []error
+	unpinned := false
+	for _, node := range p.nodes {
+		pinned, pinType, err := node.IsPinned(ctx, cid)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !pinned || pinType == IndirectPinType {
+			continue
+		}
+		if err := node.Unpin(ctx, cid); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		unpinned = true
+	}
+	if !unpinned && len(errs) == 0 {
+		return fmt.Errorf("no node of the pool pins %s", cid)
+	}
+	return errors.Join(errs...)
+}
+
+// ListPins returns the union of the pins of every node of the pool, sorted.
+func (p *NodePool) ListPins(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+	for _, node := range p.nodes {
+		pins, err := node.ListPins(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, pin := range pins {
+			seen[pin] = struct{}{}
+		}
+	}
+	pins := make([]string, 0, len(seen))
+	for pin := range seen {
+		pins = append(pins, pin)
+	}
+	sort.Strings(pins)
+	return pins, nil
+}
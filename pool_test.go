@@ -0,0 +1,148 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// MockNode is a partial mock of the IpfsCliWrapper interface; calling any
+// method which is not overridden panics.
+type MockNode struct {
+	ipfscliwrapper.IpfsCliWrapper
+
+	name    string
+	delay   time.Duration
+	catErr  error
+	pins    []string
+	mu      sync.Mutex
+	added   []string
+	maxBusy int
+	busy    int
+
+	// Used by the ReplicatePin tests.
+	peerID    string
+	connected []string
+	pinned    bool
+	pinErr    error
+}
+
+func (m *MockNode) AddFile(ctx context.Context, filepath string, opts ...ipfscliwrapper.AddOption) (string, error) {
+	m.mu.Lock()
+	m.added = append(m.added, filepath)
+	m.busy++
+	if m.busy > m.maxBusy {
+		m.maxBusy = m.busy
+	}
+	m.mu.Unlock()
+
+	time.Sleep(m.delay)
+
+	m.mu.Lock()
+	m.busy--
+	m.mu.Unlock()
+	return "cid-" + filepath, nil
+}
+
+func (m *MockNode) Cat(ctx context.Context, cid string) ([]byte, error) {
+	if m.catErr != nil {
+		return nil, m.catErr
+	}
+	return []byte(m.name), nil
+}
+
+func (m *MockNode) ListPins(ctx context.Context) ([]string, error) {
+	return m.pins, nil
+}
+
+// TestNodePoolRoundRobin checks calls are handed to the nodes in turn.
+func TestNodePoolRoundRobin(t *testing.T) {
+	a, b := &MockNode{name: "a"}, &MockNode{name: "b"}
+	pool, err := ipfscliwrapper.NewNodePool(ipfscliwrapper.RoundRobinStrategy, a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	for _, path := range []string{"1", "2", "3", "4"} {
+		if _, err := pool.AddFile(context.Background(), path); err != nil {
+			t.Fatalf("Expected no error, but got: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(a.added, []string{"1", "3"}) || !reflect.DeepEqual(b.added, []string{"2", "4"}) {
+		t.Errorf("Expected alternating nodes, but got %v and %v", a.added, b.added)
+	}
+}
+
+// TestNodePoolAddFiles checks files are added concurrently across the nodes
+// and the results aggregated.
+func TestNodePoolAddFiles(t *testing.T) {
+	a := &MockNode{name: "a", delay: 20 * time.Millisecond}
+	b := &MockNode{name: "b", delay: 20 * time.Millisecond}
+	pool, err := ipfscliwrapper.NewNodePool(ipfscliwrapper.LeastLoadedStrategy, a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	paths := []string{"1", "2", "3", "4", "5", "6"}
+	cids, err := pool.AddFiles(context.Background(), paths)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if len(cids) != len(paths) || cids["5"] != "cid-5" {
+		t.Errorf("Unexpected results: %v", cids)
+	}
+	if len(a.added) == 0 || len(b.added) == 0 {
+		t.Errorf("Expected the load to be spread, but got %v and %v", a.added, b.added)
+	}
+	if a.maxBusy != 1 || b.maxBusy != 1 {
+		t.Errorf("Expected the least loaded node to be picked, but got %d and %d calls in flight", a.maxBusy, b.maxBusy)
+	}
+}
+
+// TestNodePoolCatFailover checks the next node is tried when one fails.
+func TestNodePoolCatFailover(t *testing.T) {
+	a := &MockNode{name: "a", catErr: errors.New("offline")}
+	b := &MockNode{name: "b"}
+	pool, err := ipfscliwrapper.NewNodePool(ipfscliwrapper.RoundRobinStrategy, a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	content, err := pool.Cat(context.Background(), "bafytest")
+	if err != nil || string(content) != "b" {
+		t.Errorf("Expected content from node b, but got %q and %v", content, err)
+	}
+}
+
+// TestNodePoolListPins checks the pins of every node are merged.
+func TestNodePoolListPins(t *testing.T) {
+	a := &MockNode{pins: []string{"cid2", "cid1"}}
+	b := &MockNode{pins: []string{"cid3", "cid1"}}
+	pool, err := ipfscliwrapper.NewNodePool(ipfscliwrapper.RoundRobinStrategy, a, b)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	pins, err := pool.ListPins(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if !reflect.DeepEqual(pins, []string{"cid1", "cid2", "cid3"}) {
+		t.Errorf("Unexpected pins: %v", pins)
+	}
+}
+
+// TestNewNodePoolErrors checks invalid pools are rejected.
+func TestNewNodePoolErrors(t *testing.T) {
+	if _, err := ipfscliwrapper.NewNodePool(ipfscliwrapper.RoundRobinStrategy); !errors.Is(err, ipfscliwrapper.ErrEmptyNodePool) {
+		t.Errorf("Expected ErrEmptyNodePool, but got: %v", err)
+	}
+	if _, err := ipfscliwrapper.NewNodePool("random", &MockNode{}); err == nil {
+		t.Errorf("Expected an error for an unsupported strategy")
+	}
+}
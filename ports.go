@@ -0,0 +1,155 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// assignAutoPorts picks free ports for the API, gateway and swarm addresses
+// which were not set explicitly, when enabled by the `WithAutoPorts` option.
+// If our daemon is already running, the ports it listens on are kept instead
+// since changing them would make the daemon unreachable.
+func (wrap *ipfsCliWrapper) assignAutoPorts() error {
+	if !wrap.autoPorts {
+		return nil
+	}
+
+	pids, err := wrap.findOwnDaemonPIDs()
+	if err != nil {
+		return err
+	}
+	if len(pids) > 0 {
+		wrap.logger.Debug("ipfs daemon is already running, keeping its ports")
+		if wrap.apiAddr == "" {
+			wrap.apiAddr, _ = wrap.getConfig("Addresses.API")
+		}
+		return nil
+	}
+
+	if wrap.apiAddr == "" {
+		port, err := freePort(false)
+		if err != nil {
+			return err
+		}
+		wrap.apiAddr = fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port)
+	}
+	if wrap.gatewayAddr == "" && !wrap.gatewayDisabled {
+		port, err := freePort(false)
+		if err != nil {
+			return err
+		}
+		wrap.gatewayAddr = fmt.Sprintf("/ip4/127.0.0.1/tcp/%d", port)
+	}
+	if len(wrap.swarmAddrs) == 0 {
+		// The QUIC based transports listen on the same port number over
+		// UDP, so the port must be free for both protocols.
+		port, err := freePort(true)
+		if err != nil {
+			return err
+		}
+		wrap.swarmAddrs = []string{
+			fmt.Sprintf("/ip4/0.0.0.0/tcp/%d", port),
+			fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1", port),
+			fmt.Sprintf("/ip4/0.0.0.0/udp/%d/quic-v1/webtransport", port),
+		}
+	}
+
+	wrap.logger.Debug("automatic ports assigned",
+		"api_addr", wrap.apiAddr,
+		"gateway_addr", wrap.gatewayAddr,
+		"swarm_addrs", wrap.swarmAddrs)
+	return nil
+}
+
+// freePort asks the operating system for a free TCP port. If `withUDP` is
+// true the port must be free for UDP as well.
+//
+// Please note another program may grab the port before the daemon binds it,
+// which the daemon reports with `ErrPortInUse`.
+func freePort(withUDP bool) (int, error) {
+	const maxAttempts = 10
+	for i := 0; i < maxAttempts; i++ {
+		listener, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return 0, fmt.Errorf("failed to find free port: %v", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		if !withUDP {
+			listener.Close()
+			return port, nil
+		}
+
+		conn, err := net.ListenPacket("udp", ":"+strconv.Itoa(port))
+		listener.Close()
+		if err != nil {
+			continue // Taken over UDP, try another one.
+		}
+		conn.Close()
+		return port, nil
+	}
+	return 0, fmt.Errorf("failed to find free port: no port free for both tcp and udp after %d attempts", maxAttempts)
+}
+
+// getConfig executes `ipfs config` to read the value of the key.
+func (wrap *ipfsCliWrapper) getConfig(key string) (string, error) {
+	cmd := wrap.baseCommand(context.Background(), "config", key)
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		return "", wrap.commandError(fmt.Sprintf("get config `%s`", key), err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// multiaddrTCPPort returns the TCP port of the multiaddress, or 0 if it has
+// none.
+func multiaddrTCPPort(addr string) int {
+	parts := strings.Split(addr, "/")
+	for i := 0; i+1 < len(parts); i++ {
+		if parts[i] == "tcp" {
+			port, err := strconv.Atoi(parts[i+1])
+			if err != nil {
+				return 0
+			}
+			return port
+		}
+	}
+	return 0
+}
+
+func (wrap *ipfsCliWrapper) APIPort() int {
+	addr := wrap.apiAddr
+	if addr == "" {
+		addr, _ = wrap.getConfig("Addresses.API")
+	}
+	return multiaddrTCPPort(addr)
+}
+
+func (wrap *ipfsCliWrapper) GatewayPort() int {
+	if wrap.gatewayDisabled {
+		return 0
+	}
+	addr := wrap.gatewayAddr
+	if addr == "" {
+		addr, _ = wrap.getConfig("Addresses.Gateway")
+	}
+	return multiaddrTCPPort(addr)
+}
+
+func (wrap *ipfsCliWrapper) SwarmPort() int {
+	addrs := wrap.swarmAddrs
+	if len(addrs) == 0 {
+		if raw, err := wrap.getConfig("Addresses.Swarm"); err == nil {
+			_ = json.Unmarshal([]byte(raw), &addrs)
+		}
+	}
+	for _, addr := range addrs {
+		if port := multiaddrTCPPort(addr); port != 0 {
+			return port
+		}
+	}
+	return 0
+}
@@ -0,0 +1,235 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrPreflightFailed is wrapped by the error of `PreflightReport.Err` when
+// one of the checks of `Preflight` failed.
+var ErrPreflightFailed = errors.New("ipfs preflight check failed")
+
+// Names of the checks of `PreflightReport`.
+const (
+	PreflightBinary    = "binary"
+	PreflightRepo      = "repo"
+	PreflightLock      = "lock"
+	PreflightPorts     = "ports"
+	PreflightDiskSpace = "disk_space"
+)
+
+// PreflightCheck is the outcome of one of the checks of `Preflight`.
+type PreflightCheck struct {
+	// Name is one of `PreflightBinary`, `PreflightRepo`, `PreflightLock`,
+	// `PreflightPorts` and `PreflightDiskSpace`.
+	Name string `json:"name"`
+
+	// Passed is true if the check found nothing preventing the start.
+	Passed bool `json:"passed"`
+
+	// Detail describes what the check found, or why it failed.
+	Detail string `json:"detail"`
+}
+
+// PreflightReport holds the outcome of the checks of `Preflight`, in the
+// order they ran.
+type PreflightReport struct {
+	// Passed is true if every check passed.
+	Passed bool `json:"passed"`
+
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// Err returns nil if every check passed, or else an error wrapping
+// `ErrPreflightFailed` which lists the failed checks.
+func (r *PreflightReport) Err() error {
+	var failed []string
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failed = append(failed, check.Name+": "+check.Detail)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrPreflightFailed, strings.Join(failed, "; "))
+}
+
+func (wrap *ipfsCliWrapper) Preflight(ctx context.Context) *PreflightReport {
+	report := &PreflightReport{Passed: true}
+	add := func(name string, err error, detail string) {
+		check := PreflightCheck{Name: name, Passed: err == nil, Detail: detail}
+		if err != nil {
+			check.Detail = err.Error()
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	version, err := wrap.preflightBinary(ctx)
+	add(PreflightBinary, err, "kubo "+version+" at "+wrap.binaryPath())
+	if err != nil {
+		// The other checks need to run the binary.
+		return report
+	}
+
+	// A daemon started by the wrapper, or adopted from an earlier run, holds
+	// the repo lock and listens on the ports, which does not prevent the
+	// start as `StartDaemonInBackground` keeps using it.
+	pids, err := wrap.findOwnDaemonPIDs()
+	if err != nil {
+		add(PreflightLock, fmt.Errorf("failed to find running ipfs daemons: %v", err), "")
+		return report
+	}
+	running := len(pids) > 0
+
+	repoDetail, err := wrap.preflightRepo(ctx, running)
+	switch {
+	case running:
+		add(PreflightRepo, err, repoDetail)
+		add(PreflightLock, nil, fmt.Sprintf("held by the running daemon (pid %d)", pids[0]))
+	case errors.Is(err, ErrRepoLocked):
+		add(PreflightRepo, errors.New("repo cannot be opened while it is locked"), "")
+		add(PreflightLock, fmt.Errorf("repo `%s` is locked by another process", wrap.repoPath()), "")
+	default:
+		add(PreflightRepo, err, repoDetail)
+		add(PreflightLock, nil, "not held")
+	}
+
+	if running {
+		add(PreflightPorts, nil, "in use by the running daemon")
+	} else {
+		addrs, err := wrap.preflightPorts()
+		add(PreflightPorts, err, "available: "+strings.Join(addrs, ", "))
+	}
+
+	free, err := wrap.osOperator.FreeDiskSpace(wrap.repoPath())
+	if err == nil && free < wrap.minFreeDiskSpace {
+		err = fmt.Errorf("%w: %d bytes free, %d bytes to keep free", ErrInsufficientDiskSpace, free, wrap.minFreeDiskSpace)
+	}
+	add(PreflightDiskSpace, err, fmt.Sprintf("%d bytes free", free))
+	return report
+}
+
+// preflightBinary checks the `ipfs` binary is an executable file which runs,
+// and returns its version.
+func (wrap *ipfsCliWrapper) preflightBinary(ctx context.Context) (string, error) {
+	info, err := os.Stat(wrap.binaryPath())
+	if err != nil {
+		return "", fmt.Errorf("ipfs binary missing: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("ipfs binary `%s` is not a file", wrap.binaryPath())
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("ipfs binary `%s` is not executable, mode %v", wrap.binaryPath(), info.Mode().Perm())
+	}
+	output, err := wrap.output(wrap.baseCommand(ctx, "version", "--number"))
+	if err != nil {
+		return "", wrap.commandError("run ipfs binary", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// preflightRepo checks the repo can be opened by reading its size, through
+// the running daemon, if any. The error wraps `ErrRepoLocked` if another
+// process holds the repo lock.
+func (wrap *ipfsCliWrapper) preflightRepo(ctx context.Context, running bool) (string, error) {
+	if _, err := os.Stat(filepath.Join(wrap.repoPath(), "config")); err != nil {
+		return "", fmt.Errorf("repo `%s` is not initialized: %v", wrap.repoPath(), err)
+	}
+	cmd := wrap.baseCommand(ctx, "repo", "stat", "--size-only", "--enc=json")
+	if running {
+		cmd = wrap.command(ctx, "repo", "stat", "--size-only", "--enc=json")
+	}
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return "", wrap.commandError("open repo", err, output)
+	}
+	var stat struct {
+		RepoSize   uint64
+		StorageMax uint64
+	}
+	if err := json.Unmarshal(output, &stat); err != nil {
+		return "", fmt.Errorf("failed to decode repo stat: %v", err)
+	}
+	return fmt.Sprintf("%s, %d of %d bytes used", wrap.repoPath(), stat.RepoSize, stat.StorageMax), nil
+}
+
+// preflightPorts checks the addresses the daemon listens on are free by
+// listening on them, and returns the checked addresses.
+func (wrap *ipfsCliWrapper) preflightPorts() ([]string, error) {
+	addrs := []string{wrap.apiAddr}
+	if addrs[0] == "" {
+		addrs[0], _ = wrap.getConfig("Addresses.API")
+	}
+	if !wrap.gatewayDisabled {
+		gateway := wrap.gatewayAddr
+		if gateway == "" {
+			gateway, _ = wrap.getConfig("Addresses.Gateway")
+		}
+		addrs = append(addrs, gateway)
+	}
+	swarm := wrap.swarmAddrs
+	if len(swarm) == 0 {
+		if raw, err := wrap.getConfig("Addresses.Swarm"); err == nil {
+			_ = json.Unmarshal([]byte(raw), &swarm)
+		}
+	}
+	addrs = append(addrs, swarm...)
+
+	var checked, busy []string
+	for _, addr := range addrs {
+		network, listenAddr, ok := multiaddrListenAddress(addr)
+		if !ok {
+			continue
+		}
+		checked = append(checked, addr)
+		var err error
+		if network == "udp" {
+			var conn net.PacketConn
+			if conn, err = net.ListenPacket(network, listenAddr); err == nil {
+				conn.Close()
+			}
+		} else {
+			var listener net.Listener
+			if listener, err = net.Listen(network, listenAddr); err == nil {
+				listener.Close()
+			}
+		}
+		if err != nil {
+			busy = append(busy, addr)
+		}
+	}
+	if len(busy) > 0 {
+		return checked, fmt.Errorf("addresses in use: %s", strings.Join(busy, ", "))
+	}
+	return checked, nil
+}
+
+// multiaddrListenAddress converts a TCP or UDP multiaddress such as
+// "/ip4/0.0.0.0/udp/4001/quic-v1" to the network and "host:port" address to
+// listen on.
+func multiaddrListenAddress(addr string) (string, string, bool) {
+	parts := strings.Split(addr, "/")
+	var host string
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "ip4", "ip6":
+			host = parts[i+1]
+		case "tcp", "udp":
+			if host == "" {
+				return "", "", false
+			}
+			return parts[i], net.JoinHostPort(host, parts[i+1]), true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,280 @@
+package ipfscliwrapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/oskit"
+)
+
+// daemonPIDFileName is the name of the file, inside the repo directory, in
+// which the wrapper records the process id of the daemon it started.
+const daemonPIDFileName = "ipfs-cli-wrapper.pid"
+
+// pidFilePath returns the path of the daemon PID file.
+func (wrap *ipfsCliWrapper) pidFilePath() string {
+	return filepath.Join(wrap.repoPath(), daemonPIDFileName)
+}
+
+// writePIDFile records the process id of the daemon started by the wrapper.
+func (wrap *ipfsCliWrapper) writePIDFile(pid int) error {
+	if err := os.WriteFile(wrap.pidFilePath(), []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write daemon pid file: %v", err)
+	}
+	return nil
+}
+
+// readPIDFile returns the process id recorded in the PID file, or zero if
+// there is no (valid) PID file.
+func (wrap *ipfsCliWrapper) readPIDFile() int {
+	b, err := os.ReadFile(wrap.pidFilePath())
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return 0
+	}
+	return pid
+}
+
+// removePIDFile deletes the PID file and the daemon info file, ignoring files
+// which do not exist.
+func (wrap *ipfsCliWrapper) removePIDFile() {
+	if err := os.Remove(wrap.pidFilePath()); err != nil && !os.IsNotExist(err) {
+		wrap.logger.Warn("failed removing daemon pid file", "error", err)
+	}
+	if err := os.Remove(wrap.daemonInfoFilePath()); err != nil && !os.IsNotExist(err) {
+		wrap.logger.Warn("failed removing daemon info file", "error", err)
+	}
+	wrap.adoptedDaemon = nil
+}
+
+// daemonInfoFileName is the name of the file, inside the repo directory, which
+// describes the daemon started by the wrapper, see `daemonInfo`.
+const daemonInfoFileName = "ipfs-cli-wrapper-daemon.json"
+
+// daemonInfo describes the daemon started by the wrapper, so a later run of
+// the app can adopt a daemon left running in continous operation mode.
+type daemonInfo struct {
+	PID int `json:"pid"`
+
+	// StartTime is when the process was started according to the operating
+	// system, which tells the daemon apart from a process reusing its PID.
+	StartTime time.Time `json:"start_time"`
+
+	// KuboVersion is the release of the `ipfs` binary, e.g. "v0.29.0".
+	KuboVersion string `json:"kubo_version"`
+
+	// StdoutPath and StderrPath are the files the daemon writes its output
+	// to, see `WithDaemonOutputFiles`.
+	StdoutPath string `json:"stdout_path,omitempty"`
+	StderrPath string `json:"stderr_path,omitempty"`
+}
+
+func (wrap *ipfsCliWrapper) daemonInfoFilePath() string {
+	return filepath.Join(wrap.repoPath(), daemonInfoFileName)
+}
+
+// writeDaemonInfo records the details of the daemon started by the wrapper.
+func (wrap *ipfsCliWrapper) writeDaemonInfo(pid int) error {
+	info := daemonInfo{
+		PID:         pid,
+		StartTime:   time.Now(),
+		KuboVersion: wrap.installedKuboVersion,
+	}
+	if startTime, err := wrap.osOperator.ProcessStartTime(pid); err == nil {
+		info.StartTime = startTime
+	}
+	if wrap.isDaemonRunningContinously {
+		info.StdoutPath = wrap.daemonStdoutPath
+		info.StderrPath = wrap.daemonStderrPath
+	}
+	b, err := json.MarshalIndent(&info, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(wrap.daemonInfoFilePath(), b, 0644); err != nil {
+		return fmt.Errorf("failed to write daemon info file: %v", err)
+	}
+	return nil
+}
+
+// readDaemonInfo returns the details recorded by `writeDaemonInfo`, or nil if
+// there is no (valid) daemon info file.
+func (wrap *ipfsCliWrapper) readDaemonInfo() *daemonInfo {
+	b, err := os.ReadFile(wrap.daemonInfoFilePath())
+	if err != nil {
+		return nil
+	}
+	var info daemonInfo
+	if err := json.Unmarshal(b, &info); err != nil || info.PID <= 0 {
+		return nil
+	}
+	return &info
+}
+
+// isDaemonAlive reports whether the recorded daemon is still running, rather
+// than another process which reused its PID.
+func (wrap *ipfsCliWrapper) isDaemonAlive(info *daemonInfo) bool {
+	if running, err := wrap.osOperator.IsProcessRunning(info.PID); err != nil || !running {
+		return false
+	}
+	startTime, err := wrap.osOperator.ProcessStartTime(info.PID)
+	if err != nil {
+		return false
+	}
+	// The start time is only recorded with a millisecond precision.
+	diff := startTime.Sub(info.StartTime)
+	return diff > -time.Second && diff < time.Second
+}
+
+// adoptDaemon takes over the daemon a previous run of this app left running in
+// continous operation mode, so it can be inspected with `Status` and
+// `LogTail` and shut down gracefully. The records of a daemon which is not
+// running anymore are removed.
+func (wrap *ipfsCliWrapper) adoptDaemon() {
+	info := wrap.readDaemonInfo()
+	if info == nil {
+		return
+	}
+	if !wrap.isDaemonAlive(info) {
+		wrap.logger.Debug("removing records of stopped ipfs daemon", slog.Int("pid", info.PID))
+		wrap.removePIDFile()
+		return
+	}
+	wrap.adoptedDaemon = info
+	wrap.isDaemonRunning = true
+	wrap.logger.Debug("adopted running ipfs daemon",
+		slog.Int("pid", info.PID),
+		slog.Time("start_time", info.StartTime),
+		slog.String("kubo_version", info.KuboVersion))
+}
+
+// findOwnDaemonPIDs returns the process ids of the running `ipfs` daemons
+// which use the wrapper-managed repo. Unlike a lookup by program name, this
+// leaves alone any other `ipfs` process on the machine, such as a desktop
+// node or a daemon managed by another app.
+//
+// A process belongs to the wrapper if it is the daemon recorded in the PID
+// file, or if it is a daemon using our repo, see `isOwnDaemonProcess`.
+func (wrap *ipfsCliWrapper) findOwnDaemonPIDs() ([]int, error) {
+	// The binary set with `WithBinaryPath` may have another name.
+	procs, err := wrap.osOperator.FindProcessesByName(strings.TrimSuffix(filepath.Base(wrap.binaryPath()), ".exe"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The PID file is only trusted while the recorded start time matches, as
+	// its PID may have been reused by an unrelated process since.
+	recordedPID := 0
+	if info := wrap.readDaemonInfo(); info != nil && info.PID == wrap.readPIDFile() && wrap.isDaemonAlive(info) {
+		recordedPID = info.PID
+	}
+
+	pids := make([]int, 0)
+	for _, proc := range procs {
+		if proc.PID == recordedPID || isOwnDaemonProcess(proc, wrap.repoPath()) {
+			pids = append(pids, proc.PID)
+		}
+	}
+	return pids, nil
+}
+
+// isOwnDaemonProcess reports whether the process is an `ipfs` daemon using the
+// repo, because its command line carries the `--repo-dir` argument (see
+// `daemonArgs`) or its environment points `IPFS_PATH` at the repo. The paths
+// are compared exactly, so the repo of tenant `acme` is not mistaken for the
+// repo of tenant `acme2`.
+func isOwnDaemonProcess(proc oskit.ProcessInfo, repoPath string) bool {
+	// Only the daemon holds the repo lock, ignore any short-lived client
+	// subcommands which are executed against the same repo.
+	if len(proc.Args) < 2 || !containsString(proc.Args[1:], "daemon") {
+		return false
+	}
+	repoPath = filepath.Clean(repoPath)
+	for i, arg := range proc.Args[1:] {
+		if arg == "--" {
+			break
+		}
+		value, ok := strings.CutPrefix(arg, "--repo-dir=")
+		if !ok && arg == "--repo-dir" && i+2 < len(proc.Args) {
+			value, ok = proc.Args[i+2], true
+		}
+		if ok && filepath.Clean(value) == repoPath {
+			return true
+		}
+	}
+	for _, env := range proc.Environ {
+		if value, ok := strings.CutPrefix(env, "IPFS_PATH="); ok && filepath.Clean(value) == repoPath {
+			return true
+		}
+	}
+	return false
+}
+
+// terminateOwnDaemons sends a `SIGTERM` signal to every running daemon found
+// by `findOwnDaemonPIDs`, kills the daemons which are still running after the
+// grace period and removes the PID file.
+func (wrap *ipfsCliWrapper) terminateOwnDaemons() error {
+	pids, err := wrap.findOwnDaemonPIDs()
+	if err != nil {
+		return fmt.Errorf("failed to find running ipfs daemons: %v", err)
+	}
+	if len(pids) == 0 {
+		wrap.removePIDFile()
+		return nil
+	}
+
+	status := ShutdownStatus{Time: time.Now(), Method: ShutdownGraceful}
+	if wrap.shutdownGracePeriod > 0 {
+		for _, pid := range pids {
+			if err := wrap.osOperator.TerminateProcess(pid); err != nil {
+				return err
+			}
+			wrap.logger.Debug("terminated ipfs daemon", "pid", pid)
+		}
+	}
+
+	// Wait for the daemons to exit, then kill the remaining ones.
+	deadline := time.Now().Add(wrap.shutdownGracePeriod)
+	for len(pids) > 0 {
+		running := pids[:0]
+		for _, pid := range pids {
+			if ok, err := wrap.osOperator.IsProcessRunning(pid); err != nil || ok {
+				running = append(running, pid)
+			}
+		}
+		pids = running
+		if len(pids) == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	for _, pid := range pids {
+		if err := wrap.osOperator.KillProcess(pid); err != nil {
+			return err
+		}
+		wrap.logger.Debug("killed ipfs daemon", "pid", pid)
+		status.Method = ShutdownKilled
+	}
+	status.Duration = time.Since(status.Time)
+	wrap.lastShutdown = &status
+	wrap.removePIDFile()
+	return nil
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package ipfscliwrapper_test
+
+import (
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/oskit"
+)
+
+// TestIsOwnDaemonProcess checks only the daemons using exactly our repo are
+// recognized, and not the daemons of repos sharing a prefix with it.
+func TestIsOwnDaemonProcess(t *testing.T) {
+	const repo = "/srv/app/data/tenants/acme"
+	tests := []struct {
+		name     string
+		proc     oskit.ProcessInfo
+		expected bool
+	}{
+		{
+			name:     "RepoDirArgument",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon", "--enable-gc=true", "--repo-dir=" + repo}},
+			expected: true,
+		},
+		{
+			name:     "RepoDirArgumentUnclean",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon", "--repo-dir=/srv/app/data/tenants/./acme/"}},
+			expected: true,
+		},
+		{
+			name:     "RepoDirSeparateValue",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "--repo-dir", repo, "daemon"}},
+			expected: true,
+		},
+		{
+			name:     "RepoDirOfOtherTenant",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon", "--repo-dir=" + repo + "2"}},
+			expected: false,
+		},
+		{
+			name:     "RepoDirOfNestedRepo",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon", "--repo-dir=" + repo + "/nested"}},
+			expected: false,
+		},
+		{
+			name:     "ClientCommand",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "--repo-dir=" + repo, "cat", "bafkqaaa"}},
+			expected: false,
+		},
+		{
+			name:     "RepoDirAfterSeparator",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon", "--", "--repo-dir=" + repo}},
+			expected: false,
+		},
+		{
+			name:     "Environment",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon"}, Environ: []string{"HOME=/root", "IPFS_PATH=" + repo}},
+			expected: true,
+		},
+		{
+			name:     "EnvironmentOfOtherTenant",
+			proc:     oskit.ProcessInfo{Args: []string{"ipfs", "daemon"}, Environ: []string{"IPFS_PATH=" + repo + "2"}},
+			expected: false,
+		},
+		{
+			name:     "UnreadableCommandLine",
+			proc:     oskit.ProcessInfo{Environ: []string{"IPFS_PATH=" + repo}},
+			expected: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := ipfscliwrapper.IsOwnDaemonProcess(test.proc, repo); actual != test.expected {
+				t.Errorf("Expected %v for %+v, but got %v", test.expected, test.proc, actual)
+			}
+		})
+	}
+}
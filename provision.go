@@ -0,0 +1,45 @@
+package ipfscliwrapper
+
+import (
+	"time"
+)
+
+// ProvisionReport holds the timings of provisioning the node at runtime, see
+// `ProvisionReport`. The durations are zero for the steps which were skipped,
+// for example because the binary was already installed.
+type ProvisionReport struct {
+	// Downloaded reports whether the kubo archive was downloaded rather than
+	// taken from the archive cache, see `WithKeepArchive`. DownloadDuration
+	// is how long the download took and DownloadBytes the archive size.
+	Downloaded       bool
+	DownloadDuration time.Duration
+	DownloadBytes    int64
+
+	// ExtractDuration is how long extracting the archive took.
+	ExtractDuration time.Duration
+
+	// Initialized reports whether the repo was created and InitDuration how
+	// long it took until the repo was usable.
+	Initialized  bool
+	InitDuration time.Duration
+
+	// ConstructorDuration is how long `NewWrapper` took in total.
+	ConstructorDuration time.Duration
+
+	// FirstReadyDuration is how long the first daemon started by the wrapper
+	// took until its API accepted connections. It is zero until then.
+	FirstReadyDuration time.Duration
+}
+
+func (wrap *ipfsCliWrapper) ProvisionReport() ProvisionReport {
+	wrap.provisionMu.Lock()
+	defer wrap.provisionMu.Unlock()
+	return wrap.provision
+}
+
+// updateProvisionReport applies the update to the provisioning report.
+func (wrap *ipfsCliWrapper) updateProvisionReport(update func(report *ProvisionReport)) {
+	wrap.provisionMu.Lock()
+	defer wrap.provisionMu.Unlock()
+	update(&wrap.provision)
+}
@@ -0,0 +1,110 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"slices"
+)
+
+// ErrReadOnly is returned by the calls which would modify the node, like
+// `AddFile`, `Unpin` or `GarbageCollection`, when the wrapper was created
+// with the `WithReadOnly` option.
+var ErrReadOnly = errors.New("ipfs wrapper is read-only")
+
+// mutatingCommands are the leading words of the subcommands which modify the
+// repo, its configuration or what the node publishes. `RunCommand` refuses
+// them in read-only mode.
+var mutatingCommands = [][]string{
+	{"add"},
+	{"pin", "add"},
+	{"pin", "rm"},
+	{"pin", "update"},
+	{"pin", "remote", "add"},
+	{"pin", "remote", "rm"},
+	{"pin", "remote", "service", "add"},
+	{"pin", "remote", "service", "rm"},
+	{"object", "new"},
+	{"object", "patch"},
+	{"object", "put"},
+	{"repo", "gc"},
+	{"repo", "migrate"},
+	{"config", "edit"},
+	{"config", "replace"},
+	{"config", "profile", "apply"},
+	{"files", "chcid"},
+	{"files", "chroot"},
+	{"files", "cp"},
+	{"files", "flush"},
+	{"files", "mkdir"},
+	{"files", "mv"},
+	{"files", "rm"},
+	{"files", "write"},
+	{"dag", "import"},
+	{"dag", "put"},
+	{"block", "put"},
+	{"block", "rm"},
+	{"tar", "add"},
+	{"filestore"},
+	{"urlstore"},
+	{"name", "publish"},
+	{"key", "gen"},
+	{"key", "import"},
+	{"key", "rename"},
+	{"key", "rm"},
+	{"key", "rotate"},
+	{"bootstrap", "add"},
+	{"bootstrap", "rm"},
+	{"routing", "put"},
+	{"routing", "provide"},
+	{"dht", "put"},
+	{"dht", "provide"},
+	{"bitswap", "reprovide"},
+	{"shutdown"},
+}
+
+// checkWritable returns `ErrReadOnly` in read-only mode.
+func (wrap *ipfsCliWrapper) checkWritable() error {
+	if wrap.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// checkWritableCommand returns `ErrReadOnly` in read-only mode if the
+// arguments of `ipfs` run a subcommand which modifies the node.
+func (wrap *ipfsCliWrapper) checkWritableCommand(args []string) error {
+	if wrap.readOnly && isMutatingCommand(args) {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// isMutatingCommand reports whether the arguments of `ipfs` run one of the
+// `mutatingCommands`, or set a config value with `ipfs config <key> <value>`.
+// Arguments whose subcommand cannot be told are taken for mutating ones.
+func isMutatingCommand(args []string) bool {
+	words, err := commandWords(args)
+	if err != nil {
+		return true
+	}
+	if len(words) == 0 {
+		return false
+	}
+	if words[0] == "config" {
+		// `ipfs config <key>` reads, `ipfs config <key> <value>` writes.
+		if len(words) >= 3 && !slices.Contains([]string{"show", "profile"}, words[1]) {
+			return true
+		}
+	}
+	if words[0] == "add" {
+		// Hashing does not write anything. After "--" the arguments are
+		// paths, not flags.
+		flags := args
+		if i := slices.Index(args, "--"); i >= 0 {
+			flags = args[:i]
+		}
+		return !slices.ContainsFunc(flags, func(arg string) bool {
+			return arg == "-n" || arg == "--only-hash" || arg == "--only-hash=true"
+		})
+	}
+	return matchesCommand(words, mutatingCommands)
+}
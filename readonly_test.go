@@ -0,0 +1,105 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestIsMutatingCommand checks which arguments of `ipfs` are refused in
+// read-only mode, whatever flags come with them.
+func TestIsMutatingCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected bool
+	}{
+		{name: "Cat", args: []string{"cat", "bafkqaaa"}, expected: false},
+		{name: "PinLs", args: []string{"pin", "ls", "--type=recursive"}, expected: false},
+		{name: "PinRm", args: []string{"pin", "rm", "bafkqaaa"}, expected: true},
+		{name: "PinRmWithLeadingFlag", args: []string{"--timeout", "1s", "pin", "rm", "bafkqaaa"}, expected: true},
+		{name: "PinRmWithFlagBetweenWords", args: []string{"pin", "--timeout", "1s", "rm", "bafkqaaa"}, expected: true},
+		{name: "PinRmWithAttachedValue", args: []string{"--timeout=1s", "pin", "rm", "bafkqaaa"}, expected: true},
+		{name: "UnknownLeadingFlag", args: []string{"--unknown", "cat", "bafkqaaa"}, expected: true},
+		{name: "ConfigGet", args: []string{"config", "Addresses.Gateway"}, expected: false},
+		{name: "ConfigGetWithLeadingFlag", args: []string{"--encoding", "json", "config", "Addresses.Gateway"}, expected: false},
+		{name: "ConfigSet", args: []string{"config", "Addresses.Gateway", "/ip4/127.0.0.1/tcp/1"}, expected: true},
+		{name: "ConfigSetWithFlag", args: []string{"config", "--json", "Datastore.StorageMax", `"1GB"`}, expected: true},
+		{name: "ConfigSetAfterSeparator", args: []string{"config", "--", "Addresses.Gateway", "/ip4/127.0.0.1/tcp/1"}, expected: true},
+		{name: "ConfigShow", args: []string{"config", "show"}, expected: false},
+		{name: "ConfigProfileApply", args: []string{"config", "profile", "apply", "lowpower"}, expected: true},
+		{name: "Add", args: []string{"add", "-Q", "file.txt"}, expected: true},
+		{name: "AddOnlyHash", args: []string{"add", "--only-hash", "-Q", "file.txt"}, expected: false},
+		{name: "AddOnlyHashShort", args: []string{"add", "-n", "file.txt"}, expected: false},
+		{name: "AddFileNamedLikeFlag", args: []string{"add", "--", "-n"}, expected: true},
+		{name: "ObjectPatch", args: []string{"object", "patch", "add-link", "bafkqaaa", "name", "bafkqaaa"}, expected: true},
+		{name: "ObjectGet", args: []string{"object", "get", "bafkqaaa"}, expected: false},
+		{name: "RoutingProvide", args: []string{"routing", "provide", "bafkqaaa"}, expected: true},
+		{name: "BitswapReprovide", args: []string{"bitswap", "reprovide"}, expected: true},
+		{name: "BitswapStat", args: []string{"bitswap", "stat"}, expected: false},
+		{name: "Urlstore", args: []string{"urlstore", "add", "https://example.com/file"}, expected: true},
+		{name: "Filestore", args: []string{"filestore", "ls"}, expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if mutating := ipfscliwrapper.IsMutatingCommand(test.args); mutating != test.expected {
+				t.Errorf("Expected %v, but got %v", test.expected, mutating)
+			}
+		})
+	}
+}
+
+// TestReadOnly checks the mutating calls are refused in read-only mode,
+// without running `ipfs`.
+func TestReadOnly(t *testing.T) {
+	wrapper, _ := newFakeKuboWrapper(t, ipfscliwrapper.WithReadOnly())
+	ctx := context.Background()
+
+	if _, err := wrapper.AddFileContent(ctx, "read-only.txt", []byte("read-only")); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly adding, but got: %v", err)
+	}
+	if err := wrapper.Unpin(ctx, "bafkqaaa"); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly unpinning, but got: %v", err)
+	}
+	if _, err := wrapper.RunCommand(ctx, "--timeout", "1s", "pin", "rm", "bafkqaaa"); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly unpinning with a timeout, but got: %v", err)
+	}
+	if _, err := wrapper.RunCommand(ctx, "config", "Addresses.Gateway", "/ip4/127.0.0.1/tcp/1"); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly setting config, but got: %v", err)
+	}
+	if _, err := wrapper.RunCommand(ctx, "config", "Addresses.Gateway"); err != nil {
+		t.Errorf("Expected reading config to be allowed, but got: %v", err)
+	}
+}
+
+// TestReadOnlyFetch checks `FetchWithFallback` does not import the content
+// of the gateways into the repo in read-only mode.
+func TestReadOnlyFetch(t *testing.T) {
+	var requests atomic.Int32
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte("car"))
+	}))
+	defer gateway.Close()
+	wrapper, repoPath := newFakeKuboWrapper(t,
+		ipfscliwrapper.WithReadOnly(),
+		ipfscliwrapper.WithFallbackGateways(gateway.URL))
+
+	if _, err := wrapper.FetchWithFallback(context.Background(), "bafkqaaa"); !errors.Is(err, ipfscliwrapper.ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, but got: %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("Expected no gateway request, but got %d", n)
+	}
+	for _, invocation := range fakeKuboInvocations(t, repoPath) {
+		if strings.Contains(invocation, "dag import") {
+			t.Errorf("Expected no import, but got `ipfs %s`", invocation)
+		}
+	}
+}
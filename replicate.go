@@ -0,0 +1,152 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+)
+
+// ReplicationResult is the outcome of `ReplicatePin` on a single node.
+type ReplicationResult struct {
+	// Node is the node the result belongs to.
+	Node IpfsCliWrapper
+
+	// PeerID is the peer identity of the node, or empty if it could not be
+	// looked up.
+	PeerID string
+
+	// Pinned is true if the node pins the content after the call.
+	Pinned bool
+
+	// AlreadyPinned is true if the node pinned the content before the call.
+	AlreadyPinned bool
+
+	// Err holds the error which prevented the node from pinning the content.
+	Err error
+}
+
+// ReplicatePin pins the content on every given node, giving simple
+// redundancy without deploying a cluster. The nodes are connected to each
+// other first, unless they already are, so whichever node holds the content
+// can serve it to the others directly. The nodes pin concurrently and the
+// returned results are in the order of the nodes.
+//
+// An error is returned only if no node pins the content afterwards; inspect
+// the results for the status of every node.
+//
+// Example:
+//
+//	results, err := ReplicatePin(ctx, cid, nodeA, nodeB, nodeC)
+//	for _, res := range results {
+//	    fmt.Println(res.PeerID, res.Pinned, res.Err)
+//	}
+func ReplicatePin(ctx context.Context, cid string, nodes ...IpfsCliWrapper) ([]ReplicationResult, error) {
+	if cid == "" {
+		return nil, fmt.Errorf("cannot have missing: %v", "cid")
+	}
+	if len(nodes) == 0 {
+		return nil, ErrEmptyNodePool
+	}
+
+	results := make([]ReplicationResult, len(nodes))
+	infos := make([]*IpfsNodeInfo, len(nodes))
+	for i, node := range nodes {
+		results[i].Node = node
+		info, err := nodeInfo(ctx, node)
+		if err != nil {
+			// Keep going, the node may still be able to find the content
+			// through the network.
+			continue
+		}
+		infos[i] = info
+		results[i].PeerID = info.ID
+	}
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node IpfsCliWrapper) {
+			defer wg.Done()
+			connectToPeers(ctx, node, infos, i)
+
+			newlyPinned, err := node.PinIfAbsent(ctx, cid)
+			if err != nil {
+				results[i].Err = err
+				return
+			}
+			results[i].Pinned = true
+			results[i].AlreadyPinned = !newlyPinned
+		}(i, node)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Pinned {
+			return results, nil
+		}
+		errs = append(errs, res.Err)
+	}
+	return results, fmt.Errorf("failed replicating pin on any node: %w", errors.Join(errs...))
+}
+
+// nodeInfo returns the identity and addresses of the node.
+func nodeInfo(ctx context.Context, node IpfsCliWrapper) (*IpfsNodeInfo, error) {
+	output, err := node.RunCommand(ctx, "id", "--enc=json")
+	if err != nil {
+		return nil, err
+	}
+	var info IpfsNodeInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("failed decoding node info: %v", err)
+	}
+	return &info, nil
+}
+
+// connectToPeers connects the node to the other nodes it is not connected to
+// yet. Failures are ignored, the node may still find the content through the
+// routing system.
+func connectToPeers(ctx context.Context, node IpfsCliWrapper, infos []*IpfsNodeInfo, self int) {
+	connected := make(map[string]bool)
+	if output, err := node.RunCommand(ctx, "swarm", "peers", "--enc=json"); err == nil {
+		peers, _ := outputkit.ParseSwarmPeers(output)
+		for _, peer := range peers {
+			connected[peer] = true
+		}
+	}
+
+	for i, info := range infos {
+		if i == self || info == nil || info.ID == "" || connected[info.ID] {
+			continue
+		}
+		for _, addr := range dialOrder(info.Addresses) {
+			if _, err := node.RunCommand(ctx, "swarm", "connect", addr); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// dialOrder returns the addresses with loopback ones first, since the nodes
+// managed by this package commonly run on the same machine.
+func dialOrder(addrs []string) []string {
+	ordered := append([]string(nil), addrs...)
+	rank := func(addr string) int {
+		switch {
+		case strings.HasPrefix(addr, "/ip4/127.") || strings.HasPrefix(addr, "/ip6/::1/"):
+			return 0
+		case strings.Contains(addr, "/p2p-circuit"):
+			return 2
+		default:
+			return 1
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool { return rank(ordered[i]) < rank(ordered[j]) })
+	return ordered
+}
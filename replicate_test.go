@@ -0,0 +1,83 @@
+package ipfscliwrapper_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+func (m *MockNode) RunCommand(ctx context.Context, args ...string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch args[0] + " " + args[1] {
+	case "id --enc=json":
+		return json.Marshal(ipfscliwrapper.IpfsNodeInfo{
+			ID:        m.peerID,
+			Addresses: []string{"/ip4/10.0.0.1/tcp/4001/p2p/" + m.peerID, "/ip4/127.0.0.1/tcp/4001/p2p/" + m.peerID},
+		})
+	case "swarm peers":
+		var swarm struct {
+			Peers []map[string]string `json:"Peers"`
+		}
+		for _, addr := range m.connected {
+			swarm.Peers = append(swarm.Peers, map[string]string{"Addr": addr, "Peer": addr[strings.LastIndex(addr, "/")+1:]})
+		}
+		return json.Marshal(swarm)
+	case "swarm connect":
+		if !strings.HasPrefix(args[2], "/ip4/127.0.0.1/") {
+			return nil, errors.New("unreachable")
+		}
+		m.connected = append(m.connected, args[2])
+		return nil, nil
+	}
+	return nil, errors.New("unexpected command")
+}
+
+func (m *MockNode) PinIfAbsent(ctx context.Context, cid string) (bool, error) {
+	if m.pinErr != nil {
+		return false, m.pinErr
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	newlyPinned := !m.pinned
+	m.pinned = true
+	return newlyPinned, nil
+}
+
+// TestReplicatePin checks the nodes get connected over loopback and the
+// status of every node is reported.
+func TestReplicatePin(t *testing.T) {
+	a := &MockNode{peerID: "PeerA", pinned: true}
+	b := &MockNode{peerID: "PeerB"}
+	c := &MockNode{peerID: "PeerC", pinErr: errors.New("disk full")}
+
+	results, err := ipfscliwrapper.ReplicatePin(context.Background(), "bafytest", a, b, c)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if !results[0].Pinned || !results[0].AlreadyPinned || results[0].PeerID != "PeerA" {
+		t.Errorf("Unexpected result for node a: %+v", results[0])
+	}
+	if !results[1].Pinned || results[1].AlreadyPinned {
+		t.Errorf("Unexpected result for node b: %+v", results[1])
+	}
+	if results[2].Pinned || results[2].Err == nil {
+		t.Errorf("Unexpected result for node c: %+v", results[2])
+	}
+	if len(b.connected) != 2 || !strings.HasPrefix(b.connected[0], "/ip4/127.0.0.1/") {
+		t.Errorf("Expected node b to connect to both peers over loopback, but got %v", b.connected)
+	}
+}
+
+// TestReplicatePinFailure checks an error is returned if no node pins.
+func TestReplicatePinFailure(t *testing.T) {
+	a := &MockNode{peerID: "PeerA", pinErr: errors.New("offline")}
+	if _, err := ipfscliwrapper.ReplicatePin(context.Background(), "bafytest", a); err == nil {
+		t.Errorf("Expected an error")
+	}
+}
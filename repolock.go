@@ -0,0 +1,74 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/lockkit"
+)
+
+// ErrRepoInUse is returned by `NewWrapper` and `StartDaemonInBackground`
+// when another wrapper, in this or another process, is provisioning the repo
+// or starting its daemon, and it was not released within the time set with
+// `WithRepoLockTimeout`.
+var ErrRepoInUse = errors.New("ipfs repo is in use by another wrapper")
+
+// ErrBinDirInUse is returned by `NewWrapper` when another wrapper, in this
+// or another process, is installing binaries into the same bin directory,
+// and it was not done within the time set with `WithRepoLockTimeout`.
+var ErrBinDirInUse = errors.New("ipfs bin directory is in use by another wrapper")
+
+// repoLockFileName is the name of the file in the repo directory which is
+// locked while a wrapper provisions the repo or starts its daemon. It is
+// separate from the `repo.lock` of kubo, which is held by the daemon for as
+// long as it runs.
+const repoLockFileName = "ipfs-cli-wrapper.lock"
+
+// binLockFileName is the name of the file in the bin directory which is
+// locked while a wrapper installs binaries into it. The bin directory is
+// shared by the wrappers of all the repos, so the repo lock does not keep
+// them from downloading the same release at the same time.
+const binLockFileName = "ipfs-cli-wrapper-bin.lock"
+
+// lockRepo takes the wrapper lock of the repo, waiting for it to be released
+// up to the duration set with `WithRepoLockTimeout`, or until the context is
+// done. The lock must be released with `Unlock` once the repo is left in a
+// consistent state.
+func (wrap *ipfsCliWrapper) lockRepo(ctx context.Context) (*lockkit.Lock, error) {
+	path := filepath.Join(wrap.repoPath(), repoLockFileName)
+	lockCtx, cancel := context.WithTimeout(ctx, wrap.repoLockTimeout)
+	defer cancel()
+	lock, err := lockkit.Acquire(lockCtx, path)
+	if errors.Is(err, lockkit.ErrLocked) && ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to lock repo: %w", ctx.Err())
+	}
+	if errors.Is(err, lockkit.ErrLocked) {
+		return nil, fmt.Errorf("%w: `%s` is locked", ErrRepoInUse, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock repo: %v", err)
+	}
+	return lock, nil
+}
+
+// lockBinDir takes the wrapper lock of the bin directory like `lockRepo`.
+// It is always taken after the repo lock so two wrappers never wait for
+// each other.
+func (wrap *ipfsCliWrapper) lockBinDir(ctx context.Context) (*lockkit.Lock, error) {
+	path := filepath.Join(wrap.binDir, binLockFileName)
+	lockCtx, cancel := context.WithTimeout(ctx, wrap.repoLockTimeout)
+	defer cancel()
+	lock, err := lockkit.Acquire(lockCtx, path)
+	if errors.Is(err, lockkit.ErrLocked) && ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to lock bin directory: %w", ctx.Err())
+	}
+	if errors.Is(err, lockkit.ErrLocked) {
+		return nil, fmt.Errorf("%w: `%s` is locked", ErrBinDirInUse, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock bin directory: %v", err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,66 @@
+package ipfscliwrapper_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+	"github.com/bartmika/ipfs-cli-wrapper/internal/lockkit"
+)
+
+// TestRepoLock checks a wrapper is refused the repo locked by another one,
+// or waits for it with `WithRepoLockTimeout`.
+func TestRepoLock(t *testing.T) {
+	options, repoPath := fakeKuboOptions(t)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	lock, err := lockkit.TryLock(filepath.Join(repoPath, "ipfs-cli-wrapper.lock"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if _, err := ipfscliwrapper.NewWrapper(options...); !errors.Is(err, ipfscliwrapper.ErrRepoInUse) {
+		t.Errorf("Expected ErrRepoInUse, but got: %v", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { lock.Unlock() })
+	if _, err := ipfscliwrapper.NewWrapper(append(options, ipfscliwrapper.WithRepoLockTimeout(time.Minute))...); err != nil {
+		t.Errorf("Expected the wrapper once the lock is released, but got: %v", err)
+	}
+}
+
+// TestBinDirLock checks a wrapper is refused the bin directory locked by a
+// wrapper of another repo, or waits for it with `WithRepoLockTimeout`.
+func TestBinDirLock(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ipfs binary is a shell script")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	binDir := filepath.Join(dir, "data", "ipfs-cli-wrapper", "bin")
+	binaryPath := filepath.Join(binDir, "kubo", ipfscliwrapper.DefaultKuboVersion, "ipfs")
+	if err := os.MkdirAll(filepath.Dir(binaryPath), 0755); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if err := os.WriteFile(binaryPath, []byte(fakeKuboScript), 0755); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	lock, err := lockkit.TryLock(filepath.Join(binDir, "ipfs-cli-wrapper-bin.lock"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	options := []ipfscliwrapper.Option{ipfscliwrapper.WithXDGLayout(), ipfscliwrapper.WithWorkDir(dir)}
+	if _, err := ipfscliwrapper.NewWrapper(options...); !errors.Is(err, ipfscliwrapper.ErrBinDirInUse) {
+		t.Errorf("Expected ErrBinDirInUse, but got: %v", err)
+	}
+
+	time.AfterFunc(200*time.Millisecond, func() { lock.Unlock() })
+	if _, err := ipfscliwrapper.NewWrapper(append(options, ipfscliwrapper.WithRepoLockTimeout(time.Minute))...); err != nil {
+		t.Errorf("Expected the wrapper once the lock is released, but got: %v", err)
+	}
+}
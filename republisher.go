@@ -0,0 +1,200 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultIPNSRepublishInterval is how often tracked IPNS names get published
+// again. The value mirrors the `Ipns.RepublishPeriod` default used by kubo
+// and is comfortably shorter than the default 48 hour record lifetime.
+const DefaultIPNSRepublishInterval = 4 * time.Hour
+
+// IPNSTrackedName represents an IPNS name which the wrapper keeps alive by
+// periodically republishing it while the daemon is running.
+type IPNSTrackedName struct {
+	// Key is the name of the key (as listed by `ipfs key list`) used to sign
+	// the record, for example "self".
+	Key string
+
+	// Value is the path the name points to, for example "/ipfs/<cid>".
+	Value string
+
+	// LastPublishedAt is the time of the last successful publish, or the zero
+	// value if the name was never published by the republisher.
+	LastPublishedAt time.Time
+
+	// LastError holds the error from the most recent publish attempt, or nil
+	// if the most recent attempt succeeded.
+	LastError error
+}
+
+// IPNSRepublishFailureHook is called every time the republisher fails to
+// publish a tracked IPNS name.
+type IPNSRepublishFailureHook func(key string, value string, err error)
+
+// ipnsRepublisher keeps track of IPNS names and republishes them on an
+// interval in a background goroutine.
+type ipnsRepublisher struct {
+	mu        sync.Mutex
+	names     map[string]*IPNSTrackedName
+	interval  time.Duration
+	onFailure IPNSRepublishFailureHook
+	publish   func(ctx context.Context, key string, value string) error
+	logger    *slog.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newIPNSRepublisher(logger *slog.Logger) *ipnsRepublisher {
+	return &ipnsRepublisher{
+		names:    make(map[string]*IPNSTrackedName),
+		interval: DefaultIPNSRepublishInterval,
+		logger:   logger,
+	}
+}
+
+// track registers the key/value pair, replacing any value previously tracked
+// for the same key.
+func (r *ipnsRepublisher) track(key string, value string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names[key] = &IPNSTrackedName{Key: key, Value: value}
+}
+
+// untrack removes the key from the set of republished names.
+func (r *ipnsRepublisher) untrack(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.names, key)
+}
+
+// list returns a copy of the tracked names sorted by key.
+func (r *ipnsRepublisher) list() []IPNSTrackedName {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]IPNSTrackedName, 0, len(r.names))
+	for _, name := range r.names {
+		names = append(names, *name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Key < names[j].Key })
+	return names
+}
+
+// start launches the background republish loop. Calling start while the
+// loop is already running does nothing.
+func (r *ipnsRepublisher) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.done = make(chan struct{})
+	go r.run(ctx, r.done)
+}
+
+// stop terminates the background republish loop and waits for it to exit.
+func (r *ipnsRepublisher) stop() {
+	r.mu.Lock()
+	cancel, done := r.cancel, r.done
+	r.cancel, r.done = nil, nil
+	r.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+func (r *ipnsRepublisher) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	// Publish right away so records which expired while the daemon was
+	// offline become resolvable again as soon as possible.
+	r.republishAll(ctx)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.republishAll(ctx)
+		}
+	}
+}
+
+func (r *ipnsRepublisher) republishAll(ctx context.Context) {
+	for _, name := range r.list() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := r.publish(ctx, name.Key, name.Value)
+
+		r.mu.Lock()
+		// Only record the outcome if the name was not untracked or changed
+		// while we were publishing it.
+		if tracked, ok := r.names[name.Key]; ok && tracked.Value == name.Value {
+			tracked.LastError = err
+			if err == nil {
+				tracked.LastPublishedAt = time.Now()
+			}
+		}
+		onFailure := r.onFailure
+		r.mu.Unlock()
+
+		if err != nil {
+			r.logger.Warn("failed republishing ipns name",
+				slog.String("key", name.Key),
+				slog.String("value", name.Value),
+				slog.Any("error", err))
+			if onFailure != nil {
+				onFailure(name.Key, name.Value, err)
+			}
+			continue
+		}
+		r.logger.Debug("ipns name republished",
+			slog.String("key", name.Key),
+			slog.String("value", name.Value))
+	}
+}
+
+func (wrap *ipfsCliWrapper) TrackIPNSName(key string, value string) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	if key == "" {
+		return fmt.Errorf("cannot have missing: %v", "key")
+	}
+	if value == "" {
+		return fmt.Errorf("cannot have missing: %v", "value")
+	}
+	wrap.ipnsRepublisher.track(key, value)
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) UntrackIPNSName(key string) {
+	wrap.ipnsRepublisher.untrack(key)
+}
+
+func (wrap *ipfsCliWrapper) TrackedIPNSNames() []IPNSTrackedName {
+	return wrap.ipnsRepublisher.list()
+}
+
+// publishIPNSName publishes the value under the IPNS name of the given key
+// with `NamePublish`.
+func (wrap *ipfsCliWrapper) publishIPNSName(ctx context.Context, key string, value string) error {
+	_, err := wrap.NamePublish(ctx, value, WithPublishKey(key))
+	return err
+}
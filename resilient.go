@@ -0,0 +1,315 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+)
+
+// Defaults of the `ResilientAddOptions` fields.
+const (
+	// DefaultResilientAddSegmentSize is a multiple of the 256 KiB default
+	// chunk size of kubo so segments do not split chunks.
+	DefaultResilientAddSegmentSize = 256 * 1024 * 1024
+	DefaultResilientAddMaxRetries  = 3
+	DefaultResilientAddRetryDelay  = 5 * time.Second
+)
+
+// ResilientAddOptions controls how `AddFileResilient` splits and retries the
+// add of a large file. The zero value of every field selects its default.
+type ResilientAddOptions struct {
+	// SegmentSize is the number of bytes added per segment. Defaults to
+	// `DefaultResilientAddSegmentSize`.
+	SegmentSize int64
+
+	// MaxRetries is how many times a failed segment is retried before giving
+	// up. Defaults to `DefaultResilientAddMaxRetries`.
+	MaxRetries int
+
+	// RetryDelay is the pause before retrying a failed segment. Defaults to
+	// `DefaultResilientAddRetryDelay`.
+	RetryDelay time.Duration
+
+	// StateFilePath is where the progress is recorded so an interrupted add
+	// resumes from the last completed segment. Defaults to a file inside the
+	// repo named after the hash of the absolute path of the added file, so
+	// nothing is written next to the file itself.
+	StateFilePath string
+}
+
+// resilientAddStateDirName is the name of the directory, inside the repo
+// directory, which holds the default state files of `AddFileResilient`.
+const resilientAddStateDirName = "ipfs-cli-wrapper-add"
+
+// resilientAddState is the content of the state file of `AddFileResilient`.
+type resilientAddState struct {
+	// FileSize, ModTime and SegmentSize identify the add the state belongs
+	// to, the progress is discarded if any of them changed.
+	FileSize    int64     `json:"file_size"`
+	ModTime     time.Time `json:"mod_time"`
+	SegmentSize int64     `json:"segment_size"`
+
+	Segments []resilientAddSegment `json:"segments"`
+}
+
+type resilientAddSegment struct {
+	CID            string `json:"cid"`
+	Size           int64  `json:"size"`
+	CumulativeSize uint64 `json:"cumulative_size"`
+}
+
+func (wrap *ipfsCliWrapper) AddFileResilient(ctx context.Context, path string, opts ResilientAddOptions) (string, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return "", err
+	}
+
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultResilientAddSegmentSize
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultResilientAddMaxRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultResilientAddRetryDelay
+	}
+	if opts.StateFilePath == "" {
+		statePath, err := wrap.resilientAddStatePath(path)
+		if err != nil {
+			return "", err
+		}
+		opts.StateFilePath = statePath
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %v", err)
+	}
+	if err := wrap.checkDiskSpace(wrap.repoPath(), uint64(info.Size())); err != nil {
+		return "", err
+	}
+
+	// STEP 1: Resume from the state file if it belongs to this very file.
+	state := readResilientAddState(opts.StateFilePath)
+	if state == nil || state.FileSize != info.Size() || !state.ModTime.Equal(info.ModTime()) || state.SegmentSize != opts.SegmentSize {
+		state = &resilientAddState{FileSize: info.Size(), ModTime: info.ModTime(), SegmentSize: opts.SegmentSize}
+	} else if len(state.Segments) > 0 {
+		wrap.logger.Debug("resuming add of file",
+			slog.String("filepath", path),
+			slog.Int("segments_done", len(state.Segments)))
+	}
+
+	// STEP 2: Add the remaining segments one after another, retrying each
+	// on its own so a failure does not restart the whole file.
+	for offset := int64(len(state.Segments)) * opts.SegmentSize; offset < info.Size() || len(state.Segments) == 0; offset += opts.SegmentSize {
+		size := min(opts.SegmentSize, info.Size()-offset)
+		segment, err := wrap.addSegmentWithRetry(ctx, file, offset, size, opts)
+		if err != nil {
+			return "", err
+		}
+		state.Segments = append(state.Segments, segment)
+		if err := writeResilientAddState(opts.StateFilePath, state); err != nil {
+			return "", err
+		}
+	}
+
+	// STEP 3: Join the segments into a single file.
+	cid := state.Segments[0].CID
+	if len(state.Segments) > 1 {
+		cid, err = wrap.joinSegments(ctx, state)
+		if err != nil {
+			return "", err
+		}
+		if err := wrap.Pin(ctx, cid); err != nil {
+			return "", err
+		}
+		// The pin of the file now protects the segments.
+		for _, segment := range state.Segments {
+			if output, err := wrap.combinedOutput(wrap.command(ctx, "pin", "rm", "--", segment.CID)); err != nil {
+				wrap.logger.Warn("failed unpinning segment",
+					slog.String("cid", segment.CID),
+					slog.Any("error", err),
+					wrap.outputAttr(output))
+			}
+		}
+	}
+
+	if err := os.Remove(opts.StateFilePath); err != nil && !os.IsNotExist(err) {
+		wrap.logger.Warn("failed removing add state file", slog.Any("error", err))
+	}
+	wrap.logger.Debug("file added to ipfs successfully",
+		slog.String("filepath", path),
+		slog.Int("segments", len(state.Segments)),
+		slog.String("cid", cid))
+	return cid, nil
+}
+
+// resilientAddStatePath returns the default path of the state file for the
+// add of the file, see `ResilientAddOptions.StateFilePath`.
+func (wrap *ipfsCliWrapper) resilientAddStatePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve file path: %v", err)
+	}
+	dir := filepath.Join(wrap.repoPath(), resilientAddStateDirName)
+	if err := os.MkdirAll(dir, wrap.dirMode); err != nil {
+		return "", fmt.Errorf("failed to create add state directory: %v", err)
+	}
+	sum := sha256.Sum256([]byte(absPath))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// addSegmentWithRetry adds the section of the file, retrying on failure.
+func (wrap *ipfsCliWrapper) addSegmentWithRetry(ctx context.Context, file *os.File, offset int64, size int64, opts ResilientAddOptions) (resilientAddSegment, error) {
+	var lastErr error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			wrap.logger.Warn("retrying segment add",
+				slog.Int64("offset", offset),
+				slog.Int("attempt", attempt),
+				slog.Any("error", lastErr))
+			select {
+			case <-ctx.Done():
+				return resilientAddSegment{}, ctx.Err()
+			case <-time.After(opts.RetryDelay):
+			}
+		}
+
+		segment, err := wrap.addSegment(ctx, file, offset, size)
+		if err == nil {
+			return segment, nil
+		}
+		if ctx.Err() != nil {
+			return resilientAddSegment{}, ctx.Err()
+		}
+		lastErr = err
+	}
+	return resilientAddSegment{}, fmt.Errorf("failed to add segment at offset %d after %d retries: %v", offset, opts.MaxRetries, lastErr)
+}
+
+// addSegment adds the section of the file and verifies the result by reading
+// the section a second time and computing its CID, which catches corrupted
+// reads from flaky storage.
+func (wrap *ipfsCliWrapper) addSegment(ctx context.Context, file *os.File, offset int64, size int64) (resilientAddSegment, error) {
+	if err := wrap.waitForGC(ctx); err != nil {
+		return resilientAddSegment{}, err
+	}
+	cmd := wrap.command(ctx, "add", "--quiet", "--cid-version=1")
+	cmd.Stdin = io.NewSectionReader(file, offset, size)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return resilientAddSegment{}, wrap.commandError("add segment to ipfs", err, stderr.Bytes())
+	}
+	cid := string(bytes.TrimSpace(output))
+
+	verified, err := wrap.HashOnly(ctx, io.NewSectionReader(file, offset, size))
+	if err != nil {
+		return resilientAddSegment{}, err
+	}
+	if verified != cid {
+		return resilientAddSegment{}, fmt.Errorf("segment verification failed: added as %s but read back as %s", cid, verified)
+	}
+
+	output, err = wrap.combinedOutput(wrap.command(ctx, "files", "stat", "--enc=json", "/ipfs/"+cid))
+	if err != nil {
+		return resilientAddSegment{}, wrap.commandError("stat segment", err, output)
+	}
+	stat, err := outputkit.ParseFilesStat(output)
+	if err != nil {
+		return resilientAddSegment{}, fmt.Errorf("failed to parse segment stat: %v", err)
+	}
+	return resilientAddSegment{CID: cid, Size: size, CumulativeSize: stat.CumulativeSize}, nil
+}
+
+// joinSegments stores a UnixFS file node linking to the segments, whose
+// content is the concatenation of the segments, and returns its CID.
+func (wrap *ipfsCliWrapper) joinSegments(ctx context.Context, state *resilientAddState) (string, error) {
+	type link struct {
+		Hash  map[string]string `json:"Hash"`
+		Name  string            `json:"Name"`
+		Tsize uint64            `json:"Tsize"`
+	}
+	links := make([]link, 0, len(state.Segments))
+	blockSizes := make([]int64, 0, len(state.Segments))
+	for _, segment := range state.Segments {
+		links = append(links, link{Hash: map[string]string{"/": segment.CID}, Tsize: segment.CumulativeSize})
+		blockSizes = append(blockSizes, segment.Size)
+	}
+	data := unixfsFileData(state.FileSize, blockSizes)
+	node, err := json.Marshal(map[string]any{
+		"Data":  map[string]any{"/": map[string]string{"bytes": base64.RawStdEncoding.EncodeToString(data)}},
+		"Links": links,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	cmd := wrap.command(ctx, "dag", "put", "--input-codec=dag-json", "--store-codec=dag-pb")
+	cmd.Stdin = bytes.NewReader(node)
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		return "", wrap.commandError("join segments", err, output)
+	}
+	return string(bytes.TrimSpace(output)), nil
+}
+
+// unixfsFileData returns the protobuf encoded UnixFS `Data` message [0] of a
+// file node with the fields Type (1) set to File (2), filesize (3) and the
+// blocksizes (4) of its children.
+// [0] https://github.com/ipfs/specs/blob/main/UNIXFS.md
+func unixfsFileData(fileSize int64, blockSizes []int64) []byte {
+	data := []byte{0x08, 0x02, 0x18}
+	data = binary.AppendUvarint(data, uint64(fileSize))
+	for _, size := range blockSizes {
+		data = append(data, 0x20)
+		data = binary.AppendUvarint(data, uint64(size))
+	}
+	return data
+}
+
+func readResilientAddState(path string) *resilientAddState {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var state resilientAddState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+// writeResilientAddState saves the state through a temporary file so an
+// interruption never leaves a truncated state file behind.
+func writeResilientAddState(path string, state *resilientAddState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", b, 0644); err != nil {
+		return fmt.Errorf("failed to write add state file: %v", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return errors.Join(fmt.Errorf("failed to write add state file: %v", err), os.Remove(path+".tmp"))
+	}
+	return nil
+}
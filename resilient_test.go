@@ -0,0 +1,139 @@
+package ipfscliwrapper_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestUnixfsFileData checks the protobuf encoding of the UnixFS file node
+// joining the segments, including sizes which need multi-byte varints.
+func TestUnixfsFileData(t *testing.T) {
+	tests := []struct {
+		fileSize   int64
+		blockSizes []int64
+		expected   []byte
+	}{
+		{0, nil, []byte{0x08, 0x02, 0x18, 0x00}},
+		{300, []int64{200, 100}, []byte{0x08, 0x02, 0x18, 0xac, 0x02, 0x20, 0xc8, 0x01, 0x20, 0x64}},
+		{
+			// Two segments of the default size of 256 MiB.
+			512 << 20, []int64{256 << 20, 256 << 20},
+			[]byte{0x08, 0x02, 0x18, 0x80, 0x80, 0x80, 0x80, 0x02, 0x20, 0x80, 0x80, 0x80, 0x80, 0x01, 0x20, 0x80, 0x80, 0x80, 0x80, 0x01},
+		},
+	}
+	for _, test := range tests {
+		if actual := ipfscliwrapper.UnixfsFileData(test.fileSize, test.blockSizes); !bytes.Equal(actual, test.expected) {
+			t.Errorf("Expected % x for %d bytes in %v, but got % x", test.expected, test.fileSize, test.blockSizes, actual)
+		}
+	}
+}
+
+// TestAddFileResilientJoinSegments checks the node stored for a file spanning
+// several segments links to every segment in order, with their sizes.
+func TestAddFileResilientJoinSegments(t *testing.T) {
+	wrapper, repoPath := newFakeKuboWrapper(t)
+	path := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(path, []byte("aaaabbbbcc"), 0644); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	cid, err := wrapper.AddFileResilient(context.Background(), path, ipfscliwrapper.ResilientAddOptions{SegmentSize: 4})
+	if err != nil || cid != "bafyfakejoined" {
+		t.Fatalf("Expected the joined cid, but got %s and %v", cid, err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(repoPath, "dag-put.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	var node struct {
+		Data struct {
+			Bytes struct {
+				Bytes string `json:"bytes"`
+			} `json:"/"`
+		} `json:"Data"`
+		Links []struct {
+			Hash  map[string]string `json:"Hash"`
+			Tsize uint64            `json:"Tsize"`
+		} `json:"Links"`
+	}
+	if err := json.Unmarshal(b, &node); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	data, err := base64.RawStdEncoding.DecodeString(node.Data.Bytes.Bytes)
+	if expected := ipfscliwrapper.UnixfsFileData(10, []int64{4, 4, 2}); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("Expected the data % x, but got % x and %v", expected, data, err)
+	}
+	if len(node.Links) != 3 {
+		t.Fatalf("Expected 3 links, but got %+v", node.Links)
+	}
+	segments := []string{"aaaa", "bbbb", "cc"}
+	for i, link := range node.Links {
+		if !strings.HasPrefix(link.Hash["/"], "bafkfake") || link.Tsize != 100 {
+			t.Errorf("Unexpected link %d: %+v", i, link)
+		}
+		if i > 0 && link.Hash["/"] == node.Links[i-1].Hash["/"] {
+			t.Errorf("Expected segments %q and %q to have distinct cids, but got %+v", segments[i-1], segments[i], node.Links)
+		}
+	}
+	if _, err := os.Stat(path + ".ipfsadd.json"); !os.IsNotExist(err) {
+		t.Errorf("Expected no state file next to the added file, but got: %v", err)
+	}
+}
+
+// TestAddFileResilientResume checks an add interrupted by a failing segment
+// resumes from the last completed segment instead of starting over.
+func TestAddFileResilientResume(t *testing.T) {
+	wrapper, repoPath := newFakeKuboWrapper(t)
+	path := filepath.Join(t.TempDir(), "large.bin")
+	if err := os.WriteFile(path, []byte("aaaabbbbcc"), 0644); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	opts := ipfscliwrapper.ResilientAddOptions{SegmentSize: 4, MaxRetries: 1, RetryDelay: time.Millisecond}
+
+	// Make every add fail after the first segment.
+	failAdd := filepath.Join(repoPath, "fail-add")
+	if err := os.WriteFile(failAdd+"-next", nil, 0644); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	if _, err := wrapper.AddFileResilient(context.Background(), path, opts); err == nil {
+		t.Fatal("Expected an error, but got none")
+	}
+
+	stateFiles, _ := filepath.Glob(filepath.Join(repoPath, "ipfs-cli-wrapper-add", "*.json"))
+	if len(stateFiles) != 1 {
+		t.Fatalf("Expected a state file in the repo, but got %v", stateFiles)
+	}
+
+	if err := os.Remove(failAdd); err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+	before := len(fakeKuboInvocations(t, repoPath))
+	cid, err := wrapper.AddFileResilient(context.Background(), path, opts)
+	if err != nil || cid != "bafyfakejoined" {
+		t.Fatalf("Expected the joined cid, but got %s and %v", cid, err)
+	}
+
+	// Only the two remaining segments get added and hashed again.
+	var adds int
+	for _, invocation := range fakeKuboInvocations(t, repoPath)[before:] {
+		if strings.HasPrefix(invocation, "add ") && !strings.Contains(invocation, "--only-hash") {
+			adds++
+		}
+	}
+	if adds != 2 {
+		t.Errorf("Expected the 2 remaining segments to be added, but got %d adds", adds)
+	}
+	if _, err := os.Stat(stateFiles[0]); !os.IsNotExist(err) {
+		t.Errorf("Expected the state file to be removed, but got: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// CommandResult holds the raw result of an `ipfs` command run by the wrapper,
+// for logging or post-processing the output which the typed results of the
+// calls are parsed from.
+type CommandResult struct {
+	// Args are the arguments, starting with the path of the `ipfs` binary.
+	Args []string
+
+	// Stdout and Stderr are the output of the command.
+	Stdout []byte
+	Stderr []byte
+
+	// ExitCode is the exit code of the command, or -1 if it did not start
+	// or was terminated by a signal.
+	ExitCode int
+
+	// Duration is how long the command ran.
+	Duration time.Duration
+
+	// Err is the error running the command, if any.
+	Err error
+}
+
+// CommandResultHook is called with the result of every `ipfs` command run by
+// the wrapper, once the command exited. It is called from the goroutine which
+// ran the command, so it must be safe for concurrent use.
+type CommandResultHook func(result CommandResult)
+
+// run runs the command like `exec.Cmd.Run` and reports its result to the hook
+// set with `WithCommandResultHook`, if any. The output still goes to the
+// writers the command was set up with.
+func (wrap *ipfsCliWrapper) run(cmd *exec.Cmd) error {
+	if wrap.commandResultHook == nil {
+		return cmd.Run()
+	}
+	var stdout, stderr syncBuffer
+	cmd.Stdout = teeWriter(cmd.Stdout, &stdout)
+	cmd.Stderr = teeWriter(cmd.Stderr, &stderr)
+
+	startedAt := time.Now()
+	err := cmd.Run()
+	result := CommandResult{
+		Args:     cmd.Args,
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: -1,
+		Duration: time.Since(startedAt),
+		Err:      err,
+	}
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	wrap.commandResultHook(result)
+	return err
+}
+
+// output runs the command like `exec.Cmd.Output`, see `run`.
+func (wrap *ipfsCliWrapper) output(cmd *exec.Cmd) ([]byte, error) {
+	if wrap.commandResultHook == nil {
+		return cmd.Output()
+	}
+	var stdout syncBuffer
+	cmd.Stdout = &stdout
+	var stderr *syncBuffer
+	if cmd.Stderr == nil {
+		stderr = &syncBuffer{}
+		cmd.Stderr = stderr
+	}
+	err := wrap.run(cmd)
+
+	// Like `exec.Cmd.Output`, return the stderr in the `exec.ExitError`
+	// unless the caller collects it.
+	var exitError *exec.ExitError
+	if stderr != nil && errors.As(err, &exitError) {
+		exitError.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// combinedOutput runs the command like `exec.Cmd.CombinedOutput`, see `run`.
+func (wrap *ipfsCliWrapper) combinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	if wrap.commandResultHook == nil {
+		return cmd.CombinedOutput()
+	}
+	var combined syncBuffer
+	cmd.Stdout = &combined
+	cmd.Stderr = &combined
+	err := wrap.run(cmd)
+	return combined.Bytes(), err
+}
+
+func teeWriter(w io.Writer, buf io.Writer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// syncBuffer is a `bytes.Buffer` which can be written by the goroutines
+// copying stdout and stderr at the same time.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
@@ -0,0 +1,159 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// tempDirPath returns the absolute directory scratch files are created in.
+// The path is made absolute since the `ipfs` process may run inside the
+// directory configured with the `WithWorkDir` option.
+func (wrap *ipfsCliWrapper) tempDirPath() string {
+	dir := wrap.tempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// makeScratchDir creates a fresh directory inside the temp directory. The
+// caller must remove it with `os.RemoveAll` once done.
+func (wrap *ipfsCliWrapper) makeScratchDir() (string, error) {
+	if err := os.MkdirAll(wrap.tempDirPath(), 0700); err != nil {
+		return "", fmt.Errorf("failed creating temp directory: %v", err)
+	}
+	dir, err := os.MkdirTemp(wrap.tempDirPath(), "ipfscliwrapper-")
+	if err != nil {
+		return "", fmt.Errorf("failed creating scratch directory: %v", err)
+	}
+	return dir, nil
+}
+
+// commandDir returns the directory the commands executed with the context
+// run inside, see `baseCommand`. An empty string means the current working
+// directory of this app.
+func (wrap *ipfsCliWrapper) commandDir(ctx context.Context) string {
+	if dir, ok := ctx.Value(workDirContextKey{}).(string); ok {
+		return dir
+	}
+	return wrap.workDir
+}
+
+// moveIntoPlace moves the staged file or directory to the destination,
+// replacing whatever is there. Files are renamed when both paths are on the
+// same filesystem and copied otherwise, so the temp directory may live on a
+// different disk than the destination.
+func moveIntoPlace(src string, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("failed removing existing %s: %v", dst, err)
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	// Copy next to the destination first, so the destination never holds a
+	// partially copied file or directory.
+	tmp := dst + ".ipfscliwrapper-partial"
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+	if err := copyTree(src, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("failed copying %s: %v", src, err)
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("failed moving %s into place: %v", dst, err)
+	}
+	return os.RemoveAll(src)
+}
+
+// copyTree copies the file or directory at src to dst, keeping permissions.
+func copyTree(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return errors.New("unsupported file type: " + path)
+		}
+	})
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// downloadDenylist downloads the denylist into the temp directory and moves
+// it into place once complete, so the daemon never loads a partial denylist.
+func (wrap *ipfsCliWrapper) downloadDenylist(ctx context.Context, destination string) error {
+	stageDir, err := wrap.makeScratchDir()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stageDir)
+
+	stagedPath := filepath.Join(stageDir, filepath.Base(destination))
+	if err := wrap.urlDownloader.DownloadFile(ctx, wrap.denylistURL, stagedPath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+	return moveIntoPlace(stagedPath, destination)
+}
+
+// archiveCacheDirPath returns the directory the kubo archive is kept in when
+// the `WithKeepArchive` option is used.
+func (wrap *ipfsCliWrapper) archiveCacheDirPath() string {
+	if wrap.archiveCacheDir != "" {
+		return wrap.archiveCacheDir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "ipfs-cli-wrapper")
+	}
+	return filepath.Join(wrap.tempDirPath(), "ipfs-cli-wrapper-cache")
+}
@@ -0,0 +1,129 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/servicekit"
+)
+
+// DefaultServiceName is the name the daemon gets registered under with the
+// operating system service manager unless another name was chosen.
+const DefaultServiceName = "ipfs-cli-wrapper"
+
+// ServiceStatus represents the state of the daemon registered as a service.
+type ServiceStatus string
+
+// Constants representing the states returned by `StatusService`.
+const (
+	ServiceStatusRunning      ServiceStatus = ServiceStatus(servicekit.StatusRunning)
+	ServiceStatusStopped      ServiceStatus = ServiceStatus(servicekit.StatusStopped)
+	ServiceStatusNotInstalled ServiceStatus = ServiceStatus(servicekit.StatusNotInstalled)
+)
+
+// ServiceOptions controls how the daemon gets registered as an operating
+// system service by `InstallService`. Pass the same options to
+// `UninstallService` and `StatusService` to refer to the same service.
+type ServiceOptions struct {
+	// Name is the service name. Defaults to `DefaultServiceName`.
+	Name string
+
+	// Description is a human readable description of the service.
+	Description string
+
+	// UserScope installs the service for the current user only, which does
+	// not require administrator privileges: a `systemctl --user` unit on
+	// Linux, a launch agent on macOS and a logon task on Windows.
+	UserScope bool
+
+	// RunAsUser is the account a system-wide service runs as. Leave empty to
+	// use the service manager default.
+	RunAsUser string
+}
+
+// serviceSpec returns the description of the daemon as a service.
+func (wrap *ipfsCliWrapper) serviceSpec(opts ServiceOptions) (servicekit.Spec, error) {
+	binaryPath := wrap.binaryPath()
+	repoPath := wrap.repoPath()
+	workDir, err := filepath.Abs(wrap.workDir)
+	if err != nil {
+		return servicekit.Spec{}, err
+	}
+
+	spec := servicekit.Spec{
+		Name:        serviceName(opts),
+		Description: opts.Description,
+		BinaryPath:  binaryPath,
+		Args:        wrap.daemonArgs(),
+		Env:         []string{"IPFS_PATH=" + repoPath},
+		WorkingDir:  workDir,
+		User:        opts.RunAsUser,
+	}
+	if spec.Description == "" {
+		spec.Description = "IPFS daemon managed by ipfs-cli-wrapper"
+	}
+	return spec, nil
+}
+
+// serviceName returns the name of the service described by the options.
+func serviceName(opts ServiceOptions) string {
+	if opts.Name == "" {
+		return DefaultServiceName
+	}
+	return opts.Name
+}
+
+func (wrap *ipfsCliWrapper) InstallService(ctx context.Context, opts ServiceOptions) error {
+	if !wrap.isDaemonRunningContinously {
+		return errors.New("installing the daemon as a service requires the `WithContinousOperation` option")
+	}
+
+	spec, err := wrap.serviceSpec(opts)
+	if err != nil {
+		return err
+	}
+	manager, err := servicekit.NewManager(runtime.GOOS, opts.UserScope)
+	if err != nil {
+		return err
+	}
+	if err := manager.Install(ctx, spec); err != nil {
+		wrap.logger.Error("failed installing service",
+			slog.String("name", spec.Name),
+			slog.Any("error", err))
+		return fmt.Errorf("failed installing service: %v", err)
+	}
+
+	wrap.logger.Debug("ipfs daemon installed as service",
+		slog.String("name", spec.Name))
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) UninstallService(ctx context.Context, opts ServiceOptions) error {
+	manager, err := servicekit.NewManager(runtime.GOOS, opts.UserScope)
+	if err != nil {
+		return err
+	}
+	if err := manager.Uninstall(ctx, serviceName(opts)); err != nil {
+		wrap.logger.Error("failed uninstalling service",
+			slog.String("name", serviceName(opts)),
+			slog.Any("error", err))
+		return fmt.Errorf("failed uninstalling service: %v", err)
+	}
+	return nil
+}
+
+func (wrap *ipfsCliWrapper) StatusService(ctx context.Context, opts ServiceOptions) (ServiceStatus, error) {
+	manager, err := servicekit.NewManager(runtime.GOOS, opts.UserScope)
+	if err != nil {
+		return "", err
+	}
+	status, err := manager.Status(ctx, serviceName(opts))
+	if err != nil {
+		return "", fmt.Errorf("failed getting service status: %v", err)
+	}
+	return ServiceStatus(status), nil
+}
@@ -0,0 +1,57 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/prockit"
+)
+
+// DefaultShutdownGracePeriod is how long the daemon gets to exit after being
+// asked to before it is killed, see `WithShutdownGracePeriod`.
+const DefaultShutdownGracePeriod = 10 * time.Second
+
+// ShutdownMethod tells how the daemon was stopped, see `ShutdownStatus`.
+type ShutdownMethod string
+
+const (
+	// ShutdownGraceful means the daemon exited within the grace period after
+	// being asked to, so it flushed its repo and closed its connections.
+	ShutdownGraceful ShutdownMethod = "graceful"
+
+	// ShutdownKilled means the daemon was killed, either because it did not
+	// exit within the grace period or because the grace period is zero.
+	ShutdownKilled ShutdownMethod = "killed"
+)
+
+// ShutdownStatus describes the last time the wrapper stopped the daemon.
+type ShutdownStatus struct {
+	// Time is when the daemon was asked to stop.
+	Time time.Time
+
+	// Method tells whether the daemon exited gracefully or was killed.
+	Method ShutdownMethod
+
+	// Duration is how long the daemon took to stop.
+	Duration time.Duration
+}
+
+func (wrap *ipfsCliWrapper) LastShutdown() *ShutdownStatus {
+	return wrap.lastShutdown
+}
+
+// terminateDaemon asks the daemon to exit gracefully. It sends a `SIGTERM`
+// signal, or runs `ipfs shutdown` on platforms without signals.
+func (wrap *ipfsCliWrapper) terminateDaemon(pid int) error {
+	err := prockit.Terminate(pid)
+	if !errors.Is(err, errors.ErrUnsupported) {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wrap.shutdownGracePeriod)
+	defer cancel()
+	if output, err := wrap.combinedOutput(wrap.command(ctx, "shutdown")); err != nil {
+		return wrap.commandError("shutdown daemon", err, output)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package ipfscliwrapper
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// defaultShutdownSignals are the signals `WithShutdownOnSignals` handles when
+// none are given.
+var defaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// startSignalHandler installs the handler of the signals set with the
+// `WithShutdownOnSignals` option, which shuts the daemon down once one of them
+// is received.
+func (wrap *ipfsCliWrapper) startSignalHandler() {
+	wrap.shutdownMu.Lock()
+	defer wrap.shutdownMu.Unlock()
+
+	// A new run of the daemon has to be shut down again before `Wait`
+	// returns.
+	select {
+	case <-wrap.shutdownDone:
+		wrap.shutdownDone = make(chan struct{})
+	default:
+	}
+
+	if len(wrap.shutdownSignals) == 0 || wrap.signalStop != nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, wrap.shutdownSignals...)
+	stop := make(chan struct{})
+	wrap.signalStop = stop
+
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case sig := <-signals:
+			wrap.logger.Debug("received signal, shutting down ipfs daemon",
+				slog.String("signal", sig.String()))
+			if err := wrap.ShutdownDaemon(); err != nil {
+				wrap.logger.Error("failed shutting down ipfs daemon", slog.Any("error", err))
+			}
+		case <-stop:
+		}
+	}()
+}
+
+// stopSignalHandler removes the handler installed by `startSignalHandler`.
+func (wrap *ipfsCliWrapper) stopSignalHandler() {
+	wrap.shutdownMu.Lock()
+	defer wrap.shutdownMu.Unlock()
+	if wrap.signalStop != nil {
+		close(wrap.signalStop)
+		wrap.signalStop = nil
+	}
+}
+
+// markShutdownDone releases the callers of `Wait`.
+func (wrap *ipfsCliWrapper) markShutdownDone() {
+	wrap.shutdownMu.Lock()
+	defer wrap.shutdownMu.Unlock()
+	select {
+	case <-wrap.shutdownDone:
+	default:
+		close(wrap.shutdownDone)
+	}
+}
+
+func (wrap *ipfsCliWrapper) Wait() {
+	wrap.shutdownMu.Lock()
+	done := wrap.shutdownDone
+	wrap.shutdownMu.Unlock()
+	<-done
+}
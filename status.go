@@ -0,0 +1,102 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DaemonStatus describes the daemon started by the wrapper, see `Status`.
+type DaemonStatus struct {
+	// Running reports whether the daemon is running. The other fields
+	// describe the last started daemon and are zero if there is none.
+	Running bool
+
+	PID int
+
+	// StartTime is when the daemon was started and Uptime how long it has
+	// been running.
+	StartTime time.Time
+	Uptime    time.Duration
+
+	// KuboVersion is the release of the `ipfs` binary, e.g. "v0.29.0".
+	KuboVersion string
+
+	// Adopted reports whether the daemon was started by an earlier run of
+	// the app in continous operation mode.
+	Adopted bool
+}
+
+func (wrap *ipfsCliWrapper) Status() *DaemonStatus {
+	info := wrap.readDaemonInfo()
+	if info == nil {
+		return &DaemonStatus{}
+	}
+	status := &DaemonStatus{
+		Running:     wrap.isDaemonAlive(info),
+		PID:         info.PID,
+		StartTime:   info.StartTime,
+		KuboVersion: info.KuboVersion,
+		Adopted:     wrap.adoptedDaemon != nil && wrap.adoptedDaemon.PID == info.PID,
+	}
+	if status.Running {
+		status.Uptime = time.Since(info.StartTime)
+	}
+	return status
+}
+
+// DaemonLogs holds the tail of the output of the daemon, see `LogTail`.
+type DaemonLogs struct {
+	Stdout string
+	Stderr string
+}
+
+// ErrDaemonOutputDiscarded is returned by `LogTail` when the output of a
+// daemon running in continous operation mode is not written to files.
+var ErrDaemonOutputDiscarded = errors.New("ipfs daemon output is discarded, see WithDaemonOutputFiles")
+
+func (wrap *ipfsCliWrapper) LogTail(lines int) (*DaemonLogs, error) {
+	// The output of a daemon tied to this app is kept in memory.
+	if wrap.daemon != nil && wrap.daemonStdoutTail != nil {
+		return &DaemonLogs{
+			Stdout: lastLines(wrap.daemonStdoutTail.String(), lines),
+			Stderr: lastLines(wrap.daemonStderrTail.String(), lines),
+		}, nil
+	}
+
+	// A detached daemon writes its output to the files it was started with,
+	// which may not be the ones configured for this run of the app.
+	stdoutPath, stderrPath := wrap.daemonStdoutPath, wrap.daemonStderrPath
+	if info := wrap.readDaemonInfo(); info != nil && wrap.isDaemonAlive(info) {
+		stdoutPath, stderrPath = info.StdoutPath, info.StderrPath
+	}
+	if stdoutPath == "" && stderrPath == "" {
+		return nil, ErrDaemonOutputDiscarded
+	}
+	logs := &DaemonLogs{}
+	if stdoutPath != "" {
+		logs.Stdout = lastLines(readFileTail(stdoutPath, 0), lines)
+	}
+	if stderrPath != "" {
+		logs.Stderr = lastLines(readFileTail(stderrPath, 0), lines)
+	}
+	return logs, nil
+}
+
+// lastLines returns the last lines of the output, or all of it if lines is
+// not positive.
+func lastLines(output string, lines int) string {
+	if lines <= 0 {
+		return output
+	}
+	trimmed := strings.TrimSuffix(output, "\n")
+	for i := len(trimmed) - 1; i >= 0; i-- {
+		if trimmed[i] == '\n' {
+			lines--
+			if lines == 0 {
+				return output[i+1:]
+			}
+		}
+	}
+	return output
+}
@@ -0,0 +1,153 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NodeSummary is the storage health of the node returned by `Summary`.
+type NodeSummary struct {
+	// RecursivePins, DirectPins and IndirectPins count the pins by type.
+	RecursivePins int
+	DirectPins    int
+	IndirectPins  int
+
+	// PinnedBytes is the size of the pinned content in bytes. Blocks shared
+	// by pins are counted once, except across batches of
+	// `summaryDagStatBatchSize` recursive pins, so this is an upper bound for
+	// huge pinsets.
+	PinnedBytes uint64
+
+	// RepoSize is the size of the repo in bytes and StorageMax its disk
+	// budget, see `WithStorageMax`.
+	RepoSize   uint64
+	StorageMax uint64
+
+	// GCWatermark is the percentage of StorageMax which triggers the
+	// automatic garbage collection, see `WithStorageGCWatermark`, and
+	// AboveGCWatermark whether the repo reached it.
+	GCWatermark      int
+	AboveGCWatermark bool
+}
+
+// summaryDagStatBatchSize is the number of recursive pins `Summary` measures
+// with a single `ipfs dag stat` call.
+const summaryDagStatBatchSize = 500
+
+func (wrap *ipfsCliWrapper) Summary(ctx context.Context) (*NodeSummary, error) {
+	var summary NodeSummary
+
+	// Count the pins while they are listed, measuring the recursive pins in
+	// batches so the pinset never has to fit in memory.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pins, errs := wrap.ListPinsStream(ctx, AllPinType)
+	var batch []string
+	var measureErr error
+	for pin := range pins {
+		switch pin.Type {
+		case RecursivePinType:
+			summary.RecursivePins++
+			batch = append(batch, pin.CID)
+		case DirectPinType:
+			summary.DirectPins++
+			// Only the block itself is pinned, so its children are not
+			// necessarily stored.
+			size, err := wrap.blockSize(ctx, pin.CID)
+			summary.PinnedBytes += size
+			if err != nil {
+				measureErr = err
+			}
+		default:
+			summary.IndirectPins++
+		}
+		if len(batch) == summaryDagStatBatchSize {
+			size, err := wrap.dagSize(ctx, batch)
+			summary.PinnedBytes += size
+			if err != nil {
+				measureErr = err
+			}
+			batch = batch[:0]
+		}
+		if measureErr != nil {
+			cancel()
+			break
+		}
+	}
+	// Drain the channel so the listing goroutine can finish.
+	for range pins {
+	}
+	if measureErr != nil {
+		return nil, measureErr
+	}
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	if len(batch) > 0 {
+		size, err := wrap.dagSize(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		summary.PinnedBytes += size
+	}
+
+	repoSize, storageMax, err := wrap.repoUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summary.RepoSize = repoSize
+	summary.StorageMax = storageMax
+
+	watermark, err := wrap.getConfig("Datastore.StorageGCWatermark")
+	if err != nil {
+		return nil, err
+	}
+	if summary.GCWatermark, err = strconv.Atoi(watermark); err != nil {
+		return nil, fmt.Errorf("failed to parse gc watermark `%s`: %v", watermark, err)
+	}
+	summary.AboveGCWatermark = summary.StorageMax > 0 &&
+		summary.RepoSize*100 >= summary.StorageMax*uint64(summary.GCWatermark)
+	return &summary, nil
+}
+
+// dagSize returns the size in bytes of the DAGs, counting shared blocks once.
+// The DAGs must be stored locally.
+func (wrap *ipfsCliWrapper) dagSize(ctx context.Context, cids []string) (uint64, error) {
+	args := append([]string{"--offline", "dag", "stat", "--progress=false", "--enc=json", "--"}, cids...)
+	cmd := wrap.command(ctx, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return 0, wrap.commandError("get dag stat", err, stderr.Bytes())
+	}
+	var stat struct {
+		TotalSize uint64 `json:"TotalSize"`
+	}
+	if err := json.Unmarshal(output, &stat); err != nil {
+		return 0, fmt.Errorf("failed to parse dag stat: %v", err)
+	}
+	return stat.TotalSize, nil
+}
+
+// blockSize returns the size in bytes of the locally stored block.
+func (wrap *ipfsCliWrapper) blockSize(ctx context.Context, cid string) (uint64, error) {
+	cmd := wrap.command(ctx, "--offline", "block", "stat", "--enc=json", "--", cid)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return 0, wrap.commandError("get block stat", err, stderr.Bytes())
+	}
+	var stat struct {
+		Size uint64 `json:"Size"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(output), &stat); err != nil {
+		return 0, fmt.Errorf("failed to parse block stat: %v", strings.TrimSpace(string(output)))
+	}
+	return stat.Size, nil
+}
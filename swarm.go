@@ -0,0 +1,170 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+)
+
+const (
+	// SwarmUnlimited is the `Limit` of a `SwarmResource` without limit.
+	SwarmUnlimited int64 = -1
+
+	// SwarmDefaultLimit is the `Limit` of a `SwarmResource` which falls back
+	// to the default of its scope, which kubo does not report.
+	SwarmDefaultLimit int64 = -2
+)
+
+// SwarmResource is the usage and limit of a resource of the libp2p resource
+// manager.
+type SwarmResource struct {
+	Usage int64
+
+	// Limit is the maximum usage, `SwarmUnlimited` if there is none,
+	// `SwarmDefaultLimit` if it is the default of the scope, or 0 if the
+	// resource is blocked.
+	Limit int64
+}
+
+// SwarmResourceScope is the resource usage of a scope of the libp2p resource
+// manager, such as the whole system, a service or a protocol.
+type SwarmResourceScope struct {
+	// Memory is in bytes and FD counts file descriptors.
+	Memory SwarmResource
+	FD     SwarmResource
+
+	Conns         SwarmResource
+	ConnsInbound  SwarmResource
+	ConnsOutbound SwarmResource
+
+	Streams         SwarmResource
+	StreamsInbound  SwarmResource
+	StreamsOutbound SwarmResource
+}
+
+// SwarmStatsInfo is the resource usage of the libp2p swarm of the node, see
+// `SwarmStats`.
+type SwarmStatsInfo struct {
+	// System is the usage of the whole node and Transient the usage of the
+	// connections and streams which are not attributed to a scope yet.
+	System    SwarmResourceScope
+	Transient SwarmResourceScope
+
+	// Services, Protocols and Peers break the usage down by scope, keyed by
+	// the name of the service, the protocol ID or the peer ID.
+	Services  map[string]SwarmResourceScope
+	Protocols map[string]SwarmResourceScope
+	Peers     map[string]SwarmResourceScope
+}
+
+func (wrap *ipfsCliWrapper) SwarmStats(ctx context.Context) (*SwarmStatsInfo, error) {
+	cmd := wrap.command(ctx, "swarm", "resources", "--enc=json")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error getting swarm resources",
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return nil, wrap.commandError("get swarm resources", err, stderr.Bytes())
+	}
+
+	// Every scope holds the limit of each resource under its name, e.g.
+	// "Memory", and the usage with the "Usage" suffix, e.g. "MemoryUsage".
+	type rawScope map[string]json.RawMessage
+	var report struct {
+		System    rawScope            `json:"System"`
+		Transient rawScope            `json:"Transient"`
+		Services  map[string]rawScope `json:"Services"`
+		Protocols map[string]rawScope `json:"Protocols"`
+		Peers     map[string]rawScope `json:"Peers"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse swarm resources: %v", err)
+	}
+
+	parseScope := func(raw rawScope) (SwarmResourceScope, error) {
+		var scope SwarmResourceScope
+		resources := map[string]*SwarmResource{
+			"Memory":          &scope.Memory,
+			"FD":              &scope.FD,
+			"Conns":           &scope.Conns,
+			"ConnsInbound":    &scope.ConnsInbound,
+			"ConnsOutbound":   &scope.ConnsOutbound,
+			"Streams":         &scope.Streams,
+			"StreamsInbound":  &scope.StreamsInbound,
+			"StreamsOutbound": &scope.StreamsOutbound,
+		}
+		for name, resource := range resources {
+			limit, err := parseSwarmLimit(raw[name])
+			if err != nil {
+				return scope, fmt.Errorf("failed to parse swarm resource `%s`: %v", name, err)
+			}
+			resource.Limit = limit
+			if usage, ok := raw[name+"Usage"]; ok {
+				if err := json.Unmarshal(usage, &resource.Usage); err != nil {
+					return scope, fmt.Errorf("failed to parse swarm resource `%s` usage: %v", name, err)
+				}
+			}
+		}
+		return scope, nil
+	}
+	parseScopes := func(raws map[string]rawScope) (map[string]SwarmResourceScope, error) {
+		scopes := make(map[string]SwarmResourceScope, len(raws))
+		for name, raw := range raws {
+			scope, err := parseScope(raw)
+			if err != nil {
+				return nil, err
+			}
+			scopes[name] = scope
+		}
+		return scopes, nil
+	}
+
+	var stats SwarmStatsInfo
+	if stats.System, err = parseScope(report.System); err != nil {
+		return nil, err
+	}
+	if stats.Transient, err = parseScope(report.Transient); err != nil {
+		return nil, err
+	}
+	if stats.Services, err = parseScopes(report.Services); err != nil {
+		return nil, err
+	}
+	if stats.Protocols, err = parseScopes(report.Protocols); err != nil {
+		return nil, err
+	}
+	if stats.Peers, err = parseScopes(report.Peers); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// parseSwarmLimit parses a limit of the libp2p resource manager, which is
+// either a number, a number as a string, "unlimited", "default" or
+// "blockAll".
+func parseSwarmLimit(raw json.RawMessage) (int64, error) {
+	if len(raw) == 0 {
+		return SwarmUnlimited, nil
+	}
+	var number int64
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number, nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return 0, err
+	}
+	switch text {
+	case "unlimited":
+		return SwarmUnlimited, nil
+	case "default":
+		return SwarmDefaultLimit, nil
+	case "blockAll":
+		return 0, nil
+	}
+	return strconv.ParseInt(text, 10, 64)
+}
@@ -0,0 +1,219 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartmika/ipfs-cli-wrapper/internal/outputkit"
+)
+
+// mfsDirectoryType is the `Type` of directories listed by `listMFS`.
+const mfsDirectoryType = outputkit.FilesDirectoryType
+
+// syncStats counts the changes made by `SyncDir`.
+type syncStats struct {
+	added, updated, removed, unchanged int
+}
+
+func (wrap *ipfsCliWrapper) SyncDir(ctx context.Context, localDir string, mfsPath string) (string, error) {
+	if err := wrap.checkWritable(); err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(mfsPath, "/") {
+		return "", fmt.Errorf("mfs path must be absolute, got `%s`", mfsPath)
+	}
+	mfsPath = path.Clean(mfsPath)
+	if err := wrap.waitForGC(ctx); err != nil {
+		return "", err
+	}
+
+	localDir, err := filepath.Abs(localDir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(localDir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("`%s` is not a directory", localDir)
+	}
+
+	if err := wrap.runFilesCommand(ctx, "mkdir", "--parents", "--cid-version=1", mfsPath); err != nil {
+		return "", err
+	}
+
+	var stats syncStats
+	if err := wrap.syncDir(ctx, localDir, mfsPath, &stats); err != nil {
+		return "", err
+	}
+
+	// Persist the changes to the datastore before reading the new root.
+	if err := wrap.runFilesCommand(ctx, "flush", mfsPath); err != nil {
+		return "", err
+	}
+	stat, err := wrap.statMFS(ctx, mfsPath)
+	if err != nil {
+		return "", err
+	}
+	cid := stat.Hash
+
+	wrap.logger.Debug("directory synced to mfs",
+		slog.String("local_dir", localDir),
+		slog.String("mfs_path", mfsPath),
+		slog.String("cid", cid),
+		slog.Int("added", stats.added),
+		slog.Int("updated", stats.updated),
+		slog.Int("removed", stats.removed),
+		slog.Int("unchanged", stats.unchanged))
+	return cid, nil
+}
+
+// syncDir mirrors the local directory into the existing MFS directory.
+func (wrap *ipfsCliWrapper) syncDir(ctx context.Context, localDir string, mfsDir string, stats *syncStats) error {
+	localEntries, err := os.ReadDir(localDir)
+	if err != nil {
+		return err
+	}
+	remoteEntries, err := wrap.listMFS(ctx, mfsDir)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(localEntries))
+	for _, entry := range localEntries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := entry.Name()
+		localPath := filepath.Join(localDir, name)
+		mfsEntryPath := path.Join(mfsDir, name)
+		remote, exists := remoteEntries[name]
+
+		switch {
+		case entry.IsDir():
+			seen[name] = true
+			if exists && remote.Type != mfsDirectoryType {
+				if err := wrap.runFilesCommand(ctx, "rm", "--recursive", "--force", mfsEntryPath); err != nil {
+					return err
+				}
+				exists = false
+			}
+			if !exists {
+				if err := wrap.runFilesCommand(ctx, "mkdir", "--cid-version=1", mfsEntryPath); err != nil {
+					return err
+				}
+			}
+			if err := wrap.syncDir(ctx, localPath, mfsEntryPath, stats); err != nil {
+				return err
+			}
+
+		case entry.Type().IsRegular():
+			seen[name] = true
+			if exists && remote.Type != mfsDirectoryType {
+				// Hashing is cheap compared to adding since nothing gets
+				// written to the datastore.
+				cid, err := wrap.HashOnlyFile(ctx, localPath)
+				if err != nil {
+					return err
+				}
+				if cid == remote.Hash {
+					stats.unchanged++
+					continue
+				}
+			}
+			if exists {
+				if err := wrap.runFilesCommand(ctx, "rm", "--recursive", "--force", mfsEntryPath); err != nil {
+					return err
+				}
+				stats.updated++
+			} else {
+				stats.added++
+			}
+			if err := wrap.addToMFS(ctx, localPath, mfsEntryPath); err != nil {
+				return err
+			}
+
+		default:
+			// Symlinks, sockets, devices, etc. have no MFS counterpart.
+			wrap.logger.Debug("skipping non-regular file",
+				slog.String("path", localPath))
+		}
+	}
+
+	for name := range remoteEntries {
+		if seen[name] {
+			continue
+		}
+		if err := wrap.runFilesCommand(ctx, "rm", "--recursive", "--force", path.Join(mfsDir, name)); err != nil {
+			return err
+		}
+		stats.removed++
+	}
+	return nil
+}
+
+// listMFS returns the entries of the MFS directory keyed by name.
+func (wrap *ipfsCliWrapper) listMFS(ctx context.Context, mfsDir string) (map[string]outputkit.FilesEntry, error) {
+	cmd := wrap.command(ctx, "files", "ls", "--long", "--enc=json", mfsDir)
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return nil, wrap.commandError("list mfs directory", err, output)
+	}
+
+	listing, err := outputkit.ParseFilesLs(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mfs listing: %v", err)
+	}
+	entries := make(map[string]outputkit.FilesEntry, len(listing))
+	for _, entry := range listing {
+		entries[entry.Name] = entry
+	}
+	return entries, nil
+}
+
+// statMFS returns the details of the MFS path.
+func (wrap *ipfsCliWrapper) statMFS(ctx context.Context, mfsPath string) (*outputkit.FilesStat, error) {
+	cmd := wrap.command(ctx, "files", "stat", "--enc=json", mfsPath)
+	output, err := wrap.output(cmd)
+	if err != nil {
+		return nil, wrap.commandError("stat mfs path", err, output)
+	}
+	stat, err := outputkit.ParseFilesStat(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode mfs stat: %v", err)
+	}
+	return stat, nil
+}
+
+// addToMFS adds the local file and links it at the MFS path in one step, so
+// the blocks are protected from garbage collection without pinning them.
+func (wrap *ipfsCliWrapper) addToMFS(ctx context.Context, localPath string, mfsPath string) error {
+	cmd := wrap.command(ctx, "add", "--cid-version=1", "--pin=false", "--quieter", "--to-files="+mfsPath, "--", localPath)
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		wrap.logger.Error("error adding file to mfs",
+			slog.String("filepath", localPath),
+			slog.String("mfs_path", mfsPath),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return wrap.commandError("add file to mfs", err, output)
+	}
+	return nil
+}
+
+// runFilesCommand executes an `ipfs files` subcommand.
+func (wrap *ipfsCliWrapper) runFilesCommand(ctx context.Context, args ...string) error {
+	cmd := wrap.command(ctx, append([]string{"files"}, args...)...)
+	output, err := wrap.combinedOutput(cmd)
+	if err != nil {
+		return wrap.commandError(fmt.Sprintf("run ipfs files %s", args[0]), err, output)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// TelemetryEventType identifies the kind of a `TelemetryEvent`.
+type TelemetryEventType string
+
+const (
+	// TelemetryDaemonStarted is reported once the daemon started by the
+	// wrapper accepts API calls. The event carries the startup duration.
+	TelemetryDaemonStarted TelemetryEventType = "daemon_started"
+
+	// TelemetryDaemonStopped is reported when `ShutdownDaemon` stopped the
+	// daemon.
+	TelemetryDaemonStopped TelemetryEventType = "daemon_stopped"
+
+	// TelemetryDaemonCrashed is reported when the daemon exited without
+	// being asked to, see `LastCrash`. The event carries the exit code.
+	TelemetryDaemonCrashed TelemetryEventType = "daemon_crashed"
+
+	// TelemetryCommandRun is reported for every `ipfs` process spawned by
+	// the wrapper. The event carries the command name.
+	TelemetryCommandRun TelemetryEventType = "command_run"
+
+	// TelemetryCommandFailed is reported for every failed operation. The
+	// event carries the operation and the error category.
+	TelemetryCommandFailed TelemetryEventType = "command_failed"
+)
+
+// Error categories of `TelemetryEvent`, derived from the sentinel errors.
+const (
+	TelemetryErrorNotFound   = "not_found"
+	TelemetryErrorNotPinned  = "not_pinned"
+	TelemetryErrorInvalidCID = "invalid_cid"
+	TelemetryErrorTimeout    = "timeout"
+	TelemetryErrorRepoLocked = "repo_locked"
+	TelemetryErrorOther      = "other"
+)
+
+// TelemetryEvent is a high-level event reported to the `TelemetrySink`. Events
+// are anonymous: they never contain CIDs, paths, peer IDs, addresses or
+// command output.
+type TelemetryEvent struct {
+	Type TelemetryEventType
+	Time time.Time
+
+	// Command is the `ipfs` subcommand of `TelemetryCommandRun` events, such
+	// as "add" or "pin ls".
+	Command string
+
+	// Operation and ErrorCategory describe `TelemetryCommandFailed` events,
+	// for example "check pin on ipfs" and `TelemetryErrorTimeout`.
+	Operation     string
+	ErrorCategory string
+
+	// Duration is the startup duration of `TelemetryDaemonStarted` events.
+	Duration time.Duration
+
+	// ExitCode is the exit code of `TelemetryDaemonCrashed` events, or -1 if
+	// the daemon was killed by a signal.
+	ExitCode int
+}
+
+// TelemetrySink receives the telemetry events of the wrapper, for example to
+// aggregate the reliability of a fleet of embedded nodes. It is called from
+// the goroutine which caused the event, so it must be safe for concurrent use
+// and return quickly, for example by buffering the events.
+type TelemetrySink interface {
+	RecordEvent(event TelemetryEvent)
+}
+
+// TelemetrySinkFunc adapts a function to the `TelemetrySink` interface.
+type TelemetrySinkFunc func(event TelemetryEvent)
+
+// RecordEvent calls f(event).
+func (f TelemetrySinkFunc) RecordEvent(event TelemetryEvent) {
+	f(event)
+}
+
+// recordTelemetry reports the event to the sink set with the
+// `WithTelemetrySink` option, if any.
+func (wrap *ipfsCliWrapper) recordTelemetry(event TelemetryEvent) {
+	if wrap.telemetry == nil {
+		return
+	}
+	event.Time = time.Now()
+	wrap.telemetry.RecordEvent(event)
+}
+
+// commandError returns the error of a failed `ipfs` command, see
+// `newCommandError`, and reports the failure to the telemetry sink.
+func (wrap *ipfsCliWrapper) commandError(op string, err error, output []byte) *CommandError {
+	cmdErr := newCommandError(op, err, output)
+	wrap.recordCommandFailure(cmdErr)
+	return cmdErr
+}
+
+// recordCommandFailure reports the failed command to the telemetry sink.
+func (wrap *ipfsCliWrapper) recordCommandFailure(cmdErr *CommandError) {
+	wrap.recordTelemetry(TelemetryEvent{
+		Type:          TelemetryCommandFailed,
+		Operation:     telemetryOperation(cmdErr.Op),
+		ErrorCategory: telemetryErrorCategory(cmdErr),
+	})
+}
+
+// telemetryOperation removes the values quoted with backticks, such as CIDs,
+// from the operation of a `CommandError`.
+func telemetryOperation(op string) string {
+	parts := strings.Split(op, "`")
+	var kept []string
+	for i := 0; i < len(parts); i += 2 {
+		kept = append(kept, parts[i])
+	}
+	return strings.Join(strings.Fields(strings.Join(kept, " ")), " ")
+}
+
+// telemetryErrorCategory returns the error category of the error.
+func telemetryErrorCategory(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return TelemetryErrorNotFound
+	case errors.Is(err, ErrNotPinned):
+		return TelemetryErrorNotPinned
+	case errors.Is(err, ErrInvalidCID):
+		return TelemetryErrorInvalidCID
+	case errors.Is(err, ErrTimeout):
+		return TelemetryErrorTimeout
+	case errors.Is(err, ErrRepoLocked):
+		return TelemetryErrorRepoLocked
+	default:
+		return TelemetryErrorOther
+	}
+}
+
+// telemetryParentCommands are the `ipfs` commands whose first argument is a
+// subcommand rather than user data, so it is part of the command name.
+var telemetryParentCommands = map[string]bool{
+	"block": true, "cid": true, "dag": true, "files": true, "key": true,
+	"name": true, "pin": true, "repo": true, "routing": true, "swarm": true,
+}
+
+// telemetryCommandName returns the name of the `ipfs` command without flags
+// or user data, e.g. "pin ls" for `--api=... pin ls --type=all <cid>`.
+func telemetryCommandName(args []string) string {
+	var words []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		words = append(words, arg)
+		if len(words) == 2 || !telemetryParentCommands[words[0]] {
+			break
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,138 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// ErrTenantNotFound is returned by `GetTenantNode` for a tenant which was not
+// created with `CreateTenantNode`.
+var ErrTenantNotFound = errors.New("tenant node not found")
+
+// tenantNamePattern restricts tenant names to characters which are safe to
+// use as a directory name on every operating system.
+var tenantNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// TenantManager creates and launches isolated nodes, one per tenant, for
+// example to keep the content of the customers of a SaaS app apart. Every
+// tenant node shares the `ipfs` binary but has its own repo (and therefore
+// its own identity and datastore) in a `tenants/<name>` directory next to
+// the default repo, and its own ports which are picked automatically.
+type TenantManager struct {
+	options []Option
+
+	mu    sync.Mutex
+	nodes map[string]IpfsCliWrapper
+}
+
+// NewTenantManager returns a manager creating tenant nodes with the given
+// options. The options apply to every tenant, with the exception of the
+// addresses which get picked automatically with `WithAutoPorts`.
+//
+// Example:
+//
+//	manager := NewTenantManager(WithXDGLayout())
+//	node, err := manager.CreateTenantNode("acme")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	cid, err := node.AddFile(ctx, "./report.pdf")
+func NewTenantManager(options ...Option) *TenantManager {
+	return &TenantManager{
+		options: options,
+		nodes:   make(map[string]IpfsCliWrapper),
+	}
+}
+
+// CreateTenantNode creates the repo of the tenant, unless it exists from a
+// previous run, and starts its daemon in the background. Calling it again
+// for a tenant which is already running returns the running node.
+func (m *TenantManager) CreateTenantNode(name string) (IpfsCliWrapper, error) {
+	if !tenantNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("tenant name must only contain letters, digits, `-` and `_`, got `%s`", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if node, ok := m.nodes[name]; ok {
+		return node, nil
+	}
+
+	options := append(append([]Option{}, m.options...), WithAutoPorts(), withTenant(name))
+	node, err := NewWrapper(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating node of tenant `%s`: %w", name, err)
+	}
+	if err := node.StartDaemonInBackground(); err != nil {
+		return nil, fmt.Errorf("failed starting node of tenant `%s`: %w", name, err)
+	}
+	m.nodes[name] = node
+	return node, nil
+}
+
+// GetTenantNode returns the node of the tenant created with
+// `CreateTenantNode`, or `ErrTenantNotFound`.
+func (m *TenantManager) GetTenantNode(name string) (IpfsCliWrapper, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTenantNotFound, name)
+	}
+	return node, nil
+}
+
+// Tenants returns the names of the tenants with a running node, sorted.
+func (m *TenantManager) Tenants() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.nodes))
+	for name := range m.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ShutdownTenantNode shuts down the daemon of the tenant. The repo is kept,
+// so `CreateTenantNode` brings the node back with the same content.
+func (m *TenantManager) ShutdownTenantNode(name string) error {
+	m.mu.Lock()
+	node, ok := m.nodes[name]
+	delete(m.nodes, name)
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTenantNotFound, name)
+	}
+	return node.ShutdownDaemon()
+}
+
+// ShutdownAll shuts down the daemons of every tenant.
+func (m *TenantManager) ShutdownAll() error {
+	var errs []error
+	for _, name := range m.Tenants() {
+		if err := m.ShutdownTenantNode(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// withTenant is the option used by `TenantManager` to move the repo of the
+// node into the directory of the tenant.
+func withTenant(name string) Option {
+	return func(wrap *ipfsCliWrapper) {
+		wrap.tenant = name
+	}
+}
+
+// applyTenantLayout moves the repo into the directory of the tenant. It runs
+// after `applyXDGLayout` so tenants follow the chosen layout.
+func (wrap *ipfsCliWrapper) applyTenantLayout() {
+	wrap.repoDir = filepath.Join(filepath.Dir(wrap.repoDir), "tenants", wrap.tenant)
+}
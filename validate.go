@@ -0,0 +1,141 @@
+package ipfscliwrapper
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"slices"
+)
+
+// ErrInvalidConfiguration is returned by the constructors when the options
+// contradict each other or cannot work on this machine. The returned error
+// wraps this value together with a description of every problem found, so
+// use `errors.Is` to check for it.
+var ErrInvalidConfiguration = errors.New("invalid ipfs-cli-wrapper configuration")
+
+// compatibleArchs lists, per operating system, which binary architectures
+// other than its own a host architecture is able to execute.
+var compatibleArchs = map[string]map[string][]string{
+	"darwin":  {"arm64": {"amd64"}}, // Rosetta 2.
+	"linux":   {"amd64": {"386"}, "arm64": {"arm"}},
+	"freebsd": {"amd64": {"386"}},
+	"openbsd": {"amd64": {"386"}},
+	"windows": {"amd64": {"386"}, "arm64": {"386", "amd64"}},
+}
+
+// validate checks the configuration produced by the options and returns an
+// error describing every problem found, or nil if the configuration is valid.
+func (wrap *ipfsCliWrapper) validate() error {
+	var errs []error
+
+	if wrap.isDaemonRunningContinously && wrap.forceShutdownOnStartup {
+		errs = append(errs, errors.New("`WithContinousOperation` cannot be combined with `WithForcedShutdownDaemonOnStartup` as the daemon kept running by the former would be terminated by the latter on every startup"))
+	}
+
+	if err := validatePlatform(wrap.os, wrap.arch, runtime.GOOS, runtime.GOARCH); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := getDownloadURL(wrap.kuboVersion, wrap.os, wrap.arch); err != nil {
+		errs = append(errs, err)
+	}
+
+	if wrap.daemonInitialWarmupDuration <= 0 {
+		errs = append(errs, fmt.Errorf("daemon warmup duration must be greater than zero, got %v", wrap.daemonInitialWarmupDuration))
+	}
+
+	if (wrap.denylistFilename == "") != (wrap.denylistURL == "") {
+		errs = append(errs, errors.New("`WithDenylist` requires both a filename and a url"))
+	}
+	if err := wrap.validateLocalDenylists(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if wrap.gatewayDisabled && (wrap.gatewayAddr != "" || wrap.gatewayBindIP != "") {
+		errs = append(errs, errors.New("`WithoutGateway` cannot be combined with `WithGatewayAddress` or `WithGatewayBindAddress`"))
+	}
+
+	for name, ip := range map[string]string{"api": wrap.apiBindIP, "gateway": wrap.gatewayBindIP} {
+		if ip != "" && net.ParseIP(ip) == nil {
+			errs = append(errs, fmt.Errorf("%s bind address must be an ip address, got `%s`", name, ip))
+		}
+	}
+
+	if wrap.storageGCWatermark < 0 || wrap.storageGCWatermark > 100 {
+		errs = append(errs, fmt.Errorf("storage gc watermark must be a percentage between 0 and 100, got %d", wrap.storageGCWatermark))
+	}
+
+	if wrap.gcWatermark < 0 || wrap.gcWatermark > 100 {
+		errs = append(errs, fmt.Errorf("gc watermark must be a percentage between 0 and 100, got %d", wrap.gcWatermark))
+	}
+	for _, command := range append(slices.Clone(wrap.allowedCommands), wrap.deniedCommands...) {
+		if len(command) == 0 {
+			errs = append(errs, errors.New("allowed and denied commands cannot be empty"))
+			break
+		}
+	}
+	if wrap.downloadConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("download concurrency must be greater than zero, got %d", wrap.downloadConcurrency))
+	}
+	if wrap.readOnly && wrap.gcWatermark > 0 {
+		errs = append(errs, errors.New("`WithReadOnly` cannot be combined with `WithGCWatermark`"))
+	}
+	if wrap.gcWatermarkInterval <= 0 {
+		errs = append(errs, fmt.Errorf("gc watermark check interval must be greater than zero, got %v", wrap.gcWatermarkInterval))
+	}
+
+	if wrap.daemonOutputMaxBytes < 0 || wrap.daemonOutputMaxBackups < 0 {
+		errs = append(errs, errors.New("daemon output rotation limits cannot be negative"))
+	}
+	if (wrap.daemonStdoutPath != "" || wrap.daemonStderrPath != "") && !wrap.isDaemonRunningContinously {
+		errs = append(errs, errors.New("`WithDaemonOutputFiles` requires the `WithContinousOperation` option"))
+	}
+
+	if wrap.fileMode&0500 != 0500 || wrap.fileMode&^os.ModePerm != 0 {
+		errs = append(errs, fmt.Errorf("file mode must be a permission allowing the owner to read and execute, got %v", wrap.fileMode))
+	}
+	if wrap.dirMode&0700 != 0700 || wrap.dirMode&^os.ModePerm != 0 {
+		errs = append(errs, fmt.Errorf("dir mode must be a permission allowing the owner full access, got %v", wrap.dirMode))
+	}
+
+	if wrap.localFetchTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("local fetch timeout must be greater than zero, got %v", wrap.localFetchTimeout))
+	}
+
+	if wrap.ipnsRepublisher.interval <= 0 {
+		errs = append(errs, fmt.Errorf("ipns republish interval must be greater than zero, got %v", wrap.ipnsRepublisher.interval))
+	}
+
+	if wrap.cluster != nil && wrap.tenant != "" {
+		errs = append(errs, errors.New("cluster support cannot be enabled for tenant nodes"))
+	}
+
+	if wrap.cluster != nil && wrap.cluster.mode == ClusterFollowMode {
+		if wrap.cluster.followName == "" || wrap.cluster.followInitURL == "" {
+			errs = append(errs, errors.New("`WithClusterFollow` requires both a cluster name and an init url"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfiguration, errors.Join(errs...))
+}
+
+// validatePlatform returns an error if a binary built for `binOS` and
+// `binArch` cannot be executed on a host running `hostOS` and `hostArch`.
+func validatePlatform(binOS, binArch, hostOS, hostArch string) error {
+	if binOS != hostOS {
+		return fmt.Errorf("binary for operating system `%s` cannot run on `%s`", binOS, hostOS)
+	}
+	if binArch == hostArch {
+		return nil
+	}
+	for _, arch := range compatibleArchs[hostOS][hostArch] {
+		if arch == binArch {
+			return nil
+		}
+	}
+	return fmt.Errorf("binary for architecture `%s` cannot run on `%s/%s`", binArch, hostOS, hostArch)
+}
@@ -0,0 +1,226 @@
+package ipfscliwrapper_test
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	ipfscliwrapper "github.com/bartmika/ipfs-cli-wrapper"
+)
+
+// TestNewWrapperInvalidConfiguration checks every contradicting or unusable
+// combination of options is rejected before anything is downloaded.
+func TestNewWrapperInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []ipfscliwrapper.Option
+		expected string
+	}{
+		{
+			name:     "ContinousAndForcedShutdown",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithContinousOperation(), ipfscliwrapper.WithForcedShutdownDaemonOnStartup()},
+			expected: "`WithContinousOperation` cannot be combined with `WithForcedShutdownDaemonOnStartup`",
+		},
+		{
+			name:     "OtherOperatingSystem",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithOverrideBinaryOsAndArch("plan9", runtime.GOARCH)},
+			expected: "binary for operating system `plan9` cannot run on `" + runtime.GOOS + "`",
+		},
+		{
+			name:     "UnsupportedArchitecture",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithOverrideBinaryOsAndArch(runtime.GOOS, "mips64")},
+			expected: "binary for architecture `mips64` cannot run on `" + runtime.GOOS + "/" + runtime.GOARCH + "`",
+		},
+		{
+			name:     "ZeroWarmup",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithOverrideDaemonInitialWarmupDuration(0)},
+			expected: "daemon warmup duration must be greater than zero",
+		},
+		{
+			name:     "DenylistWithoutURL",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDenylist("badbits.deny", "")},
+			expected: "`WithDenylist` requires both a filename and a url",
+		},
+		{
+			name:     "DenylistWithoutFilename",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDenylist("", "https://badbits.dwebops.pub/badbits.deny")},
+			expected: "`WithDenylist` requires both a filename and a url",
+		},
+		{
+			name:     "LocalDenylistExtension",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDenylistFile("/etc/ipfs/blocked.txt")},
+			expected: "denylist name must end with `.deny`",
+		},
+		{
+			name:     "LocalDenylistTwice",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDenylistFile("/etc/ipfs/blocked.deny"), ipfscliwrapper.WithDenylistFile("/srv/blocked.deny")},
+			expected: "denylist `blocked.deny` is configured more than once",
+		},
+		{
+			name:     "GatewayDisabledWithAddress",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithoutGateway(), ipfscliwrapper.WithGatewayAddress("/ip4/127.0.0.1/tcp/8081")},
+			expected: "`WithoutGateway` cannot be combined with `WithGatewayAddress` or `WithGatewayBindAddress`",
+		},
+		{
+			name:     "GatewayDisabledWithBindAddress",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithoutGateway(), ipfscliwrapper.WithGatewayBindAddress("0.0.0.0")},
+			expected: "`WithoutGateway` cannot be combined with `WithGatewayAddress` or `WithGatewayBindAddress`",
+		},
+		{
+			name:     "APIBindHostname",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithAPIBindAddress("localhost")},
+			expected: "api bind address must be an ip address, got `localhost`",
+		},
+		{
+			name:     "GatewayBindHostname",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithGatewayBindAddress("localhost")},
+			expected: "gateway bind address must be an ip address, got `localhost`",
+		},
+		{
+			name:     "StorageGCWatermarkAboveHundred",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithStorageGCWatermark(101)},
+			expected: "storage gc watermark must be a percentage between 0 and 100, got 101",
+		},
+		{
+			name:     "GCWatermarkNegative",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithGCWatermark(-1)},
+			expected: "gc watermark must be a percentage between 0 and 100, got -1",
+		},
+		{
+			name:     "EmptyAllowedCommand",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithAllowedCommands("cat", "")},
+			expected: "allowed and denied commands cannot be empty",
+		},
+		{
+			name:     "EmptyDeniedCommand",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDeniedCommands("")},
+			expected: "allowed and denied commands cannot be empty",
+		},
+		{
+			name:     "ZeroDownloadConcurrency",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDownloadConcurrency(0)},
+			expected: "download concurrency must be greater than zero, got 0",
+		},
+		{
+			name:     "ReadOnlyWithGCWatermark",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithReadOnly(), ipfscliwrapper.WithGCWatermark(80)},
+			expected: "`WithReadOnly` cannot be combined with `WithGCWatermark`",
+		},
+		{
+			name:     "ZeroGCWatermarkCheckInterval",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithGCWatermarkCheckInterval(0)},
+			expected: "gc watermark check interval must be greater than zero",
+		},
+		{
+			name:     "NegativeOutputRotation",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDaemonOutputRotation(-1, 3)},
+			expected: "daemon output rotation limits cannot be negative",
+		},
+		{
+			name:     "OutputFilesWithoutContinousOperation",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDaemonOutputFiles("daemon.out", "daemon.err")},
+			expected: "`WithDaemonOutputFiles` requires the `WithContinousOperation` option",
+		},
+		{
+			name:     "FileModeNotExecutable",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithFileMode(0644)},
+			expected: "file mode must be a permission allowing the owner to read and execute",
+		},
+		{
+			name:     "DirModeNotWritable",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithDirMode(0555)},
+			expected: "dir mode must be a permission allowing the owner full access",
+		},
+		{
+			name:     "ZeroLocalFetchTimeout",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithLocalFetchTimeout(0)},
+			expected: "local fetch timeout must be greater than zero",
+		},
+		{
+			name:     "ZeroIPNSRepublishInterval",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithIPNSRepublishInterval(0)},
+			expected: "ipns republish interval must be greater than zero",
+		},
+		{
+			name:     "ClusterFollowWithoutInitURL",
+			options:  []ipfscliwrapper.Option{ipfscliwrapper.WithClusterFollow("my-cluster", "")},
+			expected: "`WithClusterFollow` requires both a cluster name and an init url",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ipfscliwrapper.NewWrapper(test.options...)
+			if !errors.Is(err, ipfscliwrapper.ErrInvalidConfiguration) {
+				t.Fatalf("Expected ErrInvalidConfiguration, but got: %v", err)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected the error to contain %q, but got: %v", test.expected, err)
+			}
+		})
+	}
+}
+
+// TestNewWrapperInvalidConfigurationReportsAll checks every problem found is
+// described in the returned error rather than only the first one.
+func TestNewWrapperInvalidConfigurationReportsAll(t *testing.T) {
+	_, err := ipfscliwrapper.NewWrapper(
+		ipfscliwrapper.WithOverrideDaemonInitialWarmupDuration(0),
+		ipfscliwrapper.WithStorageGCWatermark(101),
+		ipfscliwrapper.WithIPNSRepublishInterval(0))
+	if !errors.Is(err, ipfscliwrapper.ErrInvalidConfiguration) {
+		t.Fatalf("Expected ErrInvalidConfiguration, but got: %v", err)
+	}
+	for _, expected := range []string{"daemon warmup duration", "storage gc watermark", "ipns republish interval"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected the error to contain %q, but got: %v", expected, err)
+		}
+	}
+}
+
+// TestTenantNodeWithClusterInvalid checks cluster support is rejected for
+// the nodes of tenants.
+func TestTenantNodeWithClusterInvalid(t *testing.T) {
+	manager := ipfscliwrapper.NewTenantManager(ipfscliwrapper.WithClusterService("secret"))
+	_, err := manager.CreateTenantNode("acme")
+	if !errors.Is(err, ipfscliwrapper.ErrInvalidConfiguration) {
+		t.Fatalf("Expected ErrInvalidConfiguration, but got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "cluster support cannot be enabled for tenant nodes") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// TestValidatePlatform checks which binaries are accepted on which hosts.
+func TestValidatePlatform(t *testing.T) {
+	tests := []struct {
+		binOS, binArch, hostOS, hostArch string
+		valid                            bool
+	}{
+		{"linux", "amd64", "linux", "amd64", true},
+		{"linux", "386", "linux", "amd64", true},
+		{"linux", "arm", "linux", "arm64", true},
+		{"darwin", "amd64", "darwin", "arm64", true},
+		{"windows", "amd64", "windows", "arm64", true},
+		{"windows", "386", "windows", "arm64", true},
+		{"freebsd", "386", "freebsd", "amd64", true},
+		{"openbsd", "386", "openbsd", "amd64", true},
+		{"linux", "amd64", "linux", "arm64", false},
+		{"linux", "arm64", "linux", "amd64", false},
+		{"darwin", "arm64", "darwin", "amd64", false},
+		{"freebsd", "arm", "freebsd", "amd64", false},
+		{"linux", "amd64", "darwin", "amd64", false},
+		{"windows", "amd64", "linux", "amd64", false},
+		{"plan9", "amd64", "plan9", "arm64", false},
+		{"solaris", "amd64", "linux", "amd64", false},
+	}
+	for _, test := range tests {
+		err := ipfscliwrapper.ValidatePlatform(test.binOS, test.binArch, test.hostOS, test.hostArch)
+		if test.valid && err != nil {
+			t.Errorf("Expected %s/%s to run on %s/%s, but got: %v", test.binOS, test.binArch, test.hostOS, test.hostArch, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("Expected %s/%s to be rejected on %s/%s, but got no error", test.binOS, test.binArch, test.hostOS, test.hostArch)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ipfscliwrapper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrCannotVerify is returned by `VerifyContent` when the data does not hash to
+// a dag-pb CID with the default settings of `ipfs add`. The CID depends on the
+// chunker and layout the content was added with, so the data may still match
+// if it was added with other settings.
+var ErrCannotVerify = errors.New("ipfs content cannot be verified against cid")
+
+func (wrap *ipfsCliWrapper) HashOnly(ctx context.Context, r io.Reader) (string, error) {
+	cmd := wrap.baseCommand(ctx, "add", "--only-hash", "--quiet", "--pin=false", "--cid-version=1")
+	cmd.Stdin = r
+	return wrap.runHashOnly(cmd)
+}
+
+func (wrap *ipfsCliWrapper) HashOnlyFile(ctx context.Context, filepath string) (string, error) {
+	cmd := wrap.baseCommand(ctx, "add", "--only-hash", "--quiet", "--pin=false", "--cid-version=1", "--", filepath)
+	return wrap.runHashOnly(cmd)
+}
+
+// runHashOnly executes an `ipfs add --only-hash` command and returns the
+// computed CID. The same CID version as `AddFile` is used so the results can
+// be compared. Kubo computes the CID without writing any block to the repo,
+// so this works whether or not the daemon is running.
+func (wrap *ipfsCliWrapper) runHashOnly(cmd *exec.Cmd) (string, error) {
+	// Only capture stdout, so warnings printed by kubo do not end up in the
+	// CID.
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := wrap.output(cmd)
+	if err != nil {
+		wrap.logger.Error("error computing cid with ipfs",
+			slog.Any("error", err),
+			wrap.outputAttr(stderr.Bytes()))
+		return "", wrap.commandError("compute cid with ipfs", err, stderr.Bytes())
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// VerifyContent recomputes the CID of the data with `ipfs add --only-hash`.
+// This has two limits:
+//
+//   - A raw CID is the hash of a single block, so the data is hashed as one
+//     raw leaf with `--raw-leaves --chunker=size-N`, N being its length. The
+//     result is exact.
+//   - A dag-pb CID also depends on how the data was chunked and laid out.
+//     Only the default settings of `ipfs add` are tried, so a CID built with
+//     another chunker or layout, by `ipfs add` or another tool, cannot be
+//     told from data that does not match, and `ErrCannotVerify` is returned.
+func (wrap *ipfsCliWrapper) VerifyContent(ctx context.Context, cid string, data []byte) (bool, error) {
+	// STEP 1: Look up how the CID was built so the hash can be recomputed
+	// the same way: its version, its codec and its multihash function.
+	output, err := wrap.combinedOutput(wrap.baseCommand(ctx, "cid", "format", "-f", "%v %c %h", "--", cid))
+	if err != nil {
+		return false, wrap.commandError(fmt.Sprintf("parse cid `%s`", cid), err, output)
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) != 3 {
+		return false, fmt.Errorf("failed to parse cid `%s` from output: %s", cid, string(output))
+	}
+	version, codec, hashFunc := strings.TrimPrefix(fields[0], "cidv"), fields[1], fields[2]
+
+	// STEP 2: Recompute the CID of the data without storing it.
+	args := []string{"add", "--only-hash", "--quiet", "--pin=false", "--cid-version=" + version, "--hash=" + hashFunc}
+	switch codec {
+	case "raw":
+		// A raw CID is the hash of a single block holding the data, so make
+		// sure the data does not get split into chunks.
+		if len(data) == 0 {
+			return false, fmt.Errorf("cannot verify empty data against raw cid `%s`", cid)
+		}
+		args = append(args, "--raw-leaves", "--chunker=size-"+strconv.Itoa(len(data)))
+	case "dag-pb":
+		// The DAG of a file depends on how it was chunked, the defaults of
+		// `ipfs add` are assumed.
+	default:
+		return false, fmt.Errorf("cannot verify data against cid `%s` with unsupported codec `%s`", cid, codec)
+	}
+
+	cmd := wrap.baseCommand(ctx, args...)
+	cmd.Stdin = bytes.NewReader(data)
+	output, err = wrap.combinedOutput(cmd)
+	if err != nil {
+		wrap.logger.Error("error hashing content with ipfs",
+			slog.String("cid", cid),
+			slog.Any("error", err),
+			wrap.outputAttr(output))
+		return false, wrap.commandError("hash content with ipfs", err, output)
+	}
+
+	// STEP 3: Compare in the same multibase, since CIDv1 may be written in
+	// different encodings.
+	computed := strings.TrimSpace(string(output))
+	if computed == cid {
+		return true, nil
+	}
+	if version != "0" {
+		normalized, err := wrap.combinedOutput(wrap.baseCommand(ctx, "cid", "format", "-v", version, "-b", "base32", "--", cid, computed))
+		if lines := strings.Fields(string(normalized)); err == nil && len(lines) == 2 && lines[0] == lines[1] {
+			return true, nil
+		}
+	}
+	if codec == "dag-pb" {
+		return false, fmt.Errorf("%w: `%s` was not added with the default settings of `ipfs add`, or does not hold the data", ErrCannotVerify, cid)
+	}
+	return false, nil
+}
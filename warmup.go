@@ -0,0 +1,199 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// startupStatsFileName is the name of the file, inside the repo directory, in
+// which the measured daemon startup durations are kept across runs.
+const startupStatsFileName = "ipfs-cli-wrapper-startup.json"
+
+// Tuning of the adaptive warmup.
+const (
+	// startupStatsMaxSamples is how many of the most recent startup
+	// durations are kept.
+	startupStatsMaxSamples = 10
+
+	// startupDeadlineFactor is the safety margin applied to the slowest
+	// recent startup to get the longest time waited for the daemon.
+	startupDeadlineFactor = 2
+
+	// readinessPollInterval is how often the daemon API is probed while
+	// waiting for the daemon to become ready.
+	readinessPollInterval = 100 * time.Millisecond
+
+	// maxStartupMeasurement is how long a slow startup keeps being measured
+	// in the background after the deadline elapsed.
+	maxStartupMeasurement = 5 * time.Minute
+
+	// repoReadyTimeout bounds how long the constructor waits for the repo
+	// to become usable after `ipfs init`.
+	repoReadyTimeout = 30 * time.Second
+)
+
+// startupStats holds the measured startup durations of the daemon.
+type startupStats struct {
+	Samples []time.Duration `json:"samples"`
+}
+
+func (wrap *ipfsCliWrapper) startupStatsPath() string {
+	return filepath.Join(wrap.repoPath(), startupStatsFileName)
+}
+
+func (wrap *ipfsCliWrapper) readStartupStats() startupStats {
+	var stats startupStats
+	if b, err := os.ReadFile(wrap.startupStatsPath()); err == nil {
+		_ = json.Unmarshal(b, &stats)
+	}
+	return stats
+}
+
+// recordStartupDuration persists the time the daemon took to become ready.
+func (wrap *ipfsCliWrapper) recordStartupDuration(d time.Duration) {
+	wrap.updateProvisionReport(func(report *ProvisionReport) {
+		if report.FirstReadyDuration == 0 {
+			report.FirstReadyDuration = d
+		}
+	})
+
+	stats := wrap.readStartupStats()
+	stats.Samples = append(stats.Samples, d)
+	if len(stats.Samples) > startupStatsMaxSamples {
+		stats.Samples = stats.Samples[len(stats.Samples)-startupStatsMaxSamples:]
+	}
+	b, err := json.Marshal(stats)
+	if err == nil {
+		err = os.WriteFile(wrap.startupStatsPath(), b, 0644)
+	}
+	if err != nil {
+		wrap.logger.Warn("failed recording daemon startup duration", "error", err)
+	}
+}
+
+// warmupDeadline returns how long to wait at most for the daemon to become
+// ready. Without measurements this is the configured warmup duration, after
+// that it grows to twice the slowest recent startup when the machine turned
+// out to be slower than the configured duration allows for.
+func (wrap *ipfsCliWrapper) warmupDeadline() time.Duration {
+	deadline := wrap.daemonInitialWarmupDuration
+	for _, sample := range wrap.readStartupStats().Samples {
+		if tuned := sample * startupDeadlineFactor; tuned > deadline {
+			deadline = tuned
+		}
+	}
+	return deadline
+}
+
+// waitUntilReady waits until the daemon API accepts connections, the daemon
+// exits, the deadline elapses or the context is done, and returns whether
+// the daemon became ready and whether it exited.
+func (wrap *ipfsCliWrapper) waitUntilReady(ctx context.Context, exited <-chan struct{}, deadline time.Duration) (bool, bool) {
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-exited:
+			return false, true
+		case <-timer.C:
+			return false, false
+		case <-ctx.Done():
+			return false, false
+		case <-ticker.C:
+			if wrap.isAPIReachable() {
+				return true, false
+			}
+		}
+	}
+}
+
+// waitForRepo polls an offline command until it succeeds, which means the
+// repo was initialized and its configuration can be read, or until the
+// context is done.
+func (wrap *ipfsCliWrapper) waitForRepo(ctx context.Context) error {
+	ticker := time.NewTicker(readinessPollInterval)
+	defer ticker.Stop()
+	for {
+		output, err := wrap.combinedOutput(wrap.baseCommand(ctx, "config", "show"))
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrCommandNotAllowed) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ipfs repo not usable: %v, output: %s", err, string(output))
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordStartupWhenReady keeps waiting for a daemon which was not ready by
+// the deadline and records its startup duration once it is.
+func (wrap *ipfsCliWrapper) recordStartupWhenReady(exited <-chan struct{}, startedAt time.Time) {
+	if ready, _ := wrap.waitUntilReady(context.Background(), exited, maxStartupMeasurement); ready {
+		wrap.recordStartupDuration(time.Since(startedAt))
+	}
+}
+
+// isAPIReachable returns true if a TCP connection to the daemon API can be
+// established.
+func (wrap *ipfsCliWrapper) isAPIReachable() bool {
+	hostPort, ok := wrap.apiHostPort()
+	if !ok {
+		return false
+	}
+	conn, err := net.DialTimeout("tcp", hostPort, readinessPollInterval)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// apiHostPort returns the "host:port" address of the daemon API. The address
+// is the one configured with `WithAPIAddress`, or else the one the daemon
+// wrote to the `api` file of the repo once its API server started listening.
+func (wrap *ipfsCliWrapper) apiHostPort() (string, bool) {
+	addr := wrap.apiAddr
+	if addr == "" {
+		b, err := os.ReadFile(filepath.Join(wrap.repoPath(), "api"))
+		if err != nil {
+			return "", false
+		}
+		addr = strings.TrimSpace(string(b))
+	}
+	return multiaddrToHostPort(addr)
+}
+
+// multiaddrToHostPort converts a TCP multiaddress such as
+// "/ip4/127.0.0.1/tcp/5001" to a "host:port" dial address.
+func multiaddrToHostPort(addr string) (string, bool) {
+	parts := strings.Split(addr, "/")
+	var host string
+	for i := 0; i+1 < len(parts); i++ {
+		switch parts[i] {
+		case "ip4", "ip6", "dns", "dns4", "dns6":
+			host = parts[i+1]
+		case "tcp":
+			if host == "" {
+				return "", false
+			}
+			if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+				host = "localhost"
+			}
+			return net.JoinHostPort(host, parts[i+1]), true
+		}
+	}
+	return "", false
+}
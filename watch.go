@@ -0,0 +1,137 @@
+package ipfscliwrapper
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is how long `WatchDir` waits for the directory to stop
+// changing before publishing it, so a burst of writes (e.g. a static site
+// build) results in a single sync.
+const DefaultWatchDebounce = 500 * time.Millisecond
+
+// WatchDirUpdateHook is called by `WatchDir` every time the root CID of the
+// watched directory changes.
+type WatchDirUpdateHook func(rootCID string)
+
+func (wrap *ipfsCliWrapper) WatchDir(ctx context.Context, localDir string, mfsPath string, onUpdate WatchDirUpdateHook) error {
+	if err := wrap.checkWritable(); err != nil {
+		return err
+	}
+
+	localDir, err := filepath.Abs(localDir)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Watch before the first sync so no change made during it gets lost.
+	if err := addWatchTree(watcher, localDir); err != nil {
+		return err
+	}
+
+	var lastCID string
+	publish := func() {
+		cid, err := wrap.SyncDir(ctx, localDir, mfsPath)
+		if err != nil {
+			if ctx.Err() == nil {
+				wrap.logger.Warn("failed syncing watched directory",
+					slog.String("local_dir", localDir),
+					slog.String("mfs_path", mfsPath),
+					slog.Any("error", err))
+			}
+			return
+		}
+		if cid == lastCID {
+			return
+		}
+		lastCID = cid
+
+		if wrap.watchIPNSKey != "" {
+			value := "/ipfs/" + cid
+			if err := wrap.publishIPNSName(ctx, wrap.watchIPNSKey, value); err != nil {
+				wrap.logger.Warn("failed publishing watched directory to ipns",
+					slog.String("key", wrap.watchIPNSKey),
+					slog.String("value", value),
+					slog.Any("error", err))
+			}
+			// Keep the record alive between changes.
+			wrap.ipnsRepublisher.track(wrap.watchIPNSKey, value)
+		}
+		if onUpdate != nil {
+			onUpdate(cid)
+		}
+	}
+	publish()
+
+	debounce := time.NewTimer(wrap.watchDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("file watcher closed unexpectedly")
+			}
+			// Watches are not recursive, so new directories need their
+			// own watch.
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := addWatchTree(watcher, event.Name); err != nil {
+						wrap.logger.Warn("failed watching directory",
+							slog.String("path", event.Name),
+							slog.Any("error", err))
+					}
+				}
+			}
+			debounce.Reset(wrap.watchDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("file watcher closed unexpectedly")
+			}
+			// Most likely an overflow of the event queue; sync anyway since
+			// changes may have been missed.
+			wrap.logger.Warn("file watcher error",
+				slog.String("local_dir", localDir),
+				slog.Any("error", err))
+			debounce.Reset(wrap.watchDebounce)
+
+		case <-debounce.C:
+			publish()
+		}
+	}
+}
+
+// addWatchTree watches the directory and all of its subdirectories.
+func addWatchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// The directory may have been removed again in the meantime.
+			if errors.Is(err, fs.ErrNotExist) && path != root {
+				return nil
+			}
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		return watcher.Add(path)
+	})
+}